@@ -6,7 +6,10 @@ CREATE TABLE public.chat (
     chat_type_id integer NOT NULL,
     avatar_path text,
     chat_link_name text,
-    id uuid NOT NULL
+    id uuid NOT NULL,
+    is_history_visible boolean DEFAULT true NOT NULL,
+    message_ttl integer,
+    CONSTRAINT chat_message_ttl_check CHECK (message_ttl > 0)
 );
 
 
@@ -46,7 +49,10 @@ CREATE TABLE public.chat_user (
     id uuid DEFAULT gen_random_uuid() NOT NULL,
     user_role_id integer NOT NULL,
     chat_id uuid NOT NULL,
-    user_id uuid NOT NULL
+    user_id uuid NOT NULL,
+    last_read_message_id uuid,
+    last_read_at timestamp with time zone DEFAULT now() NOT NULL,
+    muted boolean DEFAULT false NOT NULL
 );
 
 
@@ -77,7 +83,23 @@ CREATE TABLE public.message (
     message text,
     sent_at timestamp with time zone NOT NULL,
     is_redacted boolean DEFAULT false NOT NULL,
-    sticker_path text
+    sticker_path text,
+    reply_to_id uuid,
+    forwarded_from_author_id uuid,
+    forwarded_from_chat_id uuid,
+    edited_at timestamp with time zone,
+    editor_id uuid,
+    link_preview_url text,
+    entities jsonb DEFAULT '[]'::jsonb NOT NULL,
+    expires_at timestamp with time zone,
+    client_nonce text,
+    views integer DEFAULT 0 NOT NULL,
+    views_sent integer DEFAULT 0 NOT NULL,
+    imported_from_name text,
+    search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian'::regconfig, COALESCE(message, ''::text)), 'A'::"char") ||
+        setweight(to_tsvector('simple'::regconfig, COALESCE(message, ''::text)), 'B'::"char")
+    ) STORED
 );
 
 
@@ -90,12 +112,284 @@ ALTER TABLE public.message OWNER TO postgres;
 CREATE TABLE public.message_payload (
     payload_path text NOT NULL,
     id uuid NOT NULL,
-    message_id uuid NOT NULL
+    message_id uuid NOT NULL,
+    filename text NOT NULL,
+    size bigint DEFAULT 0 NOT NULL,
+    "position" integer DEFAULT 0 NOT NULL
 );
 
 
 ALTER TABLE public.message_payload OWNER TO postgres;
 
+--
+-- Name: sticker_pack; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.sticker_pack (
+    id uuid DEFAULT gen_random_uuid() NOT NULL,
+    name text NOT NULL,
+    preview_path text
+);
+
+
+ALTER TABLE public.sticker_pack OWNER TO postgres;
+
+--
+-- Name: sticker; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.sticker (
+    id uuid DEFAULT gen_random_uuid() NOT NULL,
+    pack_id uuid NOT NULL,
+    sticker_path text NOT NULL,
+    "position" integer DEFAULT 0 NOT NULL
+);
+
+
+ALTER TABLE public.sticker OWNER TO postgres;
+
+--
+-- Name: message_reaction; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.message_reaction (
+    message_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    emoji text NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+ALTER TABLE public.message_reaction OWNER TO postgres;
+
+--
+-- Name: message_hidden; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.message_hidden (
+    user_id uuid NOT NULL,
+    message_id uuid NOT NULL,
+    hidden_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+ALTER TABLE public.message_hidden OWNER TO postgres;
+
+--
+-- Name: message_view; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.message_view (
+    message_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    viewed_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+ALTER TABLE public.message_view OWNER TO postgres;
+
+--
+-- Name: message_mention; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.message_mention (
+    message_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+ALTER TABLE public.message_mention OWNER TO postgres;
+
+--
+-- Name: chat_allowed_reaction; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.chat_allowed_reaction (
+    chat_id uuid NOT NULL,
+    emoji text NOT NULL
+);
+
+
+ALTER TABLE public.chat_allowed_reaction OWNER TO postgres;
+
+--
+-- Name: chat_pinned_message; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.chat_pinned_message (
+    chat_id uuid NOT NULL,
+    message_id uuid NOT NULL,
+    pinned_by uuid,
+    pinned_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+ALTER TABLE public.chat_pinned_message OWNER TO postgres;
+
+--
+-- Name: chat_draft; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.chat_draft (
+    user_id uuid NOT NULL,
+    chat_id uuid NOT NULL,
+    text text NOT NULL,
+    reply_to_id uuid,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+ALTER TABLE public.chat_draft OWNER TO postgres;
+
+--
+-- Name: scheduled_message; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.scheduled_message (
+    id uuid NOT NULL,
+    chat_id uuid NOT NULL,
+    author_id uuid NOT NULL,
+    message text DEFAULT ''::text NOT NULL,
+    sticker_path text,
+    reply_to_id uuid,
+    payloads jsonb DEFAULT '[]'::jsonb NOT NULL,
+    entities jsonb DEFAULT '[]'::jsonb NOT NULL,
+    send_at timestamp with time zone NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+ALTER TABLE public.scheduled_message OWNER TO postgres;
+
+--
+-- Name: chat_export; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.chat_export (
+    id uuid NOT NULL,
+    chat_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    format text NOT NULL,
+    from_time timestamp with time zone,
+    to_time timestamp with time zone,
+    status text DEFAULT 'pending'::text NOT NULL,
+    file_path text,
+    error text,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    started_at timestamp with time zone,
+    finished_at timestamp with time zone,
+    CONSTRAINT chat_export_format_check CHECK ((format = ANY (ARRAY['json'::text, 'html'::text, 'csv'::text]))),
+    CONSTRAINT chat_export_status_check CHECK ((status = ANY (ARRAY['pending'::text, 'running'::text, 'done'::text, 'failed'::text])))
+);
+
+
+ALTER TABLE public.chat_export OWNER TO postgres;
+
+--
+-- Name: chat_import; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.chat_import (
+    id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    chat_id uuid,
+    status text DEFAULT 'pending'::text NOT NULL,
+    source_path text NOT NULL,
+    media_path text,
+    authors jsonb DEFAULT '{}'::jsonb NOT NULL,
+    total integer DEFAULT 0 NOT NULL,
+    processed integer DEFAULT 0 NOT NULL,
+    imported integer DEFAULT 0 NOT NULL,
+    failed integer DEFAULT 0 NOT NULL,
+    errors jsonb DEFAULT '[]'::jsonb NOT NULL,
+    error text,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    started_at timestamp with time zone,
+    finished_at timestamp with time zone,
+    CONSTRAINT chat_import_status_check CHECK ((status = ANY (ARRAY['pending'::text, 'running'::text, 'done'::text, 'failed'::text])))
+);
+
+
+ALTER TABLE public.chat_import OWNER TO postgres;
+
+--
+-- Name: message_revision; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.message_revision (
+    id uuid DEFAULT gen_random_uuid() NOT NULL,
+    message_id uuid NOT NULL,
+    message text DEFAULT ''::text NOT NULL,
+    entities jsonb DEFAULT '[]'::jsonb NOT NULL,
+    editor_id uuid,
+    edited_at timestamp with time zone NOT NULL
+);
+
+
+ALTER TABLE public.message_revision OWNER TO postgres;
+
+--
+-- Name: link_preview; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.link_preview (
+    url text NOT NULL,
+    title text DEFAULT ''::text NOT NULL,
+    description text DEFAULT ''::text NOT NULL,
+    image_url text DEFAULT ''::text NOT NULL,
+    site_name text DEFAULT ''::text NOT NULL,
+    fetched_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+ALTER TABLE public.link_preview OWNER TO postgres;
+
+--
+-- Name: poll; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.poll (
+    message_id uuid NOT NULL,
+    question text NOT NULL,
+    is_multiple boolean DEFAULT false NOT NULL,
+    is_anonymous boolean DEFAULT true NOT NULL,
+    is_quiz boolean DEFAULT false NOT NULL,
+    correct_option integer,
+    close_at timestamp with time zone,
+    CONSTRAINT poll_quiz_check CHECK (((NOT is_quiz) OR ((correct_option IS NOT NULL) AND (NOT is_multiple))))
+);
+
+
+ALTER TABLE public.poll OWNER TO postgres;
+
+--
+-- Name: poll_option; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.poll_option (
+    message_id uuid NOT NULL,
+    "position" integer NOT NULL,
+    text text NOT NULL
+);
+
+
+ALTER TABLE public.poll_option OWNER TO postgres;
+
+--
+-- Name: poll_vote; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.poll_vote (
+    message_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    "position" integer NOT NULL,
+    voted_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+ALTER TABLE public.poll_vote OWNER TO postgres;
+
 --
 -- Name: user; Type: TABLE; Schema: public; Owner: postgres
 --
@@ -108,7 +402,8 @@ CREATE TABLE public."user" (
     bio text,
     birthdate timestamp with time zone,
     avatar_path text,
-    id uuid NOT NULL
+    id uuid NOT NULL,
+    is_imported boolean DEFAULT false NOT NULL
 );
 
 
@@ -236,6 +531,234 @@ ALTER TABLE ONLY public.message
     ADD CONSTRAINT message_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: link_preview link_preview_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.link_preview
+    ADD CONSTRAINT link_preview_pkey PRIMARY KEY (url);
+
+
+--
+-- Name: poll poll_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.poll
+    ADD CONSTRAINT poll_pkey PRIMARY KEY (message_id);
+
+
+--
+-- Name: poll_option poll_option_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.poll_option
+    ADD CONSTRAINT poll_option_pkey PRIMARY KEY (message_id, "position");
+
+
+--
+-- Name: poll_vote poll_vote_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.poll_vote
+    ADD CONSTRAINT poll_vote_pkey PRIMARY KEY (message_id, user_id, "position");
+
+
+--
+-- Name: sticker_pack sticker_pack_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.sticker_pack
+    ADD CONSTRAINT sticker_pack_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: sticker sticker_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.sticker
+    ADD CONSTRAINT sticker_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: sticker uniq_sticker_path; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.sticker
+    ADD CONSTRAINT uniq_sticker_path UNIQUE (sticker_path);
+
+
+--
+-- Name: message_reaction message_reaction_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.message_reaction
+    ADD CONSTRAINT message_reaction_pkey PRIMARY KEY (message_id, user_id, emoji);
+
+
+--
+-- Name: message_hidden message_hidden_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.message_hidden
+    ADD CONSTRAINT message_hidden_pkey PRIMARY KEY (user_id, message_id);
+
+
+--
+-- Name: message_view message_view_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.message_view
+    ADD CONSTRAINT message_view_pkey PRIMARY KEY (message_id, user_id);
+
+
+--
+-- Name: message_mention message_mention_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.message_mention
+    ADD CONSTRAINT message_mention_pkey PRIMARY KEY (message_id, user_id);
+
+
+--
+-- Name: chat_allowed_reaction chat_allowed_reaction_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.chat_allowed_reaction
+    ADD CONSTRAINT chat_allowed_reaction_pkey PRIMARY KEY (chat_id, emoji);
+
+
+--
+-- Name: chat_pinned_message chat_pinned_message_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.chat_pinned_message
+    ADD CONSTRAINT chat_pinned_message_pkey PRIMARY KEY (chat_id, message_id);
+
+
+--
+-- Name: chat_draft chat_draft_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.chat_draft
+    ADD CONSTRAINT chat_draft_pkey PRIMARY KEY (user_id, chat_id);
+
+
+--
+-- Name: scheduled_message scheduled_message_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.scheduled_message
+    ADD CONSTRAINT scheduled_message_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: scheduled_message_send_at_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE INDEX scheduled_message_send_at_idx ON public.scheduled_message USING btree (send_at);
+
+
+--
+-- Name: chat_export chat_export_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.chat_export
+    ADD CONSTRAINT chat_export_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: chat_export_status_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE INDEX chat_export_status_idx ON public.chat_export USING btree (status, created_at);
+
+
+--
+-- Name: chat_import chat_import_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.chat_import
+    ADD CONSTRAINT chat_import_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: chat_import_status_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE INDEX chat_import_status_idx ON public.chat_import USING btree (status, created_at);
+
+
+--
+-- Name: message_chat_id_sent_at_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE INDEX message_chat_id_sent_at_idx ON public.message USING btree (chat_id, sent_at, id);
+
+
+--
+-- Name: message_search_vector_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE INDEX message_search_vector_idx ON public.message USING gin (search_vector);
+
+
+--
+-- Name: message_expires_at_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE INDEX message_expires_at_idx ON public.message USING btree (expires_at) WHERE (expires_at IS NOT NULL);
+
+
+--
+-- Name: message_views_changed_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE INDEX message_views_changed_idx ON public.message USING btree (id) WHERE (views <> views_sent);
+
+
+--
+-- Name: message_branch_id_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE INDEX message_branch_id_idx ON public.message USING btree (branch_id) WHERE (branch_id IS NOT NULL);
+
+
+--
+-- Name: message_client_nonce_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE UNIQUE INDEX message_client_nonce_idx ON public.message USING btree (author_id, chat_id, client_nonce) WHERE (client_nonce IS NOT NULL);
+
+
+--
+-- Name: message_payload_payload_path_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE INDEX message_payload_payload_path_idx ON public.message_payload USING btree (payload_path);
+
+
+--
+-- Name: message_revision message_revision_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.message_revision
+    ADD CONSTRAINT message_revision_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: message_revision_message_id_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE INDEX message_revision_message_id_idx ON public.message_revision USING btree (message_id, edited_at);
+
+
+--
+-- Name: message_mention_user_id_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE INDEX message_mention_user_id_idx ON public.message_mention USING btree (user_id);
+
+
 --
 -- Name: user uniq_username; Type: CONSTRAINT; Schema: public; Owner: postgres
 --
@@ -299,7 +822,286 @@ ALTER TABLE ONLY public.contact
 --
 
 ALTER TABLE ONLY public.message_payload
-    ADD CONSTRAINT message_id_fk_message_payload_id_pk_messages FOREIGN KEY (message_id) REFERENCES public.message(id);
+    ADD CONSTRAINT message_id_fk_message_payload_id_pk_messages FOREIGN KEY (message_id) REFERENCES public.message(id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: sticker pack_id_fk_sticker_id_pk_sticker_pack; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.sticker
+    ADD CONSTRAINT pack_id_fk_sticker_id_pk_sticker_pack FOREIGN KEY (pack_id) REFERENCES public.sticker_pack(id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: message sticker_path_fk_message_sticker_path_pk_sticker; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.message
+    ADD CONSTRAINT sticker_path_fk_message_sticker_path_pk_sticker FOREIGN KEY (sticker_path) REFERENCES public.sticker(sticker_path);
+
+
+--
+-- Name: message link_preview_url_fk_message_url_pk_link_preview; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.message
+    ADD CONSTRAINT link_preview_url_fk_message_url_pk_link_preview FOREIGN KEY (link_preview_url) REFERENCES public.link_preview(url)
+    ON DELETE SET NULL;
+
+
+--
+-- Name: poll message_id_fk_poll_id_pk_message; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.poll
+    ADD CONSTRAINT message_id_fk_poll_id_pk_message FOREIGN KEY (message_id) REFERENCES public.message(id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: poll_option message_id_fk_poll_option_message_id_pk_poll; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.poll_option
+    ADD CONSTRAINT message_id_fk_poll_option_message_id_pk_poll FOREIGN KEY (message_id) REFERENCES public.poll(message_id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: poll_vote option_fk_poll_vote_pk_poll_option; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.poll_vote
+    ADD CONSTRAINT option_fk_poll_vote_pk_poll_option FOREIGN KEY (message_id, "position") REFERENCES public.poll_option(message_id, "position")
+    ON DELETE CASCADE;
+
+
+--
+-- Name: poll_vote user_id_fk_poll_vote_id_pk_user; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.poll_vote
+    ADD CONSTRAINT user_id_fk_poll_vote_id_pk_user FOREIGN KEY (user_id) REFERENCES public."user"(id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: message_reaction message_id_fk_message_reaction_id_pk_message; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.message_reaction
+    ADD CONSTRAINT message_id_fk_message_reaction_id_pk_message FOREIGN KEY (message_id) REFERENCES public.message(id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: message_reaction user_id_fk_message_reaction_id_pk_user; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.message_reaction
+    ADD CONSTRAINT user_id_fk_message_reaction_id_pk_user FOREIGN KEY (user_id) REFERENCES public."user"(id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: message_hidden message_id_fk_message_hidden_id_pk_message; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.message_hidden
+    ADD CONSTRAINT message_id_fk_message_hidden_id_pk_message FOREIGN KEY (message_id) REFERENCES public.message(id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: message_hidden user_id_fk_message_hidden_id_pk_user; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.message_hidden
+    ADD CONSTRAINT user_id_fk_message_hidden_id_pk_user FOREIGN KEY (user_id) REFERENCES public."user"(id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: message_view message_id_fk_message_view_id_pk_message; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.message_view
+    ADD CONSTRAINT message_id_fk_message_view_id_pk_message FOREIGN KEY (message_id) REFERENCES public.message(id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: message_view user_id_fk_message_view_id_pk_user; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.message_view
+    ADD CONSTRAINT user_id_fk_message_view_id_pk_user FOREIGN KEY (user_id) REFERENCES public."user"(id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: message_mention message_id_fk_message_mention_id_pk_message; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.message_mention
+    ADD CONSTRAINT message_id_fk_message_mention_id_pk_message FOREIGN KEY (message_id) REFERENCES public.message(id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: message_mention user_id_fk_message_mention_id_pk_user; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.message_mention
+    ADD CONSTRAINT user_id_fk_message_mention_id_pk_user FOREIGN KEY (user_id) REFERENCES public."user"(id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: chat_allowed_reaction chat_id_fk_chat_allowed_reaction_id_pk_chat; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.chat_allowed_reaction
+    ADD CONSTRAINT chat_id_fk_chat_allowed_reaction_id_pk_chat FOREIGN KEY (chat_id) REFERENCES public.chat(id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: chat_pinned_message chat_id_fk_chat_pinned_message_id_pk_chat; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.chat_pinned_message
+    ADD CONSTRAINT chat_id_fk_chat_pinned_message_id_pk_chat FOREIGN KEY (chat_id) REFERENCES public.chat(id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: chat_pinned_message message_id_fk_chat_pinned_message_id_pk_message; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.chat_pinned_message
+    ADD CONSTRAINT message_id_fk_chat_pinned_message_id_pk_message FOREIGN KEY (message_id) REFERENCES public.message(id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: chat_pinned_message pinned_by_fk_chat_pinned_message_id_pk_user; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.chat_pinned_message
+    ADD CONSTRAINT pinned_by_fk_chat_pinned_message_id_pk_user FOREIGN KEY (pinned_by) REFERENCES public."user"(id)
+    ON DELETE SET NULL;
+
+
+--
+-- Name: chat_draft user_id_fk_chat_draft_id_pk_user; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.chat_draft
+    ADD CONSTRAINT user_id_fk_chat_draft_id_pk_user FOREIGN KEY (user_id) REFERENCES public."user"(id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: chat_draft chat_id_fk_chat_draft_id_pk_chat; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.chat_draft
+    ADD CONSTRAINT chat_id_fk_chat_draft_id_pk_chat FOREIGN KEY (chat_id) REFERENCES public.chat(id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: chat_draft reply_to_id_fk_chat_draft_id_pk_message; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.chat_draft
+    ADD CONSTRAINT reply_to_id_fk_chat_draft_id_pk_message FOREIGN KEY (reply_to_id) REFERENCES public.message(id)
+    ON DELETE SET NULL;
+
+
+--
+-- Name: scheduled_message chat_id_fk_scheduled_message_id_pk_chat; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.scheduled_message
+    ADD CONSTRAINT chat_id_fk_scheduled_message_id_pk_chat FOREIGN KEY (chat_id) REFERENCES public.chat(id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: scheduled_message author_id_fk_scheduled_message_id_pk_user; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.scheduled_message
+    ADD CONSTRAINT author_id_fk_scheduled_message_id_pk_user FOREIGN KEY (author_id) REFERENCES public."user"(id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: chat_export chat_id_fk_chat_export_id_pk_chat; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.chat_export
+    ADD CONSTRAINT chat_id_fk_chat_export_id_pk_chat FOREIGN KEY (chat_id) REFERENCES public.chat(id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: chat_export user_id_fk_chat_export_id_pk_user; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.chat_export
+    ADD CONSTRAINT user_id_fk_chat_export_id_pk_user FOREIGN KEY (user_id) REFERENCES public."user"(id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: chat_import user_id_fk_chat_import_id_pk_user; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.chat_import
+    ADD CONSTRAINT user_id_fk_chat_import_id_pk_user FOREIGN KEY (user_id) REFERENCES public."user"(id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: chat_import chat_id_fk_chat_import_id_pk_chat; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.chat_import
+    ADD CONSTRAINT chat_id_fk_chat_import_id_pk_chat FOREIGN KEY (chat_id) REFERENCES public.chat(id)
+    ON DELETE SET NULL;
+
+
+--
+-- Name: message_revision message_id_fk_message_revision_id_pk_message; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.message_revision
+    ADD CONSTRAINT message_id_fk_message_revision_id_pk_message FOREIGN KEY (message_id) REFERENCES public.message(id)
+    ON DELETE CASCADE;
+
+
+--
+-- Name: message_revision editor_id_fk_message_revision_id_pk_user; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.message_revision
+    ADD CONSTRAINT editor_id_fk_message_revision_id_pk_user FOREIGN KEY (editor_id) REFERENCES public."user"(id)
+    ON DELETE SET NULL;
+
+
+--
+-- Name: message editor_id_fk_message_id_pk_user; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.message
+    ADD CONSTRAINT editor_id_fk_message_id_pk_user FOREIGN KEY (editor_id) REFERENCES public."user"(id)
+    ON DELETE SET NULL;
 
 
 --
@@ -14,6 +14,10 @@ const (
 	DeleteUsersFromChat = "delUsers"
 	AddNewUsersInChat   = "addUsers"
 
+	// закрепление сообщений
+	PinMessage   = "pinMessage"
+	UnpinMessage = "unpinMessage"
+
 	// пользователь стал онлайн
 	AddWebcosketUser = "addWebSocketUser"
 )
@@ -22,11 +26,35 @@ const (
 	DeleteMessage = "deleteMessage"
 	NewMessage    = "newMessage"
 	UpdateMessage = "updateMessage"
+	// изменились реакции на сообщении
+	ReactionUpdated = "reactionUpdated"
+	// участник прочитал сообщения чата до payload.messageId
+	MessagesRead = "messagesRead"
+	// пользователь удалил сообщение только у себя
+	MessageHidden = "messageHidden"
+	// пользователя упомянули в сообщении. Приходит только упомянутым
+	Mention = "mention"
+	// изменились результаты опроса
+	PollUpdated = "pollUpdated"
+	// пользователь изменил черновик чата на другом устройстве. Приходит только ему
+	DraftUpdated = "draftUpdated"
+	// сообщения чата удалены одним запросом, id в payload.messageIds
+	DeleteMessages = "deleteMessages"
+	// пользователь удалил у себя сразу несколько сообщений чата. Приходит только ему
+	MessagesHidden = "messagesHidden"
+	// изменилось количество просмотров постов канала, новые значения в payload.views
+	ViewsUpdated = "viewsUpdated"
+	// в ветке сообщения появился ответ. Приходит в родительский чат, payload - сообщение с веткой
+	BranchUpdated = "branchUpdated"
+	// сообщение с nonce сохранено, payload - сообщение вместе с nonce. Приходит только автору
+	MessageSent = "messageSent"
 )
 
 type MessageEvent struct {
 	Action  string  `json:"action"`
 	Message Message `json:"payload"`
+	// если задано, то событие получат только эти участники чата
+	Recipients []uuid.UUID `json:"recipients,omitempty"`
 }
 type Message struct {
 	MessageId  uuid.UUID  `json:"messageId" example:"1" valid:"-"`
@@ -36,6 +64,147 @@ type Message struct {
 	SentAt     time.Time  `json:"datetime" example:"2024-04-13T08:30:00Z" valid:"-"`
 	ChatId     uuid.UUID  `json:"chatId" valid:"-"`
 	IsRedacted bool       `json:"isRedacted" valid:"-"`
+	EditedAt   *time.Time `json:"editedAt" valid:"-"`
+	Payloads   []Payload  `json:"payloads" valid:"-"`
+	Sticker    *string    `json:"sticker" valid:"-"`
+
+	ReplyTo      *uuid.UUID    `json:"replyTo" valid:"-"`
+	ReplyPreview *ReplyPreview `json:"replyPreview" valid:"-"`
+
+	ForwardedFrom *ForwardedFrom `json:"forwardedFrom" valid:"-"`
+
+	Reactions      []Reaction      `json:"reactions" valid:"-"`
+	ReactionUpdate *ReactionUpdate `json:"reactionUpdate,omitempty" valid:"-"`
+
+	ReadBy *ReadReceipt `json:"readBy,omitempty" valid:"-"`
+	// новый черновик для draftUpdated, null - черновик удален
+	Draft *Draft `json:"draft,omitempty" valid:"-"`
+
+	LinkPreview *LinkPreview    `json:"linkPreview" valid:"-"`
+	Entities    []MessageEntity `json:"entities" valid:"-"`
+	Poll        *Poll           `json:"poll" valid:"-"`
+	ExpiresAt   *time.Time      `json:"expiresAt" valid:"-"`
+	// имя отправителя в Telegram у импортированных сообщений
+	ImportedFrom *string `json:"importedFrom,omitempty" valid:"-"`
+	// nonce отправителя, только в messageSent
+	Nonce *string `json:"nonce,omitempty" valid:"-"`
+	// id сообщений для deleteMessages и messagesHidden
+	MessageIds []uuid.UUID `json:"messageIds,omitempty" valid:"-"`
+	// просмотры постов для viewsUpdated
+	Views []MessageViews `json:"views,omitempty" valid:"-"`
+	// ответы в ветке сообщения
+	Branch *BranchSummary `json:"branch,omitempty" valid:"-"`
+}
+
+// BranchSummary количество ответов и последний ответ в ветке
+type BranchSummary struct {
+	ReplyCount int          `json:"replyCount"`
+	LastReply  *BranchReply `json:"lastReply"`
+}
+
+// BranchReply превью последнего ответа в ветке
+type BranchReply struct {
+	MessageId  uuid.UUID `json:"messageId"`
+	AuthorID   uuid.UUID `json:"authorID"`
+	AuthorName *string   `json:"authorName"`
+	Text       string    `json:"text"`
+	Sticker    *string   `json:"sticker"`
+	SentAt     time.Time `json:"datetime"`
+}
+
+// MessageViews количество просмотров поста канала
+type MessageViews struct {
+	MessageId uuid.UUID `json:"messageId"`
+	Views     int       `json:"views"`
+}
+
+// Poll результаты опроса без отметок конкретного пользователя
+type Poll struct {
+	Question    string       `json:"question"`
+	Options     []PollOption `json:"options"`
+	IsMultiple  bool         `json:"isMultiple"`
+	IsAnonymous bool         `json:"isAnonymous"`
+	IsQuiz      bool         `json:"isQuiz"`
+	// правильный ответ викторины, только после закрытия
+	CorrectOption *int       `json:"correctOption"`
+	CloseAt       *time.Time `json:"closeAt"`
+	IsClosed      bool       `json:"isClosed"`
+	TotalVoters   int        `json:"totalVoters"`
+}
+
+type PollOption struct {
+	Text   string      `json:"text"`
+	Votes  int         `json:"votes"`
+	Voters []uuid.UUID `json:"voters,omitempty"`
+}
+
+// MessageEntity форматирование фрагмента текста, смещения в кодовых единицах UTF-16
+type MessageEntity struct {
+	Type     string     `json:"type"`
+	Offset   int        `json:"offset"`
+	Length   int        `json:"length"`
+	URL      *string    `json:"url,omitempty"`
+	UserID   *uuid.UUID `json:"userId,omitempty"`
+	Language *string    `json:"language,omitempty"`
+}
+
+// LinkPreview метаданные страницы по первой ссылке сообщения
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Image       string `json:"image"`
+	SiteName    string `json:"siteName"`
+}
+
+// ReadReceipt кто и когда прочитал сообщения
+type ReadReceipt struct {
+	UserId uuid.UUID `json:"userId"`
+	ReadAt time.Time `json:"readAt"`
+}
+
+// Draft черновик пользователя в чате
+type Draft struct {
+	Text      string     `json:"text"`
+	ReplyTo   *uuid.UUID `json:"replyTo"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// Reaction количество реакций одного вида. Отметку "поставил я" клиент хранит сам
+type Reaction struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+}
+
+// ReactionUpdate кто и какую реакцию поставил или убрал
+type ReactionUpdate struct {
+	UserId  uuid.UUID `json:"userId"`
+	Emoji   string    `json:"emoji"`
+	IsAdded bool      `json:"isAdded"`
+}
+
+type ForwardedFrom struct {
+	AuthorID   uuid.UUID `json:"authorID"`
+	AuthorName *string   `json:"authorName"`
+	ChatId     uuid.UUID `json:"chatId"`
+	ChatName   *string   `json:"chatName"`
+	ChatType   *string   `json:"chatType"`
+}
+
+type ReplyPreview struct {
+	MessageId  uuid.UUID  `json:"messageId"`
+	AuthorID   *uuid.UUID `json:"authorID"`
+	AuthorName *string    `json:"authorName"`
+	Text       string     `json:"text"`
+	Sticker    *string    `json:"sticker"`
+	IsRedacted bool       `json:"isRedacted"`
+	IsDeleted  bool       `json:"isDeleted"`
+}
+
+type Payload struct {
+	URL      string `json:"url"`
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
 }
 
 func SerializeMessageEvent(event MessageEvent) ([]byte, error) {
@@ -52,9 +221,10 @@ func DeserializeMessageEvent(data []byte) (MessageEvent, error) {
 }
 
 type Event struct {
-	Action string      `json:"action"`
-	ChatId uuid.UUID   `json:"chatId"`
-	Users  []uuid.UUID `json:"users"`
+	Action    string      `json:"action"`
+	ChatId    uuid.UUID   `json:"chatId"`
+	Users     []uuid.UUID `json:"users"`
+	MessageId *uuid.UUID  `json:"messageId,omitempty"`
 }
 
 func SerializeEvent(event Event) ([]byte, error) {
@@ -5,6 +5,11 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	_ "github.com/go-park-mail-ru/2024_2_EaglesDesigner/docs"
 	"github.com/google/uuid"
@@ -29,7 +34,11 @@ import (
 	profileDelivery "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/profile/delivery"
 	profileRepo "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/profile/repository"
 	profileUC "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/profile/usecase"
+	stickersDelivery "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/stickers/delivery"
+	stickersRepo "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/stickers/repository"
+	stickersUC "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/stickers/usecase"
 	uploadsDelivery "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/uploads/delivery"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/utils/linkpreview"
 	authv1 "github.com/go-park-mail-ru/2024_2_EaglesDesigner/protos/gen/go/authv1"
 
 	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
@@ -127,16 +136,22 @@ func main() {
 
 	chatRepo, _ := chatRepository.NewChatRepository(pool)
 
-	messageUsecase := messageUsecase.NewMessageUsecaseImpl(messageRepo, chatRepo, ch)
-
 	chatService := chatService.NewChatUsecase(chatRepo, messageRepo, ch)
 	chat := chatController.NewChatDelivery(chatService)
 
+	messageUsecase := messageUsecase.NewMessageUsecaseImpl(messageRepo, chatRepo, chatService, ch, messageDeleteWindow,
+		linkpreview.NewFetcher(linkpreview.NewSafeClient(linkPreviewTimeout), linkPreviewMaxBody))
+
 	// contacts
 	contactsRepo := contactsRepo.New(pool)
 	contactsUC := contactsUC.New(contactsRepo)
 	contacts := contactsDelivery.New(contactsUC)
 
+	// stickers
+	stickersRepo := stickersRepo.New(pool)
+	stickersUC := stickersUC.New(stickersRepo)
+	stickers := stickersDelivery.New(stickersUC)
+
 	// messages
 
 	messageDelivery := messageDelivery.NewMessageController(messageUsecase)
@@ -168,6 +183,7 @@ func main() {
 	router.HandleFunc("/contacts", auth.Authorize(auth.Csrf(contacts.AddContactHandler))).Methods("POST", "OPTIONS")
 	router.HandleFunc("/contacts", auth.Authorize(auth.Csrf(contacts.DeleteContactHandler))).Methods("DELETE", "OPTIONS")
 	router.HandleFunc("/contacts/search", auth.Authorize(contacts.SearchContactsHandler)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/stickerpacks", auth.Authorize(stickers.GetStickerPacksHandler)).Methods("GET", "OPTIONS")
 	router.HandleFunc("/logout", auth.LogoutHandler).Methods("POST")
 	router.PathPrefix("/docs/").Handler(httpSwagger.WrapHandler)
 
@@ -182,16 +198,52 @@ func main() {
 	router.HandleFunc("/chat/{chatId}", auth.Authorize(chat.GetChatInfo)).Methods("GET", "OPTIONS")
 	router.HandleFunc("/chat/{chatId}/messages", auth.Authorize(messageDelivery.GetAllMessages)).Methods("GET", "OPTIONS")
 	router.HandleFunc("/chat/{chatId}/messages/pages/{lastMessageId}", auth.Authorize(messageDelivery.GetMessagesWithPage)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/chat/{chatId}/messages/around/{messageId}", auth.Authorize(messageDelivery.GetMessagesAround)).Methods("GET", "OPTIONS")
 	router.HandleFunc("/chat/{chatId}/messages", auth.Authorize(auth.Csrf(messageDelivery.AddNewMessage))).Methods("POST", "OPTIONS")
 	router.HandleFunc("/chat/{chatId}/{messageId}/branch", auth.Authorize(auth.Csrf(chat.AddBranch))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/chat/{chatId}/branch", auth.Authorize(chat.GetBranch)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/chat/{chatId}/pins", auth.Authorize(chat.GetPinnedMessages)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/chat/{chatId}/pins/{messageId}", auth.Authorize(auth.Csrf(chat.PinMessage))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/chat/{chatId}/pins/{messageId}", auth.Authorize(auth.Csrf(chat.UnpinMessage))).Methods("DELETE", "OPTIONS")
+	router.HandleFunc("/chat/{chatId}/read", auth.Authorize(auth.Csrf(chat.ReadMessages))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/chat/{chatId}/mute", auth.Authorize(auth.Csrf(chat.MuteChat))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/chat/{chatId}/draft", auth.Authorize(chat.GetDraft)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/chat/{chatId}/draft", auth.Authorize(auth.Csrf(chat.SaveDraft))).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/chat/{chatId}/draft", auth.Authorize(auth.Csrf(chat.DeleteDraft))).Methods("DELETE", "OPTIONS")
 	router.HandleFunc("/chat/{chatId}/leave", auth.Authorize(auth.Csrf(chat.LeaveChat))).Methods("DELETE", "OPTIONS")
 
 	router.HandleFunc("/channel/{channelId}/join", auth.Authorize(chat.JoinChannel)).Methods("POST", "OPTIONS")
 
 	router.HandleFunc("/chat/{chatId}/messages/search", auth.Authorize(auth.Csrf(messageDelivery.SearchMessages))).Methods("GET", "OPTIONS")
 
+	router.HandleFunc("/chat/{chatId}/reactions", auth.Authorize(messageDelivery.GetAllowedReactions)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/chat/{chatId}/reactions", auth.Authorize(auth.Csrf(messageDelivery.SetAllowedReactions))).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/messages/{messageId}/reactions/{emoji}", auth.Authorize(auth.Csrf(messageDelivery.AddReaction))).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/messages/{messageId}/reactions/{emoji}", auth.Authorize(auth.Csrf(messageDelivery.DeleteReaction))).Methods("DELETE", "OPTIONS")
+	router.HandleFunc("/chat/{chatId}/settings/history", auth.Authorize(messageDelivery.GetHistorySettings)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/chat/{chatId}/settings/history", auth.Authorize(auth.Csrf(messageDelivery.SetHistorySettings))).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/chat/{chatId}/settings/ttl", auth.Authorize(messageDelivery.GetMessageTTL)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/chat/{chatId}/settings/ttl", auth.Authorize(auth.Csrf(messageDelivery.SetMessageTTL))).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/messages/{messageId}/history", auth.Authorize(messageDelivery.GetMessageHistory)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/chat/{chatId}/scheduled", auth.Authorize(messageDelivery.GetScheduledMessages)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/chat/{chatId}/scheduled", auth.Authorize(auth.Csrf(messageDelivery.ScheduleMessage))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/scheduled/{scheduledId}", auth.Authorize(auth.Csrf(messageDelivery.UpdateScheduledMessage))).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/scheduled/{scheduledId}", auth.Authorize(auth.Csrf(messageDelivery.CancelScheduledMessage))).Methods("DELETE", "OPTIONS")
+	router.HandleFunc("/messages/search", auth.Authorize(messageDelivery.SearchAllMessages)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/mentions", auth.Authorize(messageDelivery.GetMentions)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/chat/{chatId}/polls", auth.Authorize(auth.Csrf(messageDelivery.SendPoll))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/messages/{messageId}/poll/vote", auth.Authorize(auth.Csrf(messageDelivery.VotePoll))).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/messages/{messageId}/poll/vote", auth.Authorize(auth.Csrf(messageDelivery.RetractPollVote))).Methods("DELETE", "OPTIONS")
+	router.HandleFunc("/messages/forward", auth.Authorize(auth.Csrf(messageDelivery.ForwardMessages))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/messages/delete", auth.Authorize(auth.Csrf(messageDelivery.DeleteMessages))).Methods("POST", "OPTIONS")
 	router.HandleFunc("/messages/{messageId}", auth.Authorize(auth.Csrf(messageDelivery.DeleteMessage))).Methods("DELETE", "OPTIONS")
 	router.HandleFunc("/messages/{messageId}", auth.Authorize(auth.Csrf(messageDelivery.UpdateMessage))).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/chat/{chatId}/export", auth.Authorize(messageDelivery.ExportChat)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/exports/{exportId}", auth.Authorize(messageDelivery.GetExport)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/exports/{exportId}/file", auth.Authorize(messageDelivery.DownloadExport)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/chat/import", auth.Authorize(auth.Csrf(messageDelivery.ImportChat))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/imports/{importId}", auth.Authorize(messageDelivery.GetImport)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/chat/{chatId}/views", auth.Authorize(auth.Csrf(messageDelivery.ViewMessages))).Methods("POST", "OPTIONS")
 
 	// мктрики
 	router.Handle("/metrics", promhttp.Handler())
@@ -203,7 +255,28 @@ func main() {
 	go startMainServer(router)
 	go startChatServerGRPC(chatService)
 
-	select {}
+	// фоновые задачи останавливаются по сигналу завершения, незаконченный проход доделывается
+	workersCtx, stopWorkers := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stopWorkers()
+
+	var workers sync.WaitGroup
+	for _, task := range []periodicTask{
+		{"scheduled messages dispatcher", scheduledDispatchInterval, messageUsecase.DispatchScheduledMessages},
+		{"expired messages reaper", expiredReapInterval, messageUsecase.DeleteExpiredMessages},
+		{"chat export worker", exportWorkerInterval, messageUsecase.RunPendingExports},
+		{"chat import worker", importWorkerInterval, messageUsecase.RunPendingImports},
+		{"channel views publisher", viewsPublishInterval, messageUsecase.PublishViewUpdates},
+	} {
+		workers.Add(1)
+		go func(task periodicTask) {
+			defer workers.Done()
+			runPeriodically(workersCtx, task.interval, task.name, task.run)
+		}(task)
+	}
+
+	<-workersCtx.Done()
+	workers.Wait()
+	log.Println("background workers stopped")
 }
 
 func startMainServer(router *mux.Router) {
@@ -240,6 +313,63 @@ func startMainServer(router *mux.Router) {
 
 }
 
+// сколько времени после отправки автор может удалить сообщение у всех
+const messageDeleteWindow = 48 * time.Hour
+
+// ограничения на загрузку страниц для превью ссылок
+const (
+	linkPreviewTimeout = 5 * time.Second
+	linkPreviewMaxBody = 1 << 20
+)
+
+// как часто проверяем отложенные сообщения. Неотправленные сообщения хранятся в базе,
+// поэтому после перезапуска диспетчер продолжит с того же места
+const scheduledDispatchInterval = 5 * time.Second
+
+// как часто удаляем истекшие исчезающие сообщения. До удаления они уже не отдаются клиентам
+const expiredReapInterval = 5 * time.Second
+
+// как часто проверяем очередь выгрузок истории. Очередь хранится в базе,
+// поэтому несколько экземпляров приложения не возьмут одну выгрузку
+const exportWorkerInterval = 10 * time.Second
+
+// как часто проверяем очередь импортов истории из Telegram
+const importWorkerInterval = 10 * time.Second
+
+// как часто рассылаем накопившиеся изменения просмотров постов, чтобы не слать событие на каждый просмотр
+const viewsPublishInterval = 5 * time.Second
+
+// periodicTask фоновая задача, run возвращает количество обработанных записей
+type periodicTask struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context) (int, error)
+}
+
+// runPeriodically выполняет fn сразу и затем раз в interval, пока ctx не отменен.
+// Ошибки только логируются, чтобы задача продолжила работу на следующем тике
+func runPeriodically(ctx context.Context, interval time.Duration, name string, fn func(ctx context.Context) (int, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("starting %s", name)
+	for {
+		done, err := fn(ctx)
+		if err != nil {
+			log.Printf("%s: ошибка: %v", name, err)
+		} else if done > 0 {
+			log.Printf("%s: обработано: %d", name, done)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("stopping %s", name)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 func startChatServerGRPC(chatService chatService.ChatUsecase) {
 	// grpc for chat
 	chatServer := grpc.NewServer()
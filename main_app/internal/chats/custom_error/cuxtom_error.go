@@ -1,6 +1,9 @@
 package customerror
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 type NoPermissionError struct {
 	User string
@@ -11,3 +14,9 @@ type NoPermissionError struct {
 func (e *NoPermissionError) Error() string {
 	return fmt.Sprintf("пользователь '%s' не имеет доступа к '%s'", e.User, e.Area)
 }
+
+// IsNoPermission проверяет, что в цепочке ошибок есть NoPermissionError
+func IsNoPermission(err error) bool {
+	var permErr *NoPermissionError
+	return errors.As(err, &permErr)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
 
@@ -28,8 +29,6 @@ const (
 	userNotFoundError = "User not found"
 )
 
-var noPerm error = &customerror.NoPermissionError{User: "Alice", Area: "секретная зона"}
-
 type ChatDelivery struct {
 	service chatlist.ChatUsecase
 }
@@ -213,7 +212,7 @@ func (c *ChatDelivery) JoinChannel(w http.ResponseWriter, r *http.Request) {
 	err = c.service.JoinChannel(ctx, user.ID, channelId)
 
 	if err != nil {
-		if errors.As(err, &noPerm) {
+		if customerror.IsNoPermission(err) {
 			responser.SendError(ctx, w, err.Error(), http.StatusForbidden)
 			return
 		}
@@ -444,7 +443,7 @@ func (c *ChatDelivery) LeaveChat(w http.ResponseWriter, r *http.Request) {
 
 	err = c.service.UserLeaveChat(ctx, user.ID, chatUUID)
 	if err != nil {
-		if errors.As(err, &noPerm) {
+		if customerror.IsNoPermission(err) {
 			w.WriteHeader(http.StatusForbidden)
 			responser.SendError(ctx, w, fmt.Sprintf("Запрещено: %v", err), http.StatusForbidden)
 			return
@@ -456,6 +455,408 @@ func (c *ChatDelivery) LeaveChat(w http.ResponseWriter, r *http.Request) {
 	responser.SendOK(w, "Пользователь вышел из чата", http.StatusOK)
 }
 
+// ReadMessages godoc
+// @Summary Отметить сообщения чата прочитанными
+// @Description Если messageId не передан, то прочитаны все сообщения. Курсор прочтения двигается только вперед
+// @Tags chat
+// @Accept json
+// @Param chatId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Param message body model.ReadMessagesDTO false "Последнее прочитанное сообщение"
+// @Success 200 "Сообщения прочитаны"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} responser.ErrorResponse "Запрещено"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось отметить сообщения"
+// @Router /chat/{chatId}/read [post]
+func (c *ChatDelivery) ReadMessages(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestChatDuration, "ReadMessages")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	chatUUID, err := getChatIdFromContext(r.Context())
+
+	if err != nil {
+		log.Println("Chat delivery -> ReadMessages: error parsing chat uuid:", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Chat delivery -> ReadMessages: error parsing chat uuid: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := r.Context().Value(auth.UserKey).(auth.User)
+	if !ok {
+		responser.SendError(ctx, w, "Не переданы параметры", http.StatusInternalServerError)
+		return
+	}
+
+	var input model.ReadMessagesDTO
+	// пустое тело - прочитать все
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && !errors.Is(err, io.EOF) {
+		log.Printf("Не удалось распарсить Json: %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Не удалось распарсить Json: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	err = c.service.ReadMessages(ctx, user.ID, chatUUID, input)
+	if err != nil {
+		if customerror.IsNoPermission(err) {
+			responser.SendError(ctx, w, fmt.Sprintf("Запрещено: %v", err), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, chatlist.ErrMessageNotInChat) {
+			responser.SendError(ctx, w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		responser.SendError(ctx, w, fmt.Sprintf("%v", err), http.StatusInternalServerError)
+		return
+	}
+
+	responser.SendOK(w, "Сообщения прочитаны", http.StatusOK)
+}
+
+// MuteChat godoc
+// @Summary Заглушить чат
+// @Description Заглушенный чат не уведомляет о новых сообщениях, упоминания пользователя приходят всегда
+// @Tags chat
+// @Accept json
+// @Param chatId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Param mute body model.MuteChatDTO true "Заглушить или вернуть уведомления"
+// @Success 200 "Уведомления чата изменены"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} responser.ErrorResponse "Запрещено"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось изменить уведомления"
+// @Router /chat/{chatId}/mute [post]
+func (c *ChatDelivery) MuteChat(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestChatDuration, "MuteChat")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	chatUUID, err := getChatIdFromContext(r.Context())
+
+	if err != nil {
+		log.Println("Chat delivery -> MuteChat: error parsing chat uuid:", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Chat delivery -> MuteChat: error parsing chat uuid: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := r.Context().Value(auth.UserKey).(auth.User)
+	if !ok {
+		responser.SendError(ctx, w, "Не переданы параметры", http.StatusInternalServerError)
+		return
+	}
+
+	var input model.MuteChatDTO
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		log.Printf("Не удалось распарсить Json: %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Не удалось распарсить Json: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	err = c.service.MuteChat(ctx, user.ID, chatUUID, input)
+	if err != nil {
+		if customerror.IsNoPermission(err) {
+			responser.SendError(ctx, w, fmt.Sprintf("Запрещено: %v", err), http.StatusForbidden)
+			return
+		}
+		responser.SendError(ctx, w, fmt.Sprintf("%v", err), http.StatusInternalServerError)
+		return
+	}
+
+	responser.SendOK(w, "Уведомления чата изменены", http.StatusOK)
+}
+
+// sendDraftError переводит ошибки черновиков в http статусы
+func sendDraftError(ctx context.Context, w http.ResponseWriter, err error) {
+	switch {
+	case customerror.IsNoPermission(err):
+		responser.SendError(ctx, w, fmt.Sprintf("Запрещено: %v", err), http.StatusForbidden)
+	case errors.Is(err, chatlist.ErrDraftNotFound):
+		responser.SendError(ctx, w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, chatlist.ErrDraftTooLong), errors.Is(err, chatlist.ErrMessageNotInChat):
+		responser.SendError(ctx, w, err.Error(), http.StatusBadRequest)
+	default:
+		responser.SendError(ctx, w, fmt.Sprintf("%v", err), http.StatusInternalServerError)
+	}
+}
+
+// GetDraft godoc
+// @Summary Черновик пользователя в чате
+// @Tags chat
+// @Produce json
+// @Param chatId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Success 200 {object} model.Draft "Черновик"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} responser.ErrorResponse "Запрещено"
+// @Failure 404	{object} responser.ErrorResponse "Черновика нет"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось получить черновик"
+// @Router /chat/{chatId}/draft [get]
+func (c *ChatDelivery) GetDraft(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestChatDuration, "GetDraft")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	chatUUID, err := getChatIdFromContext(r.Context())
+
+	if err != nil {
+		log.Println("Chat delivery -> GetDraft: error parsing chat uuid:", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Chat delivery -> GetDraft: error parsing chat uuid: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := r.Context().Value(auth.UserKey).(auth.User)
+	if !ok {
+		responser.SendError(ctx, w, "Не переданы параметры", http.StatusInternalServerError)
+		return
+	}
+
+	draft, err := c.service.GetDraft(ctx, user.ID, chatUUID)
+	if err != nil {
+		sendDraftError(ctx, w, err)
+		return
+	}
+
+	responser.SendStruct(ctx, w, draft, http.StatusOK)
+}
+
+// SaveDraft godoc
+// @Summary Сохранить черновик в чате
+// @Description Черновик общий для всех устройств пользователя, остальные устройства получат событие draftUpdated.
+// @Description Пустой черновик без ответа удаляется
+// @Tags chat
+// @Accept json
+// @Produce json
+// @Param chatId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Param draft body model.DraftInput true "Черновик"
+// @Success 200 {object} model.Draft "Черновик сохранен"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} responser.ErrorResponse "Запрещено"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось сохранить черновик"
+// @Router /chat/{chatId}/draft [put]
+func (c *ChatDelivery) SaveDraft(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestChatDuration, "SaveDraft")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	chatUUID, err := getChatIdFromContext(r.Context())
+
+	if err != nil {
+		log.Println("Chat delivery -> SaveDraft: error parsing chat uuid:", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Chat delivery -> SaveDraft: error parsing chat uuid: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := r.Context().Value(auth.UserKey).(auth.User)
+	if !ok {
+		responser.SendError(ctx, w, "Не переданы параметры", http.StatusInternalServerError)
+		return
+	}
+
+	var input model.DraftInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		log.Printf("Не удалось распарсить Json: %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Не удалось распарсить Json: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	draft, err := c.service.SaveDraft(ctx, user.ID, chatUUID, input)
+	if err != nil {
+		sendDraftError(ctx, w, err)
+		return
+	}
+
+	if draft == nil {
+		responser.SendOK(w, "Черновик удален", http.StatusOK)
+		return
+	}
+
+	responser.SendStruct(ctx, w, draft, http.StatusOK)
+}
+
+// DeleteDraft godoc
+// @Summary Удалить черновик в чате
+// @Tags chat
+// @Param chatId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Success 200 "Черновик удален"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} responser.ErrorResponse "Запрещено"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось удалить черновик"
+// @Router /chat/{chatId}/draft [delete]
+func (c *ChatDelivery) DeleteDraft(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestChatDuration, "DeleteDraft")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	chatUUID, err := getChatIdFromContext(r.Context())
+
+	if err != nil {
+		log.Println("Chat delivery -> DeleteDraft: error parsing chat uuid:", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Chat delivery -> DeleteDraft: error parsing chat uuid: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := r.Context().Value(auth.UserKey).(auth.User)
+	if !ok {
+		responser.SendError(ctx, w, "Не переданы параметры", http.StatusInternalServerError)
+		return
+	}
+
+	err = c.service.DeleteDraft(ctx, user.ID, chatUUID)
+	if err != nil {
+		sendDraftError(ctx, w, err)
+		return
+	}
+
+	responser.SendOK(w, "Черновик удален", http.StatusOK)
+}
+
+// GetPinnedMessages godoc
+// @Summary Закрепленные сообщения чата
+// @Tags chat
+// @Produce json
+// @Param chatId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Success 200 {object} model.PinnedMessagesDTO "Закрепленные сообщения, последнее закрепленное первым"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} responser.ErrorResponse "Запрещено"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось получить сообщения"
+// @Router /chat/{chatId}/pins [get]
+func (c *ChatDelivery) GetPinnedMessages(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestChatDuration, "GetPinnedMessages")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	chatUUID, err := getChatIdFromContext(r.Context())
+
+	if err != nil {
+		log.Println("Chat delivery -> GetPinnedMessages: error parsing chat uuid:", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Chat delivery -> GetPinnedMessages: error parsing chat uuid: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := r.Context().Value(auth.UserKey).(auth.User)
+	if !ok {
+		responser.SendError(ctx, w, "Не переданы параметры", http.StatusInternalServerError)
+		return
+	}
+
+	pinned, err := c.service.GetPinnedMessages(ctx, user.ID, chatUUID)
+	if err != nil {
+		if customerror.IsNoPermission(err) {
+			responser.SendError(ctx, w, fmt.Sprintf("Запрещено: %v", err), http.StatusForbidden)
+			return
+		}
+		responser.SendError(ctx, w, fmt.Sprintf("%v", err), http.StatusInternalServerError)
+		return
+	}
+
+	responser.SendStruct(ctx, w, pinned, http.StatusOK)
+}
+
+// PinMessage godoc
+// @Summary Закрепить сообщение
+// @Description В группах и каналах доступно владельцу и админам, в личном чате - обоим собеседникам
+// @Tags chat
+// @Param chatId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Param messageId path string true "Message ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Success 200 "Сообщение закреплено"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} responser.ErrorResponse "Запрещено"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось закрепить сообщение"
+// @Router /chat/{chatId}/pins/{messageId} [post]
+func (c *ChatDelivery) PinMessage(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestChatDuration, "PinMessage")
+	}()
+
+	c.handlePin(w, r, c.service.PinMessage, "Сообщение закреплено")
+}
+
+// UnpinMessage godoc
+// @Summary Открепить сообщение
+// @Tags chat
+// @Param chatId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Param messageId path string true "Message ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Success 200 "Сообщение откреплено"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} responser.ErrorResponse "Запрещено"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось открепить сообщение"
+// @Router /chat/{chatId}/pins/{messageId} [delete]
+func (c *ChatDelivery) UnpinMessage(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestChatDuration, "UnpinMessage")
+	}()
+
+	c.handlePin(w, r, c.service.UnpinMessage, "Сообщение откреплено")
+}
+
+// handlePin общая часть закрепления и открепления
+func (c *ChatDelivery) handlePin(w http.ResponseWriter, r *http.Request,
+	action func(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, messageId uuid.UUID) error, okMessage string) {
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	chatUUID, err := getChatIdFromContext(r.Context())
+
+	if err != nil {
+		log.Println("Chat delivery -> handlePin: error parsing chat uuid:", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Chat delivery -> handlePin: error parsing chat uuid: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	messageUUID, err := uuid.Parse(vars["messageId"])
+	if err != nil {
+		log.Errorf("не удалось распарсить messageId: %v", err)
+		responser.SendError(ctx, w, "invalid messageId", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := r.Context().Value(auth.UserKey).(auth.User)
+	if !ok {
+		responser.SendError(ctx, w, "Не переданы параметры", http.StatusInternalServerError)
+		return
+	}
+
+	err = action(ctx, user.ID, chatUUID, messageUUID)
+	if err != nil {
+		if customerror.IsNoPermission(err) {
+			responser.SendError(ctx, w, fmt.Sprintf("Запрещено: %v", err), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, chatlist.ErrMessageNotInChat) {
+			responser.SendError(ctx, w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		responser.SendError(ctx, w, fmt.Sprintf("%v", err), http.StatusInternalServerError)
+		return
+	}
+
+	responser.SendOK(w, okMessage, http.StatusOK)
+}
+
 // DeleteChatOrGroup godoc
 // @Summary Удаличть чат или группу
 // @Tags chat
@@ -494,7 +895,7 @@ func (c *ChatDelivery) DeleteChatOrGroup(w http.ResponseWriter, r *http.Request)
 	err = c.service.DeleteChat(r.Context(), chatUUID, user.ID)
 
 	if err != nil {
-		if errors.As(err, &noPerm) {
+		if customerror.IsNoPermission(err) {
 			w.WriteHeader(http.StatusForbidden)
 			responser.SendError(ctx, w, fmt.Sprintf("Нет доступа: %v", err), http.StatusForbidden)
 			return
@@ -601,7 +1002,7 @@ func (c *ChatDelivery) UpdateGroup(w http.ResponseWriter, r *http.Request) {
 	updatedChat, err := c.service.UpdateChat(r.Context(), chatUUID, chatUpdate, user.ID)
 
 	if err != nil {
-		if errors.As(err, &noPerm) {
+		if customerror.IsNoPermission(err) {
 			responser.SendError(ctx, w, fmt.Sprintf("Нет доступа: %v", err), http.StatusForbidden)
 			return
 		}
@@ -623,7 +1024,7 @@ func sendSuccess(ctx context.Context, w http.ResponseWriter) {
 }
 
 type SuccessfullSuccess struct {
-	Success string `json:success`
+	Success string `json:"success"`
 }
 
 // GetChatInfo godoc
@@ -662,7 +1063,7 @@ func (c *ChatDelivery) GetChatInfo(w http.ResponseWriter, r *http.Request) {
 
 	users, err := c.service.GetChatInfo(ctx, chatUUID, user.ID)
 	if err != nil {
-		if errors.Is(err, noPerm) {
+		if customerror.IsNoPermission(err) {
 			responser.SendError(ctx, w, err.Error(), http.StatusForbidden)
 			return
 		}
@@ -732,6 +1133,57 @@ func (c *ChatDelivery) AddBranch(w http.ResponseWriter, r *http.Request) {
 	responser.SendStruct(ctx, w, branch, http.StatusCreated)
 }
 
+// GetBranch godoc
+// @Summary Получить контекст ветки
+// @Description Сообщение, к которому создана ветка, и его чат. Количество ответов и последний ответ - в parentMessage.branch
+// @Tags chat
+// @Produce json
+// @Param chatId path string true "Branch ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Success 200 {object} model.BranchDTO "Контекст ветки"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} responser.ErrorResponse "Пользователь не состоит в ветке или в родительском чате"
+// @Failure 404	{object} responser.ErrorResponse "Чат не является веткой"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось получить ветку"
+// @Router /chat/{chatId}/branch [get]
+func (c *ChatDelivery) GetBranch(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestChatDuration, "GetBranch")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	branchUUID, err := getChatIdFromContext(r.Context())
+
+	if err != nil {
+		log.Println("Chat delivery -> GetBranch: error parsing chat uuid:", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Chat delivery -> GetBranch: error parsing chat uuid: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := r.Context().Value(auth.UserKey).(auth.User)
+	if !ok {
+		responser.SendError(ctx, w, "Не переданы параметры", http.StatusInternalServerError)
+		return
+	}
+
+	branch, err := c.service.GetBranch(ctx, user.ID, branchUUID)
+	if err != nil {
+		switch {
+		case customerror.IsNoPermission(err):
+			responser.SendError(ctx, w, fmt.Sprintf("Запрещено: %v", err), http.StatusForbidden)
+		case errors.Is(err, chatlist.ErrNotBranch):
+			responser.SendError(ctx, w, err.Error(), http.StatusNotFound)
+		default:
+			responser.SendError(ctx, w, fmt.Sprintf("%v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	responser.SendStruct(ctx, w, branch, http.StatusOK)
+}
+
 // SearchChats ищет чаты по названию, в query указать ключевое слово ?key_word=
 //
 // SearchChats godoc
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	auth "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	customerror "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/custom_error"
 	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/delivery"
 	model "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/models"
 	mocks "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/usecase/mocks"
@@ -352,7 +353,7 @@ func TestUpdateGroup(t *testing.T) {
 
 			mockUpdateReturn: model.ChatUpdateOutput{},
 
-			mockUpdateErr: assert.AnError,
+			mockUpdateErr: &customerror.NoPermissionError{User: userID.String(), Area: chatID},
 
 			expectedStatusCode: http.StatusForbidden,
 		},
@@ -3,6 +3,7 @@ package model
 import (
 	"encoding/json"
 	"mime/multipart"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -19,6 +20,10 @@ type Chat struct {
 	AvatarURL string
 	// типо неймтаг канала
 	ChatURLName string
+	// заполняется списком чатов пользователя, иначе nil
+	ReadState *ReadState
+	// черновик из того же запроса списка чатов, имеет смысл только вместе с ReadState
+	Draft *Draft
 }
 
 // @Schema
@@ -29,6 +34,15 @@ type ChatDTOOutput struct {
 	ChatType     string         `json:"chatType" example:"personal" valid:"in(personal|group|channel)"`
 	LastMessage  models.Message `json:"lastMessage" valid:"-"`
 	AvatarPath   string         `json:"avatarPath"  example:"/uploads/chat/f0364477-bfd4-496d-b639-d825b009d509.png" valid:"-"`
+	// количество чужих сообщений после курсора прочтения
+	UnreadCount       int        `json:"unreadCount" example:"3" valid:"-"`
+	LastReadMessageId *uuid.UUID `json:"lastReadMessageId" example:"f0364477-bfd4-496d-b639-d825b009d509" valid:"-"`
+	// сколько из непрочитанных сообщений упоминают пользователя
+	UnreadMentions int `json:"unreadMentions" example:"1" valid:"-"`
+	// недописанное сообщение пользователя, общее для всех его устройств
+	Draft *Draft `json:"draft" valid:"-"`
+	// заглушенный чат не уведомляет о новых сообщениях, упоминания приходят всегда
+	Muted bool `json:"muted" example:"false" valid:"-"`
 }
 
 // для сортировки возвращаемого списка по убыванию
@@ -90,6 +104,37 @@ func СhatToChatDTO(chat Chat, countOfUsers int, lastMessage models.Message) Cha
 	}
 }
 
+// ReadState курсор прочтения участника чата
+type ReadState struct {
+	LastReadMessageId *uuid.UUID
+	UnreadCount       int
+	UnreadMentions    int
+	Muted             bool
+}
+
+// MuteChatDTO заглушить или вернуть уведомления чата
+type MuteChatDTO struct {
+	Muted bool `json:"muted" example:"true" valid:"-"`
+}
+
+// ReadMessagesDTO если messageId не передан, то прочитаны все сообщения чата
+type ReadMessagesDTO struct {
+	MessageId *uuid.UUID `json:"messageId" example:"f0364477-bfd4-496d-b639-d825b009d509" valid:"-"`
+}
+
+// Draft черновик пользователя в чате
+type Draft struct {
+	Text      string     `json:"text" example:"Привет, я тут подумал" valid:"-"`
+	ReplyTo   *uuid.UUID `json:"replyTo" example:"f0364477-bfd4-496d-b639-d825b009d509" valid:"-"`
+	UpdatedAt time.Time  `json:"updatedAt" example:"2024-04-13T08:30:00Z" valid:"-"`
+}
+
+// DraftInput сохранение пустого черновика без ответа удаляет черновик
+type DraftInput struct {
+	Text    string     `json:"text" example:"Привет, я тут подумал" valid:"-"`
+	ReplyTo *uuid.UUID `json:"replyTo" example:"f0364477-bfd4-496d-b639-d825b009d509" valid:"-"`
+}
+
 type AddUsersIntoChatDTO struct {
 	UsersId []uuid.UUID `json:"usersId" example:"uuid1,uuid2" valid:"-"`
 }
@@ -111,6 +156,12 @@ type ChatInfoDTO struct {
 	Role     string           `json:"role" example:"owner" valid:"in(admin|owner|none)"`
 	Users    []UserInChatDTO  `json:"users" valid:"-"`
 	Messages []models.Message `json:"messages" valid:"-"`
+	// закрепленные сообщения, последнее закрепленное первым
+	PinnedMessages []models.Message `json:"pinnedMessages" valid:"-"`
+}
+
+type PinnedMessagesDTO struct {
+	Messages []models.Message `json:"messages" valid:"-"`
 }
 
 type UserInChatDTO struct {
@@ -133,6 +184,8 @@ type Event struct {
 	Action string      `json:"action"`
 	ChatId uuid.UUID   `json:"chatId"`
 	Users  []uuid.UUID `json:"users"`
+	// сообщение, к которому относится событие (закрепление)
+	MessageId *uuid.UUID `json:"messageId,omitempty"`
 }
 
 func SerializeEvent(event Event) ([]byte, error) {
@@ -152,6 +205,14 @@ type AddBranch struct {
 	ID uuid.UUID `json:"id" example:"f0364477-bfd4-496d-b639-d825b009d509" valid:"uuid"`
 }
 
+// BranchDTO ветка обсуждения вместе с сообщением, к которому она создана, и его чатом
+type BranchDTO struct {
+	ID         uuid.UUID     `json:"id" example:"f0364477-bfd4-496d-b639-d825b009d509" valid:"-"`
+	ParentChat ChatDTOOutput `json:"parentChat" valid:"-"`
+	// количество ответов и последний ответ - в parentMessage.branch
+	ParentMessage models.Message `json:"parentMessage" valid:"-"`
+}
+
 type SearchChatsDTO struct {
 	UserChats      []ChatDTOOutput `json:"user_chats" valid:"-"`
 	GlobalChannels []ChatDTOOutput `json:"global_channels" valid:"-"`
@@ -7,6 +7,7 @@ package mock_repository
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	model "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/models"
 	gomock "github.com/golang/mock/gomock"
@@ -93,6 +94,21 @@ func (mr *MockChatRepositoryMockRecorder) DeleteChat(ctx, chatId interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteChat", reflect.TypeOf((*MockChatRepository)(nil).DeleteChat), ctx, chatId)
 }
 
+// DeleteDraft mocks base method.
+func (m *MockChatRepository) DeleteDraft(ctx context.Context, userId, chatId uuid.UUID) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDraft", ctx, userId, chatId)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteDraft indicates an expected call of DeleteDraft.
+func (mr *MockChatRepositoryMockRecorder) DeleteDraft(ctx, userId, chatId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDraft", reflect.TypeOf((*MockChatRepository)(nil).DeleteDraft), ctx, userId, chatId)
+}
+
 // DeleteUserFromChat mocks base method.
 func (m *MockChatRepository) DeleteUserFromChat(ctx context.Context, userId, chatId uuid.UUID) error {
 	m.ctrl.T.Helper()
@@ -152,6 +168,21 @@ func (mr *MockChatRepositoryMockRecorder) GetCountOfUsersInChat(ctx, chatId inte
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCountOfUsersInChat", reflect.TypeOf((*MockChatRepository)(nil).GetCountOfUsersInChat), ctx, chatId)
 }
 
+// GetDraft mocks base method.
+func (m *MockChatRepository) GetDraft(ctx context.Context, userId, chatId uuid.UUID) (*model.Draft, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDraft", ctx, userId, chatId)
+	ret0, _ := ret[0].(*model.Draft)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDraft indicates an expected call of GetDraft.
+func (mr *MockChatRepositoryMockRecorder) GetDraft(ctx, userId, chatId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDraft", reflect.TypeOf((*MockChatRepository)(nil).GetDraft), ctx, userId, chatId)
+}
+
 // GetNameAndAvatar mocks base method.
 func (m *MockChatRepository) GetNameAndAvatar(ctx context.Context, userId uuid.UUID) (string, string, error) {
 	m.ctrl.T.Helper()
@@ -168,6 +199,21 @@ func (mr *MockChatRepositoryMockRecorder) GetNameAndAvatar(ctx, userId interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNameAndAvatar", reflect.TypeOf((*MockChatRepository)(nil).GetNameAndAvatar), ctx, userId)
 }
 
+// GetReadState mocks base method.
+func (m *MockChatRepository) GetReadState(ctx context.Context, userId, chatId uuid.UUID) (model.ReadState, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReadState", ctx, userId, chatId)
+	ret0, _ := ret[0].(model.ReadState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReadState indicates an expected call of GetReadState.
+func (mr *MockChatRepositoryMockRecorder) GetReadState(ctx, userId, chatId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReadState", reflect.TypeOf((*MockChatRepository)(nil).GetReadState), ctx, userId, chatId)
+}
+
 // GetUserChats mocks base method.
 func (m *MockChatRepository) GetUserChats(ctx context.Context, userId uuid.UUID) ([]model.Chat, error) {
 	m.ctrl.T.Helper()
@@ -213,6 +259,50 @@ func (mr *MockChatRepositoryMockRecorder) GetUsersFromChat(ctx, chatId interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsersFromChat", reflect.TypeOf((*MockChatRepository)(nil).GetUsersFromChat), ctx, chatId)
 }
 
+// MarkMessagesAsRead mocks base method.
+func (m *MockChatRepository) MarkMessagesAsRead(ctx context.Context, userId, chatId, messageId uuid.UUID) (time.Time, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkMessagesAsRead", ctx, userId, chatId, messageId)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// MarkMessagesAsRead indicates an expected call of MarkMessagesAsRead.
+func (mr *MockChatRepositoryMockRecorder) MarkMessagesAsRead(ctx, userId, chatId, messageId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkMessagesAsRead", reflect.TypeOf((*MockChatRepository)(nil).MarkMessagesAsRead), ctx, userId, chatId, messageId)
+}
+
+// PinMessage mocks base method.
+func (m *MockChatRepository) PinMessage(ctx context.Context, chatId, messageId, userId uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PinMessage", ctx, chatId, messageId, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PinMessage indicates an expected call of PinMessage.
+func (mr *MockChatRepositoryMockRecorder) PinMessage(ctx, chatId, messageId, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PinMessage", reflect.TypeOf((*MockChatRepository)(nil).PinMessage), ctx, chatId, messageId, userId)
+}
+
+// SaveDraft mocks base method.
+func (m *MockChatRepository) SaveDraft(ctx context.Context, userId, chatId uuid.UUID, draft model.Draft) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDraft", ctx, userId, chatId, draft)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveDraft indicates an expected call of SaveDraft.
+func (mr *MockChatRepositoryMockRecorder) SaveDraft(ctx, userId, chatId, draft interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDraft", reflect.TypeOf((*MockChatRepository)(nil).SaveDraft), ctx, userId, chatId, draft)
+}
+
 // SearchGlobalChats mocks base method.
 func (m *MockChatRepository) SearchGlobalChats(ctx context.Context, userId uuid.UUID, keyWord string) ([]model.Chat, error) {
 	m.ctrl.T.Helper()
@@ -243,6 +333,34 @@ func (mr *MockChatRepositoryMockRecorder) SearchUserChats(ctx, userId, keyWord i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchUserChats", reflect.TypeOf((*MockChatRepository)(nil).SearchUserChats), ctx, userId, keyWord)
 }
 
+// SetChatMuted mocks base method.
+func (m *MockChatRepository) SetChatMuted(ctx context.Context, userId, chatId uuid.UUID, muted bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetChatMuted", ctx, userId, chatId, muted)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetChatMuted indicates an expected call of SetChatMuted.
+func (mr *MockChatRepositoryMockRecorder) SetChatMuted(ctx, userId, chatId, muted interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetChatMuted", reflect.TypeOf((*MockChatRepository)(nil).SetChatMuted), ctx, userId, chatId, muted)
+}
+
+// UnpinMessage mocks base method.
+func (m *MockChatRepository) UnpinMessage(ctx context.Context, chatId, messageId uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnpinMessage", ctx, chatId, messageId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnpinMessage indicates an expected call of UnpinMessage.
+func (mr *MockChatRepositoryMockRecorder) UnpinMessage(ctx, chatId, messageId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnpinMessage", reflect.TypeOf((*MockChatRepository)(nil).UnpinMessage), ctx, chatId, messageId)
+}
+
 // UpdateChat mocks base method.
 func (m *MockChatRepository) UpdateChat(ctx context.Context, chatId uuid.UUID, chatUpdate string) error {
 	m.ctrl.T.Helper()
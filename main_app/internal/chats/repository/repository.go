@@ -3,8 +3,10 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4"
@@ -138,6 +140,33 @@ func (r *ChatRepositoryImpl) CreateNewChat(ctx context.Context, chat chatModel.C
 	return nil
 }
 
+// readStateColumns курсор прочтения, непрочитанные, непрочитанные упоминания и заглушен ли чат у участника cu
+const readStateColumns = `cu.last_read_message_id,
+	(
+		SELECT COUNT(m.id)
+		FROM message AS m
+		WHERE m.chat_id = cu.chat_id
+			AND m.author_id != cu.user_id
+			AND m.sent_at > cu.last_read_at
+			AND NOT EXISTS (
+				SELECT 1 FROM message_hidden AS mh
+				WHERE mh.message_id = m.id AND mh.user_id = cu.user_id
+			)
+	),
+	(
+		SELECT COUNT(m.id)
+		FROM message_mention AS mm
+		JOIN message AS m ON m.id = mm.message_id
+		WHERE mm.user_id = cu.user_id
+			AND m.chat_id = cu.chat_id
+			AND m.sent_at > cu.last_read_at
+			AND NOT EXISTS (
+				SELECT 1 FROM message_hidden AS mh
+				WHERE mh.message_id = m.id AND mh.user_id = cu.user_id
+			)
+	),
+	cu.muted`
+
 func (r *ChatRepositoryImpl) GetUserChats(ctx context.Context, userId uuid.UUID) ([]chatModel.Chat, error) {
 	log := logger.LoggerWithCtx(ctx, logger.Log)
 	conn, err := r.pool.Acquire(ctx)
@@ -153,10 +182,15 @@ func (r *ChatRepositoryImpl) GetUserChats(ctx context.Context, userId uuid.UUID)
 		c.chat_name,
 		ch.value,
 		c.avatar_path,
-		c.chat_link_name
+		c.chat_link_name,
+		`+readStateColumns+`,
+		d.text,
+		d.reply_to_id,
+		d.updated_at
 		FROM chat_user AS cu
 		JOIN chat AS c ON c.id = cu.chat_id
 		JOIN chat_type AS ch ON ch.id = c.chat_type_id
+		LEFT JOIN chat_draft AS d ON d.user_id = cu.user_id AND d.chat_id = cu.chat_id
 		WHERE cu.user_id = $1;`,
 		userId,
 	)
@@ -173,21 +207,38 @@ func (r *ChatRepositoryImpl) GetUserChats(ctx context.Context, userId uuid.UUID)
 		var chatType string
 		var avatarURL sql.NullString
 		var chatURLName sql.NullString
+		var readState chatModel.ReadState
+		var draftText sql.NullString
+		var draftReplyTo *uuid.UUID
+		var draftUpdatedAt *time.Time
 
 		log.Println("Repository: поиск параметров из запроса")
-		err = rows.Scan(&chatId, &chatName, &chatType, &avatarURL, &chatURLName)
+		err = rows.Scan(&chatId, &chatName, &chatType, &avatarURL, &chatURLName,
+			&readState.LastReadMessageId, &readState.UnreadCount, &readState.UnreadMentions, &readState.Muted,
+			&draftText, &draftReplyTo, &draftUpdatedAt)
 
 		if err != nil {
 			log.Printf("Repository: unable to scan: %v", err)
 			return nil, err
 		}
 
+		var draft *chatModel.Draft
+		if draftUpdatedAt != nil {
+			draft = &chatModel.Draft{
+				Text:      draftText.String,
+				ReplyTo:   draftReplyTo,
+				UpdatedAt: *draftUpdatedAt,
+			}
+		}
+
 		chats = append(chats, chatModel.Chat{
 			ChatId:      chatId,
 			ChatName:    chatName,
 			ChatType:    chatType,
 			AvatarURL:   avatarURL.String,
 			ChatURLName: chatURLName.String,
+			ReadState:   &readState,
+			Draft:       draft,
 		})
 	}
 
@@ -259,8 +310,15 @@ func (r *ChatRepositoryImpl) AddUserIntoChat(ctx context.Context, userId uuid.UU
 	}
 	defer conn.Release()
 
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		log.Printf("Repository: Unable to create transaction: %v\n", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
 	var id uuid.UUID
-	err = conn.QueryRow(ctx,
+	err = tx.QueryRow(ctx,
 		`INSERT INTO chat_user (id, user_role_id, chat_id, user_id)
 		VALUES ($1, (SELECT id FROM user_role WHERE value = $2), $3, $4)
 		RETURNING id;`,
@@ -271,9 +329,30 @@ func (r *ChatRepositoryImpl) AddUserIntoChat(ctx context.Context, userId uuid.UU
 	).Scan(&id)
 
 	if err != nil {
-		log.Errorf("польтзователь %v не добавлен в чат %v. Ошибка: ", userId, chatId, err)
+		log.Errorf("польтзователь %v не добавлен в чат %v. Ошибка: %v", userId, chatId, err)
 		return err
 	}
+
+	// ветки копируют участников чата при создании, поэтому вступивший позже добавляется в уже созданные
+	_, err = tx.Exec(ctx,
+		`INSERT INTO chat_user (id, user_role_id, chat_id, user_id)
+		SELECT gen_random_uuid(), (SELECT id FROM user_role WHERE value = 'none'), m.branch_id, $2
+		FROM message AS m
+		WHERE m.chat_id = $1 AND m.branch_id IS NOT NULL
+		ON CONFLICT (chat_id, user_id) DO NOTHING;`,
+		chatId,
+		userId,
+	)
+	if err != nil {
+		log.Errorf("польтзователь %v не добавлен в ветки чата %v. Ошибка: %v", userId, chatId, err)
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Errorf("Не удалось подтвердить транзакцию: %v", err)
+		return err
+	}
+
 	log.Printf("польтзователь %v добавлен в чат %v", userId, chatId)
 	return nil
 }
@@ -405,16 +484,42 @@ func (r *ChatRepositoryImpl) DeleteUserFromChat(ctx context.Context, userId uuid
 
 	log.Printf("Chat repository -> UpdateChat: начато обновление чата: %v", chatId)
 
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		log.Printf("Repository: Unable to create transaction: %v\n", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
 	deleteQuery := `DELETE FROM chat_user WHERE chat_id = $1 AND user_id = $2;`
 
 	// Выполнение удаления
-	_, err = conn.Exec(ctx, deleteQuery, chatId, userId)
+	_, err = tx.Exec(ctx, deleteQuery, chatId, userId)
 
 	if err != nil {
 		log.Printf("Chat repository -> DeleteUserFromChat: не удалось обновить чат: %v", err)
 		return err
 	}
 
+	// вышедший из чата теряет доступ и ко всем веткам его сообщений
+	_, err = tx.Exec(ctx,
+		`DELETE FROM chat_user AS cu
+		USING message AS m
+		WHERE m.chat_id = $1 AND m.branch_id IS NOT NULL
+			AND cu.chat_id = m.branch_id AND cu.user_id = $2;`,
+		chatId,
+		userId,
+	)
+	if err != nil {
+		log.Errorf("пользователь %v не удален из веток чата %v. Ошибка: %v", userId, chatId, err)
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Errorf("Не удалось подтвердить транзакцию: %v", err)
+		return err
+	}
+
 	return nil
 }
 
@@ -745,3 +850,220 @@ func (r *ChatRepositoryImpl) SearchGlobalChats(ctx context.Context, userId uuid.
 
 	return chats, nil
 }
+
+func (r *ChatRepositoryImpl) MarkMessagesAsRead(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, messageId uuid.UUID) (time.Time, bool, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: Unable to acquire a database connection: %v\n", err)
+		return time.Time{}, false, err
+	}
+	defer conn.Release()
+
+	var readAt time.Time
+	err = conn.QueryRow(ctx,
+		`UPDATE chat_user AS cu
+		SET last_read_message_id = m.id,
+			last_read_at = m.sent_at
+		FROM message AS m
+		WHERE cu.user_id = $1 AND cu.chat_id = $2
+			AND m.id = $3 AND m.chat_id = cu.chat_id
+			AND m.sent_at > cu.last_read_at
+		RETURNING cu.last_read_at;`,
+		userId,
+		chatId,
+		messageId,
+	).Scan(&readAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		log.Errorf("не удалось сдвинуть курсор прочтения в чате %v: %v", chatId, err)
+		return time.Time{}, false, err
+	}
+
+	return readAt, true, nil
+}
+
+func (r *ChatRepositoryImpl) GetReadState(ctx context.Context, userId uuid.UUID, chatId uuid.UUID) (chatModel.ReadState, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: Unable to acquire a database connection: %v\n", err)
+		return chatModel.ReadState{}, err
+	}
+	defer conn.Release()
+
+	var state chatModel.ReadState
+	err = conn.QueryRow(ctx,
+		`SELECT `+readStateColumns+`
+		FROM chat_user AS cu
+		WHERE cu.user_id = $1 AND cu.chat_id = $2;`,
+		userId,
+		chatId,
+	).Scan(&state.LastReadMessageId, &state.UnreadCount, &state.UnreadMentions, &state.Muted)
+
+	// например, канал из глобального поиска
+	if errors.Is(err, pgx.ErrNoRows) {
+		return chatModel.ReadState{}, nil
+	}
+	if err != nil {
+		log.Errorf("не удалось получить непрочитанные сообщения чата %v: %v", chatId, err)
+		return chatModel.ReadState{}, err
+	}
+
+	return state, nil
+}
+
+func (r *ChatRepositoryImpl) GetDraft(ctx context.Context, userId uuid.UUID, chatId uuid.UUID) (*chatModel.Draft, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: Unable to acquire a database connection: %v\n", err)
+		return nil, err
+	}
+	defer conn.Release()
+
+	var draft chatModel.Draft
+	err = conn.QueryRow(ctx,
+		`SELECT text, reply_to_id, updated_at
+		FROM chat_draft
+		WHERE user_id = $1 AND chat_id = $2;`,
+		userId,
+		chatId,
+	).Scan(&draft.Text, &draft.ReplyTo, &draft.UpdatedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("не удалось получить черновик чата %v: %v", chatId, err)
+		return nil, err
+	}
+
+	return &draft, nil
+}
+
+func (r *ChatRepositoryImpl) SaveDraft(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, draft chatModel.Draft) error {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: Unable to acquire a database connection: %v\n", err)
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx,
+		`INSERT INTO chat_draft (user_id, chat_id, text, reply_to_id, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, chat_id) DO UPDATE
+		SET text = EXCLUDED.text,
+			reply_to_id = EXCLUDED.reply_to_id,
+			updated_at = EXCLUDED.updated_at;`,
+		userId,
+		chatId,
+		draft.Text,
+		draft.ReplyTo,
+		draft.UpdatedAt,
+	)
+	if err != nil {
+		log.Errorf("не удалось сохранить черновик чата %v: %v", chatId, err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *ChatRepositoryImpl) DeleteDraft(ctx context.Context, userId uuid.UUID, chatId uuid.UUID) (bool, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: Unable to acquire a database connection: %v\n", err)
+		return false, err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx,
+		`DELETE FROM chat_draft WHERE user_id = $1 AND chat_id = $2;`,
+		userId,
+		chatId,
+	)
+	if err != nil {
+		log.Errorf("не удалось удалить черновик чата %v: %v", chatId, err)
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *ChatRepositoryImpl) SetChatMuted(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, muted bool) error {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: Unable to acquire a database connection: %v\n", err)
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx,
+		`UPDATE chat_user SET muted = $3 WHERE user_id = $1 AND chat_id = $2;`,
+		userId,
+		chatId,
+		muted,
+	)
+	if err != nil {
+		log.Errorf("не удалось изменить уведомления чата %v: %v", chatId, err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *ChatRepositoryImpl) PinMessage(ctx context.Context, chatId uuid.UUID, messageId uuid.UUID, userId uuid.UUID) error {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: Unable to acquire a database connection: %v\n", err)
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx,
+		`INSERT INTO chat_pinned_message (chat_id, message_id, pinned_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING;`,
+		chatId,
+		messageId,
+		userId,
+	)
+	if err != nil {
+		log.Errorf("не удалось закрепить сообщение %v в чате %v: %v", messageId, chatId, err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *ChatRepositoryImpl) UnpinMessage(ctx context.Context, chatId uuid.UUID, messageId uuid.UUID) error {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: Unable to acquire a database connection: %v\n", err)
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx,
+		`DELETE FROM chat_pinned_message
+		WHERE chat_id = $1 AND message_id = $2;`,
+		chatId,
+		messageId,
+	)
+	if err != nil {
+		log.Errorf("не удалось открепить сообщение %v в чате %v: %v", messageId, chatId, err)
+		return err
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	chatModel "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/models"
 	"github.com/google/uuid"
@@ -28,4 +29,21 @@ type ChatRepository interface {
 	AddBranch(ctx context.Context, chatId uuid.UUID, messageId uuid.UUID) (chatModel.AddBranch, error)
 	SearchUserChats(ctx context.Context, userId uuid.UUID, keyWord string) ([]chatModel.Chat, error)
 	SearchGlobalChats(ctx context.Context, userId uuid.UUID, keyWord string) ([]chatModel.Chat, error)
+
+	// MarkMessagesAsRead двигает курсор прочтения только вперед. Если курсор не сдвинулся, вернет false
+	MarkMessagesAsRead(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, messageId uuid.UUID) (time.Time, bool, error)
+	// GetReadState для пользователя не из чата вернет пустое состояние
+	GetReadState(ctx context.Context, userId uuid.UUID, chatId uuid.UUID) (chatModel.ReadState, error)
+
+	// GetDraft вернет nil, если черновика нет
+	GetDraft(ctx context.Context, userId uuid.UUID, chatId uuid.UUID) (*chatModel.Draft, error)
+	SaveDraft(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, draft chatModel.Draft) error
+	// DeleteDraft вернет false, если черновика не было
+	DeleteDraft(ctx context.Context, userId uuid.UUID, chatId uuid.UUID) (bool, error)
+
+	SetChatMuted(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, muted bool) error
+
+	// PinMessage повторное закрепление ничего не меняет
+	PinMessage(ctx context.Context, chatId uuid.UUID, messageId uuid.UUID, userId uuid.UUID) error
+	UnpinMessage(ctx context.Context, chatId uuid.UUID, messageId uuid.UUID) error
 }
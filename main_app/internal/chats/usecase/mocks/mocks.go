@@ -96,6 +96,20 @@ func (mr *MockChatUsecaseMockRecorder) DeleteChat(ctx, chatId, userId interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteChat", reflect.TypeOf((*MockChatUsecase)(nil).DeleteChat), ctx, chatId, userId)
 }
 
+// DeleteDraft mocks base method.
+func (m *MockChatUsecase) DeleteDraft(ctx context.Context, userId, chatId uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDraft", ctx, userId, chatId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteDraft indicates an expected call of DeleteDraft.
+func (mr *MockChatUsecaseMockRecorder) DeleteDraft(ctx, userId, chatId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDraft", reflect.TypeOf((*MockChatUsecase)(nil).DeleteDraft), ctx, userId, chatId)
+}
+
 // DeleteUsersFromChat mocks base method.
 func (m *MockChatUsecase) DeleteUsersFromChat(ctx context.Context, userID, chatId uuid.UUID, usertToDelete model.DeleteUsersFromChatDTO) (model.DeletdeUsersFromChatDTO, error) {
 	m.ctrl.T.Helper()
@@ -111,6 +125,37 @@ func (mr *MockChatUsecaseMockRecorder) DeleteUsersFromChat(ctx, userID, chatId,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUsersFromChat", reflect.TypeOf((*MockChatUsecase)(nil).DeleteUsersFromChat), ctx, userID, chatId, usertToDelete)
 }
 
+// GetAvatarAndNameForPersonalChat mocks base method.
+func (m *MockChatUsecase) GetAvatarAndNameForPersonalChat(ctx context.Context, userID, chatId uuid.UUID) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAvatarAndNameForPersonalChat", ctx, userID, chatId)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetAvatarAndNameForPersonalChat indicates an expected call of GetAvatarAndNameForPersonalChat.
+func (mr *MockChatUsecaseMockRecorder) GetAvatarAndNameForPersonalChat(ctx, userID, chatId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAvatarAndNameForPersonalChat", reflect.TypeOf((*MockChatUsecase)(nil).GetAvatarAndNameForPersonalChat), ctx, userID, chatId)
+}
+
+// GetBranch mocks base method.
+func (m *MockChatUsecase) GetBranch(ctx context.Context, userId, branchId uuid.UUID) (model.BranchDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBranch", ctx, userId, branchId)
+	ret0, _ := ret[0].(model.BranchDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBranch indicates an expected call of GetBranch.
+func (mr *MockChatUsecaseMockRecorder) GetBranch(ctx, userId, branchId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBranch", reflect.TypeOf((*MockChatUsecase)(nil).GetBranch), ctx, userId, branchId)
+}
+
 // GetChatInfo mocks base method.
 func (m *MockChatUsecase) GetChatInfo(ctx context.Context, chatId, userId uuid.UUID) (model.ChatInfoDTO, error) {
 	m.ctrl.T.Helper()
@@ -141,6 +186,36 @@ func (mr *MockChatUsecaseMockRecorder) GetChats(ctx, cookie interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChats", reflect.TypeOf((*MockChatUsecase)(nil).GetChats), ctx, cookie)
 }
 
+// GetDraft mocks base method.
+func (m *MockChatUsecase) GetDraft(ctx context.Context, userId, chatId uuid.UUID) (model.Draft, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDraft", ctx, userId, chatId)
+	ret0, _ := ret[0].(model.Draft)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDraft indicates an expected call of GetDraft.
+func (mr *MockChatUsecaseMockRecorder) GetDraft(ctx, userId, chatId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDraft", reflect.TypeOf((*MockChatUsecase)(nil).GetDraft), ctx, userId, chatId)
+}
+
+// GetPinnedMessages mocks base method.
+func (m *MockChatUsecase) GetPinnedMessages(ctx context.Context, userId, chatId uuid.UUID) (model.PinnedMessagesDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPinnedMessages", ctx, userId, chatId)
+	ret0, _ := ret[0].(model.PinnedMessagesDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPinnedMessages indicates an expected call of GetPinnedMessages.
+func (mr *MockChatUsecaseMockRecorder) GetPinnedMessages(ctx, userId, chatId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPinnedMessages", reflect.TypeOf((*MockChatUsecase)(nil).GetPinnedMessages), ctx, userId, chatId)
+}
+
 // GetUserChats mocks base method.
 func (m *MockChatUsecase) GetUserChats(ctx context.Context, userId string) ([]string, error) {
 	m.ctrl.T.Helper()
@@ -171,6 +246,77 @@ func (mr *MockChatUsecaseMockRecorder) GetUsersFromChat(ctx, chatId interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsersFromChat", reflect.TypeOf((*MockChatUsecase)(nil).GetUsersFromChat), ctx, chatId)
 }
 
+// JoinChannel mocks base method.
+func (m *MockChatUsecase) JoinChannel(ctx context.Context, userId, channelId uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "JoinChannel", ctx, userId, channelId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// JoinChannel indicates an expected call of JoinChannel.
+func (mr *MockChatUsecaseMockRecorder) JoinChannel(ctx, userId, channelId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JoinChannel", reflect.TypeOf((*MockChatUsecase)(nil).JoinChannel), ctx, userId, channelId)
+}
+
+// MuteChat mocks base method.
+func (m *MockChatUsecase) MuteChat(ctx context.Context, userId, chatId uuid.UUID, input model.MuteChatDTO) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MuteChat", ctx, userId, chatId, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MuteChat indicates an expected call of MuteChat.
+func (mr *MockChatUsecaseMockRecorder) MuteChat(ctx, userId, chatId, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MuteChat", reflect.TypeOf((*MockChatUsecase)(nil).MuteChat), ctx, userId, chatId, input)
+}
+
+// PinMessage mocks base method.
+func (m *MockChatUsecase) PinMessage(ctx context.Context, userId, chatId, messageId uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PinMessage", ctx, userId, chatId, messageId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PinMessage indicates an expected call of PinMessage.
+func (mr *MockChatUsecaseMockRecorder) PinMessage(ctx, userId, chatId, messageId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PinMessage", reflect.TypeOf((*MockChatUsecase)(nil).PinMessage), ctx, userId, chatId, messageId)
+}
+
+// ReadMessages mocks base method.
+func (m *MockChatUsecase) ReadMessages(ctx context.Context, userId, chatId uuid.UUID, input model.ReadMessagesDTO) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadMessages", ctx, userId, chatId, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReadMessages indicates an expected call of ReadMessages.
+func (mr *MockChatUsecaseMockRecorder) ReadMessages(ctx, userId, chatId, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadMessages", reflect.TypeOf((*MockChatUsecase)(nil).ReadMessages), ctx, userId, chatId, input)
+}
+
+// SaveDraft mocks base method.
+func (m *MockChatUsecase) SaveDraft(ctx context.Context, userId, chatId uuid.UUID, input model.DraftInput) (*model.Draft, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDraft", ctx, userId, chatId, input)
+	ret0, _ := ret[0].(*model.Draft)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveDraft indicates an expected call of SaveDraft.
+func (mr *MockChatUsecaseMockRecorder) SaveDraft(ctx, userId, chatId, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDraft", reflect.TypeOf((*MockChatUsecase)(nil).SaveDraft), ctx, userId, chatId, input)
+}
+
 // SearchChats mocks base method.
 func (m *MockChatUsecase) SearchChats(ctx context.Context, userID uuid.UUID, keyWord string) (model.SearchChatsDTO, error) {
 	m.ctrl.T.Helper()
@@ -186,6 +332,20 @@ func (mr *MockChatUsecaseMockRecorder) SearchChats(ctx, userID, keyWord interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchChats", reflect.TypeOf((*MockChatUsecase)(nil).SearchChats), ctx, userID, keyWord)
 }
 
+// UnpinMessage mocks base method.
+func (m *MockChatUsecase) UnpinMessage(ctx context.Context, userId, chatId, messageId uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnpinMessage", ctx, userId, chatId, messageId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnpinMessage indicates an expected call of UnpinMessage.
+func (mr *MockChatUsecaseMockRecorder) UnpinMessage(ctx, userId, chatId, messageId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnpinMessage", reflect.TypeOf((*MockChatUsecase)(nil).UnpinMessage), ctx, userId, chatId, messageId)
+}
+
 // UpdateChat mocks base method.
 func (m *MockChatUsecase) UpdateChat(ctx context.Context, chatId uuid.UUID, chatUpdate model.ChatUpdate, userId uuid.UUID) (model.ChatUpdateOutput, error) {
 	m.ctrl.T.Helper()
@@ -6,8 +6,12 @@ import (
 	"fmt"
 	"net/http"
 	"sort"
+	"strings"
 	"sync"
+	"time"
+	"unicode/utf8"
 
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/events"
 	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
 	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/metric"
 	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/responser"
@@ -44,6 +48,21 @@ const (
 
 const chatDir = "chat"
 
+// очередь, в которую пишет сервис сообщений
+const messageQueue = "message"
+
+var ErrMessageNotInChat = errors.New("сообщение не найдено в чате")
+
+var ErrNotBranch = errors.New("чат не является веткой")
+
+// максимальная длина черновика в символах
+const maxDraftLength = 4096
+
+var (
+	ErrDraftNotFound = errors.New("черновика нет")
+	ErrDraftTooLong  = fmt.Errorf("черновик длиннее %d символов", maxDraftLength)
+)
+
 // ивенты для сокета
 const (
 	UpdateChat          = "updateChat"
@@ -51,6 +70,8 @@ const (
 	NewChat             = "newChat"
 	DeleteUsersFromChat = "delUsers"
 	AddNewUsersInChat   = "addUsers"
+	PinMessage          = "pinMessage"
+	UnpinMessage        = "unpinMessage"
 )
 
 type ChatUsecaseImpl struct {
@@ -85,7 +106,9 @@ func NewChatUsecase(repository chatlist.ChatRepository, messageRepository messag
 
 func (s *ChatUsecaseImpl) createChatDTO(ctx context.Context, chat chatModel.Chat) (chatModel.ChatDTOOutput, error) {
 	log := logger.LoggerWithCtx(ctx, logger.Log)
-	message, err := s.messageRepository.GetLastMessage(chat.ChatId)
+	// пользователь нужен для его реакций в последнем сообщении и непрочитанных
+	user, _ := ctx.Value(auth.UserKey).(auth.User)
+	message, err := s.messageRepository.GetLastMessage(user.ID, chat.ChatId)
 	if err != nil {
 		log.Printf("Usecase: не удалось получить последнее сообщение: %v", err)
 		return chatModel.ChatDTOOutput{}, err
@@ -101,9 +124,37 @@ func (s *ChatUsecaseImpl) createChatDTO(ctx context.Context, chat chatModel.Chat
 	}
 	log.Println("Usecase: количество пользователей получено")
 
-	return chatModel.СhatToChatDTO(chat,
+	// список чатов получает непрочитанные тем же запросом
+	readState := chat.ReadState
+	if readState == nil {
+		state, err := s.repository.GetReadState(ctx, user.ID, chat.ChatId)
+		if err != nil {
+			log.Printf("Usecase: не удалось получить непрочитанные сообщения: %v", err)
+			return chatModel.ChatDTOOutput{}, err
+		}
+		readState = &state
+	}
+
+	chatDTO := chatModel.СhatToChatDTO(chat,
 		countOfUsers,
-		message), nil
+		message)
+	chatDTO.UnreadCount = readState.UnreadCount
+	chatDTO.LastReadMessageId = readState.LastReadMessageId
+	chatDTO.UnreadMentions = readState.UnreadMentions
+	chatDTO.Muted = readState.Muted
+
+	// черновик списка чатов пришел вместе с непрочитанными
+	if chat.ReadState != nil {
+		chatDTO.Draft = chat.Draft
+	} else {
+		chatDTO.Draft, err = s.repository.GetDraft(ctx, user.ID, chat.ChatId)
+		if err != nil {
+			log.Printf("Usecase: не удалось получить черновик: %v", err)
+			return chatModel.ChatDTOOutput{}, err
+		}
+	}
+
+	return chatDTO, nil
 }
 
 func (s *ChatUsecaseImpl) GetChats(ctx context.Context, cookie []*http.Cookie) ([]chatModel.ChatDTOOutput, error) {
@@ -125,7 +176,7 @@ func (s *ChatUsecaseImpl) GetChats(ctx context.Context, cookie []*http.Cookie) (
 
 	for _, chat := range chats {
 		if chat.ChatType == personal {
-			chat.ChatName, chat.AvatarURL, err = s.getAvatarAndNameForPersonalChat(ctx, user.ID, chat.ChatId)
+			chat.ChatName, chat.AvatarURL, err = s.GetAvatarAndNameForPersonalChat(ctx, user.ID, chat.ChatId)
 
 			if err != nil {
 				log.Errorf("Chat usecase -> GetChats: не удалось обработать персональный чат: %v", err)
@@ -264,7 +315,7 @@ func (s *ChatUsecaseImpl) AddNewChat(ctx context.Context, cookie []*http.Cookie,
 	s.addUsersIntoChat(ctx, chat.UsersToAdd, chatId)
 
 	if newChatDTO.ChatType == personal {
-		newChatDTO.ChatName, newChatDTO.AvatarPath, err = s.getAvatarAndNameForPersonalChat(ctx, user.ID, newChat.ChatId)
+		newChatDTO.ChatName, newChatDTO.AvatarPath, err = s.GetAvatarAndNameForPersonalChat(ctx, user.ID, newChat.ChatId)
 
 		if err != nil {
 			log.Errorf("Chat usecase -> AddNewChat: не удалось обработать персональный чат: %v", err)
@@ -277,7 +328,8 @@ func (s *ChatUsecaseImpl) AddNewChat(ctx context.Context, cookie []*http.Cookie,
 	return newChatDTO, nil
 }
 
-func (s *ChatUsecaseImpl) getAvatarAndNameForPersonalChat(ctx context.Context, userID uuid.UUID, chatId uuid.UUID) (string, string, error) {
+// GetAvatarAndNameForPersonalChat личный чат называется именем собеседника и показывает его аватар
+func (s *ChatUsecaseImpl) GetAvatarAndNameForPersonalChat(ctx context.Context, userID uuid.UUID, chatId uuid.UUID) (string, string, error) {
 	log := logger.LoggerWithCtx(ctx, logger.Log)
 	users, err := s.repository.GetUsersFromChat(ctx, chatId)
 	if err != nil {
@@ -494,6 +546,232 @@ func (s *ChatUsecaseImpl) UserLeaveChat(ctx context.Context, userId uuid.UUID, c
 	return nil
 }
 
+// ReadMessages отмечает сообщения чата прочитанными до указанного включительно
+func (s *ChatUsecaseImpl) ReadMessages(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, input chatModel.ReadMessagesDTO) error {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	role, err := s.repository.GetUserRoleInChat(ctx, userId, chatId)
+	if err != nil {
+		return err
+	}
+
+	if role == NotInChat {
+		return &customerror.NoPermissionError{
+			User: userId.String(),
+			Area: chatId.String(),
+		}
+	}
+
+	var lastRead messageModel.Message
+	if input.MessageId != nil {
+		lastRead, err = s.messageRepository.GetMessageById(ctx, userId, *input.MessageId)
+	} else {
+		lastRead, err = s.messageRepository.GetLastMessage(userId, chatId)
+	}
+	if err != nil {
+		return err
+	}
+
+	// в пустом чате читать нечего
+	if lastRead.MessageId == uuid.Nil && input.MessageId == nil {
+		return nil
+	}
+	if lastRead.MessageId == uuid.Nil || lastRead.ChatId != chatId {
+		return ErrMessageNotInChat
+	}
+
+	readAt, moved, err := s.repository.MarkMessagesAsRead(ctx, userId, chatId, lastRead.MessageId)
+	if err != nil {
+		return err
+	}
+	if !moved {
+		log.Printf("курсор прочтения пользователя %v в чате %v уже дальше", userId, chatId)
+		return nil
+	}
+
+	// в каналах отметки о прочтении не показываются
+	chatType, err := s.repository.GetChatType(ctx, chatId)
+	if err != nil {
+		return err
+	}
+	if chatType != channel {
+		s.sendReadIvent(ctx, userId, readAt, lastRead)
+	}
+
+	return nil
+}
+
+// sendReadIvent отправляет в очередь сообщений отметку о прочтении
+func (s *ChatUsecaseImpl) sendReadIvent(ctx context.Context, userId uuid.UUID, readAt time.Time, lastRead messageModel.Message) {
+	s.publishMessageIvent(ctx, events.MessageEvent{
+		Action: events.MessagesRead,
+		Message: events.Message{
+			MessageId: lastRead.MessageId,
+			AuthorID:  lastRead.AuthorID,
+			SentAt:    lastRead.SentAt,
+			ChatId:    lastRead.ChatId,
+			ReadBy: &events.ReadReceipt{
+				UserId: userId,
+				ReadAt: readAt,
+			},
+		},
+	})
+}
+
+// publishMessageIvent отправляет событие в очередь сервиса сообщений
+func (s *ChatUsecaseImpl) publishMessageIvent(ctx context.Context, newEvent events.MessageEvent) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+
+	body, err := events.SerializeMessageEvent(newEvent)
+	if err != nil {
+		log.Errorf("Не удалось сериализовать объект")
+		return
+	}
+	err = s.ch.PublishWithContext(ctx,
+		"",           // exchange
+		messageQueue, // имя очереди
+		false,        // mandatory
+		false,        // immediate
+		amqp.Publishing{
+			ContentType: "text/plain",
+			Body:        []byte(body),
+		})
+	if err != nil {
+		log.Errorf("не удалось отправить событие %s: %v", newEvent.Action, err)
+	}
+}
+
+// checkChatMember возвращает ошибку доступа, если пользователь не состоит в чате
+func (s *ChatUsecaseImpl) checkChatMember(ctx context.Context, userId uuid.UUID, chatId uuid.UUID) error {
+	role, err := s.repository.GetUserRoleInChat(ctx, userId, chatId)
+	if err != nil {
+		return err
+	}
+
+	if role == NotInChat {
+		return &customerror.NoPermissionError{
+			User: userId.String(),
+			Area: chatId.String(),
+		}
+	}
+
+	return nil
+}
+
+func (s *ChatUsecaseImpl) GetDraft(ctx context.Context, userId uuid.UUID, chatId uuid.UUID) (chatModel.Draft, error) {
+	if err := s.checkChatMember(ctx, userId, chatId); err != nil {
+		return chatModel.Draft{}, err
+	}
+
+	draft, err := s.repository.GetDraft(ctx, userId, chatId)
+	if err != nil {
+		return chatModel.Draft{}, err
+	}
+
+	if draft == nil {
+		return chatModel.Draft{}, ErrDraftNotFound
+	}
+
+	return *draft, nil
+}
+
+// SaveDraft заменяет черновик пользователя и рассылает его на другие устройства.
+// Пустой черновик без ответа удаляется, тогда вернется nil
+func (s *ChatUsecaseImpl) SaveDraft(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, input chatModel.DraftInput) (*chatModel.Draft, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Printf("пользователь %v сохраняет черновик в чате %v", userId, chatId)
+
+	if strings.TrimSpace(input.Text) == "" && input.ReplyTo == nil {
+		return nil, s.DeleteDraft(ctx, userId, chatId)
+	}
+
+	if utf8.RuneCountInString(input.Text) > maxDraftLength {
+		return nil, ErrDraftTooLong
+	}
+
+	if err := s.checkChatMember(ctx, userId, chatId); err != nil {
+		return nil, err
+	}
+
+	if input.ReplyTo != nil {
+		replyTo, err := s.messageRepository.GetMessageById(ctx, userId, *input.ReplyTo)
+		if err != nil {
+			return nil, err
+		}
+		if replyTo.MessageId == uuid.Nil || replyTo.ChatId != chatId {
+			return nil, ErrMessageNotInChat
+		}
+	}
+
+	draft := chatModel.Draft{
+		Text:      input.Text,
+		ReplyTo:   input.ReplyTo,
+		UpdatedAt: time.Now(),
+	}
+
+	err := s.repository.SaveDraft(ctx, userId, chatId, draft)
+	if err != nil {
+		return nil, err
+	}
+
+	s.sendDraftIvent(ctx, userId, chatId, &draft)
+	return &draft, nil
+}
+
+func (s *ChatUsecaseImpl) DeleteDraft(ctx context.Context, userId uuid.UUID, chatId uuid.UUID) error {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Printf("пользователь %v удаляет черновик в чате %v", userId, chatId)
+
+	if err := s.checkChatMember(ctx, userId, chatId); err != nil {
+		return err
+	}
+
+	deleted, err := s.repository.DeleteDraft(ctx, userId, chatId)
+	if err != nil {
+		return err
+	}
+
+	if deleted {
+		s.sendDraftIvent(ctx, userId, chatId, nil)
+	}
+
+	return nil
+}
+
+// MuteChat заглушенный чат остается в списке с флагом muted, клиент не показывает уведомления о его сообщениях
+func (s *ChatUsecaseImpl) MuteChat(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, input chatModel.MuteChatDTO) error {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Printf("пользователь %v меняет уведомления чата %v: muted = %v", userId, chatId, input.Muted)
+
+	if err := s.checkChatMember(ctx, userId, chatId); err != nil {
+		return err
+	}
+
+	return s.repository.SetChatMuted(ctx, userId, chatId, input.Muted)
+}
+
+// sendDraftIvent отправляет новый черновик только самому пользователю.
+// Устройство, которое его сохранило, узнает свое изменение по updatedAt
+func (s *ChatUsecaseImpl) sendDraftIvent(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, draft *chatModel.Draft) {
+	newEvent := events.MessageEvent{
+		Action: events.DraftUpdated,
+		Message: events.Message{
+			AuthorID: userId,
+			ChatId:   chatId,
+		},
+		Recipients: []uuid.UUID{userId},
+	}
+
+	if draft != nil {
+		newEvent.Message.Draft = &events.Draft{
+			Text:      draft.Text,
+			ReplyTo:   draft.ReplyTo,
+			UpdatedAt: draft.UpdatedAt,
+		}
+	}
+
+	s.publishMessageIvent(ctx, newEvent)
+}
+
 func (s *ChatUsecaseImpl) GetChatInfo(ctx context.Context, chatId uuid.UUID, userId uuid.UUID) (chatModel.ChatInfoDTO, error) {
 	role, err := s.repository.GetUserRoleInChat(ctx, userId, chatId)
 	if err != nil {
@@ -524,17 +802,118 @@ func (s *ChatUsecaseImpl) GetChatInfo(ctx context.Context, chatId uuid.UUID, use
 	})
 
 	g.Go(func() error {
-		messages, err = s.messageRepository.GetFirstMessages(ctx, chatId)
+		messages, err = s.messageRepository.GetFirstMessages(ctx, userId, chatId)
 		return err
 	})
 
+	var pinnedMessages []messageModel.Message
+	g.Go(func() error {
+		var pinErr error
+		pinnedMessages, pinErr = s.messageRepository.GetPinnedMessages(ctx, userId, chatId)
+		return pinErr
+	})
+
 	if err := g.Wait(); err != nil {
 		return chatModel.ChatInfoDTO{}, err
 	}
 
 	return chatModel.ChatInfoDTO{
-		Role:     role,
-		Users:    usersDTO,
+		Role:           role,
+		Users:          usersDTO,
+		Messages:       messages,
+		PinnedMessages: pinnedMessages,
+	}, nil
+}
+
+// checkPinPermission закреплять могут владелец и админы, а в личном чате - оба собеседника
+func (s *ChatUsecaseImpl) checkPinPermission(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, messageId uuid.UUID) error {
+	role, err := s.repository.GetUserRoleInChat(ctx, userId, chatId)
+	if err != nil {
+		return err
+	}
+
+	if role == NotInChat {
+		return &customerror.NoPermissionError{
+			User: userId.String(),
+			Area: chatId.String(),
+		}
+	}
+
+	chatType, err := s.repository.GetChatType(ctx, chatId)
+	if err != nil {
+		return err
+	}
+
+	if chatType != personal && role != Owner && role != Admin {
+		return &customerror.NoPermissionError{
+			User: userId.String(),
+			Area: fmt.Sprintf("закрепление сообщений в чате %v", chatId),
+		}
+	}
+
+	message, err := s.messageRepository.GetMessageById(ctx, userId, messageId)
+	if err != nil {
+		return err
+	}
+
+	if message.MessageId == uuid.Nil || message.ChatId != chatId {
+		return ErrMessageNotInChat
+	}
+
+	return nil
+}
+
+func (s *ChatUsecaseImpl) PinMessage(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, messageId uuid.UUID) error {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Printf("пользователь %v закрепляет сообщение %v в чате %v", userId, messageId, chatId)
+
+	if err := s.checkPinPermission(ctx, userId, chatId, messageId); err != nil {
+		return err
+	}
+
+	if err := s.repository.PinMessage(ctx, chatId, messageId, userId); err != nil {
+		return err
+	}
+
+	s.sendMessageIdIvent(ctx, PinMessage, chatId, messageId)
+	return nil
+}
+
+func (s *ChatUsecaseImpl) UnpinMessage(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, messageId uuid.UUID) error {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Printf("пользователь %v открепляет сообщение %v в чате %v", userId, messageId, chatId)
+
+	if err := s.checkPinPermission(ctx, userId, chatId, messageId); err != nil {
+		return err
+	}
+
+	if err := s.repository.UnpinMessage(ctx, chatId, messageId); err != nil {
+		return err
+	}
+
+	s.sendMessageIdIvent(ctx, UnpinMessage, chatId, messageId)
+	return nil
+}
+
+func (s *ChatUsecaseImpl) GetPinnedMessages(ctx context.Context, userId uuid.UUID, chatId uuid.UUID) (chatModel.PinnedMessagesDTO, error) {
+	role, err := s.repository.GetUserRoleInChat(ctx, userId, chatId)
+	if err != nil {
+		return chatModel.PinnedMessagesDTO{}, err
+	}
+
+	if role == NotInChat {
+		return chatModel.PinnedMessagesDTO{}, &customerror.NoPermissionError{
+			User: userId.String(),
+			Area: chatId.String(),
+		}
+	}
+
+	messages, err := s.messageRepository.GetPinnedMessages(ctx, userId, chatId)
+	if err != nil {
+		return chatModel.PinnedMessagesDTO{}, err
+	}
+
+	return chatModel.PinnedMessagesDTO{
 		Messages: messages,
 	}, nil
 }
@@ -569,6 +948,45 @@ func (s *ChatUsecaseImpl) AddBranch(ctx context.Context, chatId uuid.UUID, messa
 	return branch, nil
 }
 
+// GetBranch контекст ветки доступен ее участникам
+func (s *ChatUsecaseImpl) GetBranch(ctx context.Context, userId uuid.UUID, branchId uuid.UUID) (chatModel.BranchDTO, error) {
+	err := s.checkChatMember(ctx, userId, branchId)
+	if err != nil {
+		return chatModel.BranchDTO{}, err
+	}
+
+	parent, err := s.messageRepository.GetBranchParentMessage(ctx, userId, branchId)
+	if err != nil {
+		return chatModel.BranchDTO{}, err
+	}
+
+	if parent.MessageId == uuid.Nil {
+		return chatModel.BranchDTO{}, ErrNotBranch
+	}
+
+	// участник ветки мог выйти из родительского чата, тогда его содержимое ему не показываем
+	err = s.checkChatMember(ctx, userId, parent.ChatId)
+	if err != nil {
+		return chatModel.BranchDTO{}, err
+	}
+
+	chat, err := s.repository.GetChatById(ctx, parent.ChatId)
+	if err != nil {
+		return chatModel.BranchDTO{}, err
+	}
+
+	parentChat, err := s.createChatDTO(ctx, chat)
+	if err != nil {
+		return chatModel.BranchDTO{}, err
+	}
+
+	return chatModel.BranchDTO{
+		ID:            branchId,
+		ParentChat:    parentChat,
+		ParentMessage: parent,
+	}, nil
+}
+
 func (s *ChatUsecaseImpl) SearchChats(ctx context.Context, userID uuid.UUID, keyWord string) (chatModel.SearchChatsDTO, error) {
 	log := logger.LoggerWithCtx(ctx, logger.Log)
 
@@ -588,7 +1006,7 @@ func (s *ChatUsecaseImpl) SearchChats(ctx context.Context, userID uuid.UUID, key
 
 		for _, chat := range userChats {
 			if chat.ChatType == personal {
-				chat.ChatName, chat.AvatarURL, err = s.getAvatarAndNameForPersonalChat(ctx, userID, chat.ChatId)
+				chat.ChatName, chat.AvatarURL, err = s.GetAvatarAndNameForPersonalChat(ctx, userID, chat.ChatId)
 
 				if err != nil {
 					log.Errorf("не удалось обработать персональный чат: %v", err)
@@ -705,12 +1123,24 @@ func convertUsersInChatToDTO(users []chatModel.UserInChatDAO) []chatModel.UserIn
 }
 
 func (s *ChatUsecaseImpl) sendIvent(ctx context.Context, action string, chatId uuid.UUID, users []uuid.UUID) {
-	log := logger.LoggerWithCtx(ctx, logger.Log)
-	newEvent := chatModel.Event{
+	s.publishIvent(ctx, chatModel.Event{
 		Action: action,
 		ChatId: chatId,
 		Users:  users,
-	}
+	})
+}
+
+// sendMessageIdIvent событие чата, относящееся к одному сообщению
+func (s *ChatUsecaseImpl) sendMessageIdIvent(ctx context.Context, action string, chatId uuid.UUID, messageId uuid.UUID) {
+	s.publishIvent(ctx, chatModel.Event{
+		Action:    action,
+		ChatId:    chatId,
+		MessageId: &messageId,
+	})
+}
+
+func (s *ChatUsecaseImpl) publishIvent(ctx context.Context, newEvent chatModel.Event) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
 
 	body, err := chatModel.SerializeEvent(newEvent)
 	if err != nil {
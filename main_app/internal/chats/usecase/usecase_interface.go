@@ -23,16 +23,34 @@ type ChatUsecase interface {
 	DeleteUsersFromChat(ctx context.Context, userID uuid.UUID, chatId uuid.UUID, usertToDelete chatModel.DeleteUsersFromChatDTO) (chatModel.DeletdeUsersFromChatDTO, error)
 
 	JoinChannel(ctx context.Context, userId uuid.UUID, channelId uuid.UUID) error
-	
+
 	// UserLeaveChat удаляет владельца обращения из чата
 	UserLeaveChat(ctx context.Context, userId uuid.UUID, chatId uuid.UUID) error
 
 	GetChatInfo(ctx context.Context, chatId uuid.UUID, userId uuid.UUID) (chatModel.ChatInfoDTO, error)
 
 	AddBranch(ctx context.Context, chatId uuid.UUID, messageID uuid.UUID, userId uuid.UUID) (chatModel.AddBranch, error)
+	// GetBranch сообщение, к которому создана ветка, и чат этого сообщения. Нужно состоять и в ветке, и в родительском чате
+	GetBranch(ctx context.Context, userId uuid.UUID, branchId uuid.UUID) (chatModel.BranchDTO, error)
+
+	// ReadMessages сдвигает курсор прочтения пользователя в чате
+	ReadMessages(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, input chatModel.ReadMessagesDTO) error
+	MuteChat(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, input chatModel.MuteChatDTO) error
+
+	GetDraft(ctx context.Context, userId uuid.UUID, chatId uuid.UUID) (chatModel.Draft, error)
+	// SaveDraft пустой черновик без ответа удаляется, тогда вернется nil
+	SaveDraft(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, input chatModel.DraftInput) (*chatModel.Draft, error)
+	DeleteDraft(ctx context.Context, userId uuid.UUID, chatId uuid.UUID) error
+
+	PinMessage(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, messageId uuid.UUID) error
+	UnpinMessage(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, messageId uuid.UUID) error
+	GetPinnedMessages(ctx context.Context, userId uuid.UUID, chatId uuid.UUID) (chatModel.PinnedMessagesDTO, error)
 
 	SearchChats(ctx context.Context, userID uuid.UUID, keyWord string) (chatModel.SearchChatsDTO, error)
 
+	// GetAvatarAndNameForPersonalChat имя и аватар собеседника в личном чате
+	GetAvatarAndNameForPersonalChat(ctx context.Context, userID uuid.UUID, chatId uuid.UUID) (string, string, error)
+
 	// grpc
 	GetUserChats(ctx context.Context, userId string) (chatIds []string, err error)
 	GetUsersFromChat(ctx context.Context, chatId string) (userIds []string, err error)
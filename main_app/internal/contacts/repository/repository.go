@@ -95,7 +95,7 @@ func (r *Repository) AddContact(ctx context.Context, contactData models.ContactD
 		user_id, 
 		contact_id
 		)
-		VALUES ($1,$2, (SELECT id FROM public."user" WHERE username = $3));`,
+		VALUES ($1,$2, (SELECT id FROM public."user" WHERE username = $3 AND NOT is_imported));`,
 		newUUID.String(),
 		contactData.UserID,
 		contactData.ContactUsername,
@@ -253,6 +253,7 @@ func (r *Repository) SearchGlobalUsers(ctx context.Context, userID uuid.UUID, ke
 			FROM public."user"
 			WHERE 
 				id <> $1 AND
+				NOT is_imported AND
 				(POSITION(LOWER($2) IN LOWER(username)) > 0 OR POSITION(LOWER($2) IN LOWER(name)) > 0)
 		) AS u
 		WHERE id NOT IN (
@@ -0,0 +1,123 @@
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/metric"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/responser"
+	auth "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/usecase"
+
+	"github.com/google/uuid"
+)
+
+// SearchAllMessages godoc
+// @Summary поиск сообщений по всем чатам пользователя
+// @Description Полнотекстовый поиск по всем чатам, в которых состоит пользователь. Результаты сгруппированы по чатам
+// @Tags message
+// @Produce json
+// @Param q query string true "Поиск" example(котики)
+// @Param chatType query string false "Тип чата" Enums(personal, group, channel, branch)
+// @Param authorId query string false "Автор сообщения (UUID)"
+// @Param from query string false "Начало периода (RFC3339)" example(2024-04-13T08:30:00Z)
+// @Param to query string false "Конец периода (RFC3339)" example(2024-04-14T08:30:00Z)
+// @Param hasAttachment query bool false "Только сообщения с вложениями"
+// @Param cursor query string false "nextCursor из предыдущей страницы"
+// @Param limit query int false "Размер страницы, не больше 50" example(20)
+// @Success 200 {object} models.GlobalSearchDTO "Найденные сообщения"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось найти сообщения"
+// @Router /messages/search [get]
+func (h *MessageController) SearchAllMessages(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "SearchAllMessages")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	filter, err := parseGlobalSearchFilter(r)
+	if err != nil {
+		log.Printf("некорректные фильтры поиска: %v", err)
+		responser.SendError(ctx, w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit, err := parsePageLimit(r)
+	if err != nil {
+		responser.SendError(ctx, w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.usecase.SearchAllMessages(ctx, user, filter, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		if errors.Is(err, usecase.ErrEmptySearchQuery) ||
+			errors.Is(err, usecase.ErrBadSearchCursor) ||
+			errors.Is(err, usecase.ErrBadChatType) ||
+			errors.Is(err, usecase.ErrBadDateRange) {
+			responser.SendError(ctx, w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		responser.SendError(ctx, w, fmt.Sprintf("внутренняя ошибка: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	responser.SendStruct(ctx, w, result, http.StatusOK)
+}
+
+func parseGlobalSearchFilter(r *http.Request) (models.GlobalSearchFilter, error) {
+	query := r.URL.Query()
+	filter := models.GlobalSearchFilter{
+		Query:    query.Get("q"),
+		ChatType: query.Get("chatType"),
+	}
+
+	if raw := query.Get("authorId"); raw != "" {
+		authorId, err := uuid.Parse(raw)
+		if err != nil {
+			return models.GlobalSearchFilter{}, fmt.Errorf("некорректный authorId: %v", err)
+		}
+		filter.AuthorID = &authorId
+	}
+
+	if raw := query.Get("from"); raw != "" {
+		from, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return models.GlobalSearchFilter{}, fmt.Errorf("некорректный from: %v", err)
+		}
+		filter.From = &from
+	}
+
+	if raw := query.Get("to"); raw != "" {
+		to, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return models.GlobalSearchFilter{}, fmt.Errorf("некорректный to: %v", err)
+		}
+		filter.To = &to
+	}
+
+	if raw := query.Get("hasAttachment"); raw != "" {
+		hasAttachment, err := strconv.ParseBool(raw)
+		if err != nil {
+			return models.GlobalSearchFilter{}, fmt.Errorf("некорректный hasAttachment: %v", err)
+		}
+		filter.HasAttachment = hasAttachment
+	}
+
+	return filter, nil
+}
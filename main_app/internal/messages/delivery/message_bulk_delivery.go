@@ -0,0 +1,71 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/metric"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/responser"
+	auth "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+)
+
+// DeleteMessages godoc
+// @Summary Delete several messages
+// @Description Удаляет до 300 сообщений одним запросом. Сообщения без доступа не прерывают удаление,
+// @Description а отмечаются в результатах статусом forbidden, несуществующие - notFound
+// @Tags message
+// @Accept json
+// @Produce json
+// @Param messages body models.BulkDeleteInput true "Сообщения и область удаления: me или everyone"
+// @Success 200 {object} models.BulkDeleteDTO "Результат по каждому сообщению"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось удалить сообщения"
+// @Router /messages/delete [post]
+func (h *MessageController) DeleteMessages(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "DeleteMessages")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	var input models.BulkDeleteInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		log.Printf("Не удалось распарсить Json: %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Не удалось распарсить Json: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var result models.BulkDeleteDTO
+	switch input.Scope {
+	case deleteForMe:
+		result, err = h.usecase.HideMessages(ctx, user, input.MessageIds)
+	case deleteForEveryone, "":
+		result, err = h.usecase.DeleteMessages(ctx, user, input.MessageIds)
+	default:
+		responser.SendError(ctx, w, "scope должен быть me или everyone", http.StatusBadRequest)
+		return
+	}
+
+	if err != nil {
+		log.Printf("Не удалось удалить сообщения: %v", err)
+		sendMessageError(ctx, w, err)
+		return
+	}
+
+	responser.SendStruct(ctx, w, result, http.StatusOK)
+}
@@ -5,7 +5,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"mime/multipart"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
@@ -14,6 +17,7 @@ import (
 	auth "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
 	customerror "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/custom_error"
 	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/repository"
 	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/usecase"
 	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/utils/validator"
 	"github.com/prometheus/client_golang/prometheus"
@@ -21,7 +25,8 @@ import (
 	"github.com/google/uuid"
 )
 
-var noPerm error = &customerror.NoPermissionError{User: "Alice", Area: "секретная зона"}
+// максимальный размер multipart запроса с вложениями
+const maxPayloadsSize = 50 << 20
 
 type MessageController struct {
 	usecase usecase.MessageUsecase
@@ -48,12 +53,18 @@ var requestMessageDuration = prometheus.NewHistogramVec(
 
 // AddNewMessageHandler godoc
 // @Summary Add new message
+// @Description Принимает json или multipart/form-data: в поле message_data json сообщения, в полях files вложения.
+// @Description Если передан nonce, то повтор запроса с тем же nonce вернет уже сохраненное сообщение
 // @Tags message
-// @Accept json
+// @Accept json,mpfd
+// @Produce json
 // @Param chatId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
 // @Param message body models.MessageInput true "Message info"
-// @Success 201 "Сообщение успешно добавлено"
+// @Param message_data formData string false "Message info (json)"
+// @Param files formData file false "Вложения"
+// @Success 201 {object} models.Message "Сообщение успешно добавлено"
 // @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 409	{object} responser.ErrorResponse "Сообщение с этим nonce уже исчезло"
 // @Failure 500	{object} responser.ErrorResponse "Не удалось добавить сообщение"
 // @Router /chat/{chatId}/messages [post]
 func (h *MessageController) AddNewMessage(w http.ResponseWriter, r *http.Request) {
@@ -93,32 +104,81 @@ func (h *MessageController) AddNewMessage(w http.ResponseWriter, r *http.Request
 	}
 
 	var messageDTO models.Message
-	err = json.NewDecoder(r.Body).Decode(&messageDTO)
-
+	messageDTO.Files, err = decodeMessageRequest(r, &messageDTO)
+	if r.MultipartForm != nil {
+		defer r.MultipartForm.RemoveAll()
+	}
 	if err != nil {
-		log.Printf("Не удалось распарсить Json: %v", err)
-		responser.SendError(ctx, w, fmt.Sprintf("Не удалось распарсить Json: %v", err), http.StatusBadRequest)
+		log.Println(err)
+		responser.SendError(ctx, w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	err = h.usecase.SendMessage(r.Context(), user, chatUUID, messageDTO)
+	message, err := h.usecase.SendMessage(r.Context(), user, chatUUID, messageDTO)
 
 	if err != nil {
+		if errors.Is(err, usecase.ErrEmptyMessage) ||
+			errors.Is(err, usecase.ErrBadNonce) ||
+			errors.Is(err, usecase.ErrTooManyPayloads) ||
+			errors.Is(err, usecase.ErrStickerWithText) ||
+			errors.Is(err, usecase.ErrBadEntities) ||
+			errors.Is(err, usecase.ErrReplyNotInChat) ||
+			errors.Is(err, repository.ErrStickerNotFound) {
+			responser.SendError(ctx, w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, usecase.ErrNonceExpired) {
+			responser.SendError(ctx, w, err.Error(), http.StatusConflict)
+			return
+		}
 		log.Printf("Не удалось добавить сообщение: %v", err)
 		responser.SendError(ctx, w, fmt.Sprintf("Не удалось добавить сообщение: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	responser.SendStruct(ctx, w, message, http.StatusCreated)
+}
+
+// decodeMessageRequest читает json сообщения из тела или из поля message_data multipart запроса.
+// Возвращает вложения из полей files
+func decodeMessageRequest(r *http.Request, dst any) ([]*multipart.FileHeader, error) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+			return nil, fmt.Errorf("Не удалось распарсить Json: %v", err)
+		}
+		return nil, nil
+	}
+
+	if err := r.ParseMultipartForm(maxPayloadsSize); err != nil {
+		return nil, fmt.Errorf("Unable to parse form: %v", err)
+	}
+
+	jsonString := r.FormValue("message_data")
+	if jsonString != "" {
+		if err := json.Unmarshal([]byte(jsonString), dst); err != nil {
+			return nil, fmt.Errorf("Не удалось распарсить Json: %v", err)
+		}
+	}
+
+	return r.MultipartForm.File["files"], nil
 }
 
+// области удаления сообщения
+const (
+	deleteForMe       = "me"
+	deleteForEveryone = "everyone"
+)
+
 // DeleteMessage godoc
 // @Summary Delete message
+// @Description scope=me удаляет сообщение только у себя, scope=everyone (по умолчанию) - у всех участников чата
 // @Tags message
 // @Param messageId path string true "messageId ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Param scope query string false "У кого удалить сообщение" Enums(me, everyone)
 // @Success 200 "Сообщение успешно удалено"
 // @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
 // @Failure 403	{object} customerror.NoPermissionError "Нет доступа"
+// @Failure 404	{object} responser.ErrorResponse "Сообщение не найдено"
 // @Failure 500	{object} responser.ErrorResponse "Не удалось удалить сообщение"
 // @Router /messages/{messageId} [delete]
 func (h *MessageController) DeleteMessage(w http.ResponseWriter, r *http.Request) {
@@ -151,14 +211,19 @@ func (h *MessageController) DeleteMessage(w http.ResponseWriter, r *http.Request
 		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
 		return
 	}
-	err = h.usecase.DeleteMessage(ctx, user, messageUUID)
+
+	switch r.URL.Query().Get("scope") {
+	case deleteForMe:
+		err = h.usecase.HideMessage(ctx, user, messageUUID)
+	case deleteForEveryone, "":
+		err = h.usecase.DeleteMessage(ctx, user, messageUUID)
+	default:
+		responser.SendError(ctx, w, "scope должен быть me или everyone", http.StatusBadRequest)
+		return
+	}
 
 	if err != nil {
-		if errors.As(err, &noPerm) {
-			responser.SendError(ctx, w, fmt.Sprintf("Нет доступа: %v", err), http.StatusForbidden)
-			return
-		}
-		responser.SendError(ctx, w, fmt.Sprintf("внутренняя ошибка: %v", err), http.StatusInternalServerError)
+		sendMessageError(ctx, w, err)
 		return
 	}
 	responser.SendOK(w, "Сообщение удалено", http.StatusOK)
@@ -166,6 +231,7 @@ func (h *MessageController) DeleteMessage(w http.ResponseWriter, r *http.Request
 
 // UpdateMessage godoc
 // @Summary Update message
+// @Description Разметка entities заменяется целиком: если ее не передать, форматирование пропадет
 // @Tags message
 // @Param message body models.MessageInput true "Message info"
 // @Param messageId path string true "messageId ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
@@ -217,16 +283,75 @@ func (h *MessageController) UpdateMessage(w http.ResponseWriter, r *http.Request
 	err = h.usecase.UpdateMessage(ctx, user, messageUUID, messageDTO)
 
 	if err != nil {
-		if errors.As(err, &noPerm) {
+		if customerror.IsNoPermission(err) {
 			responser.SendError(ctx, w, fmt.Sprintf("Нет доступа: %v", err), http.StatusForbidden)
 			return
 		}
+		if errors.Is(err, usecase.ErrStickerUpdate) ||
+			errors.Is(err, usecase.ErrPollUpdate) ||
+			errors.Is(err, usecase.ErrBadEntities) {
+			responser.SendError(ctx, w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		responser.SendError(ctx, w, fmt.Sprintf("внутренняя ошибка: %v", err), http.StatusInternalServerError)
 		return
 	}
 	responser.SendOK(w, "Сообщение обновлено", http.StatusOK)
 }
 
+// ForwardMessages godoc
+// @Summary Forward messages into chats
+// @Tags message
+// @Accept json
+// @Produce json
+// @Param messages body models.ForwardMessagesInput true "Сообщения и чаты назначения"
+// @Success 201 {object} models.MessagesArrayDTO "Сообщения пересланы"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} customerror.NoPermissionError "Нет доступа"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось переслать сообщения"
+// @Router /messages/forward [post]
+func (h *MessageController) ForwardMessages(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "ForwardMessages")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	var input models.ForwardMessagesInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		log.Printf("Не удалось распарсить Json: %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Не удалось распарсить Json: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	messages, err := h.usecase.ForwardMessages(ctx, user, input)
+	if err != nil {
+		if customerror.IsNoPermission(err) {
+			responser.SendError(ctx, w, fmt.Sprintf("Нет доступа: %v", err), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, usecase.ErrBadForward) || errors.Is(err, usecase.ErrMessageNotFound) ||
+			errors.Is(err, usecase.ErrPollChatType) {
+			responser.SendError(ctx, w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		responser.SendError(ctx, w, fmt.Sprintf("внутренняя ошибка: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	responser.SendStruct(ctx, w, messages, http.StatusCreated)
+}
 
 // GetAllMessages godoc
 // @Summary Get All messages
@@ -266,7 +391,14 @@ func (h *MessageController) GetAllMessages(w http.ResponseWriter, r *http.Reques
 	log.Println(mapVars["chatId"])
 	log.Printf("Message Delivery: starting getting all messages for chat: %v", chatUUID)
 
-	messages, err := h.usecase.GetFirstMessages(r.Context(), chatUUID)
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	messages, err := h.usecase.GetFirstMessages(r.Context(), user.ID, chatUUID)
 	if err != nil {
 		log.Println("Error reading message:", err)
 		responser.SendError(ctx, w, fmt.Sprintf("Error reading message:%v", err), http.StatusInternalServerError)
@@ -291,15 +423,38 @@ func (h *MessageController) GetAllMessages(w http.ResponseWriter, r *http.Reques
 	w.Write(jsonResp)
 }
 
+// направления листания истории
+const (
+	pageOlder = "older"
+	pageNewer = "newer"
+)
+
+// parsePageLimit пустой limit означает размер страницы по умолчанию
+func parsePageLimit(r *http.Request) (int, error) {
+	rawLimit := r.URL.Query().Get("limit")
+	if rawLimit == "" {
+		return 0, nil
+	}
+
+	limit, err := strconv.Atoi(rawLimit)
+	if err != nil {
+		return 0, fmt.Errorf("некорректный limit: %v", err)
+	}
+	return limit, nil
+}
 
 // GetMessagesWithPage godoc
-// @Summary получить 25 сообщений до определенного
+// @Summary получить страницу сообщений до или после определенного
+// @Description Сообщения на странице отсортированы от новых к старым
 // @Tags message
 // @Param chatId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
-// @Param lastMessageId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
-// @Success 200 {object} models.MessagesArrayDTO "Сообщение успешно отаправлены"
+// @Param lastMessageId path string true "Message ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Param direction query string false "older (по умолчанию) или newer" Enums(older, newer)
+// @Param limit query int false "Размер страницы, не больше 100" example(25)
+// @Success 200 {object} models.MessagesPageDTO "Сообщение успешно отаправлены"
 // @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
 // @Failure 403	{object} customerror.NoPermissionError "Нет доступа"
+// @Failure 404	{object} responser.ErrorResponse "Сообщение не найдено в чате"
 // @Failure 500	{object} responser.ErrorResponse "Не удалось получить сообщениея"
 // @Router /chat/{chatId}/messages/pages/{lastMessageId} [get]
 func (h *MessageController) GetMessagesWithPage(w http.ResponseWriter, r *http.Request) {
@@ -345,13 +500,90 @@ func (h *MessageController) GetMessagesWithPage(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	messages, err := h.usecase.GetMessagesWithPage(ctx, user.ID, chatUUID, lastMessageUUID)
+	var older bool
+	switch r.URL.Query().Get("direction") {
+	case pageOlder, "":
+		older = true
+	case pageNewer:
+		older = false
+	default:
+		responser.SendError(ctx, w, "direction должен быть older или newer", http.StatusBadRequest)
+		return
+	}
+
+	limit, err := parsePageLimit(r)
 	if err != nil {
-		if errors.As(err, &noPerm) {
-			responser.SendError(ctx, w, fmt.Sprintf("Нет доступа: %v", err), http.StatusForbidden)
-			return
-		}
-		responser.SendError(ctx, w, fmt.Sprintf("внутренняя ошибка: %v", err), http.StatusInternalServerError)
+		responser.SendError(ctx, w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	messages, err := h.usecase.GetMessagesWithPage(ctx, user.ID, chatUUID, lastMessageUUID, older, limit)
+	if err != nil {
+		sendMessageError(ctx, w, err)
+		return
+	}
+
+	responser.SendStruct(ctx, w, messages, http.StatusOK)
+}
+
+// GetMessagesAround godoc
+// @Summary получить сообщения вокруг определенного
+// @Description Для перехода к ответу или результату поиска. Сообщения отсортированы от новых к старым, само сообщение в середине
+// @Tags message
+// @Param chatId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Param messageId path string true "Message ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Param limit query int false "Размер окна, не больше 100" example(25)
+// @Success 200 {object} models.MessagesPageDTO "Сообщения вокруг указанного"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} customerror.NoPermissionError "Нет доступа"
+// @Failure 404	{object} responser.ErrorResponse "Сообщение не найдено в чате"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось получить сообщения"
+// @Router /chat/{chatId}/messages/around/{messageId} [get]
+func (h *MessageController) GetMessagesAround(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "GetMessagesAround")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	mapVars, ok := ctx.Value(auth.MuxParamsKey).(map[string]string)
+	if !ok {
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	chatUUID, err := uuid.Parse(mapVars["chatId"])
+	if err != nil {
+		log.Printf("Получен кривой Id чата %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Получен кривой Id чата %v", err), http.StatusBadRequest)
+		return
+	}
+
+	messageUUID, err := uuid.Parse(mapVars["messageId"])
+	if err != nil {
+		log.Printf("Получен кривой Id сообщения %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Получен кривой Id сообщения %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	limit, err := parsePageLimit(r)
+	if err != nil {
+		responser.SendError(ctx, w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	messages, err := h.usecase.GetMessagesAround(ctx, user.ID, chatUUID, messageUUID, limit)
+	if err != nil {
+		sendMessageError(ctx, w, err)
 		return
 	}
 
@@ -360,10 +592,13 @@ func (h *MessageController) GetMessagesWithPage(w http.ResponseWriter, r *http.R
 
 // SearchMessages godoc
 // @Summary поиск сообщений
+// @Description Полнотекстовый поиск с учетом словоформ. Результаты отсортированы по релевантности
 // @Tags message
 // @Param chatId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
-// @Param search_query query int false "Поиск" example(sosal?)
-// @Success 200 {object} models.MessagesArrayDTO "Сообщение успешно отаправлены"
+// @Param search_query query string true "Поиск" example(котики)
+// @Param cursor query string false "nextCursor из предыдущей страницы"
+// @Param limit query int false "Размер страницы, не больше 50" example(20)
+// @Success 200 {object} models.SearchMessagesDTO "Сообщение успешно отаправлены"
 // @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
 // @Failure 403	{object} customerror.NoPermissionError "Нет доступа"
 // @Failure 500	{object} responser.ErrorResponse "Не удалось получить сообщениея"
@@ -401,16 +636,21 @@ func (h *MessageController) SearchMessages(w http.ResponseWriter, r *http.Reques
 	}
 	log.Println(r.URL.Query())
 	query := r.URL.Query().Get("search_query")
-	if query == "" {
-		log.Errorf("Поисковый запрос пуст")
-		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+
+	limit, err := parsePageLimit(r)
+	if err != nil {
+		responser.SendError(ctx, w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	messages, err := h.usecase.SearchMessagesWithQuery(ctx, user, chatUUID, query)
+	messages, err := h.usecase.SearchMessagesWithQuery(ctx, user, chatUUID, query, r.URL.Query().Get("cursor"), limit)
 
 	if err != nil {
-		if errors.As(err, &noPerm) {
+		if errors.Is(err, usecase.ErrEmptySearchQuery) || errors.Is(err, usecase.ErrBadSearchCursor) {
+			responser.SendError(ctx, w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if customerror.IsNoPermission(err) {
 			responser.SendError(ctx, w, fmt.Sprintf("Нет доступа: %v", err), http.StatusForbidden)
 			return
 		}
@@ -419,3 +659,206 @@ func (h *MessageController) SearchMessages(w http.ResponseWriter, r *http.Reques
 	}
 	responser.SendStruct(ctx, w, messages, http.StatusOK)
 }
+
+// sendMessageError переводит ошибки работы с существующими сообщениями и настройками чата в http статусы
+func sendMessageError(ctx context.Context, w http.ResponseWriter, err error) {
+	switch {
+	case customerror.IsNoPermission(err):
+		responser.SendError(ctx, w, fmt.Sprintf("Нет доступа: %v", err), http.StatusForbidden)
+	case errors.Is(err, usecase.ErrMessageNotFound):
+		responser.SendError(ctx, w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, usecase.ErrInvalidReaction),
+		errors.Is(err, usecase.ErrReactionNotAllowed),
+		errors.Is(err, usecase.ErrNotChannel),
+		errors.Is(err, usecase.ErrTooManyAllowedReact),
+		errors.Is(err, usecase.ErrBadMessageTTL),
+		errors.Is(err, usecase.ErrBadBulk),
+		errors.Is(err, usecase.ErrBadViews):
+		responser.SendError(ctx, w, err.Error(), http.StatusBadRequest)
+	default:
+		responser.SendError(ctx, w, fmt.Sprintf("внутренняя ошибка: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// AddReaction godoc
+// @Summary Add reaction to message
+// @Tags message
+// @Param messageId path string true "messageId ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Param emoji path string true "Эмодзи реакции" example("👍")
+// @Success 200 "Реакция поставлена"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос или реакция запрещена в канале"
+// @Failure 403	{object} customerror.NoPermissionError "Нет доступа"
+// @Failure 404	{object} responser.ErrorResponse "Сообщение не найдено"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось поставить реакцию"
+// @Router /messages/{messageId}/reactions/{emoji} [put]
+func (h *MessageController) AddReaction(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "AddReaction")
+	}()
+
+	h.handleReaction(w, r, h.usecase.AddReaction, "Реакция поставлена")
+}
+
+// DeleteReaction godoc
+// @Summary Delete reaction from message
+// @Tags message
+// @Param messageId path string true "messageId ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Param emoji path string true "Эмодзи реакции" example("👍")
+// @Success 200 "Реакция убрана"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} customerror.NoPermissionError "Нет доступа"
+// @Failure 404	{object} responser.ErrorResponse "Сообщение не найдено"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось убрать реакцию"
+// @Router /messages/{messageId}/reactions/{emoji} [delete]
+func (h *MessageController) DeleteReaction(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "DeleteReaction")
+	}()
+
+	h.handleReaction(w, r, h.usecase.DeleteReaction, "Реакция убрана")
+}
+
+// handleReaction общая часть постановки и снятия реакции
+func (h *MessageController) handleReaction(w http.ResponseWriter, r *http.Request,
+	action func(ctx context.Context, user auth.User, messageId uuid.UUID, emoji string) error, okMessage string) {
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	mapVars, ok := ctx.Value(auth.MuxParamsKey).(map[string]string)
+	if !ok {
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	messageUUID, err := uuid.Parse(mapVars["messageId"])
+	if err != nil {
+		log.Printf("Получен кривой Id сообщения %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Получен кривой Id сообщения %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	err = action(ctx, user, messageUUID, mapVars["emoji"])
+	if err != nil {
+		sendMessageError(ctx, w, err)
+		return
+	}
+
+	responser.SendOK(w, okMessage, http.StatusOK)
+}
+
+// GetAllowedReactions godoc
+// @Summary Get reactions allowed in channel
+// @Description Пустой список означает, что разрешены все реакции
+// @Tags message
+// @Produce json
+// @Param chatId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Success 200 {object} models.AllowedReactionsDTO "Разрешенные реакции"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} customerror.NoPermissionError "Нет доступа"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось получить реакции"
+// @Router /chat/{chatId}/reactions [get]
+func (h *MessageController) GetAllowedReactions(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "GetAllowedReactions")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	mapVars, ok := ctx.Value(auth.MuxParamsKey).(map[string]string)
+	if !ok {
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	chatUUID, err := uuid.Parse(mapVars["chatId"])
+	if err != nil {
+		log.Printf("Получен кривой Id чата %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Получен кривой Id чата %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	reactions, err := h.usecase.GetAllowedReactions(ctx, user, chatUUID)
+	if err != nil {
+		sendMessageError(ctx, w, err)
+		return
+	}
+
+	responser.SendStruct(ctx, w, reactions, http.StatusOK)
+}
+
+// SetAllowedReactions godoc
+// @Summary Set reactions allowed in channel
+// @Description Доступно владельцу и админам канала. Пустой список снимает ограничение
+// @Tags message
+// @Accept json
+// @Param chatId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Param reactions body models.AllowedReactionsDTO true "Разрешенные реакции"
+// @Success 200 "Реакции обновлены"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} customerror.NoPermissionError "Нет доступа"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось обновить реакции"
+// @Router /chat/{chatId}/reactions [put]
+func (h *MessageController) SetAllowedReactions(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "SetAllowedReactions")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	mapVars, ok := ctx.Value(auth.MuxParamsKey).(map[string]string)
+	if !ok {
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	chatUUID, err := uuid.Parse(mapVars["chatId"])
+	if err != nil {
+		log.Printf("Получен кривой Id чата %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Получен кривой Id чата %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	var input models.AllowedReactionsDTO
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		log.Printf("Не удалось распарсить Json: %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Не удалось распарсить Json: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	err = h.usecase.SetAllowedReactions(ctx, user, chatUUID, input)
+	if err != nil {
+		sendMessageError(ctx, w, err)
+		return
+	}
+
+	responser.SendOK(w, "Реакции обновлены", http.StatusOK)
+}
@@ -0,0 +1,230 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/metric"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/responser"
+	auth "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	customerror "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/custom_error"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/usecase"
+
+	"github.com/google/uuid"
+)
+
+// sendExportError переводит ошибки выгрузки в http статусы
+func sendExportError(ctx context.Context, w http.ResponseWriter, err error) {
+	switch {
+	case customerror.IsNoPermission(err):
+		responser.SendError(ctx, w, fmt.Sprintf("Нет доступа: %v", err), http.StatusForbidden)
+	case errors.Is(err, usecase.ErrExportNotFound):
+		responser.SendError(ctx, w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, usecase.ErrExportNotReady):
+		responser.SendError(ctx, w, err.Error(), http.StatusConflict)
+	case errors.Is(err, usecase.ErrBadExportFormat),
+		errors.Is(err, usecase.ErrBadExportPeriod):
+		responser.SendError(ctx, w, err.Error(), http.StatusBadRequest)
+	default:
+		responser.SendError(ctx, w, fmt.Sprintf("внутренняя ошибка: %v", err), http.StatusInternalServerError)
+	}
+}
+
+func parseExportInput(r *http.Request) (models.ExportInput, error) {
+	query := r.URL.Query()
+	input := models.ExportInput{
+		Format: query.Get("format"),
+	}
+	if input.Format == "" {
+		input.Format = models.ExportJSON
+	}
+
+	if raw := query.Get("from"); raw != "" {
+		from, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return models.ExportInput{}, fmt.Errorf("некорректный from: %v", err)
+		}
+		input.From = &from
+	}
+
+	if raw := query.Get("to"); raw != "" {
+		to, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return models.ExportInput{}, fmt.Errorf("некорректный to: %v", err)
+		}
+		input.To = &to
+	}
+
+	return input, nil
+}
+
+// ExportChat godoc
+// @Summary Export chat history
+// @Description Небольшая история отдается файлом сразу. Если сообщений больше 5000, выгрузка выполняется в фоне:
+// @Description возвращается 202 с задачей, готовность проверяется через GET /exports/{exportId}
+// @Tags message
+// @Produce json,html,plain
+// @Param chatId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Param format query string false "Формат выгрузки" Enums(json, html, csv) default(json)
+// @Param from query string false "Начало периода (RFC3339)" example(2024-04-13T08:30:00Z)
+// @Param to query string false "Конец периода (RFC3339)" example(2024-04-14T08:30:00Z)
+// @Success 200 {file} string "Файл выгрузки"
+// @Success 202 {object} models.ChatExport "Выгрузка поставлена в очередь"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} customerror.NoPermissionError "Нет доступа"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось выгрузить историю"
+// @Router /chat/{chatId}/export [get]
+func (h *MessageController) ExportChat(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "ExportChat")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	mapVars, ok := ctx.Value(auth.MuxParamsKey).(map[string]string)
+	if !ok {
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	chatUUID, err := uuid.Parse(mapVars["chatId"])
+	if err != nil {
+		log.Printf("Получен кривой Id чата %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Получен кривой Id чата %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	input, err := parseExportInput(r)
+	if err != nil {
+		responser.SendError(ctx, w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	export, err := h.usecase.PrepareExport(ctx, user, chatUUID, input)
+	if err != nil {
+		log.Printf("Не удалось начать выгрузку чата %v: %v", chatUUID, err)
+		sendExportError(ctx, w, err)
+		return
+	}
+
+	if export != nil {
+		responser.SendStruct(ctx, w, export, http.StatusAccepted)
+		return
+	}
+
+	w.Header().Set("Content-Type", usecase.ExportContentType(input.Format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, usecase.ExportFilename(chatUUID, input.Format)))
+
+	// заголовки уже отправлены, поэтому ошибку посреди выгрузки можно только залогировать
+	err = h.usecase.WriteExport(ctx, user, chatUUID, input, w)
+	if err != nil {
+		log.Errorf("Не удалось выгрузить историю чата %v: %v", chatUUID, err)
+	}
+}
+
+// GetExport godoc
+// @Summary Get chat export status
+// @Tags message
+// @Produce json
+// @Param exportId path string true "Export ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Success 200 {object} models.ChatExport "Состояние выгрузки, у готовой есть url"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 404	{object} responser.ErrorResponse "Выгрузка не найдена"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось получить выгрузку"
+// @Router /exports/{exportId} [get]
+func (h *MessageController) GetExport(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "GetExport")
+	}()
+
+	ctx := r.Context()
+	user, exportUUID, ok := exportParams(w, r)
+	if !ok {
+		return
+	}
+
+	export, err := h.usecase.GetExport(ctx, user, exportUUID)
+	if err != nil {
+		sendExportError(ctx, w, err)
+		return
+	}
+
+	responser.SendStruct(ctx, w, export, http.StatusOK)
+}
+
+// DownloadExport godoc
+// @Summary Download finished chat export
+// @Tags message
+// @Produce json,html,plain
+// @Param exportId path string true "Export ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Success 200 {file} string "Файл выгрузки"
+// @Failure 404	{object} responser.ErrorResponse "Выгрузка не найдена"
+// @Failure 409	{object} responser.ErrorResponse "Выгрузка еще не готова"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось получить выгрузку"
+// @Router /exports/{exportId}/file [get]
+func (h *MessageController) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "DownloadExport")
+	}()
+
+	ctx := r.Context()
+	user, exportUUID, ok := exportParams(w, r)
+	if !ok {
+		return
+	}
+
+	export, err := h.usecase.GetExportFile(ctx, user, exportUUID)
+	if err != nil {
+		sendExportError(ctx, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", usecase.ExportContentType(export.Format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, usecase.ExportFilename(export.ChatId, export.Format)))
+	http.ServeFile(w, r, *export.FilePath)
+}
+
+// exportParams достает пользователя и id выгрузки, при ошибке сам отвечает клиенту
+func exportParams(w http.ResponseWriter, r *http.Request) (auth.User, uuid.UUID, bool) {
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	mapVars, ok := ctx.Value(auth.MuxParamsKey).(map[string]string)
+	if !ok {
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return auth.User{}, uuid.Nil, false
+	}
+
+	exportUUID, err := uuid.Parse(mapVars["exportId"])
+	if err != nil {
+		log.Printf("Получен кривой Id выгрузки %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Получен кривой Id выгрузки %v", err), http.StatusBadRequest)
+		return auth.User{}, uuid.Nil, false
+	}
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return auth.User{}, uuid.Nil, false
+	}
+
+	return user, exportUUID, true
+}
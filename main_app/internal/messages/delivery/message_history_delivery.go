@@ -0,0 +1,172 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/metric"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/responser"
+	auth "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+
+	"github.com/google/uuid"
+)
+
+// GetMessageHistory godoc
+// @Summary Get message edit history
+// @Description Прежние версии текста от первой к последней. Если владелец скрыл историю, то ее видят только автор, владелец и админы
+// @Tags message
+// @Produce json
+// @Param messageId path string true "messageId ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Success 200 {object} models.MessageHistoryDTO "История правок"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} customerror.NoPermissionError "Нет доступа"
+// @Failure 404	{object} responser.ErrorResponse "Сообщение не найдено"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось получить историю"
+// @Router /messages/{messageId}/history [get]
+func (h *MessageController) GetMessageHistory(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "GetMessageHistory")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	mapVars, ok := ctx.Value(auth.MuxParamsKey).(map[string]string)
+	if !ok {
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	messageUUID, err := uuid.Parse(mapVars["messageId"])
+	if err != nil {
+		log.Printf("Получен кривой Id сообщения %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Получен кривой Id сообщения %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	history, err := h.usecase.GetMessageHistory(ctx, user, messageUUID)
+	if err != nil {
+		sendMessageError(ctx, w, err)
+		return
+	}
+
+	responser.SendStruct(ctx, w, history, http.StatusOK)
+}
+
+// GetHistorySettings godoc
+// @Summary Get edit history visibility of chat
+// @Tags message
+// @Produce json
+// @Param chatId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Success 200 {object} models.HistorySettingsDTO "Настройка истории правок"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} customerror.NoPermissionError "Нет доступа"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось получить настройку"
+// @Router /chat/{chatId}/settings/history [get]
+func (h *MessageController) GetHistorySettings(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "GetHistorySettings")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	mapVars, ok := ctx.Value(auth.MuxParamsKey).(map[string]string)
+	if !ok {
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	chatUUID, err := uuid.Parse(mapVars["chatId"])
+	if err != nil {
+		log.Printf("Получен кривой Id чата %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Получен кривой Id чата %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	settings, err := h.usecase.GetHistorySettings(ctx, user, chatUUID)
+	if err != nil {
+		sendMessageError(ctx, w, err)
+		return
+	}
+
+	responser.SendStruct(ctx, w, settings, http.StatusOK)
+}
+
+// SetHistorySettings godoc
+// @Summary Allow or forbid viewing edit history in chat
+// @Description Доступно только владельцу чата
+// @Tags message
+// @Accept json
+// @Param chatId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Param settings body models.HistorySettingsDTO true "Настройка истории правок"
+// @Success 200 "Настройка обновлена"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} customerror.NoPermissionError "Нет доступа"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось обновить настройку"
+// @Router /chat/{chatId}/settings/history [put]
+func (h *MessageController) SetHistorySettings(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "SetHistorySettings")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	mapVars, ok := ctx.Value(auth.MuxParamsKey).(map[string]string)
+	if !ok {
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	chatUUID, err := uuid.Parse(mapVars["chatId"])
+	if err != nil {
+		log.Printf("Получен кривой Id чата %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Получен кривой Id чата %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	var input models.HistorySettingsDTO
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		log.Printf("Не удалось распарсить Json: %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Не удалось распарсить Json: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	err = h.usecase.SetHistorySettings(ctx, user, chatUUID, input)
+	if err != nil {
+		sendMessageError(ctx, w, err)
+		return
+	}
+
+	responser.SendOK(w, "Настройка обновлена", http.StatusOK)
+}
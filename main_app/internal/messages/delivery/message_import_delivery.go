@@ -0,0 +1,157 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/metric"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/responser"
+	auth "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/usecase"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// максимальный размер запроса импорта вместе с архивом медиа
+	maxImportSize = 1 << 30
+	// часть формы импорта, которая держится в памяти, остальное пишется во временные файлы
+	maxImportMemory = 32 << 20
+)
+
+// sendImportError переводит ошибки импорта в http статусы
+func sendImportError(ctx context.Context, w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrImportNotFound):
+		responser.SendError(ctx, w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, usecase.ErrBadTelegramExport),
+		errors.Is(err, usecase.ErrBadImportMedia),
+		errors.Is(err, usecase.ErrImportUnknownUser),
+		errors.Is(err, usecase.ErrImportForeignAuthor):
+		responser.SendError(ctx, w, err.Error(), http.StatusBadRequest)
+	default:
+		responser.SendError(ctx, w, fmt.Sprintf("внутренняя ошибка: %v", err), http.StatusInternalServerError)
+	}
+}
+
+func formFile(form *multipart.Form, name string) *multipart.FileHeader {
+	if files := form.File[name]; len(files) > 0 {
+		return files[0]
+	}
+	return nil
+}
+
+// ImportChat godoc
+// @Summary Импортировать историю чата из Telegram Desktop
+// @Description Создает группу с историей из выгрузки Telegram Desktop (один чат, формат JSON).
+// @Description Импорт выполняется в фоне: возвращается 202 с задачей, прогресс и журнал ошибок - через GET /imports/{importId}
+// @Tags message
+// @Accept multipart/form-data
+// @Produce json
+// @Param result formData file true "result.json из папки выгрузки"
+// @Param media formData file false "zip-архив папки выгрузки с медиа"
+// @Param import_data formData string false "Сопоставление авторов со своим логином (json): {\"authors\": {\"user123456\": \"login\"}}"
+// @Success 202 {object} models.ChatImport "Импорт поставлен в очередь"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось начать импорт"
+// @Router /chat/import [post]
+func (h *MessageController) ImportChat(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "ImportChat")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	err := r.ParseMultipartForm(maxImportMemory)
+	if r.MultipartForm != nil {
+		defer r.MultipartForm.RemoveAll()
+	}
+	if err != nil {
+		responser.SendError(ctx, w, fmt.Sprintf("Unable to parse form: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var input models.ImportInput
+	if jsonString := r.FormValue("import_data"); jsonString != "" {
+		if err := json.Unmarshal([]byte(jsonString), &input); err != nil {
+			responser.SendError(ctx, w, fmt.Sprintf("Не удалось распарсить Json: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	chatImport, err := h.usecase.StartImport(ctx, user, input,
+		formFile(r.MultipartForm, "result"), formFile(r.MultipartForm, "media"))
+	if err != nil {
+		log.Printf("Не удалось начать импорт: %v", err)
+		sendImportError(ctx, w, err)
+		return
+	}
+
+	responser.SendStruct(ctx, w, chatImport, http.StatusAccepted)
+}
+
+// GetImport godoc
+// @Summary Получить прогресс импорта чата
+// @Tags message
+// @Produce json
+// @Param importId path string true "Import ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Success 200 {object} models.ChatImport "Прогресс импорта и ошибки по сообщениям"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 404	{object} responser.ErrorResponse "Импорт не найден"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось получить импорт"
+// @Router /imports/{importId} [get]
+func (h *MessageController) GetImport(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "GetImport")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	mapVars, ok := ctx.Value(auth.MuxParamsKey).(map[string]string)
+	if !ok {
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	importUUID, err := uuid.Parse(mapVars["importId"])
+	if err != nil {
+		log.Printf("Получен кривой Id импорта %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Получен кривой Id импорта %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	chatImport, err := h.usecase.GetImport(ctx, user, importUUID)
+	if err != nil {
+		sendImportError(ctx, w, err)
+		return
+	}
+
+	responser.SendStruct(ctx, w, chatImport, http.StatusOK)
+}
@@ -0,0 +1,83 @@
+package delivery
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/metric"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/responser"
+	auth "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+
+	"github.com/google/uuid"
+)
+
+// GetMentions godoc
+// @Summary упоминания текущего пользователя
+// @Description Сообщения из всех чатов пользователя, в которых его упомянули через @username. От новых к старым
+// @Tags message
+// @Produce json
+// @Param before query string false "Id последнего упоминания предыдущей страницы (UUID)"
+// @Param unread query bool false "Только непрочитанные упоминания"
+// @Param limit query int false "Размер страницы, не больше 100" example(25)
+// @Success 200 {object} models.MentionsDTO "Упоминания"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось получить упоминания"
+// @Router /mentions [get]
+func (h *MessageController) GetMentions(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "GetMentions")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+
+	var before *uuid.UUID
+	if raw := query.Get("before"); raw != "" {
+		beforeId, err := uuid.Parse(raw)
+		if err != nil {
+			log.Printf("Получен кривой Id сообщения %v", err)
+			responser.SendError(ctx, w, fmt.Sprintf("Получен кривой Id сообщения %v", err), http.StatusBadRequest)
+			return
+		}
+		before = &beforeId
+	}
+
+	unreadOnly := false
+	if raw := query.Get("unread"); raw != "" {
+		var err error
+		unreadOnly, err = strconv.ParseBool(raw)
+		if err != nil {
+			responser.SendError(ctx, w, fmt.Sprintf("некорректный unread: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit, err := parsePageLimit(r)
+	if err != nil {
+		responser.SendError(ctx, w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mentions, err := h.usecase.GetMentions(ctx, user, before, unreadOnly, limit)
+	if err != nil {
+		log.Errorf("не удалось получить упоминания: %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("внутренняя ошибка: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	responser.SendStruct(ctx, w, mentions, http.StatusOK)
+}
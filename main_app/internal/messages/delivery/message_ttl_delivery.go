@@ -0,0 +1,123 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/metric"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/responser"
+	auth "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+
+	"github.com/google/uuid"
+)
+
+// GetMessageTTL godoc
+// @Summary Получить настройку исчезающих сообщений чата
+// @Tags message
+// @Produce json
+// @Param chatId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Success 200 {object} models.MessageTTLDTO "Время жизни сообщений"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} customerror.NoPermissionError "Нет доступа"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось получить настройку"
+// @Router /chat/{chatId}/settings/ttl [get]
+func (h *MessageController) GetMessageTTL(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "GetMessageTTL")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	mapVars, ok := ctx.Value(auth.MuxParamsKey).(map[string]string)
+	if !ok {
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	chatUUID, err := uuid.Parse(mapVars["chatId"])
+	if err != nil {
+		log.Printf("Получен кривой Id чата %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Получен кривой Id чата %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	settings, err := h.usecase.GetMessageTTL(ctx, user, chatUUID)
+	if err != nil {
+		sendMessageError(ctx, w, err)
+		return
+	}
+
+	responser.SendStruct(ctx, w, settings, http.StatusOK)
+}
+
+// SetMessageTTL godoc
+// @Summary Включить или выключить исчезающие сообщения
+// @Description Доступно владельцу чата, в личном чате - обоим собеседникам. Действует только на новые сообщения.
+// @Description ttl в секундах, null выключает исчезающие сообщения
+// @Tags message
+// @Accept json
+// @Param chatId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Param settings body models.MessageTTLDTO true "Время жизни сообщений"
+// @Success 200 "Настройка обновлена"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} customerror.NoPermissionError "Нет доступа"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось обновить настройку"
+// @Router /chat/{chatId}/settings/ttl [put]
+func (h *MessageController) SetMessageTTL(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "SetMessageTTL")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	mapVars, ok := ctx.Value(auth.MuxParamsKey).(map[string]string)
+	if !ok {
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	chatUUID, err := uuid.Parse(mapVars["chatId"])
+	if err != nil {
+		log.Printf("Получен кривой Id чата %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Получен кривой Id чата %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	var input models.MessageTTLDTO
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		log.Printf("Не удалось распарсить Json: %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Не удалось распарсить Json: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	err = h.usecase.SetMessageTTL(ctx, user, chatUUID, input)
+	if err != nil {
+		sendMessageError(ctx, w, err)
+		return
+	}
+
+	responser.SendOK(w, "Настройка обновлена", http.StatusOK)
+}
@@ -0,0 +1,77 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/metric"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/responser"
+	auth "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+
+	"github.com/google/uuid"
+)
+
+// ViewMessages godoc
+// @Summary Mark channel posts as viewed
+// @Description Засчитывает просмотр до 100 постов канала, например при прокрутке ленты без загрузки истории.
+// @Description Свои посты, сообщения не из каналов и повторные просмотры не учитываются.
+// @Description Новые счетчики приходят всем участникам событием viewsUpdated
+// @Tags message
+// @Accept json
+// @Param chatId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Param views body models.ViewMessagesInput true "Просмотренные посты"
+// @Success 200 "Просмотры учтены"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} customerror.NoPermissionError "Нет доступа"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось учесть просмотры"
+// @Router /chat/{chatId}/views [post]
+func (h *MessageController) ViewMessages(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "ViewMessages")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	mapVars, ok := ctx.Value(auth.MuxParamsKey).(map[string]string)
+	if !ok {
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	chatUUID, err := uuid.Parse(mapVars["chatId"])
+	if err != nil {
+		log.Printf("Получен кривой Id чата %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Получен кривой Id чата %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	var input models.ViewMessagesInput
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		log.Printf("Не удалось распарсить Json: %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Не удалось распарсить Json: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	err = h.usecase.ViewMessages(ctx, user, chatUUID, input.MessageIds)
+	if err != nil {
+		log.Printf("Не удалось учесть просмотры в чате %v: %v", chatUUID, err)
+		sendMessageError(ctx, w, err)
+		return
+	}
+
+	responser.SendOK(w, "Просмотры учтены", http.StatusOK)
+}
@@ -0,0 +1,185 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/metric"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/responser"
+	auth "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	customerror "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/custom_error"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/usecase"
+
+	"github.com/google/uuid"
+)
+
+// sendPollError переводит ошибки опросов в http статусы
+func sendPollError(ctx context.Context, w http.ResponseWriter, err error) {
+	switch {
+	case customerror.IsNoPermission(err):
+		responser.SendError(ctx, w, fmt.Sprintf("Нет доступа: %v", err), http.StatusForbidden)
+	case errors.Is(err, usecase.ErrMessageNotFound):
+		responser.SendError(ctx, w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, usecase.ErrBadPoll),
+		errors.Is(err, usecase.ErrPollChatType),
+		errors.Is(err, usecase.ErrNotPoll),
+		errors.Is(err, usecase.ErrPollClosed),
+		errors.Is(err, usecase.ErrBadPollVote),
+		errors.Is(err, usecase.ErrQuizRevote),
+		errors.Is(err, usecase.ErrPollWithoutVote):
+		responser.SendError(ctx, w, err.Error(), http.StatusBadRequest)
+	default:
+		responser.SendError(ctx, w, fmt.Sprintf("внутренняя ошибка: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// SendPoll godoc
+// @Summary Send poll
+// @Description Опрос или викторина в группе или канале. Вопрос становится текстом сообщения
+// @Tags message
+// @Accept json
+// @Param chatId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Param poll body models.PollInput true "Опрос"
+// @Success 201 "Опрос отправлен"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный опрос"
+// @Failure 403	{object} customerror.NoPermissionError "Нет доступа"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось отправить опрос"
+// @Router /chat/{chatId}/polls [post]
+func (h *MessageController) SendPoll(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "SendPoll")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	mapVars, ok := ctx.Value(auth.MuxParamsKey).(map[string]string)
+	if !ok {
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	chatUUID, err := uuid.Parse(mapVars["chatId"])
+	if err != nil {
+		log.Printf("Получен кривой Id чата %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Получен кривой Id чата %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	var input models.PollInput
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		log.Printf("Не удалось распарсить Json: %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Не удалось распарсить Json: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	err = h.usecase.SendPoll(ctx, user, chatUUID, input)
+	if err != nil {
+		log.Printf("Не удалось отправить опрос: %v", err)
+		sendPollError(ctx, w, err)
+		return
+	}
+
+	responser.SendOK(w, "Опрос отправлен", http.StatusCreated)
+}
+
+// VotePoll godoc
+// @Summary Vote in poll
+// @Description Заменяет голос пользователя. В викторине ответ изменить нельзя
+// @Tags message
+// @Accept json
+// @Produce json
+// @Param messageId path string true "messageId ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Param vote body models.PollVoteInput true "Номера выбранных вариантов, начиная с 0"
+// @Success 200 {object} models.Poll "Результаты опроса"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный выбор или опрос закрыт"
+// @Failure 403	{object} customerror.NoPermissionError "Нет доступа"
+// @Failure 404	{object} responser.ErrorResponse "Сообщение не найдено"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось проголосовать"
+// @Router /messages/{messageId}/poll/vote [put]
+func (h *MessageController) VotePoll(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "VotePoll")
+	}()
+
+	var input models.PollVoteInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		responser.SendError(r.Context(), w, fmt.Sprintf("Не удалось распарсить Json: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	h.handleVote(w, r, input)
+}
+
+// RetractPollVote godoc
+// @Summary Retract poll vote
+// @Tags message
+// @Produce json
+// @Param messageId path string true "messageId ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Success 200 {object} models.Poll "Результаты опроса"
+// @Failure 400	{object} responser.ErrorResponse "Пользователь не голосовал, опрос закрыт или это викторина"
+// @Failure 403	{object} customerror.NoPermissionError "Нет доступа"
+// @Failure 404	{object} responser.ErrorResponse "Сообщение не найдено"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось отозвать голос"
+// @Router /messages/{messageId}/poll/vote [delete]
+func (h *MessageController) RetractPollVote(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "RetractPollVote")
+	}()
+
+	h.handleVote(w, r, models.PollVoteInput{Options: []int{}})
+}
+
+// handleVote общая часть голосования и отзыва голоса
+func (h *MessageController) handleVote(w http.ResponseWriter, r *http.Request, input models.PollVoteInput) {
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	mapVars, ok := ctx.Value(auth.MuxParamsKey).(map[string]string)
+	if !ok {
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	messageUUID, err := uuid.Parse(mapVars["messageId"])
+	if err != nil {
+		log.Printf("Получен кривой Id сообщения %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Получен кривой Id сообщения %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	poll, err := h.usecase.VotePoll(ctx, user, messageUUID, input)
+	if err != nil {
+		log.Printf("Не удалось проголосовать в опросе %v: %v", messageUUID, err)
+		sendPollError(ctx, w, err)
+		return
+	}
+
+	responser.SendStruct(ctx, w, poll, http.StatusOK)
+}
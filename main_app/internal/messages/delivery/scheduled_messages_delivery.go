@@ -0,0 +1,267 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/metric"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/responser"
+	auth "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	customerror "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/custom_error"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/repository"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/usecase"
+
+	"github.com/google/uuid"
+)
+
+// sendScheduledError переводит ошибки отложенных сообщений в http статусы
+func sendScheduledError(ctx context.Context, w http.ResponseWriter, err error) {
+	switch {
+	case customerror.IsNoPermission(err):
+		responser.SendError(ctx, w, fmt.Sprintf("Нет доступа: %v", err), http.StatusForbidden)
+	case errors.Is(err, usecase.ErrScheduledNotFound):
+		responser.SendError(ctx, w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, usecase.ErrScheduleInPast),
+		errors.Is(err, usecase.ErrEmptyScheduledUpdate),
+		errors.Is(err, usecase.ErrScheduledStickerUpdate),
+		errors.Is(err, usecase.ErrEmptyMessage),
+		errors.Is(err, usecase.ErrTooManyPayloads),
+		errors.Is(err, usecase.ErrStickerWithText),
+		errors.Is(err, usecase.ErrBadEntities),
+		errors.Is(err, usecase.ErrReplyNotInChat),
+		errors.Is(err, repository.ErrStickerNotFound):
+		responser.SendError(ctx, w, err.Error(), http.StatusBadRequest)
+	default:
+		responser.SendError(ctx, w, fmt.Sprintf("внутренняя ошибка: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// ScheduleMessage godoc
+// @Summary Schedule message
+// @Description Принимает json или multipart/form-data: в поле message_data json сообщения, в полях files вложения.
+// @Description Сообщение будет отправлено в sendAt
+// @Tags message
+// @Accept json,mpfd
+// @Produce json
+// @Param chatId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Param message body models.ScheduledMessageInput true "Message info"
+// @Param message_data formData string false "Message info (json)"
+// @Param files formData file false "Вложения"
+// @Success 201 {object} models.ScheduledMessage "Сообщение запланировано"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} customerror.NoPermissionError "Нет доступа"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось запланировать сообщение"
+// @Router /chat/{chatId}/scheduled [post]
+func (h *MessageController) ScheduleMessage(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "ScheduleMessage")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	mapVars, ok := ctx.Value(auth.MuxParamsKey).(map[string]string)
+	if !ok {
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	chatUUID, err := uuid.Parse(mapVars["chatId"])
+	if err != nil {
+		log.Printf("Получен кривой Id чата %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Получен кривой Id чата %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	var input models.ScheduledMessageInput
+	input.Files, err = decodeMessageRequest(r, &input)
+	if r.MultipartForm != nil {
+		defer r.MultipartForm.RemoveAll()
+	}
+	if err != nil {
+		log.Println(err)
+		responser.SendError(ctx, w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	scheduled, err := h.usecase.ScheduleMessage(ctx, user, chatUUID, input)
+	if err != nil {
+		log.Printf("Не удалось запланировать сообщение: %v", err)
+		sendScheduledError(ctx, w, err)
+		return
+	}
+
+	responser.SendStruct(ctx, w, scheduled, http.StatusCreated)
+}
+
+// GetScheduledMessages godoc
+// @Summary Get scheduled messages of chat
+// @Description Владелец и админы видят все отложенные сообщения, остальные участники - только свои
+// @Tags message
+// @Produce json
+// @Param chatId path string true "Chat ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Success 200 {object} models.ScheduledMessagesDTO "Отложенные сообщения"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} customerror.NoPermissionError "Нет доступа"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось получить отложенные сообщения"
+// @Router /chat/{chatId}/scheduled [get]
+func (h *MessageController) GetScheduledMessages(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "GetScheduledMessages")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	mapVars, ok := ctx.Value(auth.MuxParamsKey).(map[string]string)
+	if !ok {
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	chatUUID, err := uuid.Parse(mapVars["chatId"])
+	if err != nil {
+		log.Printf("Получен кривой Id чата %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Получен кривой Id чата %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	messages, err := h.usecase.GetScheduledMessages(ctx, user, chatUUID)
+	if err != nil {
+		sendScheduledError(ctx, w, err)
+		return
+	}
+
+	responser.SendStruct(ctx, w, messages, http.StatusOK)
+}
+
+// UpdateScheduledMessage godoc
+// @Summary Update scheduled message
+// @Description Можно изменить текст и время отправки. Доступно только автору
+// @Tags message
+// @Accept json
+// @Produce json
+// @Param scheduledId path string true "Scheduled message ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Param message body models.ScheduledMessageUpdate true "Новые значения"
+// @Success 200 {object} models.ScheduledMessage "Отложенное сообщение изменено"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} customerror.NoPermissionError "Нет доступа"
+// @Failure 404	{object} responser.ErrorResponse "Отложенное сообщение не найдено"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось изменить отложенное сообщение"
+// @Router /scheduled/{scheduledId} [put]
+func (h *MessageController) UpdateScheduledMessage(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "UpdateScheduledMessage")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	mapVars, ok := ctx.Value(auth.MuxParamsKey).(map[string]string)
+	if !ok {
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	scheduledUUID, err := uuid.Parse(mapVars["scheduledId"])
+	if err != nil {
+		log.Printf("Получен кривой Id отложенного сообщения %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Получен кривой Id отложенного сообщения %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	var input models.ScheduledMessageUpdate
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		log.Printf("Не удалось распарсить Json: %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Не удалось распарсить Json: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	scheduled, err := h.usecase.UpdateScheduledMessage(ctx, user, scheduledUUID, input)
+	if err != nil {
+		sendScheduledError(ctx, w, err)
+		return
+	}
+
+	responser.SendStruct(ctx, w, scheduled, http.StatusOK)
+}
+
+// CancelScheduledMessage godoc
+// @Summary Cancel scheduled message
+// @Description Доступно автору, владельцу и админам чата
+// @Tags message
+// @Param scheduledId path string true "Scheduled message ID (UUID)" minlength(36) maxlength(36) example("123e4567-e89b-12d3-a456-426614174000")
+// @Success 200 "Отложенное сообщение отменено"
+// @Failure 400	{object} responser.ErrorResponse "Некорректный запрос"
+// @Failure 403	{object} customerror.NoPermissionError "Нет доступа"
+// @Failure 404	{object} responser.ErrorResponse "Отложенное сообщение не найдено"
+// @Failure 500	{object} responser.ErrorResponse "Не удалось отменить отложенное сообщение"
+// @Router /scheduled/{scheduledId} [delete]
+func (h *MessageController) CancelScheduledMessage(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestMessageDuration, "CancelScheduledMessage")
+	}()
+
+	log := logger.LoggerWithCtx(r.Context(), logger.Log)
+	ctx := r.Context()
+	mapVars, ok := ctx.Value(auth.MuxParamsKey).(map[string]string)
+	if !ok {
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	scheduledUUID, err := uuid.Parse(mapVars["scheduledId"])
+	if err != nil {
+		log.Printf("Получен кривой Id отложенного сообщения %v", err)
+		responser.SendError(ctx, w, fmt.Sprintf("Получен кривой Id отложенного сообщения %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := ctx.Value(auth.UserKey).(auth.User)
+	if !ok {
+		log.Println("нет юзера в контексте")
+		responser.SendError(ctx, w, "Нет нужных параметров", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.usecase.CancelScheduledMessage(ctx, user, scheduledUUID)
+	if err != nil {
+		sendScheduledError(ctx, w, err)
+		return
+	}
+
+	responser.SendOK(w, "Отложенное сообщение отменено", http.StatusOK)
+}
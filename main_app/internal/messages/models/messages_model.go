@@ -2,6 +2,7 @@ package models
 
 import (
 	"encoding/json"
+	"mime/multipart"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,6 +17,178 @@ type Message struct {
 	SentAt     time.Time  `json:"datetime" example:"2024-04-13T08:30:00Z" valid:"-"`
 	ChatId     uuid.UUID  `json:"chatId" valid:"-"`
 	IsRedacted bool       `json:"isRedacted" valid:"-"`
+	// время последнего изменения, если сообщение изменялось
+	EditedAt *time.Time `json:"editedAt" example:"2024-04-13T08:30:00Z" valid:"-"`
+	Payloads []Payload  `json:"payloads" valid:"-"`
+	// путь до стикера, если сообщение является стикером. Тогда текст сообщения пустой
+	Sticker *string `json:"sticker" example:"/uploads/sticker/f0364477-bfd4-496d-b639-d825b009d509.png" valid:"-"`
+	// id сообщения, на которое отвечают
+	ReplyTo      *uuid.UUID    `json:"replyTo" valid:"-"`
+	ReplyPreview *ReplyPreview `json:"replyPreview" valid:"-"`
+	// откуда переслано сообщение
+	ForwardedFrom *ForwardedFrom `json:"forwardedFrom" valid:"-"`
+	Reactions     []Reaction     `json:"reactions" valid:"-"`
+	// превью первой ссылки из текста. Появляется после отправки, когда страница загрузится
+	LinkPreview *LinkPreview `json:"linkPreview" valid:"-"`
+	// форматирование текста
+	Entities []MessageEntity `json:"entities" valid:"-"`
+	// опрос, если сообщение является опросом. Тогда текст сообщения - вопрос опроса
+	Poll *Poll `json:"poll" valid:"-"`
+	// когда сообщение исчезнет, если в чате включены исчезающие сообщения
+	ExpiresAt *time.Time `json:"expiresAt" example:"2024-04-13T08:30:00Z" valid:"-"`
+	// имя отправителя в Telegram, если сообщение импортировано. Автор такого сообщения - импортировавший
+	// пользователь или автор-заглушка, а не настоящий отправитель
+	ImportedFrom *string `json:"importedFrom" example:"Vincent Vega" valid:"-"`
+	// id, сгенерированный клиентом. Повторная отправка с тем же nonce вернет уже сохраненное сообщение.
+	// Виден только автору
+	Nonce *string `json:"nonce" example:"c1a2f3e4-5b6c-7d8e-9f00-112233445566" valid:"-"`
+	// количество уникальных просмотров. Есть только у постов каналов
+	Views *int `json:"views" example:"42" valid:"-"`
+	// ответы в ветке обсуждения, если она есть (branchId)
+	Branch *BranchSummary `json:"branch" valid:"-"`
+
+	// вложения, пришедшие в multipart запросе
+	Files []*multipart.FileHeader `json:"-" valid:"-"`
+}
+
+// Payload вложение сообщения (картинка, документ)
+type Payload struct {
+	URL      string `json:"url" example:"/uploads/payload/f0364477-bfd4-496d-b639-d825b009d509.pdf" valid:"-"`
+	Filename string `json:"filename" example:"отчет.pdf" valid:"-"`
+	Size     int64  `json:"size" example:"1024" valid:"-"`
+}
+
+// Poll опрос или викторина с результатами для текущего пользователя
+type Poll struct {
+	Question    string       `json:"question" example:"Куда идем?" valid:"-"`
+	Options     []PollOption `json:"options" valid:"-"`
+	IsMultiple  bool         `json:"isMultiple" valid:"-"`
+	IsAnonymous bool         `json:"isAnonymous" valid:"-"`
+	IsQuiz      bool         `json:"isQuiz" valid:"-"`
+	// номер правильного варианта викторины. Виден после голосования или закрытия опроса
+	CorrectOption *int       `json:"correctOption" example:"0" valid:"-"`
+	CloseAt       *time.Time `json:"closeAt" example:"2024-04-13T08:30:00Z" valid:"-"`
+	IsClosed      bool       `json:"isClosed" valid:"-"`
+	TotalVoters   int        `json:"totalVoters" example:"12" valid:"-"`
+}
+
+type PollOption struct {
+	Text      string `json:"text" example:"В кино" valid:"-"`
+	Votes     int    `json:"votes" example:"5" valid:"-"`
+	VotedByMe bool   `json:"votedByMe" valid:"-"`
+	// проголосовавшие, только в публичных опросах
+	Voters []uuid.UUID `json:"voters,omitempty" valid:"-"`
+}
+
+type PollInput struct {
+	Question    string   `json:"question" example:"Куда идем?" valid:"-"`
+	Options     []string `json:"options" example:"В кино,В театр" valid:"-"`
+	IsMultiple  bool     `json:"isMultiple" valid:"-"`
+	IsAnonymous bool     `json:"isAnonymous" valid:"-"`
+	IsQuiz      bool     `json:"isQuiz" valid:"-"`
+	// обязателен для викторины
+	CorrectOption *int       `json:"correctOption" example:"0" valid:"-"`
+	CloseAt       *time.Time `json:"closeAt" example:"2024-04-13T08:30:00Z" valid:"-"`
+}
+
+// PollVoteInput номера выбранных вариантов
+type PollVoteInput struct {
+	Options []int `json:"options" example:"0,2" valid:"-"`
+}
+
+// типы форматирования текста
+const (
+	EntityBold    = "bold"
+	EntityItalic  = "italic"
+	EntityCode    = "code"
+	EntityPre     = "pre"
+	EntityLink    = "link"
+	EntityMention = "mention"
+	EntitySpoiler = "spoiler"
+)
+
+// MessageEntity форматирование фрагмента текста. Offset и Length считаются в кодовых единицах UTF-16,
+// как длина строки в JS. Сущности могут быть вложены друг в друга, но не пересекаться
+type MessageEntity struct {
+	// @Enum [bold, italic, code, pre, link, mention, spoiler]
+	Type   string `json:"type" example:"bold" valid:"-"`
+	Offset int    `json:"offset" example:"0" valid:"-"`
+	Length int    `json:"length" example:"5" valid:"-"`
+	// адрес ссылки, только для link
+	URL *string `json:"url,omitempty" example:"https://example.com" valid:"-"`
+	// упомянутый пользователь, только для mention
+	UserID *uuid.UUID `json:"userId,omitempty" valid:"-"`
+	// язык блока кода, только для pre
+	Language *string `json:"language,omitempty" example:"go" valid:"-"`
+}
+
+// LinkPreview метаданные страницы по ссылке из сообщения
+type LinkPreview struct {
+	URL         string `json:"url" example:"https://example.com/article" valid:"-"`
+	Title       string `json:"title" example:"Заголовок статьи" valid:"-"`
+	Description string `json:"description" example:"Краткое описание" valid:"-"`
+	Image       string `json:"image" example:"https://example.com/cover.png" valid:"-"`
+	SiteName    string `json:"siteName" example:"Example" valid:"-"`
+}
+
+// ReplyPreview краткое содержание сообщения, на которое ответили
+type ReplyPreview struct {
+	MessageId  uuid.UUID  `json:"messageId" valid:"-"`
+	AuthorID   *uuid.UUID `json:"authorID" valid:"-"`
+	AuthorName *string    `json:"authorName" example:"Vincent Vega" valid:"-"`
+	// первые 100 символов текста
+	Text       string  `json:"text" example:"тут много текста" valid:"-"`
+	Sticker    *string `json:"sticker" valid:"-"`
+	IsRedacted bool    `json:"isRedacted" valid:"-"`
+	IsDeleted  bool    `json:"isDeleted" valid:"-"`
+}
+
+// ForwardedFrom исходный автор и чат пересланного сообщения
+type ForwardedFrom struct {
+	AuthorID   uuid.UUID `json:"authorID" valid:"-"`
+	AuthorName *string   `json:"authorName" example:"Vincent Vega" valid:"-"`
+	ChatId     uuid.UUID `json:"chatId" valid:"-"`
+	ChatName   *string   `json:"chatName" example:"funny channel" valid:"-"`
+	// @Enum [personal, group, channel, branch]
+	ChatType *string `json:"chatType" example:"channel" valid:"-"`
+}
+
+// Reaction количество реакций одного вида на сообщении
+type Reaction struct {
+	Emoji string `json:"emoji" example:"👍" valid:"-"`
+	Count int    `json:"count" example:"3" valid:"-"`
+	// ставил ли эту реакцию текущий пользователь
+	ReactedByMe bool `json:"reactedByMe" valid:"-"`
+}
+
+// AllowedReactionsDTO реакции, разрешенные в канале. Пустой список - разрешены все
+type AllowedReactionsDTO struct {
+	Reactions []string `json:"reactions" example:"👍,🔥" valid:"-"`
+}
+
+// MessageRevision прежний текст сообщения: EditorID и EditedAt - кто и когда его написал.
+// У первой версии это автор и время отправки
+type MessageRevision struct {
+	Text     string          `json:"text" example:"тут много текста" valid:"-"`
+	Entities []MessageEntity `json:"entities" valid:"-"`
+	EditorID *uuid.UUID      `json:"editorID" valid:"-"`
+	EditedAt time.Time       `json:"editedAt" example:"2024-04-13T08:30:00Z" valid:"-"`
+}
+
+// MessageHistoryDTO история правок от первой к последней. Текущий текст лежит в самом сообщении
+type MessageHistoryDTO struct {
+	MessageId uuid.UUID         `json:"messageId" valid:"-"`
+	Revisions []MessageRevision `json:"revisions" valid:"-"`
+}
+
+// HistorySettingsDTO могут ли участники чата смотреть историю правок
+type HistorySettingsDTO struct {
+	IsHistoryVisible bool `json:"isHistoryVisible" valid:"-"`
+}
+
+// MessageTTLDTO через сколько секунд после отправки исчезают новые сообщения чата. null - не исчезают
+type MessageTTLDTO struct {
+	TTL *int `json:"ttl" example:"86400" valid:"-"`
 }
 
 func (m Message) MarshalBinary() ([]byte, error) {
@@ -28,7 +201,150 @@ func (m *Message) UnmarshalBinary(data []byte) error {
 }
 
 type MessageInput struct {
-	Message string `json:"text" example:"тут много текста" valid:"-"`
+	Message  string          `json:"text" example:"тут много текста" valid:"-"`
+	Entities []MessageEntity `json:"entities" valid:"-"`
+	Sticker  *string         `json:"sticker" example:"/uploads/sticker/f0364477-bfd4-496d-b639-d825b009d509.png" valid:"-"`
+	ReplyTo  *uuid.UUID      `json:"replyTo" example:"f0364477-bfd4-496d-b639-d825b009d509" valid:"-"`
+	Nonce    *string         `json:"nonce" example:"c1a2f3e4-5b6c-7d8e-9f00-112233445566" valid:"-"`
+}
+
+type ForwardMessagesInput struct {
+	MessageIds []uuid.UUID `json:"messageIds" example:"uuid1,uuid2" valid:"-"`
+	ChatIds    []uuid.UUID `json:"chatIds" example:"uuid1,uuid2" valid:"-"`
+}
+
+// BulkDeleteInput scope как у удаления одного сообщения: me или everyone (по умолчанию)
+type BulkDeleteInput struct {
+	MessageIds []uuid.UUID `json:"messageIds" example:"uuid1,uuid2" valid:"-"`
+	Scope      string      `json:"scope" example:"everyone" valid:"-"`
+}
+
+// результат удаления отдельного сообщения
+const (
+	BulkDeleted   = "deleted"
+	BulkNotFound  = "notFound"
+	BulkForbidden = "forbidden"
+)
+
+type BulkMessageResult struct {
+	MessageId uuid.UUID `json:"messageId" example:"f0364477-bfd4-496d-b639-d825b009d509" valid:"-"`
+	Status    string    `json:"status" example:"deleted" valid:"-"`
+}
+
+// BulkDeleteDTO результаты в порядке id из запроса
+type BulkDeleteDTO struct {
+	Results []BulkMessageResult `json:"results" valid:"-"`
+}
+
+// ScheduledMessage сообщение, которое будет отправлено в SendAt.
+// При отправке его id становится id сообщения, поэтому повторная отправка невозможна
+type ScheduledMessage struct {
+	Id        uuid.UUID       `json:"id" example:"f0364477-bfd4-496d-b639-d825b009d509" valid:"-"`
+	ChatId    uuid.UUID       `json:"chatId" valid:"-"`
+	AuthorID  uuid.UUID       `json:"authorID" valid:"-"`
+	Message   string          `json:"text" example:"тут много текста" valid:"-"`
+	Entities  []MessageEntity `json:"entities" valid:"-"`
+	Sticker   *string         `json:"sticker" valid:"-"`
+	ReplyTo   *uuid.UUID      `json:"replyTo" valid:"-"`
+	Payloads  []Payload       `json:"payloads" valid:"-"`
+	SendAt    time.Time       `json:"sendAt" example:"2024-04-13T08:30:00Z" valid:"-"`
+	CreatedAt time.Time       `json:"createdAt" example:"2024-04-13T08:30:00Z" valid:"-"`
+}
+
+type ScheduledMessageInput struct {
+	Message  string          `json:"text" example:"тут много текста" valid:"-"`
+	Entities []MessageEntity `json:"entities" valid:"-"`
+	Sticker  *string         `json:"sticker" example:"/uploads/sticker/f0364477-bfd4-496d-b639-d825b009d509.png" valid:"-"`
+	ReplyTo  *uuid.UUID      `json:"replyTo" example:"f0364477-bfd4-496d-b639-d825b009d509" valid:"-"`
+	SendAt   time.Time       `json:"sendAt" example:"2024-04-13T08:30:00Z" valid:"-"`
+
+	// вложения, пришедшие в multipart запросе
+	Files []*multipart.FileHeader `json:"-" valid:"-"`
+}
+
+// ScheduledMessageUpdate поля, которые можно изменить до отправки
+type ScheduledMessageUpdate struct {
+	Message *string `json:"text" example:"тут много текста" valid:"-"`
+	// разметка нового текста, учитывается только вместе с text
+	Entities []MessageEntity `json:"entities" valid:"-"`
+	SendAt   *time.Time      `json:"sendAt" example:"2024-04-13T08:30:00Z" valid:"-"`
+}
+
+type ScheduledMessagesDTO struct {
+	Messages []ScheduledMessage `json:"scheduledMessages" valid:"-"`
+}
+
+// SearchCursor позиция последнего результата поиска на странице
+type SearchCursor struct {
+	Rank      float32   `json:"r"`
+	SentAt    time.Time `json:"t"`
+	MessageId uuid.UUID `json:"id"`
+}
+
+// FoundMessage сообщение из результатов поиска
+type FoundMessage struct {
+	Message Message `json:"message" valid:"-"`
+	Rank    float32 `json:"rank" example:"0.6" valid:"-"`
+	// текст с найденными словами в <mark>, html из сообщения экранирован
+	Snippet string `json:"snippet" example:"тут <mark>много</mark> текста" valid:"-"`
+}
+
+type SearchMessagesDTO struct {
+	Messages []FoundMessage `json:"messages" valid:"-"`
+	// передается в cursor для получения следующей страницы. Пустой, если страниц больше нет
+	NextCursor string `json:"nextCursor,omitempty" valid:"-"`
+}
+
+// GlobalSearchFilter фильтры поиска по всем чатам пользователя. Пустые поля не ограничивают поиск
+type GlobalSearchFilter struct {
+	Query string
+	// @Enum [personal, group, channel, branch]
+	ChatType      string
+	AuthorID      *uuid.UUID
+	From          *time.Time
+	To            *time.Time
+	HasAttachment bool
+}
+
+// ChatSearchResult найденные сообщения одного чата
+type ChatSearchResult struct {
+	ChatId   uuid.UUID `json:"chatId" valid:"-"`
+	ChatName string    `json:"chatName" example:"Чат с пользователем 2" valid:"-"`
+	// @Enum [personal, group, channel, branch]
+	ChatType   string         `json:"chatType" example:"group" valid:"-"`
+	AvatarPath string         `json:"avatarPath" example:"/uploads/chat/f0364477-bfd4-496d-b639-d825b009d509.png" valid:"-"`
+	Messages   []FoundMessage `json:"messages" valid:"-"`
+}
+
+// GlobalSearchDTO чаты отсортированы по самому релевантному сообщению в них.
+// Сообщения чата могут продолжиться на следующей странице
+type GlobalSearchDTO struct {
+	Chats []ChatSearchResult `json:"chats" valid:"-"`
+	// передается в cursor для получения следующей страницы. Пустой, если страниц больше нет
+	NextCursor string `json:"nextCursor,omitempty" valid:"-"`
+}
+
+// MessagesPageDTO страница истории от новых сообщений к старым
+type MessagesPageDTO struct {
+	Messages []Message `json:"messages" valid:"-"`
+	// есть ли сообщения старше последнего на странице
+	HasOlder bool `json:"hasOlder" valid:"-"`
+	// есть ли сообщения новее первого на странице
+	HasNewer bool `json:"hasNewer" valid:"-"`
+}
+
+// Mention сообщение, в котором упомянули пользователя
+type Mention struct {
+	Message Message `json:"message" valid:"-"`
+	// прочитано, если курсор прочтения чата уже дошел до сообщения
+	IsRead bool `json:"isRead" valid:"-"`
+}
+
+// MentionsDTO упоминания от новых к старым
+type MentionsDTO struct {
+	Mentions []Mention `json:"mentions" valid:"-"`
+	// есть ли упоминания старше последнего на странице
+	HasMore bool `json:"hasMore" valid:"-"`
 }
 
 type MessagesArrayDTO struct {
@@ -57,3 +373,150 @@ type WebScoketDTO struct {
 	MsgType MsgType     `json:"messageType"`
 	Payload interface{} `json:"payload"`
 }
+
+// форматы выгрузки истории чата
+const (
+	ExportJSON = "json"
+	ExportHTML = "html"
+	ExportCSV  = "csv"
+)
+
+// состояния фоновой выгрузки
+const (
+	ExportPending = "pending"
+	ExportRunning = "running"
+	ExportDone    = "done"
+	ExportFailed  = "failed"
+)
+
+// ExportInput параметры выгрузки. Границы периода необязательны и включаются в выгрузку
+type ExportInput struct {
+	Format string
+	From   *time.Time
+	To     *time.Time
+}
+
+// ExportCursor последнее выгруженное сообщение, выгрузка идет от старых к новым
+type ExportCursor struct {
+	SentAt    time.Time
+	MessageId uuid.UUID
+}
+
+// ExportedMessage сообщение в выгрузке: автор с именем, вложения путями на сервере
+type ExportedMessage struct {
+	MessageId     uuid.UUID      `json:"messageId" valid:"-"`
+	AuthorID      uuid.UUID      `json:"authorID" valid:"-"`
+	AuthorName    string         `json:"authorName" example:"Vincent Vega" valid:"-"`
+	Text          string         `json:"text" valid:"-"`
+	SentAt        time.Time      `json:"datetime" valid:"-"`
+	IsRedacted    bool           `json:"isRedacted" valid:"-"`
+	EditedAt      *time.Time     `json:"editedAt" valid:"-"`
+	ReplyTo       *uuid.UUID     `json:"replyTo" valid:"-"`
+	ForwardedFrom *ForwardedFrom `json:"forwardedFrom" valid:"-"`
+	// у сообщения есть ветка обсуждения
+	BranchID *uuid.UUID `json:"branchId" valid:"-"`
+	Sticker  *string    `json:"sticker" valid:"-"`
+	Payloads []Payload  `json:"payloads" valid:"-"`
+	Poll     *Poll      `json:"poll,omitempty" valid:"-"`
+}
+
+// ChatExport фоновая выгрузка истории чата. Файл доступен только запросившему ее пользователю
+type ChatExport struct {
+	Id     uuid.UUID  `json:"id" example:"f0364477-bfd4-496d-b639-d825b009d509" valid:"-"`
+	ChatId uuid.UUID  `json:"chatId" valid:"-"`
+	UserId uuid.UUID  `json:"-" valid:"-"`
+	Format string     `json:"format" example:"json" valid:"-"`
+	From   *time.Time `json:"from" valid:"-"`
+	To     *time.Time `json:"to" valid:"-"`
+	// @Enum [pending, running, done, failed]
+	Status     string     `json:"status" example:"pending" valid:"-"`
+	Error      *string    `json:"error" valid:"-"`
+	CreatedAt  time.Time  `json:"createdAt" valid:"-"`
+	FinishedAt *time.Time `json:"finishedAt" valid:"-"`
+	// ссылка на скачивание, когда выгрузка готова
+	URL      *string `json:"url" example:"/exports/f0364477-bfd4-496d-b639-d825b009d509/file" valid:"-"`
+	FilePath *string `json:"-" valid:"-"`
+}
+
+// состояния импорта истории
+const (
+	ImportPending = "pending"
+	ImportRunning = "running"
+	ImportDone    = "done"
+	ImportFailed  = "failed"
+)
+
+// ImportError ошибка импорта отдельного сообщения
+type ImportError struct {
+	// id сообщения в выгрузке Telegram
+	MessageId int64  `json:"messageId" example:"42" valid:"-"`
+	Error     string `json:"error" example:"вложение photos/photo_1.jpg не найдено в архиве" valid:"-"`
+}
+
+// ImportProgress сколько сообщений импорта уже разобрано
+type ImportProgress struct {
+	Total     int `json:"total" example:"1000" valid:"-"`
+	Processed int `json:"processed" example:"500" valid:"-"`
+	// сохранено, в том числе с ошибками во вложениях или разметке
+	Imported int `json:"imported" example:"480" valid:"-"`
+	// не сохранено
+	Failed int `json:"failed" example:"5" valid:"-"`
+}
+
+// ChatImport импорт истории из выгрузки Telegram Desktop. Выполняется в фоне
+type ChatImport struct {
+	Id     uuid.UUID  `json:"id" example:"f0364477-bfd4-496d-b639-d825b009d509" valid:"-"`
+	UserId uuid.UUID  `json:"-" valid:"-"`
+	ChatId *uuid.UUID `json:"chatId" valid:"-"`
+	// @Enum [pending, running, done, failed]
+	Status string `json:"status" example:"running" valid:"-"`
+	ImportProgress
+	// первые ошибки по сообщениям, не больше 1000
+	Errors     []ImportError `json:"errors" valid:"-"`
+	Error      *string       `json:"error" valid:"-"`
+	CreatedAt  time.Time     `json:"createdAt" valid:"-"`
+	FinishedAt *time.Time    `json:"finishedAt" valid:"-"`
+
+	SourcePath string  `json:"-" valid:"-"`
+	MediaPath  *string `json:"-" valid:"-"`
+	// from_id отправителя в Telegram -> id пользователя
+	Authors map[string]uuid.UUID `json:"-" valid:"-"`
+}
+
+// ImportInput параметры импорта из формы
+type ImportInput struct {
+	// from_id отправителя в Telegram (например, user123456) -> логин в приложении.
+	// Сопоставить можно только со своим логином: сообщения от имени других пользователей
+	// без их согласия не создаются. Остальные отправители станут авторами-заглушками
+	Authors map[string]string `json:"authors" valid:"-"`
+}
+
+// ViewMessagesInput посты канала, которые пользователь увидел
+type ViewMessagesInput struct {
+	MessageIds []uuid.UUID `json:"messageIds" valid:"-"`
+}
+
+// MessageViews новое количество просмотров поста
+type MessageViews struct {
+	MessageId uuid.UUID `json:"messageId" valid:"-"`
+	ChatId    uuid.UUID `json:"-" valid:"-"`
+	Views     int       `json:"views" example:"42" valid:"-"`
+}
+
+// BranchSummary ответы в ветке обсуждения сообщения
+type BranchSummary struct {
+	ReplyCount int `json:"replyCount" example:"12" valid:"-"`
+	// последний ответ, null - ответов еще нет
+	LastReply *BranchReply `json:"lastReply" valid:"-"`
+}
+
+// BranchReply превью ответа в ветке
+type BranchReply struct {
+	MessageId  uuid.UUID `json:"messageId" valid:"-"`
+	AuthorID   uuid.UUID `json:"authorID" valid:"-"`
+	AuthorName *string   `json:"authorName" example:"Иван" valid:"-"`
+	// первые 100 символов ответа
+	Text    string    `json:"text" example:"тут много текста" valid:"-"`
+	Sticker *string   `json:"sticker" valid:"-"`
+	SentAt  time.Time `json:"datetime" example:"2024-04-13T08:30:00Z" valid:"-"`
+}
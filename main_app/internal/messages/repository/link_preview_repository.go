@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+func (r *MessageRepositoryImpl) GetLinkPreview(ctx context.Context, url string, fetchedAfter time.Time) (*models.LinkPreview, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return nil, err
+	}
+	defer conn.Release()
+
+	var preview models.LinkPreview
+	err = conn.QueryRow(ctx,
+		`SELECT url, title, description, image_url, site_name
+	FROM public.link_preview
+	WHERE url = $1 AND fetched_at > $2;`,
+		url,
+		fetchedAfter,
+	).Scan(
+		&preview.URL,
+		&preview.Title,
+		&preview.Description,
+		&preview.Image,
+		&preview.SiteName,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("Repository: не удалось получить превью ссылки: %v", err)
+		return nil, err
+	}
+
+	return &preview, nil
+}
+
+func (r *MessageRepositoryImpl) SaveLinkPreview(ctx context.Context, preview models.LinkPreview) error {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx,
+		`INSERT INTO public.link_preview (url, title, description, image_url, site_name, fetched_at)
+	VALUES ($1, $2, $3, $4, $5, now())
+	ON CONFLICT (url) DO UPDATE SET
+		title = EXCLUDED.title,
+		description = EXCLUDED.description,
+		image_url = EXCLUDED.image_url,
+		site_name = EXCLUDED.site_name,
+		fetched_at = EXCLUDED.fetched_at;`,
+		preview.URL,
+		preview.Title,
+		preview.Description,
+		preview.Image,
+		preview.SiteName,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось сохранить превью ссылки: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *MessageRepositoryImpl) SetMessageLinkPreview(ctx context.Context, messageId uuid.UUID, url *string) (bool, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return false, err
+	}
+	defer conn.Release()
+
+	// пока страница загружалась, ссылку могли убрать из текста
+	tag, err := conn.Exec(ctx,
+		`UPDATE public.message SET link_preview_url = $2
+	WHERE id = $1 AND ($2::text IS NULL OR strpos(COALESCE(message, ''), $2) > 0);`,
+		messageId,
+		url,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось прикрепить превью к сообщению %v: %v", messageId, err)
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"log"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+
+	"github.com/google/uuid"
+)
+
+// branchColumn количество ответов и последний ответ в ветке сообщения для messageColumns
+const branchColumn = `CASE WHEN m.branch_id IS NOT NULL THEN json_build_object(
+		'replyCount', (
+			SELECT count(*)
+			FROM public.message AS bm
+			WHERE bm.chat_id = m.branch_id AND (bm.expires_at IS NULL OR bm.expires_at > now())
+		),
+		'lastReply', (
+			SELECT json_build_object(
+				'messageId', lm.id,
+				'authorID', lm.author_id,
+				'authorName', lu.name,
+				'text', left(COALESCE(lm.message, ''), 100),
+				'sticker', lm.sticker_path,
+				'datetime', lm.sent_at
+			)
+			FROM public.message AS lm
+			LEFT JOIN public."user" AS lu ON lu.id = lm.author_id
+			WHERE lm.chat_id = m.branch_id AND (lm.expires_at IS NULL OR lm.expires_at > now())
+			ORDER BY lm.sent_at DESC, lm.id DESC
+			LIMIT 1
+		)
+	) END`
+
+// GetBranchParentMessage сообщение, к которому создана ветка branchId.
+// Если branchId не ветка, возвращает пустое сообщение с uuid.Nil
+func (r *MessageRepositoryImpl) GetBranchParentMessage(ctx context.Context, userId uuid.UUID, branchId uuid.UUID) (models.Message, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return models.Message{}, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx,
+		`SELECT `+messageColumns+`
+	FROM public.message AS m
+	WHERE m.branch_id = $2
+	LIMIT 1;`,
+		userId,
+		branchId,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось получить сообщение ветки %v: %v", branchId, err)
+		return models.Message{}, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return models.Message{}, rows.Err()
+	}
+
+	message, err := scanMessage(rows)
+	if err != nil {
+		log.Printf("Repository: unable to scan: %v", err)
+		return models.Message{}, err
+	}
+
+	return message, nil
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"log"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+
+	"github.com/google/uuid"
+)
+
+// uuidStrings pgtype не умеет кодировать []uuid.UUID в массив, поэтому передаем строки
+func uuidStrings(uuids []uuid.UUID) []string {
+	ids := make([]string, 0, len(uuids))
+	for _, id := range uuids {
+		ids = append(ids, id.String())
+	}
+	return ids
+}
+
+// GetMessagesByIds как и GetMessageById, возвращает в том числе скрытые пользователем сообщения.
+// Несуществующие id пропускаются
+func (r *MessageRepositoryImpl) GetMessagesByIds(ctx context.Context, userId uuid.UUID, messageIds []uuid.UUID) ([]models.Message, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx,
+		`SELECT `+messageColumns+`
+	FROM public.message AS m
+	WHERE m.id = ANY($2::uuid[]) AND (m.expires_at IS NULL OR m.expires_at > now());`,
+		userId,
+		uuidStrings(messageIds),
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось получить сообщения: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []models.Message{}
+	for rows.Next() {
+		message, err := scanMessage(rows)
+		if err != nil {
+			log.Printf("Repository: unable to scan: %v", err)
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// DeleteMessages удаляет сообщения одним запросом и возвращает id действительно удаленных
+// и пути их вложений, на которые больше не ссылается ни одно сообщение
+func (r *MessageRepositoryImpl) DeleteMessages(ctx context.Context, messageIds []uuid.UUID) ([]uuid.UUID, []string, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return nil, nil, err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось начать транзакцию: %v", err)
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	ids := uuidStrings(messageIds)
+	paths, err := payloadPaths(ctx, tx, ids)
+	if err != nil {
+		log.Printf("Repository: не удалось получить вложения сообщений: %v", err)
+		return nil, nil, err
+	}
+
+	var deleted []string
+	err = tx.QueryRow(ctx,
+		`WITH d AS (
+		DELETE FROM public.message WHERE id = ANY($1::uuid[]) RETURNING id
+	)
+	SELECT COALESCE(array_agg(id::text), '{}') FROM d;`,
+		ids,
+	).Scan(&deleted)
+	if err != nil {
+		log.Printf("Repository: не удалось удалить сообщения: %v", err)
+		return nil, nil, err
+	}
+
+	orphans, err := orphanPaths(ctx, tx, paths)
+	if err != nil {
+		log.Printf("Repository: не удалось проверить вложения сообщений: %v", err)
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Repository: не удалось подтвердить транзакцию: %v", err)
+		return nil, nil, err
+	}
+
+	deletedIds := make([]uuid.UUID, 0, len(deleted))
+	for _, id := range deleted {
+		deletedId, err := uuid.Parse(id)
+		if err != nil {
+			return nil, nil, err
+		}
+		deletedIds = append(deletedIds, deletedId)
+	}
+
+	return deletedIds, orphans, nil
+}
+
+// HideMessages скрывает сообщения у пользователя одним запросом. Уже скрытые пропускаются
+func (r *MessageRepositoryImpl) HideMessages(ctx context.Context, userId uuid.UUID, messageIds []uuid.UUID) error {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx,
+		`INSERT INTO public.message_hidden (user_id, message_id)
+	SELECT $1, id FROM unnest($2::uuid[]) AS id
+	ON CONFLICT DO NOTHING;`,
+		userId,
+		uuidStrings(messageIds),
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось скрыть сообщения: %v", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,275 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// exportCondition сообщения чата $2 за период [$3, $4], которые видит пользователь $1
+const exportCondition = `m.chat_id = $2 AND ` + notHiddenCondition + `
+		AND ($3::timestamptz IS NULL OR m.sent_at >= $3)
+		AND ($4::timestamptz IS NULL OR m.sent_at <= $4)`
+
+const exportColumns = `id, chat_id, user_id, format, from_time, to_time, status, error, created_at, finished_at, file_path`
+
+func scanExport(row pgx.Row) (models.ChatExport, error) {
+	var export models.ChatExport
+	err := row.Scan(
+		&export.Id,
+		&export.ChatId,
+		&export.UserId,
+		&export.Format,
+		&export.From,
+		&export.To,
+		&export.Status,
+		&export.Error,
+		&export.CreatedAt,
+		&export.FinishedAt,
+		&export.FilePath,
+	)
+	return export, err
+}
+
+func (r *MessageRepositoryImpl) CountMessagesForExport(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, input models.ExportInput) (int, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return 0, err
+	}
+	defer conn.Release()
+
+	var count int
+	err = conn.QueryRow(ctx,
+		`SELECT count(*) FROM public.message AS m WHERE `+exportCondition+`;`,
+		userId,
+		chatId,
+		input.From,
+		input.To,
+	).Scan(&count)
+	if err != nil {
+		log.Printf("Repository: не удалось посчитать сообщения чата %v для выгрузки: %v", chatId, err)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *MessageRepositoryImpl) GetMessagesForExport(ctx context.Context, userId uuid.UUID, chatId uuid.UUID,
+	input models.ExportInput, after *models.ExportCursor, limit int) ([]models.ExportedMessage, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return nil, err
+	}
+	defer conn.Release()
+
+	var afterSentAt *time.Time
+	var afterId *uuid.UUID
+	if after != nil {
+		afterSentAt = &after.SentAt
+		afterId = &after.MessageId
+	}
+
+	rows, err := conn.Query(ctx,
+		`SELECT `+messageColumns+`, COALESCE(u.name, '')
+	FROM public.message AS m
+	LEFT JOIN public."user" AS u ON u.id = m.author_id
+	WHERE `+exportCondition+`
+		AND ($5::timestamptz IS NULL OR (m.sent_at, m.id) > ($5, $6::uuid))
+	ORDER BY m.sent_at, m.id
+	LIMIT $7;`,
+		userId,
+		chatId,
+		input.From,
+		input.To,
+		afterSentAt,
+		afterId,
+		limit,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось получить сообщения чата %v для выгрузки: %v", chatId, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []models.ExportedMessage{}
+	for rows.Next() {
+		var authorName string
+		message, err := scanMessage(rows, &authorName)
+		if err != nil {
+			log.Printf("Repository: unable to scan: %v", err)
+			return nil, err
+		}
+
+		messages = append(messages, models.ExportedMessage{
+			MessageId:     message.MessageId,
+			AuthorID:      message.AuthorID,
+			AuthorName:    authorName,
+			Text:          message.Message,
+			SentAt:        message.SentAt,
+			IsRedacted:    message.IsRedacted,
+			EditedAt:      message.EditedAt,
+			ReplyTo:       message.ReplyTo,
+			ForwardedFrom: message.ForwardedFrom,
+			BranchID:      message.BranchID,
+			Sticker:       message.Sticker,
+			Payloads:      message.Payloads,
+			Poll:          message.Poll,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (r *MessageRepositoryImpl) CreateExport(ctx context.Context, export models.ChatExport) error {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx,
+		`INSERT INTO public.chat_export (id, chat_id, user_id, format, from_time, to_time, status, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8);`,
+		export.Id,
+		export.ChatId,
+		export.UserId,
+		export.Format,
+		export.From,
+		export.To,
+		export.Status,
+		export.CreatedAt,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось создать выгрузку чата %v: %v", export.ChatId, err)
+		return err
+	}
+
+	return nil
+}
+
+// GetExport если выгрузки нет, возвращает пустую структуру
+func (r *MessageRepositoryImpl) GetExport(ctx context.Context, exportId uuid.UUID) (models.ChatExport, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return models.ChatExport{}, err
+	}
+	defer conn.Release()
+
+	export, err := scanExport(conn.QueryRow(ctx,
+		`SELECT `+exportColumns+` FROM public.chat_export WHERE id = $1;`,
+		exportId,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ChatExport{}, nil
+	}
+	if err != nil {
+		log.Printf("Repository: не удалось получить выгрузку %v: %v", exportId, err)
+		return models.ChatExport{}, err
+	}
+
+	return export, nil
+}
+
+// TakePendingExport забирает самую старую ожидающую выгрузку и отмечает ее выполняемой.
+// Выгрузки, начатые раньше staleBefore, считаются брошенными упавшим экземпляром и берутся заново.
+// Если выгружать нечего, возвращает пустую структуру
+func (r *MessageRepositoryImpl) TakePendingExport(ctx context.Context, now time.Time, staleBefore time.Time) (models.ChatExport, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return models.ChatExport{}, err
+	}
+	defer conn.Release()
+
+	export, err := scanExport(conn.QueryRow(ctx,
+		`UPDATE public.chat_export SET status = 'running', started_at = $1
+	WHERE id = (
+		SELECT id FROM public.chat_export
+		WHERE status = 'pending' OR (status = 'running' AND started_at < $2)
+		ORDER BY created_at
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING `+exportColumns+`;`,
+		now,
+		staleBefore,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ChatExport{}, nil
+	}
+	if err != nil {
+		log.Printf("Repository: не удалось взять выгрузку в работу: %v", err)
+		return models.ChatExport{}, err
+	}
+
+	return export, nil
+}
+
+// FinishExport exportErr == nil - выгрузка готова и лежит в filePath
+func (r *MessageRepositoryImpl) FinishExport(ctx context.Context, exportId uuid.UUID, filePath *string, exportErr *string, finishedAt time.Time) error {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx,
+		`UPDATE public.chat_export SET
+		status = CASE WHEN $3::text IS NULL THEN 'done' ELSE 'failed' END,
+		file_path = $2,
+		error = $3,
+		finished_at = $4
+	WHERE id = $1;`,
+		exportId,
+		filePath,
+		exportErr,
+		finishedAt,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось завершить выгрузку %v: %v", exportId, err)
+		return err
+	}
+
+	return nil
+}
+
+// DeleteFinishedExports удаляет завершенные до before выгрузки и возвращает пути их файлов
+func (r *MessageRepositoryImpl) DeleteFinishedExports(ctx context.Context, before time.Time) ([]string, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return nil, err
+	}
+	defer conn.Release()
+
+	var paths []string
+	err = conn.QueryRow(ctx,
+		`WITH d AS (
+		DELETE FROM public.chat_export
+		WHERE status IN ('done', 'failed') AND finished_at < $1
+		RETURNING file_path
+	)
+	SELECT COALESCE(array_agg(file_path) FILTER (WHERE file_path IS NOT NULL), '{}') FROM d;`,
+		before,
+	).Scan(&paths)
+	if err != nil {
+		log.Printf("Repository: не удалось удалить старые выгрузки: %v", err)
+		return nil, err
+	}
+
+	return paths, nil
+}
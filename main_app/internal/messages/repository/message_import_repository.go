@@ -0,0 +1,416 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// importedAuthorPassword не совпадает ни с одним хэшем пароля, поэтому войти под автором из импорта нельзя
+const importedAuthorPassword = "!imported"
+
+const importColumns = `id, user_id, chat_id, status, source_path, media_path, authors,
+	total, processed, imported, failed, errors, error, created_at, finished_at`
+
+func scanImport(row pgx.Row) (models.ChatImport, error) {
+	var chatImport models.ChatImport
+	err := row.Scan(
+		&chatImport.Id,
+		&chatImport.UserId,
+		&chatImport.ChatId,
+		&chatImport.Status,
+		&chatImport.SourcePath,
+		&chatImport.MediaPath,
+		&chatImport.Authors,
+		&chatImport.Total,
+		&chatImport.Processed,
+		&chatImport.Imported,
+		&chatImport.Failed,
+		&chatImport.Errors,
+		&chatImport.Error,
+		&chatImport.CreatedAt,
+		&chatImport.FinishedAt,
+	)
+	return chatImport, err
+}
+
+func (r *MessageRepositoryImpl) CreateImport(ctx context.Context, chatImport models.ChatImport) error {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx,
+		`INSERT INTO public.chat_import (id, user_id, status, source_path, media_path, authors, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7);`,
+		chatImport.Id,
+		chatImport.UserId,
+		chatImport.Status,
+		chatImport.SourcePath,
+		chatImport.MediaPath,
+		chatImport.Authors,
+		chatImport.CreatedAt,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось создать импорт: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetImport если импорта нет, возвращает пустую структуру
+func (r *MessageRepositoryImpl) GetImport(ctx context.Context, importId uuid.UUID) (models.ChatImport, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return models.ChatImport{}, err
+	}
+	defer conn.Release()
+
+	chatImport, err := scanImport(conn.QueryRow(ctx,
+		`SELECT `+importColumns+` FROM public.chat_import WHERE id = $1;`,
+		importId,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ChatImport{}, nil
+	}
+	if err != nil {
+		log.Printf("Repository: не удалось получить импорт %v: %v", importId, err)
+		return models.ChatImport{}, err
+	}
+
+	return chatImport, nil
+}
+
+// TakePendingImport как и TakePendingExport, забирает самый старый ожидающий или брошенный импорт.
+// Брошенный импорт начинается заново, поэтому счетчики и ошибки сбрасываются
+func (r *MessageRepositoryImpl) TakePendingImport(ctx context.Context, now time.Time, staleBefore time.Time) (models.ChatImport, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return models.ChatImport{}, err
+	}
+	defer conn.Release()
+
+	chatImport, err := scanImport(conn.QueryRow(ctx,
+		`UPDATE public.chat_import SET
+		status = 'running',
+		started_at = $1,
+		processed = 0,
+		imported = 0,
+		failed = 0,
+		errors = '[]'
+	WHERE id = (
+		SELECT id FROM public.chat_import
+		WHERE status = 'pending' OR (status = 'running' AND started_at < $2)
+		ORDER BY created_at
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING `+importColumns+`;`,
+		now,
+		staleBefore,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ChatImport{}, nil
+	}
+	if err != nil {
+		log.Printf("Repository: не удалось взять импорт в работу: %v", err)
+		return models.ChatImport{}, err
+	}
+
+	return chatImport, nil
+}
+
+func (r *MessageRepositoryImpl) SetImportChat(ctx context.Context, importId uuid.UUID, chatId uuid.UUID) error {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx,
+		`UPDATE public.chat_import SET chat_id = $2 WHERE id = $1;`,
+		importId,
+		chatId,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось сохранить чат импорта %v: %v", importId, err)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateImportProgress сохраняет счетчики и дописывает новые ошибки, пока их не больше maxErrors.
+// Обновление продлевает started_at, чтобы долгий импорт не посчитали брошенным
+func (r *MessageRepositoryImpl) UpdateImportProgress(ctx context.Context, importId uuid.UUID, progress models.ImportProgress,
+	newErrors []models.ImportError, maxErrors int, now time.Time) error {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return err
+	}
+	defer conn.Release()
+
+	if newErrors == nil {
+		newErrors = []models.ImportError{}
+	}
+
+	_, err = conn.Exec(ctx,
+		`UPDATE public.chat_import SET
+		total = $2,
+		processed = $3,
+		imported = $4,
+		failed = $5,
+		errors = CASE
+			WHEN jsonb_array_length(errors) >= $7 THEN errors
+			ELSE (
+				SELECT COALESCE(jsonb_agg(e.value ORDER BY e.n), '[]'::jsonb)
+				FROM jsonb_array_elements(errors || $6::jsonb) WITH ORDINALITY AS e(value, n)
+				WHERE e.n <= $7
+			)
+		END,
+		started_at = $8
+	WHERE id = $1;`,
+		importId,
+		progress.Total,
+		progress.Processed,
+		progress.Imported,
+		progress.Failed,
+		newErrors,
+		maxErrors,
+		now,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось сохранить прогресс импорта %v: %v", importId, err)
+		return err
+	}
+
+	return nil
+}
+
+// FinishImport importErr == nil - импорт завершен успешно
+func (r *MessageRepositoryImpl) FinishImport(ctx context.Context, importId uuid.UUID, importErr *string, finishedAt time.Time) error {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx,
+		`UPDATE public.chat_import SET
+		status = CASE WHEN $2::text IS NULL THEN 'done' ELSE 'failed' END,
+		error = $2,
+		finished_at = $3
+	WHERE id = $1;`,
+		importId,
+		importErr,
+		finishedAt,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось завершить импорт %v: %v", importId, err)
+		return err
+	}
+
+	return nil
+}
+
+// GetUserIdsByUsernames ключи результата - логины в нижнем регистре. Ненайденных логинов в нем нет
+func (r *MessageRepositoryImpl) GetUserIdsByUsernames(ctx context.Context, usernames []string) (map[string]uuid.UUID, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return nil, err
+	}
+	defer conn.Release()
+
+	lowered := make([]string, 0, len(usernames))
+	for _, username := range usernames {
+		lowered = append(lowered, strings.ToLower(username))
+	}
+
+	rows, err := conn.Query(ctx,
+		`SELECT lower(username), id FROM public."user" WHERE lower(username) = ANY($1::text[]) AND NOT is_imported;`,
+		lowered,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось найти пользователей по логинам: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := map[string]uuid.UUID{}
+	for rows.Next() {
+		var username string
+		var id uuid.UUID
+		if err := rows.Scan(&username, &id); err != nil {
+			log.Printf("Repository: unable to scan: %v", err)
+			return nil, err
+		}
+		ids[username] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// AddImportedAuthor создает пользователя-заглушку для отправителя, которого нет в приложении,
+// и добавляет его в чат импорта: писать в чат могут только его участники.
+// Заглушка помечена is_imported, чтобы не попадать в поиск пользователей и контакты.
+// Повторный вызов с тем же id ничего не меняет
+func (r *MessageRepositoryImpl) AddImportedAuthor(ctx context.Context, id uuid.UUID, chatId uuid.UUID, name string) error {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось начать транзакцию: %v", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO public."user" (id, username, version, password, name, is_imported)
+	VALUES ($1, $2, 0, $3, $4, true)
+	ON CONFLICT (id) DO NOTHING;`,
+		id,
+		"imported_"+strings.ReplaceAll(id.String(), "-", ""),
+		importedAuthorPassword,
+		name,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось создать автора импорта %v: %v", id, err)
+		return err
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO public.chat_user (id, user_role_id, chat_id, user_id)
+	VALUES (gen_random_uuid(), (SELECT id FROM public.user_role WHERE value = 'none'), $2, $1)
+	ON CONFLICT (chat_id, user_id) DO NOTHING;`,
+		id,
+		chatId,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось добавить автора импорта %v в чат %v: %v", id, chatId, err)
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Repository: не удалось подтвердить транзакцию: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// AddImportedMessages сохраняет сообщения одной транзакцией. Сообщения и вложения с уже существующими id
+// пропускаются, поэтому повторный импорт той же пачки ничего не меняет. Возвращает пути файлов пропущенных
+// вложений: на них ссылок нет, ссылки остались на файлы предыдущего запуска
+func (r *MessageRepositoryImpl) AddImportedMessages(ctx context.Context, chatId uuid.UUID, messages []models.Message) ([]string, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return nil, err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось начать транзакцию: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	// путь файла для каждого запроса пачки, у вставки сообщения - пустой
+	queuedPaths := []string{}
+	for _, message := range messages {
+		entities := message.Entities
+		if entities == nil {
+			entities = []models.MessageEntity{}
+		}
+
+		batch.Queue(
+			`INSERT INTO public.message (id, chat_id, author_id, message, sent_at, is_redacted, edited_at, reply_to_id, entities,
+			imported_from_name)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (id) DO NOTHING;`,
+			message.MessageId,
+			chatId,
+			message.AuthorID,
+			message.Message,
+			message.SentAt,
+			message.IsRedacted,
+			message.EditedAt,
+			message.ReplyTo,
+			entities,
+			message.ImportedFrom,
+		)
+		queuedPaths = append(queuedPaths, "")
+
+		for i, payload := range message.Payloads {
+			batch.Queue(
+				`INSERT INTO public.message_payload (id, message_id, payload_path, filename, size, position)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (id) DO NOTHING
+			RETURNING id;`,
+				uuid.NewSHA1(message.MessageId, []byte{byte(i)}),
+				message.MessageId,
+				payload.URL,
+				payload.Filename,
+				payload.Size,
+				i,
+			)
+			queuedPaths = append(queuedPaths, payload.URL)
+		}
+	}
+
+	skipped := []string{}
+	results := tx.SendBatch(ctx, batch)
+	for _, path := range queuedPaths {
+		if path == "" {
+			_, err = results.Exec()
+		} else {
+			var id uuid.UUID
+			err = results.QueryRow().Scan(&id)
+			if errors.Is(err, pgx.ErrNoRows) {
+				skipped = append(skipped, path)
+				err = nil
+			}
+		}
+		if err != nil {
+			results.Close()
+			log.Printf("Repository: не удалось сохранить импортированные сообщения: %v", err)
+			return nil, err
+		}
+	}
+	if err := results.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Repository: не удалось подтвердить транзакцию: %v", err)
+		return nil, err
+	}
+
+	return skipped, nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"log"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+
+	"github.com/google/uuid"
+)
+
+func (r *MessageRepositoryImpl) ReplaceMentions(ctx context.Context, messageId uuid.UUID, userIds []uuid.UUID) ([]uuid.UUID, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return nil, err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось начать транзакцию: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		`DELETE FROM public.message_mention
+	WHERE message_id = $1 AND NOT user_id = ANY($2::uuid[]);`,
+		messageId,
+		uuidStrings(userIds),
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось удалить упоминания сообщения %v: %v", messageId, err)
+		return nil, err
+	}
+
+	added := []uuid.UUID{}
+	for _, userId := range userIds {
+		tag, err := tx.Exec(ctx,
+			`INSERT INTO public.message_mention (message_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING;`,
+			messageId,
+			userId,
+		)
+		if err != nil {
+			log.Printf("Repository: не удалось добавить упоминание: %v", err)
+			return nil, err
+		}
+
+		if tag.RowsAffected() > 0 {
+			added = append(added, userId)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Repository: не удалось подтвердить транзакцию: %v", err)
+		return nil, err
+	}
+
+	return added, nil
+}
+
+func (r *MessageRepositoryImpl) GetUserMentions(ctx context.Context, userId uuid.UUID, before *uuid.UUID, unreadOnly bool, limit int) ([]models.Mention, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return nil, err
+	}
+	defer conn.Release()
+
+	// упоминания из чатов, из которых пользователь вышел, не показываем
+	rows, err := conn.Query(ctx,
+		`SELECT `+messageColumns+`, m.sent_at <= cu.last_read_at
+	FROM public.message_mention AS mm
+	JOIN public.message AS m ON m.id = mm.message_id
+	JOIN public.chat_user AS cu ON cu.chat_id = m.chat_id AND cu.user_id = mm.user_id
+	WHERE mm.user_id = $1 AND `+notHiddenCondition+`
+		AND (NOT $2 OR m.sent_at > cu.last_read_at)
+		AND ($3::uuid IS NULL OR (m.sent_at, m.id) < (SELECT b.sent_at, b.id FROM public.message AS b WHERE b.id = $3))
+	ORDER BY m.sent_at DESC, m.id DESC
+	LIMIT $4;`,
+		userId,
+		unreadOnly,
+		before,
+		limit,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось получить упоминания пользователя %v: %v", userId, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentions := []models.Mention{}
+	for rows.Next() {
+		var mention models.Mention
+		mention.Message, err = scanMessage(rows, &mention.IsRead)
+		if err != nil {
+			log.Printf("Repository: unable to scan: %v", err)
+			return nil, err
+		}
+
+		mentions = append(mentions, mention)
+	}
+
+	return mentions, rows.Err()
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+
+	"github.com/google/uuid"
+)
+
+func (r *MessageRepositoryImpl) GetMessageTTL(ctx context.Context, chatId uuid.UUID) (*int, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return nil, err
+	}
+	defer conn.Release()
+
+	var ttl *int
+	err = conn.QueryRow(ctx,
+		`SELECT message_ttl FROM public.chat WHERE id = $1;`,
+		chatId,
+	).Scan(&ttl)
+	if err != nil {
+		log.Printf("Repository: не удалось получить время жизни сообщений чата %v: %v", chatId, err)
+		return nil, err
+	}
+
+	return ttl, nil
+}
+
+func (r *MessageRepositoryImpl) SetMessageTTL(ctx context.Context, chatId uuid.UUID, ttl *int) error {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx,
+		`UPDATE public.chat SET message_ttl = $1 WHERE id = $2;`,
+		ttl,
+		chatId,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось изменить время жизни сообщений чата %v: %v", chatId, err)
+		return err
+	}
+
+	return nil
+}
+
+// DeleteExpiredMessages удаляет до limit сообщений, истекших к now.
+// Возвращает удаленные сообщения и пути вложений, на которые больше не ссылается ни одно сообщение:
+// пересланные копии используют те же файлы
+func (r *MessageRepositoryImpl) DeleteExpiredMessages(ctx context.Context, now time.Time, limit int) ([]models.Message, []string, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return nil, nil, err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось начать транзакцию: %v", err)
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// сообщения, которые сейчас удаляет другой экземпляр приложения, пропускаем
+	var expiredIds []string
+	err = tx.QueryRow(ctx,
+		`SELECT COALESCE(array_agg(e.id::text), '{}')
+	FROM (
+		SELECT id FROM public.message
+		WHERE expires_at <= $1
+		ORDER BY expires_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	) AS e;`,
+		now,
+		limit,
+	).Scan(&expiredIds)
+	if err != nil {
+		log.Printf("Repository: не удалось получить истекшие сообщения: %v", err)
+		return nil, nil, err
+	}
+
+	if len(expiredIds) == 0 {
+		return []models.Message{}, []string{}, nil
+	}
+
+	paths, err := payloadPaths(ctx, tx, expiredIds)
+	if err != nil {
+		log.Printf("Repository: не удалось получить вложения истекших сообщений: %v", err)
+		return nil, nil, err
+	}
+
+	rows, err := tx.Query(ctx,
+		`DELETE FROM public.message
+	WHERE id = ANY($1::uuid[])
+	RETURNING id, chat_id, author_id, branch_id, sent_at, expires_at;`,
+		expiredIds,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось удалить истекшие сообщения: %v", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	messages := []models.Message{}
+	for rows.Next() {
+		var message models.Message
+		err = rows.Scan(
+			&message.MessageId,
+			&message.ChatId,
+			&message.AuthorID,
+			&message.BranchID,
+			&message.SentAt,
+			&message.ExpiresAt,
+		)
+		if err != nil {
+			log.Printf("Repository: unable to scan: %v", err)
+			return nil, nil, err
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	orphans, err := orphanPaths(ctx, tx, paths)
+	if err != nil {
+		log.Printf("Repository: не удалось проверить вложения истекших сообщений: %v", err)
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Repository: не удалось подтвердить транзакцию: %v", err)
+		return nil, nil, err
+	}
+
+	return messages, orphans, nil
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+
+	"github.com/google/uuid"
+)
+
+// viewsColumn просмотры сообщения для messageColumns. Для сообщений не из каналов - NULL
+const viewsColumn = `CASE WHEN EXISTS (
+		SELECT 1
+		FROM public.chat AS vc
+		JOIN public.chat_type AS vct ON vct.id = vc.chat_type_id
+		WHERE vc.id = m.chat_id AND vct.value = 'channel'
+	) THEN m.views END`
+
+// AddMessageViews отмечает посты канала просмотренными пользователем. Учитываются только посты
+// канала chatId, в котором он состоит, и не его собственные. Возвращает посты, просмотренные впервые
+func (r *MessageRepositoryImpl) AddMessageViews(ctx context.Context, userId uuid.UUID, chatId uuid.UUID,
+	messageIds []uuid.UUID, viewedAt time.Time) ([]uuid.UUID, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx,
+		`WITH viewed AS (
+		INSERT INTO public.message_view (message_id, user_id, viewed_at)
+		SELECT m.id, $1, $4
+		FROM public.message AS m
+		JOIN public.chat AS c ON c.id = m.chat_id
+		JOIN public.chat_type AS ct ON ct.id = c.chat_type_id
+		WHERE m.chat_id = $2 AND m.id = ANY($3::uuid[])
+			AND ct.value = 'channel'
+			AND m.author_id <> $1
+			AND EXISTS (
+				SELECT 1 FROM public.chat_user AS cu
+				WHERE cu.chat_id = m.chat_id AND cu.user_id = $1
+			)
+		ON CONFLICT (message_id, user_id) DO NOTHING
+		RETURNING message_id
+	)
+	UPDATE public.message SET views = views + 1
+	WHERE id IN (SELECT message_id FROM viewed)
+	RETURNING id;`,
+		userId,
+		chatId,
+		uuidStrings(messageIds),
+		viewedAt,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось сохранить просмотры: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	viewed := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			log.Printf("Repository: unable to scan: %v", err)
+			return nil, err
+		}
+		viewed = append(viewed, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return viewed, nil
+}
+
+// TakeChangedViews забирает до limit постов, у которых просмотры изменились с последней отправки,
+// и отмечает новые значения отправленными
+func (r *MessageRepositoryImpl) TakeChangedViews(ctx context.Context, limit int) ([]models.MessageViews, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx,
+		`UPDATE public.message SET views_sent = views
+	WHERE id IN (
+		SELECT id FROM public.message
+		WHERE views <> views_sent
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id, chat_id, views;`,
+		limit,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось получить изменившиеся просмотры: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	changed := []models.MessageViews{}
+	for rows.Next() {
+		var views models.MessageViews
+		if err := rows.Scan(&views.MessageId, &views.ChatId, &views.Views); err != nil {
+			log.Printf("Repository: unable to scan: %v", err)
+			return nil, err
+		}
+		changed = append(changed, views)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return changed, nil
+}
@@ -4,17 +4,149 @@ import (
 	"context"
 	"errors"
 	"log"
+	"slices"
 	"time"
 
 	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 const pageSize = 25
 
+var (
+	ErrStickerNotFound = errors.New("стикер не найден")
+	// сообщение с таким id уже сохранено (например, отложенное уже отправлено)
+	ErrMessageExists = errors.New("сообщение уже существует")
+	// автор уже отправил в этот чат сообщение с таким nonce
+	ErrNonceExists = errors.New("сообщение с таким nonce уже существует")
+)
+
+// messageColumns общий набор полей сообщения для всех запросов на чтение.
+// Id пользователя, который читает сообщения, передается первым параметром ($1)
+const messageColumns = `m.id,
+	m.author_id,
+	COALESCE(m.message, ''),
+	m.sent_at,
+	m.is_redacted,
+	m.edited_at,
+	m.branch_id,
+	m.chat_id,
+	COALESCE((
+		SELECT json_agg(json_build_object(
+			'url', mp.payload_path,
+			'filename', mp.filename,
+			'size', mp.size
+		) ORDER BY mp.position)
+		FROM public.message_payload AS mp
+		WHERE mp.message_id = m.id
+	), '[]'::json),
+	m.sticker_path,
+	m.reply_to_id,
+	CASE WHEN m.reply_to_id IS NOT NULL THEN COALESCE((
+		SELECT json_build_object(
+			'messageId', rm.id,
+			'authorID', rm.author_id,
+			'authorName', ru.name,
+			'text', left(COALESCE(rm.message, ''), 100),
+			'sticker', rm.sticker_path,
+			'isRedacted', rm.is_redacted,
+			'isDeleted', false
+		)
+		FROM public.message AS rm
+		LEFT JOIN public."user" AS ru ON ru.id = rm.author_id
+		WHERE rm.id = m.reply_to_id
+	), json_build_object('messageId', m.reply_to_id, 'isDeleted', true)) END,
+	CASE WHEN m.forwarded_from_chat_id IS NOT NULL THEN json_build_object(
+		'authorID', m.forwarded_from_author_id,
+		'authorName', (SELECT fu.name FROM public."user" AS fu WHERE fu.id = m.forwarded_from_author_id),
+		'chatId', m.forwarded_from_chat_id,
+		'chatName', (SELECT fc.chat_name FROM public.chat AS fc WHERE fc.id = m.forwarded_from_chat_id),
+		'chatType', (
+			SELECT fct.value
+			FROM public.chat AS fc
+			JOIN public.chat_type AS fct ON fct.id = fc.chat_type_id
+			WHERE fc.id = m.forwarded_from_chat_id
+		)
+	) END,
+	COALESCE((
+		SELECT json_agg(json_build_object(
+			'emoji', r.emoji,
+			'count', r.count,
+			'reactedByMe', r.reacted_by_me
+		) ORDER BY r.first_reacted_at)
+		FROM (
+			SELECT mr.emoji,
+				count(*) AS count,
+				bool_or(mr.user_id = $1) AS reacted_by_me,
+				min(mr.created_at) AS first_reacted_at
+			FROM public.message_reaction AS mr
+			WHERE mr.message_id = m.id
+			GROUP BY mr.emoji
+		) AS r
+	), '[]'::json),
+	(
+		SELECT json_build_object(
+			'url', lp.url,
+			'title', lp.title,
+			'description', lp.description,
+			'image', lp.image_url,
+			'siteName', lp.site_name
+		)
+		FROM public.link_preview AS lp
+		WHERE lp.url = m.link_preview_url
+	),
+	m.entities,
+	m.expires_at,
+	m.imported_from_name,
+	CASE WHEN m.author_id = $1 THEN m.client_nonce END,
+	` + viewsColumn + `,
+	` + branchColumn + `,
+	` + pollColumn
+
+// notHiddenCondition отсекает сообщения, которые пользователь $1 удалил у себя,
+// и истекшие сообщения, до которых еще не дошла очистка
+const notHiddenCondition = `NOT EXISTS (
+		SELECT 1 FROM public.message_hidden AS mh
+		WHERE mh.message_id = m.id AND mh.user_id = $1
+	) AND (m.expires_at IS NULL OR m.expires_at > now())`
+
+// scanMessage читает сообщение, выбранное через messageColumns. В extra попадают колонки, выбранные после них
+func scanMessage(row pgx.Row, extra ...any) (models.Message, error) {
+	var message models.Message
+
+	dest := []any{
+		&message.MessageId,
+		&message.AuthorID,
+		&message.Message,
+		&message.SentAt,
+		&message.IsRedacted,
+		&message.EditedAt,
+		&message.BranchID,
+		&message.ChatId,
+		&message.Payloads,
+		&message.Sticker,
+		&message.ReplyTo,
+		&message.ReplyPreview,
+		&message.ForwardedFrom,
+		&message.Reactions,
+		&message.LinkPreview,
+		&message.Entities,
+		&message.ExpiresAt,
+		&message.ImportedFrom,
+		&message.Nonce,
+		&message.Views,
+		&message.Branch,
+		&message.Poll,
+	}
+
+	err := row.Scan(append(dest, extra...)...)
+	return message, err
+}
+
 type MessageRepositoryImpl struct {
 	pool *pgxpool.Pool
 }
@@ -25,7 +157,7 @@ func NewMessageRepositoryImpl(pool *pgxpool.Pool) MessageRepository {
 	}
 }
 
-func (r *MessageRepositoryImpl) GetFirstMessages(ctx context.Context, chatId uuid.UUID) ([]models.Message, error) {
+func (r *MessageRepositoryImpl) GetFirstMessages(ctx context.Context, userId uuid.UUID, chatId uuid.UUID) ([]models.Message, error) {
 	conn, err := r.pool.Acquire(context.Background())
 	if err != nil {
 		log.Printf("Repository: не удалось установить соединение: %v", err)
@@ -36,18 +168,12 @@ func (r *MessageRepositoryImpl) GetFirstMessages(ctx context.Context, chatId uui
 	log.Printf("Repository: соединение успешно установлено")
 
 	rows, err := conn.Query(context.Background(),
-		`SELECT
-	m.id,
-	m.author_id,
-	m.message,
-	m.sent_at, 
-	m.is_redacted,
-	m.branch_id,
-	m.chat_id
+		`SELECT `+messageColumns+`
 	FROM public.message AS m
-	WHERE m.chat_id = $1
-	ORDER BY sent_at DESC
-	LIMIT $2;`,
+	WHERE m.chat_id = $2 AND `+notHiddenCondition+`
+	ORDER BY m.sent_at DESC, m.id DESC
+	LIMIT $3;`,
+		userId,
 		chatId,
 		pageSize,
 	)
@@ -55,33 +181,18 @@ func (r *MessageRepositoryImpl) GetFirstMessages(ctx context.Context, chatId uui
 		log.Printf("Repository: Unable to SELECT chats: %v\n", err)
 		return nil, err
 	}
+	defer rows.Close()
 	log.Println("Repository: сообщения получены")
 
 	messages := []models.Message{}
 	for rows.Next() {
-		var messageId uuid.UUID
-		var authorID uuid.UUID
-		var message string
-		var sentAt time.Time
-		var isRedacted bool
-		var branchID *uuid.UUID
-		var chatID uuid.UUID
-
-		err = rows.Scan(&messageId, &authorID, &message, &sentAt, &isRedacted, branchID, &chatID)
+		message, err := scanMessage(rows)
 		if err != nil {
 			log.Printf("Repository: unable to scan: %v", err)
 			return nil, err
 		}
 
-		messages = append(messages, models.Message{
-			MessageId:  messageId,
-			AuthorID:   authorID,
-			Message:    message,
-			SentAt:     sentAt,
-			IsRedacted: isRedacted,
-			BranchID:   branchID,
-			ChatId:     chatID,
-		})
+		messages = append(messages, message)
 	}
 
 	log.Printf("Repository: сообщения успешно найдеты. Количество сообшений: %d", len(messages))
@@ -97,35 +208,181 @@ func (r *MessageRepositoryImpl) AddMessage(message models.Message, chatId uuid.U
 	defer conn.Release()
 	log.Printf("Repository: соединение успешно установлено")
 
-	// нужно чё-то придумать со стикерами
-	row := conn.QueryRow(context.Background(),
-		`INSERT INTO public.message (id, chat_id, author_id, message, sent_at, is_redacted)
-	VALUES ($1, $2, $3, $4, $5, false) RETURNING id;`,
+	tx, err := conn.Begin(context.Background())
+	if err != nil {
+		log.Printf("Repository: не удалось начать транзакцию: %v", err)
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	if err := insertMessage(context.Background(), tx, message, chatId); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		log.Printf("Repository: не удалось подтвердить транзакцию: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// AddMessages сохраняет сообщения в их чаты message.ChatId одной транзакцией:
+// если не сохранилось одно, не сохраняется ни одно
+func (r *MessageRepositoryImpl) AddMessages(ctx context.Context, messages []models.Message) error {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось начать транзакцию: %v", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, message := range messages {
+		if err := insertMessage(ctx, tx, message, message.ChatId); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Repository: не удалось подтвердить транзакцию: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// insertMessage добавляет сообщение с опросом и вложениями в транзакции tx
+func insertMessage(ctx context.Context, tx pgx.Tx, message models.Message, chatId uuid.UUID) error {
+	// у стикера нет текста: message и sticker_path не могут быть заполнены одновременно
+	var text *string
+	if message.Sticker == nil {
+		text = &message.Message
+	}
+
+	var forwardedAuthorId, forwardedChatId *uuid.UUID
+	if message.ForwardedFrom != nil {
+		forwardedAuthorId = &message.ForwardedFrom.AuthorID
+		forwardedChatId = &message.ForwardedFrom.ChatId
+	}
+
+	// пересланное сообщение сразу получает превью оригинала
+	var linkPreviewURL *string
+	if message.LinkPreview != nil {
+		linkPreviewURL = &message.LinkPreview.URL
+	}
+
+	if message.Entities == nil {
+		message.Entities = []models.MessageEntity{}
+	}
+
+	row := tx.QueryRow(ctx,
+		`INSERT INTO public.message (
+		id,
+		chat_id,
+		author_id,
+		message,
+		sent_at,
+		is_redacted,
+		sticker_path,
+		reply_to_id,
+		forwarded_from_author_id,
+		forwarded_from_chat_id,
+		link_preview_url,
+		entities,
+		expires_at,
+		client_nonce
+	)
+	VALUES ($1, $2, $3, $4, $5, false, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id;`,
 		message.MessageId,
 		chatId,
 		message.AuthorID,
-		message.Message,
+		text,
 		message.SentAt,
+		message.Sticker,
+		message.ReplyTo,
+		forwardedAuthorId,
+		forwardedChatId,
+		linkPreviewURL,
+		message.Entities,
+		message.ExpiresAt,
+		message.Nonce,
 	)
 
 	var id uuid.UUID
 	if err := row.Scan(&id); err != nil {
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.ConstraintName == "sticker_path_fk_message_sticker_path_pk_sticker" {
+			log.Printf("Repository: стикер %v не существует", *message.Sticker)
+			return ErrStickerNotFound
+		}
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.ConstraintName == "message_pkey" {
+			log.Printf("Repository: сообщение %v уже существует", message.MessageId)
+			return ErrMessageExists
+		}
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.ConstraintName == "message_client_nonce_idx" {
+			log.Printf("Repository: сообщение с nonce %v уже существует", *message.Nonce)
+			return ErrNonceExists
+		}
+
 		log.Printf("Repository: не удалось добавить сообщение: %v", err)
 		return err
 	}
 
+	if message.Poll != nil {
+		if err := addPoll(ctx, tx, id, *message.Poll); err != nil {
+			return err
+		}
+	}
+
+	for i, payload := range message.Payloads {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO public.message_payload (id, message_id, payload_path, filename, size, position)
+		VALUES ($1, $2, $3, $4, $5, $6);`,
+			uuid.New(),
+			id,
+			payload.URL,
+			payload.Filename,
+			payload.Size,
+			i,
+		)
+		if err != nil {
+			log.Printf("Repository: не удалось добавить вложение: %v", err)
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (r *MessageRepositoryImpl) DeleteMessage(ctx context.Context, messageId uuid.UUID) error {
+// DeleteMessage удаляет сообщение и возвращает пути его вложений, на которые больше не ссылается ни одно сообщение
+func (r *MessageRepositoryImpl) DeleteMessage(ctx context.Context, messageId uuid.UUID) ([]string, error) {
 	conn, err := r.pool.Acquire(context.Background())
 	if err != nil {
 		log.Printf("Repository: не удалось установить соединение: %v", err)
-		return err
+		return nil, err
 	}
 	defer conn.Release()
 
-	row := conn.QueryRow(context.Background(),
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось начать транзакцию: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	paths, err := payloadPaths(ctx, tx, []string{messageId.String()})
+	if err != nil {
+		log.Printf("Repository: не удалось получить вложения сообщения %v: %v", messageId, err)
+		return nil, err
+	}
+
+	row := tx.QueryRow(ctx,
 		`DELETE FROM message WHERE id = $1 RETURNING id`,
 		messageId,
 	)
@@ -134,41 +391,234 @@ func (r *MessageRepositoryImpl) DeleteMessage(ctx context.Context, messageId uui
 	err = row.Scan(&msgId)
 
 	if err != nil {
+		return nil, err
+	}
+
+	orphans, err := orphanPaths(ctx, tx, paths)
+	if err != nil {
+		log.Printf("Repository: не удалось проверить вложения сообщения %v: %v", messageId, err)
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Repository: не удалось подтвердить транзакцию: %v", err)
+		return nil, err
+	}
+
+	return orphans, nil
+}
+
+// payloadPaths вложения сообщений. Их запоминают до удаления: строки message_payload удалятся каскадом
+func payloadPaths(ctx context.Context, tx pgx.Tx, messageIds []string) ([]string, error) {
+	var paths []string
+	err := tx.QueryRow(ctx,
+		`SELECT COALESCE(array_agg(DISTINCT payload_path), '{}')
+	FROM public.message_payload
+	WHERE message_id = ANY($1::uuid[]);`,
+		messageIds,
+	).Scan(&paths)
+
+	return paths, err
+}
+
+// orphanPaths пути из paths, на которые после удаления не ссылается ни одно сообщение:
+// пересланные копии используют те же файлы
+func orphanPaths(ctx context.Context, tx pgx.Tx, paths []string) ([]string, error) {
+	orphans := []string{}
+	if len(paths) == 0 {
+		return orphans, nil
+	}
+
+	err := tx.QueryRow(ctx,
+		`SELECT COALESCE(array_agg(p.path), '{}')
+	FROM unnest($1::text[]) AS p(path)
+	WHERE NOT EXISTS (
+		SELECT 1 FROM public.message_payload AS mp WHERE mp.payload_path = p.path
+	);`,
+		paths,
+	).Scan(&orphans)
+
+	return orphans, err
+}
+
+func (r *MessageRepositoryImpl) HideMessage(ctx context.Context, userId uuid.UUID, messageId uuid.UUID) error {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx,
+		`INSERT INTO public.message_hidden (user_id, message_id)
+	VALUES ($1, $2)
+	ON CONFLICT DO NOTHING;`,
+		userId,
+		messageId,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось скрыть сообщение %v: %v", messageId, err)
 		return err
 	}
 
 	return nil
 }
 
-func (r *MessageRepositoryImpl) UpdateMessage(ctx context.Context, messageId uuid.UUID, newText string) error {
-	conn, err := r.pool.Acquire(context.Background())
+func (r *MessageRepositoryImpl) UpdateMessage(ctx context.Context, messageId uuid.UUID, editorId uuid.UUID,
+	newText string, entities []models.MessageEntity) (time.Time, error) {
+	conn, err := r.pool.Acquire(ctx)
 	if err != nil {
 		log.Printf("Repository: не удалось установить соединение: %v", err)
-		return err
+		return time.Time{}, err
 	}
 	defer conn.Release()
 
-	row := conn.QueryRow(context.Background(),
-		`UPDATE message AS m SET
-		message = $1,
-		is_redacted = true
-		WHERE m.id = $2
-		RETURNING m.id;`,
+	// прежний текст сохраняется в истории тем же запросом, что и изменение, вместе с тем,
+	// кто и когда его написал: до первой правки это автор и время отправки
+	row := conn.QueryRow(ctx,
+		`WITH old AS (
+		SELECT id, COALESCE(message, '') AS message, entities,
+			COALESCE(editor_id, author_id) AS editor_id,
+			COALESCE(edited_at, sent_at) AS written_at
+		FROM public.message
+		WHERE id = $1
+		FOR UPDATE
+	), revision AS (
+		INSERT INTO public.message_revision (message_id, message, entities, editor_id, edited_at)
+		SELECT id, message, entities, editor_id, written_at FROM old
+	)
+	UPDATE public.message AS m SET
+		message = $3,
+		entities = $5,
+		is_redacted = true,
+		edited_at = $4,
+		editor_id = $2
+	FROM old
+	WHERE m.id = old.id
+	RETURNING m.edited_at;`,
+		messageId,
+		editorId,
 		newText,
+		time.Now(),
+		entities,
+	)
+
+	var editedAt time.Time
+	err = row.Scan(&editedAt)
+	if err != nil {
+		log.Printf("Repository: не удалось изменить сообщение %v: %v", messageId, err)
+		return time.Time{}, err
+	}
+
+	return editedAt, nil
+}
+
+func (r *MessageRepositoryImpl) GetMessageRevisions(ctx context.Context, messageId uuid.UUID) ([]models.MessageRevision, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx,
+		`SELECT message, entities, editor_id, edited_at
+	FROM public.message_revision
+	WHERE message_id = $1
+	ORDER BY edited_at;`,
 		messageId,
 	)
+	if err != nil {
+		log.Printf("Repository: не удалось получить историю сообщения %v: %v", messageId, err)
+		return nil, err
+	}
+	defer rows.Close()
 
-	var msgId uuid.UUID
-	err = row.Scan(&msgId)
+	revisions := []models.MessageRevision{}
+	for rows.Next() {
+		var revision models.MessageRevision
+		if err := rows.Scan(&revision.Text, &revision.Entities, &revision.EditorID, &revision.EditedAt); err != nil {
+			log.Printf("Repository: не удалось прочитать правку сообщения: %v", err)
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
 
+func (r *MessageRepositoryImpl) GetHistoryVisibility(ctx context.Context, chatId uuid.UUID) (bool, error) {
+	conn, err := r.pool.Acquire(ctx)
 	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return false, err
+	}
+	defer conn.Release()
+
+	var isVisible bool
+	err = conn.QueryRow(ctx,
+		`SELECT is_history_visible FROM public.chat WHERE id = $1;`,
+		chatId,
+	).Scan(&isVisible)
+	if err != nil {
+		log.Printf("Repository: не удалось получить настройку истории чата %v: %v", chatId, err)
+		return false, err
+	}
+
+	return isVisible, nil
+}
+
+func (r *MessageRepositoryImpl) SetHistoryVisibility(ctx context.Context, chatId uuid.UUID, isVisible bool) error {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx,
+		`UPDATE public.chat SET is_history_visible = $1 WHERE id = $2;`,
+		isVisible,
+		chatId,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось изменить настройку истории чата %v: %v", chatId, err)
 		return err
 	}
 
 	return nil
 }
 
-func (r *MessageRepositoryImpl) GetMessageById(ctx context.Context, messageId uuid.UUID) (models.Message, error) {
+func (r *MessageRepositoryImpl) GetMessageByNonce(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, nonce string) (models.Message, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return models.Message{}, err
+	}
+	defer conn.Release()
+
+	row := conn.QueryRow(ctx,
+		`SELECT `+messageColumns+`
+		FROM public.message AS m
+		WHERE m.author_id = $1 AND m.chat_id = $2 AND m.client_nonce = $3;`,
+		userId,
+		chatId,
+		nonce,
+	)
+
+	message, err := scanMessage(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Message{}, nil
+	}
+	if err != nil {
+		log.Printf("Repository: не удалось получить сообщение по nonce: %v", err)
+		return models.Message{}, err
+	}
+
+	return message, nil
+}
+
+func (r *MessageRepositoryImpl) GetMessageById(ctx context.Context, userId uuid.UUID, messageId uuid.UUID) (models.Message, error) {
 	conn, err := r.pool.Acquire(context.Background())
 	if err != nil {
 		log.Printf("Repository: не удалось установить соединение: %v", err)
@@ -177,26 +627,16 @@ func (r *MessageRepositoryImpl) GetMessageById(ctx context.Context, messageId uu
 	defer conn.Release()
 
 	row := conn.QueryRow(context.Background(),
-		`SELECT
-		m.author_id,
-		m.message,
-		m.sent_at, 
-		m.is_redacted,
-		m.chat_id
+		`SELECT `+messageColumns+`
 		FROM public.message AS m
-		WHERE m.id = $1
+		WHERE m.id = $2 AND (m.expires_at IS NULL OR m.expires_at > now())
 		ORDER BY sent_at DESC
 		LIMIT 1;`,
+		userId,
 		messageId,
 	)
 
-	var authorID uuid.UUID
-	var message string
-	var sentAt time.Time
-	var isRedacted bool
-	var chatId uuid.UUID
-
-	err = row.Scan(&authorID, &message, &sentAt, &isRedacted, &chatId)
+	messageModel, err := scanMessage(row)
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		return models.Message{}, nil
@@ -207,73 +647,152 @@ func (r *MessageRepositoryImpl) GetMessageById(ctx context.Context, messageId uu
 		return models.Message{}, err
 	}
 
-	messageModel := models.Message{
-		MessageId:  messageId,
-		AuthorID:   authorID,
-		Message:    message,
-		SentAt:     sentAt,
-		IsRedacted: isRedacted,
-		ChatId:     chatId,
-	}
-
 	return messageModel, nil
 }
 
-func (r *MessageRepositoryImpl) SearchMessagesWithQuery(ctx context.Context, chatId uuid.UUID, searchQuery string) ([]models.Message, error) {
-	conn, err := r.pool.Acquire(context.Background())
+// searchQueryCTE поисковый запрос ищет и по словоформам (russian), и по точному написанию (simple)
+const searchQueryCTE = `q AS (
+		SELECT websearch_to_tsquery('russian', $3) || websearch_to_tsquery('simple', $3) AS query
+	)`
+
+// searchHeadline фрагмент текста с найденными словами в <mark>. Текст экранируется до подсветки,
+// чтобы html из сообщения не попал в разметку
+const searchHeadline = `ts_headline('russian',
+		replace(replace(replace(COALESCE(m.message, ''), '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+		q.query,
+		'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5'
+	)`
+
+func (r *MessageRepositoryImpl) SearchMessagesWithQuery(ctx context.Context, userId uuid.UUID, chatId uuid.UUID,
+	searchQuery string, cursor *models.SearchCursor, limit int) ([]models.FoundMessage, error) {
+	conn, err := r.pool.Acquire(ctx)
 	if err != nil {
 		log.Printf("Repository: не удалось установить соединение: %v", err)
 		return nil, err
 	}
 	defer conn.Release()
 
+	var (
+		cursorRank   *float32
+		cursorSentAt *time.Time
+		cursorId     *uuid.UUID
+	)
+	if cursor != nil {
+		cursorRank, cursorSentAt, cursorId = &cursor.Rank, &cursor.SentAt, &cursor.MessageId
+	}
+
+	// сначала отбираем страницу по рангу, а тяжелые поля и подсветку считаем только для нее
 	rows, err := conn.Query(ctx,
-		`SELECT
-	m.id,
-	m.author_id,
-	m.message,
-	m.sent_at, 
-	m.is_redacted
-	FROM public.message AS m
-	WHERE m.chat_id = $1 AND lower(m.message) LIKE lower($2)
-	ORDER BY sent_at DESC;`,
+		`WITH `+searchQueryCTE+`, found AS (
+		SELECT m.id, m.sent_at, ts_rank(m.search_vector, q.query) AS rank
+		FROM public.message AS m, q
+		WHERE m.chat_id = $2 AND m.search_vector @@ q.query AND `+notHiddenCondition+`
+			AND ($4::real IS NULL OR (ts_rank(m.search_vector, q.query), m.sent_at, m.id) < ($4::real, $5::timestamptz, $6::uuid))
+		ORDER BY rank DESC, m.sent_at DESC, m.id DESC
+		LIMIT $7
+	)
+	SELECT `+messageColumns+`, f.rank, `+searchHeadline+`
+	FROM found AS f
+	JOIN public.message AS m ON m.id = f.id, q
+	ORDER BY f.rank DESC, f.sent_at DESC, f.id DESC;`,
+		userId,
 		chatId,
-		"%"+searchQuery+"%",
+		searchQuery,
+		cursorRank,
+		cursorSentAt,
+		cursorId,
+		limit,
 	)
-
 	if err != nil {
-		log.Printf("Repository: Unable to SELECT chats: %v\n", err)
+		log.Printf("Repository: не удалось найти сообщения: %v", err)
 		return nil, err
 	}
-	log.Println("Repository: сообщения получены")
+	defer rows.Close()
 
-	messages := []models.Message{}
+	messages := []models.FoundMessage{}
 	for rows.Next() {
-		var messageId uuid.UUID
-		var authorID uuid.UUID
-		var message string
-		var sentAt time.Time
-		var isRedacted bool
-
-		err = rows.Scan(&messageId, &authorID, &message, &sentAt, &isRedacted)
+		var found models.FoundMessage
+		found.Message, err = scanMessage(rows, &found.Rank, &found.Snippet)
 		if err != nil {
 			log.Printf("Repository: unable to scan: %v", err)
 			return nil, err
 		}
 
-		messages = append(messages, models.Message{
-			MessageId:  messageId,
-			AuthorID:   authorID,
-			Message:    message,
-			SentAt:     sentAt,
-			IsRedacted: isRedacted,
-		})
+		messages = append(messages, found)
 	}
 	log.Printf("Сообщения успешно найдеты. Количество сообшений: %d", len(messages))
-	return messages, nil
+	return messages, rows.Err()
+}
+
+func (r *MessageRepositoryImpl) SearchMessagesInChats(ctx context.Context, userId uuid.UUID, chatIds []uuid.UUID,
+	filter models.GlobalSearchFilter, cursor *models.SearchCursor, limit int) ([]models.FoundMessage, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return nil, err
+	}
+	defer conn.Release()
+
+	var (
+		cursorRank   *float32
+		cursorSentAt *time.Time
+		cursorId     *uuid.UUID
+	)
+	if cursor != nil {
+		cursorRank, cursorSentAt, cursorId = &cursor.Rank, &cursor.SentAt, &cursor.MessageId
+	}
+
+	rows, err := conn.Query(ctx,
+		`WITH `+searchQueryCTE+`, found AS (
+		SELECT m.id, m.sent_at, ts_rank(m.search_vector, q.query) AS rank
+		FROM public.message AS m, q
+		WHERE m.chat_id = ANY($2::uuid[]) AND m.search_vector @@ q.query AND `+notHiddenCondition+`
+			AND ($4::uuid IS NULL OR m.author_id = $4)
+			AND ($5::timestamptz IS NULL OR m.sent_at >= $5)
+			AND ($6::timestamptz IS NULL OR m.sent_at <= $6)
+			AND (NOT $7 OR EXISTS (SELECT 1 FROM public.message_payload AS mp WHERE mp.message_id = m.id))
+			AND ($8::real IS NULL OR (ts_rank(m.search_vector, q.query), m.sent_at, m.id) < ($8::real, $9::timestamptz, $10::uuid))
+		ORDER BY rank DESC, m.sent_at DESC, m.id DESC
+		LIMIT $11
+	)
+	SELECT `+messageColumns+`, f.rank, `+searchHeadline+`
+	FROM found AS f
+	JOIN public.message AS m ON m.id = f.id, q
+	ORDER BY f.rank DESC, f.sent_at DESC, f.id DESC;`,
+		userId,
+		uuidStrings(chatIds),
+		filter.Query,
+		filter.AuthorID,
+		filter.From,
+		filter.To,
+		filter.HasAttachment,
+		cursorRank,
+		cursorSentAt,
+		cursorId,
+		limit,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось найти сообщения: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []models.FoundMessage{}
+	for rows.Next() {
+		var found models.FoundMessage
+		found.Message, err = scanMessage(rows, &found.Rank, &found.Snippet)
+		if err != nil {
+			log.Printf("Repository: unable to scan: %v", err)
+			return nil, err
+		}
+
+		messages = append(messages, found)
+	}
+
+	return messages, rows.Err()
 }
 
-func (r *MessageRepositoryImpl) GetLastMessage(chatId uuid.UUID) (models.Message, error) {
+func (r *MessageRepositoryImpl) GetLastMessage(userId uuid.UUID, chatId uuid.UUID) (models.Message, error) {
 	conn, err := r.pool.Acquire(context.Background())
 	if err != nil {
 		log.Printf("Repository: не удалось установить соединение: %v", err)
@@ -281,28 +800,17 @@ func (r *MessageRepositoryImpl) GetLastMessage(chatId uuid.UUID) (models.Message
 	}
 	defer conn.Release()
 
-	// нужно чё-то придумать со стикерами
 	row := conn.QueryRow(context.Background(),
-		`SELECT
-	m.id,
-	m.author_id,
-	m.message,
-	m.sent_at, 
-	m.is_redacted
+		`SELECT `+messageColumns+`
 	FROM public.message AS m
-	WHERE m.chat_id = $1
+	WHERE m.chat_id = $2 AND `+notHiddenCondition+`
 	ORDER BY sent_at DESC
 	LIMIT 1;`,
+		userId,
 		chatId,
 	)
 
-	var messageId uuid.UUID
-	var authorID uuid.UUID
-	var message string
-	var sentAt time.Time
-	var isRedacted bool
-
-	err = row.Scan(&messageId, &authorID, &message, &sentAt, &isRedacted)
+	messageModel, err := scanMessage(row)
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		return models.Message{}, nil
@@ -313,18 +821,11 @@ func (r *MessageRepositoryImpl) GetLastMessage(chatId uuid.UUID) (models.Message
 		return models.Message{}, err
 	}
 
-	messageModel := models.Message{
-		MessageId:  messageId,
-		AuthorID:   authorID,
-		Message:    message,
-		SentAt:     sentAt,
-		IsRedacted: isRedacted,
-	}
-
 	return messageModel, nil
 }
 
-func (r *MessageRepositoryImpl) GetAllMessagesAfter(ctx context.Context, chatId uuid.UUID, lastMessageId uuid.UUID) ([]models.Message, error) {
+func (r *MessageRepositoryImpl) GetMessagesPage(ctx context.Context, userId uuid.UUID, chatId uuid.UUID,
+	anchorId uuid.UUID, older bool, limit int) ([]models.Message, error) {
 	conn, err := r.pool.Acquire(ctx)
 	if err != nil {
 		log.Printf("Repository: не удалось установить соединение: %v", err)
@@ -333,51 +834,209 @@ func (r *MessageRepositoryImpl) GetAllMessagesAfter(ctx context.Context, chatId
 	defer conn.Release()
 	log.Printf("Repository: соединение успешно установлено")
 
+	// сравнение по паре (sent_at, id) не теряет сообщения с одинаковым временем
+	condition, order := "<", "DESC"
+	if !older {
+		condition, order = ">", "ASC"
+	}
+
 	rows, err := conn.Query(ctx,
-		`SELECT
-	m.id,
-	m.author_id,
-	m.message,
-	m.sent_at, 
-	m.is_redacted
+		`SELECT `+messageColumns+`
 	FROM public.message AS m
-	WHERE m.chat_id = $1 AND m.sent_at <= (SELECT sent_at FROM message WHERE id = $2) AND m.id != $2
-	ORDER BY sent_at DESC
-	LIMIT $3;`,
+	WHERE m.chat_id = $2
+		AND (m.sent_at, m.id) `+condition+` (SELECT a.sent_at, a.id FROM public.message AS a WHERE a.id = $3 AND a.chat_id = $2)
+		AND `+notHiddenCondition+`
+	ORDER BY m.sent_at `+order+`, m.id `+order+`
+	LIMIT $4;`,
+		userId,
 		chatId,
-		lastMessageId,
-		pageSize,
+		anchorId,
+		limit,
 	)
 
 	if err != nil {
 		log.Printf("Repository: Unable to SELECT chats: %v\n", err)
 		return nil, err
 	}
+	defer rows.Close()
 	log.Println("Repository: сообщения получены")
 
 	messages := []models.Message{}
 	for rows.Next() {
-		var messageId uuid.UUID
-		var authorID uuid.UUID
-		var message string
-		var sentAt time.Time
-		var isRedacted bool
-
-		err = rows.Scan(&messageId, &authorID, &message, &sentAt, &isRedacted)
+		message, err := scanMessage(rows)
 		if err != nil {
 			log.Printf("Repository: unable to scan: %v", err)
 			return nil, err
 		}
 
-		messages = append(messages, models.Message{
-			MessageId:  messageId,
-			AuthorID:   authorID,
-			Message:    message,
-			SentAt:     sentAt,
-			IsRedacted: isRedacted,
-		})
+		messages = append(messages, message)
+	}
+
+	// страница всегда отдается от новых к старым
+	if !older {
+		slices.Reverse(messages)
 	}
 
 	log.Printf("Repository: сообщения успешно найдеты. Количество сообшений: %d", len(messages))
 	return messages, nil
 }
+
+func (r *MessageRepositoryImpl) AddReaction(ctx context.Context, messageId uuid.UUID, userId uuid.UUID, emoji string) error {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return err
+	}
+	defer conn.Release()
+
+	// повторная реакция тем же эмодзи ничего не меняет
+	_, err = conn.Exec(ctx,
+		`INSERT INTO public.message_reaction (message_id, user_id, emoji)
+	VALUES ($1, $2, $3)
+	ON CONFLICT DO NOTHING;`,
+		messageId,
+		userId,
+		emoji,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось добавить реакцию: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *MessageRepositoryImpl) DeleteReaction(ctx context.Context, messageId uuid.UUID, userId uuid.UUID, emoji string) error {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx,
+		`DELETE FROM public.message_reaction
+	WHERE message_id = $1 AND user_id = $2 AND emoji = $3;`,
+		messageId,
+		userId,
+		emoji,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось удалить реакцию: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *MessageRepositoryImpl) GetAllowedReactions(ctx context.Context, chatId uuid.UUID) ([]string, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx,
+		`SELECT emoji FROM public.chat_allowed_reaction WHERE chat_id = $1 ORDER BY emoji;`,
+		chatId,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось получить разрешенные реакции: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	reactions := []string{}
+	for rows.Next() {
+		var emoji string
+		if err := rows.Scan(&emoji); err != nil {
+			log.Printf("Repository: unable to scan: %v", err)
+			return nil, err
+		}
+
+		reactions = append(reactions, emoji)
+	}
+
+	return reactions, nil
+}
+
+func (r *MessageRepositoryImpl) SetAllowedReactions(ctx context.Context, chatId uuid.UUID, reactions []string) error {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось начать транзакцию: %v", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `DELETE FROM public.chat_allowed_reaction WHERE chat_id = $1;`, chatId)
+	if err != nil {
+		log.Printf("Repository: не удалось очистить разрешенные реакции: %v", err)
+		return err
+	}
+
+	for _, emoji := range reactions {
+		_, err = tx.Exec(ctx,
+			`INSERT INTO public.chat_allowed_reaction (chat_id, emoji)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING;`,
+			chatId,
+			emoji,
+		)
+		if err != nil {
+			log.Printf("Repository: не удалось добавить разрешенную реакцию: %v", err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Repository: не удалось подтвердить транзакцию: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *MessageRepositoryImpl) GetPinnedMessages(ctx context.Context, userId uuid.UUID, chatId uuid.UUID) ([]models.Message, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx,
+		`SELECT `+messageColumns+`
+	FROM public.chat_pinned_message AS p
+	JOIN public.message AS m ON m.id = p.message_id
+	WHERE p.chat_id = $2 AND `+notHiddenCondition+`
+	ORDER BY p.pinned_at DESC;`,
+		userId,
+		chatId,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось получить закрепленные сообщения: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []models.Message{}
+	for rows.Next() {
+		message, err := scanMessage(rows)
+		if err != nil {
+			log.Printf("Repository: unable to scan: %v", err)
+			return nil, err
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
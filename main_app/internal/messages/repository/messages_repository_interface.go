@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
 	"github.com/google/uuid"
@@ -9,16 +10,116 @@ import (
 
 //go:generate mockgen -source=messages_repository_interface.go -destination=mocks/mocks.go
 
+// в методах чтения userId - пользователь, для которого считается reactedByMe
 type MessageRepository interface {
 	AddMessage(message models.Message, chatId uuid.UUID) error
+	// AddMessages сохраняет все сообщения одной транзакцией, каждое в чат message.ChatId
+	AddMessages(ctx context.Context, messages []models.Message) error
 
-	DeleteMessage(ctx context.Context, messageId uuid.UUID) error
+	// DeleteMessage возвращает пути вложений, которые больше не нужны ни одному сообщению
+	DeleteMessage(ctx context.Context, messageId uuid.UUID) ([]string, error)
+	// HideMessage удаляет сообщение только у пользователя. Повторное скрытие ничего не меняет
+	HideMessage(ctx context.Context, userId uuid.UUID, messageId uuid.UUID) error
+	// DeleteMessages удаляет сообщения одним запросом и возвращает id действительно удаленных
+	// и пути вложений, которые больше не нужны ни одному сообщению
+	DeleteMessages(ctx context.Context, messageIds []uuid.UUID) ([]uuid.UUID, []string, error)
+	HideMessages(ctx context.Context, userId uuid.UUID, messageIds []uuid.UUID) error
 
-	UpdateMessage(ctx context.Context, messageId uuid.UUID, newText string) error
+	// UpdateMessage сохраняет прежний текст в истории правок и возвращает время изменения
+	UpdateMessage(ctx context.Context, messageId uuid.UUID, editorId uuid.UUID, newText string, entities []models.MessageEntity) (time.Time, error)
+	GetMessageRevisions(ctx context.Context, messageId uuid.UUID) ([]models.MessageRevision, error)
 
-	SearchMessagesWithQuery(ctx context.Context, chatId uuid.UUID, searchQuery string) ([]models.Message, error)
-	GetFirstMessages(ctx context.Context, chatId uuid.UUID) ([]models.Message, error)
-	GetMessageById(ctx context.Context, messageId uuid.UUID) (models.Message, error)
-	GetLastMessage(chatId uuid.UUID) (models.Message, error)
-	GetAllMessagesAfter(ctx context.Context, chatId uuid.UUID, lastMessageId uuid.UUID) ([]models.Message, error)
+	// SearchMessagesWithQuery полнотекстовый поиск. Результаты отсортированы по рангу, cursor == nil - первая страница
+	SearchMessagesWithQuery(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, searchQuery string, cursor *models.SearchCursor, limit int) ([]models.FoundMessage, error)
+	// SearchMessagesInChats полнотекстовый поиск сразу по нескольким чатам, результаты отсортированы по рангу
+	SearchMessagesInChats(ctx context.Context, userId uuid.UUID, chatIds []uuid.UUID, filter models.GlobalSearchFilter, cursor *models.SearchCursor, limit int) ([]models.FoundMessage, error)
+	GetFirstMessages(ctx context.Context, userId uuid.UUID, chatId uuid.UUID) ([]models.Message, error)
+	// GetMessageById возвращает сообщение, даже если пользователь скрыл его у себя
+	GetMessageById(ctx context.Context, userId uuid.UUID, messageId uuid.UUID) (models.Message, error)
+	// GetMessagesByIds несуществующие и истекшие сообщения пропускаются
+	GetMessagesByIds(ctx context.Context, userId uuid.UUID, messageIds []uuid.UUID) ([]models.Message, error)
+	// GetMessageByNonce сообщение автора userId в чате с клиентским nonce. Если его нет, то вернет пустое сообщение.
+	// Истекшие сообщения тоже возвращаются
+	GetMessageByNonce(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, nonce string) (models.Message, error)
+	GetLastMessage(userId uuid.UUID, chatId uuid.UUID) (models.Message, error)
+	// GetMessagesPage сообщения старше (older) или новее anchorId, от новых к старым.
+	// Если anchorId нет в чате, то вернет пустой список
+	GetMessagesPage(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, anchorId uuid.UUID, older bool, limit int) ([]models.Message, error)
+	// GetPinnedMessages закрепленные сообщения чата, последнее закрепленное первым
+	GetPinnedMessages(ctx context.Context, userId uuid.UUID, chatId uuid.UUID) ([]models.Message, error)
+
+	AddReaction(ctx context.Context, messageId uuid.UUID, userId uuid.UUID, emoji string) error
+	DeleteReaction(ctx context.Context, messageId uuid.UUID, userId uuid.UUID, emoji string) error
+	GetAllowedReactions(ctx context.Context, chatId uuid.UUID) ([]string, error)
+	SetAllowedReactions(ctx context.Context, chatId uuid.UUID, reactions []string) error
+	// ReplaceMentions заменяет упомянутых в сообщении пользователей и возвращает тех, кого упомянули впервые
+	ReplaceMentions(ctx context.Context, messageId uuid.UUID, userIds []uuid.UUID) ([]uuid.UUID, error)
+	// GetUserMentions упоминания пользователя от новых к старым. before == nil - первая страница
+	GetUserMentions(ctx context.Context, userId uuid.UUID, before *uuid.UUID, unreadOnly bool, limit int) ([]models.Mention, error)
+
+	// GetLinkPreview превью из кэша, загруженное позже fetchedAfter. Если его нет, то вернет nil
+	GetLinkPreview(ctx context.Context, url string, fetchedAfter time.Time) (*models.LinkPreview, error)
+	SaveLinkPreview(ctx context.Context, preview models.LinkPreview) error
+	// SetMessageLinkPreview url == nil убирает превью. Вернет false, если сообщения нет или ссылки уже нет в тексте
+	SetMessageLinkPreview(ctx context.Context, messageId uuid.UUID, url *string) (bool, error)
+
+	// GetPoll настройки и варианты опроса без результатов, правильный ответ викторины заполнен.
+	// Если сообщение не опрос, то вернет nil
+	GetPoll(ctx context.Context, messageId uuid.UUID) (*models.Poll, error)
+	GetPollVotes(ctx context.Context, messageId uuid.UUID, userId uuid.UUID) ([]int, error)
+	// SetPollVotes заменяет голоса пользователя. Пустой positions отзывает голос
+	SetPollVotes(ctx context.Context, messageId uuid.UUID, userId uuid.UUID, positions []int) error
+
+	GetHistoryVisibility(ctx context.Context, chatId uuid.UUID) (bool, error)
+	SetHistoryVisibility(ctx context.Context, chatId uuid.UUID, isVisible bool) error
+
+	// GetMessageTTL время жизни новых сообщений чата в секундах, nil - сообщения не исчезают
+	GetMessageTTL(ctx context.Context, chatId uuid.UUID) (*int, error)
+	SetMessageTTL(ctx context.Context, chatId uuid.UUID, ttl *int) error
+	// DeleteExpiredMessages возвращает удаленные сообщения и пути вложений, которые можно удалить с диска
+	DeleteExpiredMessages(ctx context.Context, now time.Time, limit int) ([]models.Message, []string, error)
+
+	AddScheduledMessage(ctx context.Context, message models.ScheduledMessage) error
+	// GetScheduledMessages если authorId == nil, то вернет отложенные сообщения всех авторов
+	GetScheduledMessages(ctx context.Context, chatId uuid.UUID, authorId *uuid.UUID) ([]models.ScheduledMessage, error)
+	// GetScheduledMessage если сообщения нет, то вернет пустое сообщение с uuid.Nil
+	GetScheduledMessage(ctx context.Context, id uuid.UUID) (models.ScheduledMessage, error)
+	UpdateScheduledMessage(ctx context.Context, message models.ScheduledMessage) error
+	DeleteScheduledMessage(ctx context.Context, id uuid.UUID) error
+	// GetDueScheduledMessages отложенные сообщения, время отправки которых наступило
+	GetDueScheduledMessages(ctx context.Context, now time.Time, limit int) ([]models.ScheduledMessage, error)
+
+	CountMessagesForExport(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, input models.ExportInput) (int, error)
+	// GetMessagesForExport страница сообщений от старых к новым после after, after == nil - первая страница
+	GetMessagesForExport(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, input models.ExportInput, after *models.ExportCursor, limit int) ([]models.ExportedMessage, error)
+	CreateExport(ctx context.Context, export models.ChatExport) error
+	// GetExport если выгрузки нет, то вернет пустую структуру с uuid.Nil
+	GetExport(ctx context.Context, exportId uuid.UUID) (models.ChatExport, error)
+	// TakePendingExport если выгружать нечего, то вернет пустую структуру с uuid.Nil
+	TakePendingExport(ctx context.Context, now time.Time, staleBefore time.Time) (models.ChatExport, error)
+	FinishExport(ctx context.Context, exportId uuid.UUID, filePath *string, exportErr *string, finishedAt time.Time) error
+	DeleteFinishedExports(ctx context.Context, before time.Time) ([]string, error)
+
+	CreateImport(ctx context.Context, chatImport models.ChatImport) error
+	// GetImport если импорта нет, то вернет пустую структуру с uuid.Nil
+	GetImport(ctx context.Context, importId uuid.UUID) (models.ChatImport, error)
+	// TakePendingImport если импортировать нечего, то вернет пустую структуру с uuid.Nil
+	TakePendingImport(ctx context.Context, now time.Time, staleBefore time.Time) (models.ChatImport, error)
+	SetImportChat(ctx context.Context, importId uuid.UUID, chatId uuid.UUID) error
+	UpdateImportProgress(ctx context.Context, importId uuid.UUID, progress models.ImportProgress, newErrors []models.ImportError, maxErrors int, now time.Time) error
+	FinishImport(ctx context.Context, importId uuid.UUID, importErr *string, finishedAt time.Time) error
+	// GetUserIdsByUsernames ключи - логины в нижнем регистре. Авторы-заглушки не находятся
+	GetUserIdsByUsernames(ctx context.Context, usernames []string) (map[string]uuid.UUID, error)
+	// AddImportedAuthor создает автора-заглушку и добавляет его в чат импорта
+	AddImportedAuthor(ctx context.Context, id uuid.UUID, chatId uuid.UUID, name string) error
+	// AddImportedMessages возвращает пути вложений, которые не сохранились, потому что уже сохранены раньше
+	AddImportedMessages(ctx context.Context, chatId uuid.UUID, messages []models.Message) ([]string, error)
+
+	// AddMessageViews возвращает посты, которые пользователь просмотрел впервые
+	AddMessageViews(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, messageIds []uuid.UUID, viewedAt time.Time) ([]uuid.UUID, error)
+	// TakeChangedViews просмотры, изменившиеся с последней отправки
+	TakeChangedViews(ctx context.Context, limit int) ([]models.MessageViews, error)
+
+	// GetBranchParentMessage если чат не ветка, то вернет пустое сообщение с uuid.Nil
+	GetBranchParentMessage(ctx context.Context, userId uuid.UUID, branchId uuid.UUID) (models.Message, error)
 }
@@ -7,6 +7,7 @@ package mock_repository
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	models "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
 	gomock "github.com/golang/mock/gomock"
@@ -36,6 +37,35 @@ func (m *MockMessageRepository) EXPECT() *MockMessageRepositoryMockRecorder {
 	return m.recorder
 }
 
+// AddImportedAuthor mocks base method.
+func (m *MockMessageRepository) AddImportedAuthor(ctx context.Context, id, chatId uuid.UUID, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddImportedAuthor", ctx, id, chatId, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddImportedAuthor indicates an expected call of AddImportedAuthor.
+func (mr *MockMessageRepositoryMockRecorder) AddImportedAuthor(ctx, id, chatId, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddImportedAuthor", reflect.TypeOf((*MockMessageRepository)(nil).AddImportedAuthor), ctx, id, chatId, name)
+}
+
+// AddImportedMessages mocks base method.
+func (m *MockMessageRepository) AddImportedMessages(ctx context.Context, chatId uuid.UUID, messages []models.Message) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddImportedMessages", ctx, chatId, messages)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddImportedMessages indicates an expected call of AddImportedMessages.
+func (mr *MockMessageRepositoryMockRecorder) AddImportedMessages(ctx, chatId, messages interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddImportedMessages", reflect.TypeOf((*MockMessageRepository)(nil).AddImportedMessages), ctx, chatId, messages)
+}
+
 // AddMessage mocks base method.
 func (m *MockMessageRepository) AddMessage(message models.Message, chatId uuid.UUID) error {
 	m.ctrl.T.Helper()
@@ -50,105 +80,825 @@ func (mr *MockMessageRepositoryMockRecorder) AddMessage(message, chatId interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddMessage", reflect.TypeOf((*MockMessageRepository)(nil).AddMessage), message, chatId)
 }
 
-// DeleteMessage mocks base method.
-func (m *MockMessageRepository) DeleteMessage(ctx context.Context, messageId uuid.UUID) error {
+// AddMessageViews mocks base method.
+func (m *MockMessageRepository) AddMessageViews(ctx context.Context, userId, chatId uuid.UUID, messageIds []uuid.UUID, viewedAt time.Time) ([]uuid.UUID, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteMessage", ctx, messageId)
+	ret := m.ctrl.Call(m, "AddMessageViews", ctx, userId, chatId, messageIds, viewedAt)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddMessageViews indicates an expected call of AddMessageViews.
+func (mr *MockMessageRepositoryMockRecorder) AddMessageViews(ctx, userId, chatId, messageIds, viewedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddMessageViews", reflect.TypeOf((*MockMessageRepository)(nil).AddMessageViews), ctx, userId, chatId, messageIds, viewedAt)
+}
+
+// AddMessages mocks base method.
+func (m *MockMessageRepository) AddMessages(ctx context.Context, messages []models.Message) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddMessages", ctx, messages)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
+// AddMessages indicates an expected call of AddMessages.
+func (mr *MockMessageRepositoryMockRecorder) AddMessages(ctx, messages interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddMessages", reflect.TypeOf((*MockMessageRepository)(nil).AddMessages), ctx, messages)
+}
+
+// AddReaction mocks base method.
+func (m *MockMessageRepository) AddReaction(ctx context.Context, messageId, userId uuid.UUID, emoji string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddReaction", ctx, messageId, userId, emoji)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddReaction indicates an expected call of AddReaction.
+func (mr *MockMessageRepositoryMockRecorder) AddReaction(ctx, messageId, userId, emoji interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddReaction", reflect.TypeOf((*MockMessageRepository)(nil).AddReaction), ctx, messageId, userId, emoji)
+}
+
+// AddScheduledMessage mocks base method.
+func (m *MockMessageRepository) AddScheduledMessage(ctx context.Context, message models.ScheduledMessage) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddScheduledMessage", ctx, message)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddScheduledMessage indicates an expected call of AddScheduledMessage.
+func (mr *MockMessageRepositoryMockRecorder) AddScheduledMessage(ctx, message interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddScheduledMessage", reflect.TypeOf((*MockMessageRepository)(nil).AddScheduledMessage), ctx, message)
+}
+
+// CountMessagesForExport mocks base method.
+func (m *MockMessageRepository) CountMessagesForExport(ctx context.Context, userId, chatId uuid.UUID, input models.ExportInput) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountMessagesForExport", ctx, userId, chatId, input)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountMessagesForExport indicates an expected call of CountMessagesForExport.
+func (mr *MockMessageRepositoryMockRecorder) CountMessagesForExport(ctx, userId, chatId, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountMessagesForExport", reflect.TypeOf((*MockMessageRepository)(nil).CountMessagesForExport), ctx, userId, chatId, input)
+}
+
+// CreateExport mocks base method.
+func (m *MockMessageRepository) CreateExport(ctx context.Context, export models.ChatExport) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateExport", ctx, export)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateExport indicates an expected call of CreateExport.
+func (mr *MockMessageRepositoryMockRecorder) CreateExport(ctx, export interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateExport", reflect.TypeOf((*MockMessageRepository)(nil).CreateExport), ctx, export)
+}
+
+// CreateImport mocks base method.
+func (m *MockMessageRepository) CreateImport(ctx context.Context, chatImport models.ChatImport) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateImport", ctx, chatImport)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateImport indicates an expected call of CreateImport.
+func (mr *MockMessageRepositoryMockRecorder) CreateImport(ctx, chatImport interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateImport", reflect.TypeOf((*MockMessageRepository)(nil).CreateImport), ctx, chatImport)
+}
+
+// DeleteExpiredMessages mocks base method.
+func (m *MockMessageRepository) DeleteExpiredMessages(ctx context.Context, now time.Time, limit int) ([]models.Message, []string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredMessages", ctx, now, limit)
+	ret0, _ := ret[0].([]models.Message)
+	ret1, _ := ret[1].([]string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// DeleteExpiredMessages indicates an expected call of DeleteExpiredMessages.
+func (mr *MockMessageRepositoryMockRecorder) DeleteExpiredMessages(ctx, now, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredMessages", reflect.TypeOf((*MockMessageRepository)(nil).DeleteExpiredMessages), ctx, now, limit)
+}
+
+// DeleteFinishedExports mocks base method.
+func (m *MockMessageRepository) DeleteFinishedExports(ctx context.Context, before time.Time) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFinishedExports", ctx, before)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteFinishedExports indicates an expected call of DeleteFinishedExports.
+func (mr *MockMessageRepositoryMockRecorder) DeleteFinishedExports(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFinishedExports", reflect.TypeOf((*MockMessageRepository)(nil).DeleteFinishedExports), ctx, before)
+}
+
+// DeleteMessage mocks base method.
+func (m *MockMessageRepository) DeleteMessage(ctx context.Context, messageId uuid.UUID) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteMessage", ctx, messageId)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
 // DeleteMessage indicates an expected call of DeleteMessage.
 func (mr *MockMessageRepositoryMockRecorder) DeleteMessage(ctx, messageId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMessage", reflect.TypeOf((*MockMessageRepository)(nil).DeleteMessage), ctx, messageId)
 }
 
-// GetAllMessagesAfter mocks base method.
-func (m *MockMessageRepository) GetAllMessagesAfter(ctx context.Context, chatId, lastMessageId uuid.UUID) ([]models.Message, error) {
+// DeleteMessages mocks base method.
+func (m *MockMessageRepository) DeleteMessages(ctx context.Context, messageIds []uuid.UUID) ([]uuid.UUID, []string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllMessagesAfter", ctx, chatId, lastMessageId)
-	ret0, _ := ret[0].([]models.Message)
+	ret := m.ctrl.Call(m, "DeleteMessages", ctx, messageIds)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].([]string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// DeleteMessages indicates an expected call of DeleteMessages.
+func (mr *MockMessageRepositoryMockRecorder) DeleteMessages(ctx, messageIds interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMessages", reflect.TypeOf((*MockMessageRepository)(nil).DeleteMessages), ctx, messageIds)
+}
+
+// DeleteReaction mocks base method.
+func (m *MockMessageRepository) DeleteReaction(ctx context.Context, messageId, userId uuid.UUID, emoji string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteReaction", ctx, messageId, userId, emoji)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteReaction indicates an expected call of DeleteReaction.
+func (mr *MockMessageRepositoryMockRecorder) DeleteReaction(ctx, messageId, userId, emoji interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteReaction", reflect.TypeOf((*MockMessageRepository)(nil).DeleteReaction), ctx, messageId, userId, emoji)
+}
+
+// DeleteScheduledMessage mocks base method.
+func (m *MockMessageRepository) DeleteScheduledMessage(ctx context.Context, id uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteScheduledMessage", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteScheduledMessage indicates an expected call of DeleteScheduledMessage.
+func (mr *MockMessageRepositoryMockRecorder) DeleteScheduledMessage(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteScheduledMessage", reflect.TypeOf((*MockMessageRepository)(nil).DeleteScheduledMessage), ctx, id)
+}
+
+// FinishExport mocks base method.
+func (m *MockMessageRepository) FinishExport(ctx context.Context, exportId uuid.UUID, filePath, exportErr *string, finishedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinishExport", ctx, exportId, filePath, exportErr, finishedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FinishExport indicates an expected call of FinishExport.
+func (mr *MockMessageRepositoryMockRecorder) FinishExport(ctx, exportId, filePath, exportErr, finishedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinishExport", reflect.TypeOf((*MockMessageRepository)(nil).FinishExport), ctx, exportId, filePath, exportErr, finishedAt)
+}
+
+// FinishImport mocks base method.
+func (m *MockMessageRepository) FinishImport(ctx context.Context, importId uuid.UUID, importErr *string, finishedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinishImport", ctx, importId, importErr, finishedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FinishImport indicates an expected call of FinishImport.
+func (mr *MockMessageRepositoryMockRecorder) FinishImport(ctx, importId, importErr, finishedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinishImport", reflect.TypeOf((*MockMessageRepository)(nil).FinishImport), ctx, importId, importErr, finishedAt)
+}
+
+// GetAllowedReactions mocks base method.
+func (m *MockMessageRepository) GetAllowedReactions(ctx context.Context, chatId uuid.UUID) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllowedReactions", ctx, chatId)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllowedReactions indicates an expected call of GetAllowedReactions.
+func (mr *MockMessageRepositoryMockRecorder) GetAllowedReactions(ctx, chatId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllowedReactions", reflect.TypeOf((*MockMessageRepository)(nil).GetAllowedReactions), ctx, chatId)
+}
+
+// GetBranchParentMessage mocks base method.
+func (m *MockMessageRepository) GetBranchParentMessage(ctx context.Context, userId, branchId uuid.UUID) (models.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBranchParentMessage", ctx, userId, branchId)
+	ret0, _ := ret[0].(models.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBranchParentMessage indicates an expected call of GetBranchParentMessage.
+func (mr *MockMessageRepositoryMockRecorder) GetBranchParentMessage(ctx, userId, branchId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBranchParentMessage", reflect.TypeOf((*MockMessageRepository)(nil).GetBranchParentMessage), ctx, userId, branchId)
+}
+
+// GetDueScheduledMessages mocks base method.
+func (m *MockMessageRepository) GetDueScheduledMessages(ctx context.Context, now time.Time, limit int) ([]models.ScheduledMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDueScheduledMessages", ctx, now, limit)
+	ret0, _ := ret[0].([]models.ScheduledMessage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetAllMessagesAfter indicates an expected call of GetAllMessagesAfter.
-func (mr *MockMessageRepositoryMockRecorder) GetAllMessagesAfter(ctx, chatId, lastMessageId interface{}) *gomock.Call {
+// GetDueScheduledMessages indicates an expected call of GetDueScheduledMessages.
+func (mr *MockMessageRepositoryMockRecorder) GetDueScheduledMessages(ctx, now, limit interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllMessagesAfter", reflect.TypeOf((*MockMessageRepository)(nil).GetAllMessagesAfter), ctx, chatId, lastMessageId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDueScheduledMessages", reflect.TypeOf((*MockMessageRepository)(nil).GetDueScheduledMessages), ctx, now, limit)
+}
+
+// GetExport mocks base method.
+func (m *MockMessageRepository) GetExport(ctx context.Context, exportId uuid.UUID) (models.ChatExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExport", ctx, exportId)
+	ret0, _ := ret[0].(models.ChatExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExport indicates an expected call of GetExport.
+func (mr *MockMessageRepositoryMockRecorder) GetExport(ctx, exportId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExport", reflect.TypeOf((*MockMessageRepository)(nil).GetExport), ctx, exportId)
 }
 
 // GetFirstMessages mocks base method.
-func (m *MockMessageRepository) GetFirstMessages(ctx context.Context, chatId uuid.UUID) ([]models.Message, error) {
+func (m *MockMessageRepository) GetFirstMessages(ctx context.Context, userId, chatId uuid.UUID) ([]models.Message, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetFirstMessages", ctx, chatId)
+	ret := m.ctrl.Call(m, "GetFirstMessages", ctx, userId, chatId)
 	ret0, _ := ret[0].([]models.Message)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetFirstMessages indicates an expected call of GetFirstMessages.
-func (mr *MockMessageRepositoryMockRecorder) GetFirstMessages(ctx, chatId interface{}) *gomock.Call {
+func (mr *MockMessageRepositoryMockRecorder) GetFirstMessages(ctx, userId, chatId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFirstMessages", reflect.TypeOf((*MockMessageRepository)(nil).GetFirstMessages), ctx, userId, chatId)
+}
+
+// GetHistoryVisibility mocks base method.
+func (m *MockMessageRepository) GetHistoryVisibility(ctx context.Context, chatId uuid.UUID) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHistoryVisibility", ctx, chatId)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHistoryVisibility indicates an expected call of GetHistoryVisibility.
+func (mr *MockMessageRepositoryMockRecorder) GetHistoryVisibility(ctx, chatId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHistoryVisibility", reflect.TypeOf((*MockMessageRepository)(nil).GetHistoryVisibility), ctx, chatId)
+}
+
+// GetImport mocks base method.
+func (m *MockMessageRepository) GetImport(ctx context.Context, importId uuid.UUID) (models.ChatImport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImport", ctx, importId)
+	ret0, _ := ret[0].(models.ChatImport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImport indicates an expected call of GetImport.
+func (mr *MockMessageRepositoryMockRecorder) GetImport(ctx, importId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFirstMessages", reflect.TypeOf((*MockMessageRepository)(nil).GetFirstMessages), ctx, chatId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImport", reflect.TypeOf((*MockMessageRepository)(nil).GetImport), ctx, importId)
 }
 
 // GetLastMessage mocks base method.
-func (m *MockMessageRepository) GetLastMessage(chatId uuid.UUID) (models.Message, error) {
+func (m *MockMessageRepository) GetLastMessage(userId, chatId uuid.UUID) (models.Message, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetLastMessage", chatId)
+	ret := m.ctrl.Call(m, "GetLastMessage", userId, chatId)
 	ret0, _ := ret[0].(models.Message)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetLastMessage indicates an expected call of GetLastMessage.
-func (mr *MockMessageRepositoryMockRecorder) GetLastMessage(chatId interface{}) *gomock.Call {
+func (mr *MockMessageRepositoryMockRecorder) GetLastMessage(userId, chatId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLastMessage", reflect.TypeOf((*MockMessageRepository)(nil).GetLastMessage), chatId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLastMessage", reflect.TypeOf((*MockMessageRepository)(nil).GetLastMessage), userId, chatId)
+}
+
+// GetLinkPreview mocks base method.
+func (m *MockMessageRepository) GetLinkPreview(ctx context.Context, url string, fetchedAfter time.Time) (*models.LinkPreview, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLinkPreview", ctx, url, fetchedAfter)
+	ret0, _ := ret[0].(*models.LinkPreview)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLinkPreview indicates an expected call of GetLinkPreview.
+func (mr *MockMessageRepositoryMockRecorder) GetLinkPreview(ctx, url, fetchedAfter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLinkPreview", reflect.TypeOf((*MockMessageRepository)(nil).GetLinkPreview), ctx, url, fetchedAfter)
 }
 
 // GetMessageById mocks base method.
-func (m *MockMessageRepository) GetMessageById(ctx context.Context, messageId uuid.UUID) (models.Message, error) {
+func (m *MockMessageRepository) GetMessageById(ctx context.Context, userId, messageId uuid.UUID) (models.Message, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetMessageById", ctx, messageId)
+	ret := m.ctrl.Call(m, "GetMessageById", ctx, userId, messageId)
 	ret0, _ := ret[0].(models.Message)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetMessageById indicates an expected call of GetMessageById.
-func (mr *MockMessageRepositoryMockRecorder) GetMessageById(ctx, messageId interface{}) *gomock.Call {
+func (mr *MockMessageRepositoryMockRecorder) GetMessageById(ctx, userId, messageId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessageById", reflect.TypeOf((*MockMessageRepository)(nil).GetMessageById), ctx, messageId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessageById", reflect.TypeOf((*MockMessageRepository)(nil).GetMessageById), ctx, userId, messageId)
 }
 
-// SearchMessagesWithQuery mocks base method.
-func (m *MockMessageRepository) SearchMessagesWithQuery(ctx context.Context, chatId uuid.UUID, searchQuery string) ([]models.Message, error) {
+// GetMessageByNonce mocks base method.
+func (m *MockMessageRepository) GetMessageByNonce(ctx context.Context, userId, chatId uuid.UUID, nonce string) (models.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMessageByNonce", ctx, userId, chatId, nonce)
+	ret0, _ := ret[0].(models.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMessageByNonce indicates an expected call of GetMessageByNonce.
+func (mr *MockMessageRepositoryMockRecorder) GetMessageByNonce(ctx, userId, chatId, nonce interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessageByNonce", reflect.TypeOf((*MockMessageRepository)(nil).GetMessageByNonce), ctx, userId, chatId, nonce)
+}
+
+// GetMessageRevisions mocks base method.
+func (m *MockMessageRepository) GetMessageRevisions(ctx context.Context, messageId uuid.UUID) ([]models.MessageRevision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMessageRevisions", ctx, messageId)
+	ret0, _ := ret[0].([]models.MessageRevision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMessageRevisions indicates an expected call of GetMessageRevisions.
+func (mr *MockMessageRepositoryMockRecorder) GetMessageRevisions(ctx, messageId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessageRevisions", reflect.TypeOf((*MockMessageRepository)(nil).GetMessageRevisions), ctx, messageId)
+}
+
+// GetMessageTTL mocks base method.
+func (m *MockMessageRepository) GetMessageTTL(ctx context.Context, chatId uuid.UUID) (*int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMessageTTL", ctx, chatId)
+	ret0, _ := ret[0].(*int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMessageTTL indicates an expected call of GetMessageTTL.
+func (mr *MockMessageRepositoryMockRecorder) GetMessageTTL(ctx, chatId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessageTTL", reflect.TypeOf((*MockMessageRepository)(nil).GetMessageTTL), ctx, chatId)
+}
+
+// GetMessagesByIds mocks base method.
+func (m *MockMessageRepository) GetMessagesByIds(ctx context.Context, userId uuid.UUID, messageIds []uuid.UUID) ([]models.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMessagesByIds", ctx, userId, messageIds)
+	ret0, _ := ret[0].([]models.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMessagesByIds indicates an expected call of GetMessagesByIds.
+func (mr *MockMessageRepositoryMockRecorder) GetMessagesByIds(ctx, userId, messageIds interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessagesByIds", reflect.TypeOf((*MockMessageRepository)(nil).GetMessagesByIds), ctx, userId, messageIds)
+}
+
+// GetMessagesForExport mocks base method.
+func (m *MockMessageRepository) GetMessagesForExport(ctx context.Context, userId, chatId uuid.UUID, input models.ExportInput, after *models.ExportCursor, limit int) ([]models.ExportedMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMessagesForExport", ctx, userId, chatId, input, after, limit)
+	ret0, _ := ret[0].([]models.ExportedMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMessagesForExport indicates an expected call of GetMessagesForExport.
+func (mr *MockMessageRepositoryMockRecorder) GetMessagesForExport(ctx, userId, chatId, input, after, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessagesForExport", reflect.TypeOf((*MockMessageRepository)(nil).GetMessagesForExport), ctx, userId, chatId, input, after, limit)
+}
+
+// GetMessagesPage mocks base method.
+func (m *MockMessageRepository) GetMessagesPage(ctx context.Context, userId, chatId, anchorId uuid.UUID, older bool, limit int) ([]models.Message, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SearchMessagesWithQuery", ctx, chatId, searchQuery)
+	ret := m.ctrl.Call(m, "GetMessagesPage", ctx, userId, chatId, anchorId, older, limit)
 	ret0, _ := ret[0].([]models.Message)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
+// GetMessagesPage indicates an expected call of GetMessagesPage.
+func (mr *MockMessageRepositoryMockRecorder) GetMessagesPage(ctx, userId, chatId, anchorId, older, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessagesPage", reflect.TypeOf((*MockMessageRepository)(nil).GetMessagesPage), ctx, userId, chatId, anchorId, older, limit)
+}
+
+// GetPinnedMessages mocks base method.
+func (m *MockMessageRepository) GetPinnedMessages(ctx context.Context, userId, chatId uuid.UUID) ([]models.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPinnedMessages", ctx, userId, chatId)
+	ret0, _ := ret[0].([]models.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPinnedMessages indicates an expected call of GetPinnedMessages.
+func (mr *MockMessageRepositoryMockRecorder) GetPinnedMessages(ctx, userId, chatId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPinnedMessages", reflect.TypeOf((*MockMessageRepository)(nil).GetPinnedMessages), ctx, userId, chatId)
+}
+
+// GetPoll mocks base method.
+func (m *MockMessageRepository) GetPoll(ctx context.Context, messageId uuid.UUID) (*models.Poll, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPoll", ctx, messageId)
+	ret0, _ := ret[0].(*models.Poll)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPoll indicates an expected call of GetPoll.
+func (mr *MockMessageRepositoryMockRecorder) GetPoll(ctx, messageId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPoll", reflect.TypeOf((*MockMessageRepository)(nil).GetPoll), ctx, messageId)
+}
+
+// GetPollVotes mocks base method.
+func (m *MockMessageRepository) GetPollVotes(ctx context.Context, messageId, userId uuid.UUID) ([]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPollVotes", ctx, messageId, userId)
+	ret0, _ := ret[0].([]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPollVotes indicates an expected call of GetPollVotes.
+func (mr *MockMessageRepositoryMockRecorder) GetPollVotes(ctx, messageId, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPollVotes", reflect.TypeOf((*MockMessageRepository)(nil).GetPollVotes), ctx, messageId, userId)
+}
+
+// GetScheduledMessage mocks base method.
+func (m *MockMessageRepository) GetScheduledMessage(ctx context.Context, id uuid.UUID) (models.ScheduledMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetScheduledMessage", ctx, id)
+	ret0, _ := ret[0].(models.ScheduledMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetScheduledMessage indicates an expected call of GetScheduledMessage.
+func (mr *MockMessageRepositoryMockRecorder) GetScheduledMessage(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScheduledMessage", reflect.TypeOf((*MockMessageRepository)(nil).GetScheduledMessage), ctx, id)
+}
+
+// GetScheduledMessages mocks base method.
+func (m *MockMessageRepository) GetScheduledMessages(ctx context.Context, chatId uuid.UUID, authorId *uuid.UUID) ([]models.ScheduledMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetScheduledMessages", ctx, chatId, authorId)
+	ret0, _ := ret[0].([]models.ScheduledMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetScheduledMessages indicates an expected call of GetScheduledMessages.
+func (mr *MockMessageRepositoryMockRecorder) GetScheduledMessages(ctx, chatId, authorId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScheduledMessages", reflect.TypeOf((*MockMessageRepository)(nil).GetScheduledMessages), ctx, chatId, authorId)
+}
+
+// GetUserIdsByUsernames mocks base method.
+func (m *MockMessageRepository) GetUserIdsByUsernames(ctx context.Context, usernames []string) (map[string]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserIdsByUsernames", ctx, usernames)
+	ret0, _ := ret[0].(map[string]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserIdsByUsernames indicates an expected call of GetUserIdsByUsernames.
+func (mr *MockMessageRepositoryMockRecorder) GetUserIdsByUsernames(ctx, usernames interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserIdsByUsernames", reflect.TypeOf((*MockMessageRepository)(nil).GetUserIdsByUsernames), ctx, usernames)
+}
+
+// GetUserMentions mocks base method.
+func (m *MockMessageRepository) GetUserMentions(ctx context.Context, userId uuid.UUID, before *uuid.UUID, unreadOnly bool, limit int) ([]models.Mention, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserMentions", ctx, userId, before, unreadOnly, limit)
+	ret0, _ := ret[0].([]models.Mention)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserMentions indicates an expected call of GetUserMentions.
+func (mr *MockMessageRepositoryMockRecorder) GetUserMentions(ctx, userId, before, unreadOnly, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserMentions", reflect.TypeOf((*MockMessageRepository)(nil).GetUserMentions), ctx, userId, before, unreadOnly, limit)
+}
+
+// HideMessage mocks base method.
+func (m *MockMessageRepository) HideMessage(ctx context.Context, userId, messageId uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HideMessage", ctx, userId, messageId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HideMessage indicates an expected call of HideMessage.
+func (mr *MockMessageRepositoryMockRecorder) HideMessage(ctx, userId, messageId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HideMessage", reflect.TypeOf((*MockMessageRepository)(nil).HideMessage), ctx, userId, messageId)
+}
+
+// HideMessages mocks base method.
+func (m *MockMessageRepository) HideMessages(ctx context.Context, userId uuid.UUID, messageIds []uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HideMessages", ctx, userId, messageIds)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HideMessages indicates an expected call of HideMessages.
+func (mr *MockMessageRepositoryMockRecorder) HideMessages(ctx, userId, messageIds interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HideMessages", reflect.TypeOf((*MockMessageRepository)(nil).HideMessages), ctx, userId, messageIds)
+}
+
+// ReplaceMentions mocks base method.
+func (m *MockMessageRepository) ReplaceMentions(ctx context.Context, messageId uuid.UUID, userIds []uuid.UUID) ([]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceMentions", ctx, messageId, userIds)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReplaceMentions indicates an expected call of ReplaceMentions.
+func (mr *MockMessageRepositoryMockRecorder) ReplaceMentions(ctx, messageId, userIds interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceMentions", reflect.TypeOf((*MockMessageRepository)(nil).ReplaceMentions), ctx, messageId, userIds)
+}
+
+// SaveLinkPreview mocks base method.
+func (m *MockMessageRepository) SaveLinkPreview(ctx context.Context, preview models.LinkPreview) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveLinkPreview", ctx, preview)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveLinkPreview indicates an expected call of SaveLinkPreview.
+func (mr *MockMessageRepositoryMockRecorder) SaveLinkPreview(ctx, preview interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveLinkPreview", reflect.TypeOf((*MockMessageRepository)(nil).SaveLinkPreview), ctx, preview)
+}
+
+// SearchMessagesInChats mocks base method.
+func (m *MockMessageRepository) SearchMessagesInChats(ctx context.Context, userId uuid.UUID, chatIds []uuid.UUID, filter models.GlobalSearchFilter, cursor *models.SearchCursor, limit int) ([]models.FoundMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchMessagesInChats", ctx, userId, chatIds, filter, cursor, limit)
+	ret0, _ := ret[0].([]models.FoundMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchMessagesInChats indicates an expected call of SearchMessagesInChats.
+func (mr *MockMessageRepositoryMockRecorder) SearchMessagesInChats(ctx, userId, chatIds, filter, cursor, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchMessagesInChats", reflect.TypeOf((*MockMessageRepository)(nil).SearchMessagesInChats), ctx, userId, chatIds, filter, cursor, limit)
+}
+
+// SearchMessagesWithQuery mocks base method.
+func (m *MockMessageRepository) SearchMessagesWithQuery(ctx context.Context, userId, chatId uuid.UUID, searchQuery string, cursor *models.SearchCursor, limit int) ([]models.FoundMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchMessagesWithQuery", ctx, userId, chatId, searchQuery, cursor, limit)
+	ret0, _ := ret[0].([]models.FoundMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
 // SearchMessagesWithQuery indicates an expected call of SearchMessagesWithQuery.
-func (mr *MockMessageRepositoryMockRecorder) SearchMessagesWithQuery(ctx, chatId, searchQuery interface{}) *gomock.Call {
+func (mr *MockMessageRepositoryMockRecorder) SearchMessagesWithQuery(ctx, userId, chatId, searchQuery, cursor, limit interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchMessagesWithQuery", reflect.TypeOf((*MockMessageRepository)(nil).SearchMessagesWithQuery), ctx, chatId, searchQuery)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchMessagesWithQuery", reflect.TypeOf((*MockMessageRepository)(nil).SearchMessagesWithQuery), ctx, userId, chatId, searchQuery, cursor, limit)
 }
 
-// UpdateMessage mocks base method.
-func (m *MockMessageRepository) UpdateMessage(ctx context.Context, messageId uuid.UUID, newText string) error {
+// SetAllowedReactions mocks base method.
+func (m *MockMessageRepository) SetAllowedReactions(ctx context.Context, chatId uuid.UUID, reactions []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetAllowedReactions", ctx, chatId, reactions)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetAllowedReactions indicates an expected call of SetAllowedReactions.
+func (mr *MockMessageRepositoryMockRecorder) SetAllowedReactions(ctx, chatId, reactions interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAllowedReactions", reflect.TypeOf((*MockMessageRepository)(nil).SetAllowedReactions), ctx, chatId, reactions)
+}
+
+// SetHistoryVisibility mocks base method.
+func (m *MockMessageRepository) SetHistoryVisibility(ctx context.Context, chatId uuid.UUID, isVisible bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetHistoryVisibility", ctx, chatId, isVisible)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetHistoryVisibility indicates an expected call of SetHistoryVisibility.
+func (mr *MockMessageRepositoryMockRecorder) SetHistoryVisibility(ctx, chatId, isVisible interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHistoryVisibility", reflect.TypeOf((*MockMessageRepository)(nil).SetHistoryVisibility), ctx, chatId, isVisible)
+}
+
+// SetImportChat mocks base method.
+func (m *MockMessageRepository) SetImportChat(ctx context.Context, importId, chatId uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetImportChat", ctx, importId, chatId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetImportChat indicates an expected call of SetImportChat.
+func (mr *MockMessageRepositoryMockRecorder) SetImportChat(ctx, importId, chatId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetImportChat", reflect.TypeOf((*MockMessageRepository)(nil).SetImportChat), ctx, importId, chatId)
+}
+
+// SetMessageLinkPreview mocks base method.
+func (m *MockMessageRepository) SetMessageLinkPreview(ctx context.Context, messageId uuid.UUID, url *string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMessageLinkPreview", ctx, messageId, url)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetMessageLinkPreview indicates an expected call of SetMessageLinkPreview.
+func (mr *MockMessageRepositoryMockRecorder) SetMessageLinkPreview(ctx, messageId, url interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMessageLinkPreview", reflect.TypeOf((*MockMessageRepository)(nil).SetMessageLinkPreview), ctx, messageId, url)
+}
+
+// SetMessageTTL mocks base method.
+func (m *MockMessageRepository) SetMessageTTL(ctx context.Context, chatId uuid.UUID, ttl *int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMessageTTL", ctx, chatId, ttl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetMessageTTL indicates an expected call of SetMessageTTL.
+func (mr *MockMessageRepositoryMockRecorder) SetMessageTTL(ctx, chatId, ttl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMessageTTL", reflect.TypeOf((*MockMessageRepository)(nil).SetMessageTTL), ctx, chatId, ttl)
+}
+
+// SetPollVotes mocks base method.
+func (m *MockMessageRepository) SetPollVotes(ctx context.Context, messageId, userId uuid.UUID, positions []int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPollVotes", ctx, messageId, userId, positions)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPollVotes indicates an expected call of SetPollVotes.
+func (mr *MockMessageRepositoryMockRecorder) SetPollVotes(ctx, messageId, userId, positions interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPollVotes", reflect.TypeOf((*MockMessageRepository)(nil).SetPollVotes), ctx, messageId, userId, positions)
+}
+
+// TakeChangedViews mocks base method.
+func (m *MockMessageRepository) TakeChangedViews(ctx context.Context, limit int) ([]models.MessageViews, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TakeChangedViews", ctx, limit)
+	ret0, _ := ret[0].([]models.MessageViews)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TakeChangedViews indicates an expected call of TakeChangedViews.
+func (mr *MockMessageRepositoryMockRecorder) TakeChangedViews(ctx, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakeChangedViews", reflect.TypeOf((*MockMessageRepository)(nil).TakeChangedViews), ctx, limit)
+}
+
+// TakePendingExport mocks base method.
+func (m *MockMessageRepository) TakePendingExport(ctx context.Context, now, staleBefore time.Time) (models.ChatExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TakePendingExport", ctx, now, staleBefore)
+	ret0, _ := ret[0].(models.ChatExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TakePendingExport indicates an expected call of TakePendingExport.
+func (mr *MockMessageRepositoryMockRecorder) TakePendingExport(ctx, now, staleBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakePendingExport", reflect.TypeOf((*MockMessageRepository)(nil).TakePendingExport), ctx, now, staleBefore)
+}
+
+// TakePendingImport mocks base method.
+func (m *MockMessageRepository) TakePendingImport(ctx context.Context, now, staleBefore time.Time) (models.ChatImport, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateMessage", ctx, messageId, newText)
+	ret := m.ctrl.Call(m, "TakePendingImport", ctx, now, staleBefore)
+	ret0, _ := ret[0].(models.ChatImport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TakePendingImport indicates an expected call of TakePendingImport.
+func (mr *MockMessageRepositoryMockRecorder) TakePendingImport(ctx, now, staleBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakePendingImport", reflect.TypeOf((*MockMessageRepository)(nil).TakePendingImport), ctx, now, staleBefore)
+}
+
+// UpdateImportProgress mocks base method.
+func (m *MockMessageRepository) UpdateImportProgress(ctx context.Context, importId uuid.UUID, progress models.ImportProgress, newErrors []models.ImportError, maxErrors int, now time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateImportProgress", ctx, importId, progress, newErrors, maxErrors, now)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
+// UpdateImportProgress indicates an expected call of UpdateImportProgress.
+func (mr *MockMessageRepositoryMockRecorder) UpdateImportProgress(ctx, importId, progress, newErrors, maxErrors, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateImportProgress", reflect.TypeOf((*MockMessageRepository)(nil).UpdateImportProgress), ctx, importId, progress, newErrors, maxErrors, now)
+}
+
+// UpdateMessage mocks base method.
+func (m *MockMessageRepository) UpdateMessage(ctx context.Context, messageId, editorId uuid.UUID, newText string, entities []models.MessageEntity) (time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateMessage", ctx, messageId, editorId, newText, entities)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
 // UpdateMessage indicates an expected call of UpdateMessage.
-func (mr *MockMessageRepositoryMockRecorder) UpdateMessage(ctx, messageId, newText interface{}) *gomock.Call {
+func (mr *MockMessageRepositoryMockRecorder) UpdateMessage(ctx, messageId, editorId, newText, entities interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMessage", reflect.TypeOf((*MockMessageRepository)(nil).UpdateMessage), ctx, messageId, editorId, newText, entities)
+}
+
+// UpdateScheduledMessage mocks base method.
+func (m *MockMessageRepository) UpdateScheduledMessage(ctx context.Context, message models.ScheduledMessage) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateScheduledMessage", ctx, message)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateScheduledMessage indicates an expected call of UpdateScheduledMessage.
+func (mr *MockMessageRepositoryMockRecorder) UpdateScheduledMessage(ctx, message interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMessage", reflect.TypeOf((*MockMessageRepository)(nil).UpdateMessage), ctx, messageId, newText)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateScheduledMessage", reflect.TypeOf((*MockMessageRepository)(nil).UpdateScheduledMessage), ctx, message)
 }
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// pollColumn опрос сообщения m с результатами для пользователя $1.
+// Правильный ответ викторины виден только проголосовавшим и после закрытия
+const pollColumn = `(
+		SELECT json_build_object(
+			'question', p.question,
+			'isMultiple', p.is_multiple,
+			'isAnonymous', p.is_anonymous,
+			'isQuiz', p.is_quiz,
+			'closeAt', p.close_at,
+			'isClosed', p.close_at IS NOT NULL AND p.close_at <= now(),
+			'totalVoters', (
+				SELECT count(DISTINCT pv.user_id) FROM public.poll_vote AS pv WHERE pv.message_id = p.message_id
+			),
+			'correctOption', CASE WHEN (p.close_at IS NOT NULL AND p.close_at <= now()) OR EXISTS (
+				SELECT 1 FROM public.poll_vote AS pv WHERE pv.message_id = p.message_id AND pv.user_id = $1
+			) THEN p.correct_option END,
+			'options', (
+				SELECT json_agg(json_build_object(
+					'text', po.text,
+					'votes', (
+						SELECT count(*) FROM public.poll_vote AS pv
+						WHERE pv.message_id = po.message_id AND pv.position = po.position
+					),
+					'votedByMe', EXISTS (
+						SELECT 1 FROM public.poll_vote AS pv
+						WHERE pv.message_id = po.message_id AND pv.position = po.position AND pv.user_id = $1
+					),
+					'voters', CASE WHEN NOT p.is_anonymous THEN COALESCE((
+						SELECT json_agg(pv.user_id ORDER BY pv.voted_at)
+						FROM public.poll_vote AS pv
+						WHERE pv.message_id = po.message_id AND pv.position = po.position
+					), '[]'::json) END
+				) ORDER BY po.position)
+				FROM public.poll_option AS po
+				WHERE po.message_id = p.message_id
+			)
+		)
+		FROM public.poll AS p
+		WHERE p.message_id = m.id
+	)`
+
+// addPoll сохраняет опрос в транзакции добавления сообщения
+func addPoll(ctx context.Context, tx pgx.Tx, messageId uuid.UUID, poll models.Poll) error {
+	_, err := tx.Exec(ctx,
+		`INSERT INTO public.poll (message_id, question, is_multiple, is_anonymous, is_quiz, correct_option, close_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7);`,
+		messageId,
+		poll.Question,
+		poll.IsMultiple,
+		poll.IsAnonymous,
+		poll.IsQuiz,
+		poll.CorrectOption,
+		poll.CloseAt,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось добавить опрос: %v", err)
+		return err
+	}
+
+	for i, option := range poll.Options {
+		_, err = tx.Exec(ctx,
+			`INSERT INTO public.poll_option (message_id, position, text)
+		VALUES ($1, $2, $3);`,
+			messageId,
+			i,
+			option.Text,
+		)
+		if err != nil {
+			log.Printf("Repository: не удалось добавить вариант опроса: %v", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *MessageRepositoryImpl) GetPoll(ctx context.Context, messageId uuid.UUID) (*models.Poll, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return nil, err
+	}
+	defer conn.Release()
+
+	var poll models.Poll
+	err = conn.QueryRow(ctx,
+		`SELECT p.question,
+		p.is_multiple,
+		p.is_anonymous,
+		p.is_quiz,
+		p.correct_option,
+		p.close_at,
+		p.close_at IS NOT NULL AND p.close_at <= now(),
+		(SELECT json_agg(json_build_object('text', po.text) ORDER BY po.position)
+			FROM public.poll_option AS po WHERE po.message_id = p.message_id)
+	FROM public.poll AS p
+	WHERE p.message_id = $1;`,
+		messageId,
+	).Scan(
+		&poll.Question,
+		&poll.IsMultiple,
+		&poll.IsAnonymous,
+		&poll.IsQuiz,
+		&poll.CorrectOption,
+		&poll.CloseAt,
+		&poll.IsClosed,
+		&poll.Options,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("Repository: не удалось получить опрос %v: %v", messageId, err)
+		return nil, err
+	}
+
+	return &poll, nil
+}
+
+func (r *MessageRepositoryImpl) GetPollVotes(ctx context.Context, messageId uuid.UUID, userId uuid.UUID) ([]int, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx,
+		`SELECT position FROM public.poll_vote
+	WHERE message_id = $1 AND user_id = $2
+	ORDER BY position;`,
+		messageId,
+		userId,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось получить голоса: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	positions := []int{}
+	for rows.Next() {
+		var position int
+		if err := rows.Scan(&position); err != nil {
+			return nil, err
+		}
+		positions = append(positions, position)
+	}
+
+	return positions, rows.Err()
+}
+
+func (r *MessageRepositoryImpl) SetPollVotes(ctx context.Context, messageId uuid.UUID, userId uuid.UUID, positions []int) error {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось начать транзакцию: %v", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		`DELETE FROM public.poll_vote WHERE message_id = $1 AND user_id = $2;`,
+		messageId,
+		userId,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось отозвать голос: %v", err)
+		return err
+	}
+
+	for _, position := range positions {
+		_, err = tx.Exec(ctx,
+			`INSERT INTO public.poll_vote (message_id, user_id, position)
+		VALUES ($1, $2, $3);`,
+			messageId,
+			userId,
+			position,
+		)
+		if err != nil {
+			log.Printf("Repository: не удалось сохранить голос: %v", err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Repository: не удалось подтвердить транзакцию: %v", err)
+		return err
+	}
+
+	return nil
+}
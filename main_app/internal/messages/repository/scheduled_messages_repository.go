@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+const scheduledColumns = `id, chat_id, author_id, message, sticker_path, reply_to_id, payloads, entities, send_at, created_at`
+
+func scanScheduledMessage(row pgx.Row) (models.ScheduledMessage, error) {
+	var message models.ScheduledMessage
+
+	err := row.Scan(
+		&message.Id,
+		&message.ChatId,
+		&message.AuthorID,
+		&message.Message,
+		&message.Sticker,
+		&message.ReplyTo,
+		&message.Payloads,
+		&message.Entities,
+		&message.SendAt,
+		&message.CreatedAt,
+	)
+
+	return message, err
+}
+
+func (r *MessageRepositoryImpl) AddScheduledMessage(ctx context.Context, message models.ScheduledMessage) error {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return err
+	}
+	defer conn.Release()
+
+	if message.Payloads == nil {
+		message.Payloads = []models.Payload{}
+	}
+	if message.Entities == nil {
+		message.Entities = []models.MessageEntity{}
+	}
+
+	_, err = conn.Exec(ctx,
+		`INSERT INTO public.scheduled_message (id, chat_id, author_id, message, sticker_path, reply_to_id, payloads, entities, send_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);`,
+		message.Id,
+		message.ChatId,
+		message.AuthorID,
+		message.Message,
+		message.Sticker,
+		message.ReplyTo,
+		message.Payloads,
+		message.Entities,
+		message.SendAt,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось добавить отложенное сообщение: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *MessageRepositoryImpl) GetScheduledMessages(ctx context.Context, chatId uuid.UUID, authorId *uuid.UUID) ([]models.ScheduledMessage, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx,
+		`SELECT `+scheduledColumns+`
+	FROM public.scheduled_message
+	WHERE chat_id = $1 AND ($2::uuid IS NULL OR author_id = $2)
+	ORDER BY send_at;`,
+		chatId,
+		authorId,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось получить отложенные сообщения: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []models.ScheduledMessage{}
+	for rows.Next() {
+		message, err := scanScheduledMessage(rows)
+		if err != nil {
+			log.Printf("Repository: unable to scan: %v", err)
+			return nil, err
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+func (r *MessageRepositoryImpl) GetScheduledMessage(ctx context.Context, id uuid.UUID) (models.ScheduledMessage, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return models.ScheduledMessage{}, err
+	}
+	defer conn.Release()
+
+	message, err := scanScheduledMessage(conn.QueryRow(ctx,
+		`SELECT `+scheduledColumns+`
+	FROM public.scheduled_message
+	WHERE id = $1;`,
+		id,
+	))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ScheduledMessage{}, nil
+	}
+	if err != nil {
+		log.Printf("Repository: unable to scan: %v", err)
+		return models.ScheduledMessage{}, err
+	}
+
+	return message, nil
+}
+
+func (r *MessageRepositoryImpl) UpdateScheduledMessage(ctx context.Context, message models.ScheduledMessage) error {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx,
+		`UPDATE public.scheduled_message
+	SET message = $1, entities = $2, send_at = $3
+	WHERE id = $4;`,
+		message.Message,
+		message.Entities,
+		message.SendAt,
+		message.Id,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось изменить отложенное сообщение: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *MessageRepositoryImpl) DeleteScheduledMessage(ctx context.Context, id uuid.UUID) error {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, `DELETE FROM public.scheduled_message WHERE id = $1;`, id)
+	if err != nil {
+		log.Printf("Repository: не удалось удалить отложенное сообщение: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *MessageRepositoryImpl) GetDueScheduledMessages(ctx context.Context, now time.Time, limit int) ([]models.ScheduledMessage, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Repository: не удалось установить соединение: %v", err)
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx,
+		`SELECT `+scheduledColumns+`
+	FROM public.scheduled_message
+	WHERE send_at <= $1
+	ORDER BY send_at
+	LIMIT $2;`,
+		now,
+		limit,
+	)
+	if err != nil {
+		log.Printf("Repository: не удалось получить отложенные сообщения к отправке: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []models.ScheduledMessage{}
+	for rows.Next() {
+		message, err := scanScheduledMessage(rows)
+		if err != nil {
+			log.Printf("Repository: unable to scan: %v", err)
+			return nil, err
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
@@ -0,0 +1,135 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	jwt "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	chatModel "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/models"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrBadChatType  = errors.New("тип чата должен быть personal, group, channel или branch")
+	ErrBadDateRange = errors.New("начало периода позже его конца")
+)
+
+func (u *MessageUsecaseImplm) SearchAllMessages(ctx context.Context, user jwt.User, filter models.GlobalSearchFilter,
+	cursor string, limit int) (models.GlobalSearchDTO, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Infof("пользователь %v ищет по всем чатам: %v", user.ID, filter.Query)
+
+	if strings.TrimSpace(filter.Query) == "" {
+		return models.GlobalSearchDTO{}, ErrEmptySearchQuery
+	}
+
+	switch filter.ChatType {
+	case "", personal, group, channel, branch:
+	default:
+		return models.GlobalSearchDTO{}, ErrBadChatType
+	}
+
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		return models.GlobalSearchDTO{}, ErrBadDateRange
+	}
+
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	limit = min(limit, MaxSearchLimit)
+
+	var after *models.SearchCursor
+	if cursor != "" {
+		decoded, err := decodeSearchCursor(cursor)
+		if err != nil {
+			log.Errorf("получен кривой курсор поиска: %v", err)
+			return models.GlobalSearchDTO{}, ErrBadSearchCursor
+		}
+		after = &decoded
+	}
+
+	chats, err := u.chatRepository.GetUserChats(ctx, user.ID)
+	if err != nil {
+		log.Errorf("не удалось получить чаты пользователя: %v", err)
+		return models.GlobalSearchDTO{}, err
+	}
+
+	chatsById := map[uuid.UUID]chatModel.Chat{}
+	chatIds := []uuid.UUID{}
+	for _, chat := range chats {
+		if filter.ChatType != "" && chat.ChatType != filter.ChatType {
+			continue
+		}
+		chatsById[chat.ChatId] = chat
+		chatIds = append(chatIds, chat.ChatId)
+	}
+
+	result := models.GlobalSearchDTO{
+		Chats: []models.ChatSearchResult{},
+	}
+	if len(chatIds) == 0 {
+		return result, nil
+	}
+
+	// берем на один результат больше, чтобы понять, есть ли следующая страница
+	found, err := u.messageRepository.SearchMessagesInChats(ctx, user.ID, chatIds, filter, after, limit+1)
+	if err != nil {
+		return models.GlobalSearchDTO{}, err
+	}
+
+	if len(found) > limit {
+		found = found[:limit]
+		last := found[limit-1]
+		result.NextCursor = encodeSearchCursor(models.SearchCursor{
+			Rank:      last.Rank,
+			SentAt:    last.Message.SentAt,
+			MessageId: last.Message.MessageId,
+		})
+	}
+
+	// сообщения уже отсортированы по рангу, поэтому чаты идут в порядке лучшего совпадения
+	groupIndex := map[uuid.UUID]int{}
+	for _, message := range found {
+		chatId := message.Message.ChatId
+
+		index, ok := groupIndex[chatId]
+		if !ok {
+			group, err := u.newChatSearchResult(ctx, user, chatsById[chatId])
+			if err != nil {
+				return models.GlobalSearchDTO{}, err
+			}
+
+			index = len(result.Chats)
+			groupIndex[chatId] = index
+			result.Chats = append(result.Chats, group)
+		}
+
+		result.Chats[index].Messages = append(result.Chats[index].Messages, message)
+	}
+
+	return result, nil
+}
+
+func (u *MessageUsecaseImplm) newChatSearchResult(ctx context.Context, user jwt.User, chat chatModel.Chat) (models.ChatSearchResult, error) {
+	group := models.ChatSearchResult{
+		ChatId:     chat.ChatId,
+		ChatName:   chat.ChatName,
+		ChatType:   chat.ChatType,
+		AvatarPath: chat.AvatarURL,
+		Messages:   []models.FoundMessage{},
+	}
+
+	if chat.ChatType == personal {
+		var err error
+		group.ChatName, group.AvatarPath, err = u.chats.GetAvatarAndNameForPersonalChat(ctx, user.ID, chat.ChatId)
+		if err != nil {
+			return models.ChatSearchResult{}, err
+		}
+	}
+
+	return group, nil
+}
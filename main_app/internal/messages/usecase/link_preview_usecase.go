@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	socketUsecase "github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/events"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/utils/linkpreview"
+	"github.com/google/uuid"
+)
+
+// ограничения на загрузку превью ссылок
+const (
+	// сколько превью живет в кэше
+	linkPreviewTTL = 24 * time.Hour
+	// сколько ждем загрузку страницы вместе с сохранением
+	linkPreviewTimeout = 15 * time.Second
+	// сколько страниц загружается одновременно, остальные ссылки остаются без превью
+	maxLinkPreviewFetches = 16
+)
+
+// requestLinkPreview в фоне загружает превью первой ссылки сообщения
+func (u *MessageUsecaseImplm) requestLinkPreview(ctx context.Context, message models.Message) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+
+	link := linkpreview.FindURL(message.Message)
+	if link == "" {
+		return
+	}
+
+	select {
+	case u.previewSlots <- struct{}{}:
+	default:
+		log.Warnf("превью ссылки %s пропущено: слишком много загрузок", link)
+		return
+	}
+
+	go func() {
+		defer func() { <-u.previewSlots }()
+
+		// запрос пользователя к этому моменту уже завершится
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), linkPreviewTimeout)
+		defer cancel()
+
+		u.attachLinkPreview(ctx, message, link)
+	}()
+}
+
+// attachLinkPreview берет превью из кэша или загружает страницу и обновляет сообщение у клиентов
+func (u *MessageUsecaseImplm) attachLinkPreview(ctx context.Context, message models.Message, link string) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+
+	preview, err := u.messageRepository.GetLinkPreview(ctx, link, time.Now().Add(-linkPreviewTTL))
+	if err != nil {
+		return
+	}
+
+	if preview == nil {
+		fetched, err := u.previewFetcher.Fetch(ctx, link)
+		if err != nil {
+			log.Infof("не удалось загрузить превью ссылки %s: %v", link, err)
+			return
+		}
+
+		preview = &models.LinkPreview{
+			URL:         link,
+			Title:       fetched.Title,
+			Description: fetched.Description,
+			Image:       fetched.Image,
+			SiteName:    fetched.SiteName,
+		}
+
+		err = u.messageRepository.SaveLinkPreview(ctx, *preview)
+		if err != nil {
+			return
+		}
+	}
+
+	attached, err := u.messageRepository.SetMessageLinkPreview(ctx, message.MessageId, &link)
+	if err != nil || !attached {
+		// сообщение удалили или изменили, пока загружалась страница
+		return
+	}
+
+	// событие получат все участники, поэтому без отметок автора
+	stored, err := u.messageRepository.GetMessageById(ctx, uuid.Nil, message.MessageId)
+	if err != nil {
+		log.Errorf("не удалось получить сообщение %v с превью: %v", message.MessageId, err)
+		return
+	}
+
+	u.sendIvent(ctx, socketUsecase.UpdateMessage, stored)
+}
+
+// dropStaleLinkPreview после правки убирает превью, если его ссылка больше не первая в тексте.
+// Возвращает true, если нужно запросить превью заново
+func (u *MessageUsecaseImplm) dropStaleLinkPreview(ctx context.Context, message *models.Message) bool {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+
+	if message.LinkPreview == nil {
+		return true
+	}
+
+	if message.LinkPreview.URL == linkpreview.FindURL(message.Message) {
+		return false
+	}
+
+	_, err := u.messageRepository.SetMessageLinkPreview(ctx, message.MessageId, nil)
+	if err != nil {
+		log.Errorf("не удалось убрать превью сообщения %v: %v", message.MessageId, err)
+		return false
+	}
+
+	message.LinkPreview = nil
+	return true
+}
@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	socketUsecase "github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/events"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+	messagesMockRepo "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/repository/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttachLinkPreview(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	messageRepo := messagesMockRepo.NewMockMessageRepository(ctrl)
+	publisher := &fakePublisher{}
+	usecase := &MessageUsecaseImplm{
+		messageRepository: messageRepo,
+		ch:                publisher,
+	}
+
+	link := "https://example.com"
+	cached := &models.LinkPreview{URL: link, Title: "пример"}
+	message := models.Message{MessageId: uuid.New(), ChatId: uuid.New(), AuthorID: uuid.New()}
+
+	tests := []struct {
+		name          string
+		prepareMock   func()
+		expectedEvent bool
+	}{
+		{
+			name: "сообщение рассылается без отметок автора",
+			prepareMock: func() {
+				messageRepo.EXPECT().GetLinkPreview(gomock.Any(), link, gomock.Any()).Return(cached, nil)
+				messageRepo.EXPECT().SetMessageLinkPreview(gomock.Any(), message.MessageId, &link).Return(true, nil)
+				// uuid.Nil: reactedByMe и прочие отметки автора не должны уйти всему чату
+				messageRepo.EXPECT().GetMessageById(gomock.Any(), uuid.Nil, message.MessageId).
+					Return(models.Message{MessageId: message.MessageId, ChatId: message.ChatId, LinkPreview: cached}, nil)
+			},
+			expectedEvent: true,
+		},
+		{
+			name: "сообщение изменили, пока загружалась страница",
+			prepareMock: func() {
+				messageRepo.EXPECT().GetLinkPreview(gomock.Any(), link, gomock.Any()).Return(cached, nil)
+				messageRepo.EXPECT().SetMessageLinkPreview(gomock.Any(), message.MessageId, &link).Return(false, nil)
+			},
+		},
+		{
+			name: "ошибка получения сообщения",
+			prepareMock: func() {
+				messageRepo.EXPECT().GetLinkPreview(gomock.Any(), link, gomock.Any()).Return(cached, nil)
+				messageRepo.EXPECT().SetMessageLinkPreview(gomock.Any(), message.MessageId, &link).Return(true, nil)
+				messageRepo.EXPECT().GetMessageById(gomock.Any(), uuid.Nil, message.MessageId).Return(models.Message{}, errRepo)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher.events = nil
+			tt.prepareMock()
+
+			usecase.attachLinkPreview(context.Background(), message, link)
+
+			if !tt.expectedEvent {
+				assert.Empty(t, publisher.events)
+				return
+			}
+			if assert.Len(t, publisher.events, 1) {
+				assert.Equal(t, socketUsecase.UpdateMessage, publisher.events[0].Action)
+				assert.Equal(t, message.MessageId, publisher.events[0].Message.MessageId)
+				if assert.NotNil(t, publisher.events[0].Message.LinkPreview) {
+					assert.Equal(t, cached.Title, publisher.events[0].Message.LinkPreview.Title)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package usecase
+
+import (
+	"context"
+
+	socketUsecase "github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/events"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+
+	"github.com/google/uuid"
+)
+
+// sendBranchUpdate если chatId - ветка, рассылает в родительский чат сообщение с новым
+// количеством ответов и последним ответом. Ошибка только логируется: ответ уже сохранен
+func (u *MessageUsecaseImplm) sendBranchUpdate(ctx context.Context, chatId uuid.UUID) {
+	// сообщение собирается для всех участников, поэтому без отметок конкретного пользователя
+	parent, err := u.messageRepository.GetBranchParentMessage(ctx, uuid.Nil, chatId)
+	if err != nil {
+		logger.LoggerWithCtx(ctx, logger.Log).Errorf("не удалось получить сообщение ветки %v: %v", chatId, err)
+		return
+	}
+
+	if parent.MessageId == uuid.Nil {
+		return
+	}
+
+	u.sendIvent(ctx, socketUsecase.BranchUpdated, parent)
+}
@@ -0,0 +1,208 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	socketUsecase "github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/events"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/metric"
+	jwt "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+
+	"github.com/google/uuid"
+)
+
+// MaxBulkMessages сколько сообщений можно удалить одним запросом
+const MaxBulkMessages = 300
+
+var ErrBadBulk = fmt.Errorf("можно удалить от 1 до %d сообщений за раз", MaxBulkMessages)
+
+// uniqueBulkIds убирает повторы, сохраняя порядок запроса
+func uniqueBulkIds(messageIds []uuid.UUID) ([]uuid.UUID, error) {
+	if len(messageIds) == 0 || len(messageIds) > MaxBulkMessages {
+		return nil, ErrBadBulk
+	}
+
+	seen := make(map[uuid.UUID]struct{}, len(messageIds))
+	ids := make([]uuid.UUID, 0, len(messageIds))
+	for _, id := range messageIds {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// bulkResults собирает результаты в порядке запроса. Сообщения без статуса не найдены
+func bulkResults(ids []uuid.UUID, statuses map[uuid.UUID]string) models.BulkDeleteDTO {
+	results := make([]models.BulkMessageResult, 0, len(ids))
+	for _, id := range ids {
+		status, ok := statuses[id]
+		if !ok {
+			status = models.BulkNotFound
+		}
+		results = append(results, models.BulkMessageResult{
+			MessageId: id,
+			Status:    status,
+		})
+	}
+
+	return models.BulkDeleteDTO{
+		Results: results,
+	}
+}
+
+// groupByChat id сообщений по чатам, чаты в порядке первого появления
+func groupByChat(messages []models.Message, ids map[uuid.UUID]struct{}) ([]uuid.UUID, map[uuid.UUID][]uuid.UUID) {
+	chats := []uuid.UUID{}
+	byChat := map[uuid.UUID][]uuid.UUID{}
+	for _, message := range messages {
+		if _, ok := ids[message.MessageId]; !ok {
+			continue
+		}
+		if _, ok := byChat[message.ChatId]; !ok {
+			chats = append(chats, message.ChatId)
+		}
+		byChat[message.ChatId] = append(byChat[message.ChatId], message.MessageId)
+	}
+
+	return chats, byChat
+}
+
+// DeleteMessages удаляет сообщения у всех по тем же правилам, что и DeleteMessage.
+// Права проверяются для каждого сообщения, роль в чате запрашивается один раз на чат.
+// Разрешенные сообщения удаляются одним запросом, каждый чат получает одно событие deleteMessages
+func (u *MessageUsecaseImplm) DeleteMessages(ctx context.Context, user jwt.User, messageIds []uuid.UUID) (models.BulkDeleteDTO, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Infof("пользователь %v удаляет у всех %d сообщений", user.ID, len(messageIds))
+
+	ids, err := uniqueBulkIds(messageIds)
+	if err != nil {
+		return models.BulkDeleteDTO{}, err
+	}
+
+	messages, err := u.messageRepository.GetMessagesByIds(ctx, user.ID, ids)
+	if err != nil {
+		return models.BulkDeleteDTO{}, err
+	}
+
+	statuses := make(map[uuid.UUID]string, len(ids))
+	moderatedChats := map[uuid.UUID]bool{}
+	toDelete := []uuid.UUID{}
+	for _, message := range messages {
+		canDelete, err := u.canDeleteForEveryone(ctx, user, message, moderatedChats)
+		if err != nil {
+			return models.BulkDeleteDTO{}, err
+		}
+
+		if !canDelete {
+			statuses[message.MessageId] = models.BulkForbidden
+			continue
+		}
+		toDelete = append(toDelete, message.MessageId)
+	}
+
+	if len(toDelete) == 0 {
+		return bulkResults(ids, statuses), nil
+	}
+
+	// сообщение могли удалить параллельно, тогда для этого запроса оно не найдено
+	deleted, orphans, err := u.messageRepository.DeleteMessages(ctx, toDelete)
+	if err != nil {
+		return models.BulkDeleteDTO{}, err
+	}
+
+	deletedSet := make(map[uuid.UUID]struct{}, len(deleted))
+	for _, id := range deleted {
+		deletedSet[id] = struct{}{}
+		statuses[id] = models.BulkDeleted
+		metric.IncMetric(*deleteMessageMetric)
+	}
+
+	chats, byChat := groupByChat(messages, deletedSet)
+	for _, chatId := range chats {
+		u.publishIvent(ctx, socketUsecase.MessageEvent{
+			Action: socketUsecase.DeleteMessages,
+			Message: socketUsecase.Message{
+				ChatId:     chatId,
+				MessageIds: byChat[chatId],
+			},
+		})
+		u.sendBranchUpdate(ctx, chatId)
+	}
+	u.removeOrphanFiles(ctx, orphans)
+
+	log.Infof("пользователь %v удалил у всех %d сообщений из %d", user.ID, len(deleted), len(ids))
+	return bulkResults(ids, statuses), nil
+}
+
+// HideMessages удаляет сообщения только у пользователя. Скрыть можно сообщения чатов, где он состоит
+func (u *MessageUsecaseImplm) HideMessages(ctx context.Context, user jwt.User, messageIds []uuid.UUID) (models.BulkDeleteDTO, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Infof("пользователь %v удаляет у себя %d сообщений", user.ID, len(messageIds))
+
+	ids, err := uniqueBulkIds(messageIds)
+	if err != nil {
+		return models.BulkDeleteDTO{}, err
+	}
+
+	messages, err := u.messageRepository.GetMessagesByIds(ctx, user.ID, ids)
+	if err != nil {
+		return models.BulkDeleteDTO{}, err
+	}
+
+	statuses := make(map[uuid.UUID]string, len(ids))
+	memberChats := map[uuid.UUID]bool{}
+	toHide := []uuid.UUID{}
+	hiddenSet := map[uuid.UUID]struct{}{}
+	for _, message := range messages {
+		isMember, ok := memberChats[message.ChatId]
+		if !ok {
+			role, err := u.chatRepository.GetUserRoleInChat(ctx, user.ID, message.ChatId)
+			if err != nil {
+				return models.BulkDeleteDTO{}, err
+			}
+			isMember = role != NotInChat
+			memberChats[message.ChatId] = isMember
+		}
+
+		if !isMember {
+			statuses[message.MessageId] = models.BulkForbidden
+			continue
+		}
+		toHide = append(toHide, message.MessageId)
+		hiddenSet[message.MessageId] = struct{}{}
+	}
+
+	if len(toHide) == 0 {
+		return bulkResults(ids, statuses), nil
+	}
+
+	err = u.messageRepository.HideMessages(ctx, user.ID, toHide)
+	if err != nil {
+		return models.BulkDeleteDTO{}, err
+	}
+
+	for _, id := range toHide {
+		statuses[id] = models.BulkDeleted
+	}
+
+	// остальные сессии пользователя тоже должны убрать сообщения
+	chats, byChat := groupByChat(messages, hiddenSet)
+	for _, chatId := range chats {
+		u.publishIvent(ctx, socketUsecase.MessageEvent{
+			Action: socketUsecase.MessagesHidden,
+			Message: socketUsecase.Message{
+				ChatId:     chatId,
+				MessageIds: byChat[chatId],
+			},
+			Recipients: []uuid.UUID{user.ID},
+		})
+	}
+
+	return bulkResults(ids, statuses), nil
+}
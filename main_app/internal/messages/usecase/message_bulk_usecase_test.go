@@ -0,0 +1,278 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	jwt "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	chatMockRepo "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/repository/mocks"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+	messagesMockRepo "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/repository/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUniqueBulkIds(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name          string
+		ids           []uuid.UUID
+		expected      []uuid.UUID
+		expectedError error
+	}{
+		{
+			name:     "повторы убираются, порядок сохраняется",
+			ids:      []uuid.UUID{b, a, b},
+			expected: []uuid.UUID{b, a},
+		},
+		{
+			name:          "пустой запрос",
+			ids:           []uuid.UUID{},
+			expectedError: ErrBadBulk,
+		},
+		{
+			name:          "больше лимита",
+			ids:           make([]uuid.UUID, MaxBulkMessages+1),
+			expectedError: ErrBadBulk,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, err := uniqueBulkIds(tt.ids)
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			if tt.expectedError != nil {
+				return
+			}
+			assert.Equal(t, tt.expected, ids)
+		})
+	}
+}
+
+func TestGroupByChat(t *testing.T) {
+	chatA, chatB := uuid.New(), uuid.New()
+	m1, m2, m3, m4 := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	messages := []models.Message{
+		{MessageId: m1, ChatId: chatB},
+		{MessageId: m2, ChatId: chatA},
+		{MessageId: m3, ChatId: chatB},
+		{MessageId: m4, ChatId: chatA},
+	}
+	ids := map[uuid.UUID]struct{}{m1: {}, m2: {}, m3: {}}
+
+	chats, byChat := groupByChat(messages, ids)
+
+	// чаты в порядке первого сообщения из каждого
+	assert.Equal(t, []uuid.UUID{chatB, chatA}, chats)
+	assert.Equal(t, []uuid.UUID{m1, m3}, byChat[chatB])
+	assert.Equal(t, []uuid.UUID{m2}, byChat[chatA])
+}
+
+// bulkStatuses статусы результата по id сообщений
+func bulkStatuses(result models.BulkDeleteDTO) map[uuid.UUID]string {
+	statuses := make(map[uuid.UUID]string, len(result.Results))
+	for _, r := range result.Results {
+		statuses[r.MessageId] = r.Status
+	}
+	return statuses
+}
+
+func TestDeleteMessages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	messageRepo := messagesMockRepo.NewMockMessageRepository(ctrl)
+	chatRepo := chatMockRepo.NewMockChatRepository(ctrl)
+	usecase := &MessageUsecaseImplm{
+		messageRepository: messageRepo,
+		chatRepository:    chatRepo,
+		deleteWindow:      time.Hour,
+	}
+
+	user := jwt.User{ID: uuid.New()}
+	chatId := uuid.New()
+	own := models.Message{MessageId: uuid.New(), ChatId: chatId, AuthorID: user.ID, SentAt: time.Now()}
+	old := models.Message{MessageId: uuid.New(), ChatId: chatId, AuthorID: user.ID, SentAt: time.Now().Add(-2 * time.Hour)}
+	foreign := models.Message{MessageId: uuid.New(), ChatId: chatId, AuthorID: uuid.New(), SentAt: time.Now()}
+	missing := uuid.New()
+
+	tests := []struct {
+		name             string
+		ids              []uuid.UUID
+		prepareMock      func()
+		expectedStatuses map[uuid.UUID]string
+		expectedError    error
+	}{
+		{
+			name:          "пустой запрос",
+			ids:           []uuid.UUID{},
+			prepareMock:   func() {},
+			expectedError: ErrBadBulk,
+		},
+		{
+			name: "ошибка получения сообщений",
+			ids:  []uuid.UUID{own.MessageId},
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessagesByIds(gomock.Any(), user.ID, []uuid.UUID{own.MessageId}).Return(nil, errRepo)
+			},
+			expectedError: errRepo,
+		},
+		{
+			name: "чужие и старые сообщения без прав модератора, роль запрашивается один раз",
+			ids:  []uuid.UUID{old.MessageId, foreign.MessageId, missing},
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessagesByIds(gomock.Any(), user.ID, gomock.Any()).
+					Return([]models.Message{old, foreign}, nil)
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return(none, nil).Times(1)
+			},
+			expectedStatuses: map[uuid.UUID]string{
+				old.MessageId:     models.BulkForbidden,
+				foreign.MessageId: models.BulkForbidden,
+				missing:           models.BulkNotFound,
+			},
+		},
+		{
+			name: "модератор личного чата не может удалять чужие",
+			ids:  []uuid.UUID{foreign.MessageId},
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessagesByIds(gomock.Any(), user.ID, gomock.Any()).
+					Return([]models.Message{foreign}, nil)
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return(owner, nil)
+				chatRepo.EXPECT().GetChatType(gomock.Any(), chatId).Return(personal, nil)
+			},
+			expectedStatuses: map[uuid.UUID]string{
+				foreign.MessageId: models.BulkForbidden,
+			},
+		},
+		{
+			name: "ошибка удаления",
+			ids:  []uuid.UUID{own.MessageId},
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessagesByIds(gomock.Any(), user.ID, gomock.Any()).
+					Return([]models.Message{own}, nil)
+				messageRepo.EXPECT().DeleteMessages(gomock.Any(), []uuid.UUID{own.MessageId}).Return(nil, nil, errRepo)
+			},
+			expectedError: errRepo,
+		},
+		{
+			name: "удалено параллельно",
+			ids:  []uuid.UUID{own.MessageId, foreign.MessageId},
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessagesByIds(gomock.Any(), user.ID, gomock.Any()).
+					Return([]models.Message{own, foreign}, nil)
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return(admin, nil)
+				chatRepo.EXPECT().GetChatType(gomock.Any(), chatId).Return(group, nil)
+				messageRepo.EXPECT().DeleteMessages(gomock.Any(), []uuid.UUID{own.MessageId, foreign.MessageId}).
+					Return([]uuid.UUID{}, nil, nil)
+			},
+			expectedStatuses: map[uuid.UUID]string{
+				own.MessageId:     models.BulkNotFound,
+				foreign.MessageId: models.BulkNotFound,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.prepareMock()
+
+			result, err := usecase.DeleteMessages(context.Background(), user, tt.ids)
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			if tt.expectedError != nil {
+				return
+			}
+
+			// результаты в порядке запроса
+			resultIds := make([]uuid.UUID, 0, len(result.Results))
+			for _, r := range result.Results {
+				resultIds = append(resultIds, r.MessageId)
+			}
+			assert.Equal(t, tt.ids, resultIds)
+			assert.Equal(t, tt.expectedStatuses, bulkStatuses(result))
+		})
+	}
+}
+
+func TestHideMessages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	messageRepo := messagesMockRepo.NewMockMessageRepository(ctrl)
+	chatRepo := chatMockRepo.NewMockChatRepository(ctrl)
+	usecase := &MessageUsecaseImplm{
+		messageRepository: messageRepo,
+		chatRepository:    chatRepo,
+	}
+
+	user := jwt.User{ID: uuid.New()}
+	chatId := uuid.New()
+	first := models.Message{MessageId: uuid.New(), ChatId: chatId}
+	second := models.Message{MessageId: uuid.New(), ChatId: chatId}
+
+	tests := []struct {
+		name             string
+		ids              []uuid.UUID
+		prepareMock      func()
+		expectedStatuses map[uuid.UUID]string
+		expectedError    error
+	}{
+		{
+			name:          "больше лимита",
+			ids:           make([]uuid.UUID, MaxBulkMessages+1),
+			prepareMock:   func() {},
+			expectedError: ErrBadBulk,
+		},
+		{
+			name: "пользователь вышел из чата",
+			ids:  []uuid.UUID{first.MessageId, second.MessageId},
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessagesByIds(gomock.Any(), user.ID, gomock.Any()).
+					Return([]models.Message{first, second}, nil)
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return(NotInChat, nil).Times(1)
+			},
+			expectedStatuses: map[uuid.UUID]string{
+				first.MessageId:  models.BulkForbidden,
+				second.MessageId: models.BulkForbidden,
+			},
+		},
+		{
+			name: "ошибка получения роли",
+			ids:  []uuid.UUID{first.MessageId},
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessagesByIds(gomock.Any(), user.ID, gomock.Any()).
+					Return([]models.Message{first}, nil)
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return("", errRepo)
+			},
+			expectedError: errRepo,
+		},
+		{
+			name: "ошибка скрытия",
+			ids:  []uuid.UUID{first.MessageId, first.MessageId},
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessagesByIds(gomock.Any(), user.ID, []uuid.UUID{first.MessageId}).
+					Return([]models.Message{first}, nil)
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return(none, nil)
+				messageRepo.EXPECT().HideMessages(gomock.Any(), user.ID, []uuid.UUID{first.MessageId}).Return(errRepo)
+			},
+			expectedError: errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.prepareMock()
+
+			result, err := usecase.HideMessages(context.Background(), user, tt.ids)
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			if tt.expectedError != nil {
+				return
+			}
+			assert.Equal(t, tt.expectedStatuses, bulkStatuses(result))
+		})
+	}
+}
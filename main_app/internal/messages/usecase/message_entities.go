@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"unicode/utf16"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+)
+
+// максимальное количество сущностей форматирования в одном сообщении
+const MaxEntities = 100
+
+var ErrBadEntities = errors.New("некорректная разметка сообщения")
+
+func badEntity(i int, reason string) error {
+	return fmt.Errorf("%w: сущность %d %s", ErrBadEntities, i, reason)
+}
+
+// prepareEntities проверяет разметку текста и возвращает ее отсортированной по началу фрагмента
+func prepareEntities(text string, entities []models.MessageEntity) ([]models.MessageEntity, error) {
+	if len(entities) > MaxEntities {
+		return nil, fmt.Errorf("%w: больше %d сущностей", ErrBadEntities, MaxEntities)
+	}
+
+	units := utf16.Encode([]rune(text))
+	// граница фрагмента не должна разрезать суррогатную пару
+	isBoundary := func(pos int) bool {
+		return pos == len(units) || !utf16.IsSurrogate(rune(units[pos])) || units[pos] < 0xDC00
+	}
+
+	for i, entity := range entities {
+		if entity.Offset < 0 || entity.Length <= 0 || entity.Offset+entity.Length > len(units) {
+			return nil, badEntity(i, "выходит за границы текста")
+		}
+		if !isBoundary(entity.Offset) || !isBoundary(entity.Offset+entity.Length) {
+			return nil, badEntity(i, "разрезает символ")
+		}
+
+		switch entity.Type {
+		case models.EntityBold, models.EntityItalic, models.EntityCode, models.EntityPre, models.EntitySpoiler,
+			models.EntityLink, models.EntityMention:
+		default:
+			return nil, badEntity(i, fmt.Sprintf("имеет неизвестный тип %q", entity.Type))
+		}
+
+		if (entity.URL != nil) != (entity.Type == models.EntityLink) {
+			return nil, badEntity(i, "url задается только у link и обязателен для нее")
+		}
+		if entity.URL != nil {
+			link, err := url.Parse(*entity.URL)
+			if err != nil || (link.Scheme != "http" && link.Scheme != "https") || link.Host == "" {
+				return nil, badEntity(i, "содержит ссылку не на http(s)")
+			}
+		}
+
+		if (entity.UserID != nil) != (entity.Type == models.EntityMention) {
+			return nil, badEntity(i, "userId задается только у mention и обязателен для нее")
+		}
+
+		if entity.Language != nil && entity.Type != models.EntityPre {
+			return nil, badEntity(i, "language задается только у pre")
+		}
+	}
+
+	sorted := slices.Clone(entities)
+	if sorted == nil {
+		sorted = []models.MessageEntity{}
+	}
+	// внешние сущности идут раньше вложенных
+	slices.SortStableFunc(sorted, func(a, b models.MessageEntity) int {
+		return cmp.Or(cmp.Compare(a.Offset, b.Offset), cmp.Compare(b.Length, a.Length))
+	})
+
+	for i := range sorted {
+		for j := i + 1; j < len(sorted); j++ {
+			outer, inner := sorted[i], sorted[j]
+			if inner.Offset >= outer.Offset+outer.Length {
+				// дальше сущности начинаются еще правее
+				break
+			}
+
+			if err := checkNesting(outer, inner); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return sorted, nil
+}
+
+// checkNesting правила для двух пересекающихся сущностей, outer начинается не позже inner
+func checkNesting(outer models.MessageEntity, inner models.MessageEntity) error {
+	if inner.Offset+inner.Length > outer.Offset+outer.Length {
+		return fmt.Errorf("%w: %s и %s пересекаются, но не вложены друг в друга", ErrBadEntities, outer.Type, inner.Type)
+	}
+
+	if outer.Type == inner.Type {
+		return fmt.Errorf("%w: %s вложен в %s", ErrBadEntities, inner.Type, outer.Type)
+	}
+
+	// внутри кода форматирование не отображается, а код не может быть частью ссылки или упоминания
+	if isCode(outer.Type) || isCode(inner.Type) {
+		return fmt.Errorf("%w: %s не может пересекаться с %s", ErrBadEntities, inner.Type, outer.Type)
+	}
+
+	if isLinkLike(outer.Type) && isLinkLike(inner.Type) {
+		return fmt.Errorf("%w: %s не может быть внутри %s", ErrBadEntities, inner.Type, outer.Type)
+	}
+
+	return nil
+}
+
+func isCode(entityType string) bool {
+	return entityType == models.EntityCode || entityType == models.EntityPre
+}
+
+func isLinkLike(entityType string) bool {
+	return entityType == models.EntityLink || entityType == models.EntityMention
+}
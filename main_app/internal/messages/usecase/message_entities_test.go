@@ -0,0 +1,200 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestPrepareEntities(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		entities      []models.MessageEntity
+		expectedOrder []string
+		expectedError error
+	}{
+		{
+			name:          "пустая разметка",
+			text:          "привет",
+			entities:      nil,
+			expectedOrder: []string{},
+		},
+		{
+			name: "внешняя сущность идет раньше вложенной",
+			text: "жирный курсив",
+			entities: []models.MessageEntity{
+				{Type: models.EntityItalic, Offset: 7, Length: 6},
+				{Type: models.EntityBold, Offset: 0, Length: 13},
+			},
+			expectedOrder: []string{models.EntityBold, models.EntityItalic},
+		},
+		{
+			name: "смещение считается в UTF-16",
+			text: "😀 text",
+			entities: []models.MessageEntity{
+				{Type: models.EntityBold, Offset: 3, Length: 4},
+			},
+			expectedOrder: []string{models.EntityBold},
+		},
+		{
+			name: "отрицательное смещение",
+			text: "text",
+			entities: []models.MessageEntity{
+				{Type: models.EntityBold, Offset: -1, Length: 2},
+			},
+			expectedError: ErrBadEntities,
+		},
+		{
+			name: "нулевая длина",
+			text: "text",
+			entities: []models.MessageEntity{
+				{Type: models.EntityBold, Offset: 0, Length: 0},
+			},
+			expectedError: ErrBadEntities,
+		},
+		{
+			name: "выходит за конец текста",
+			text: "text",
+			entities: []models.MessageEntity{
+				{Type: models.EntityBold, Offset: 2, Length: 3},
+			},
+			expectedError: ErrBadEntities,
+		},
+		{
+			name: "разрезает суррогатную пару",
+			text: "😀 text",
+			entities: []models.MessageEntity{
+				{Type: models.EntityBold, Offset: 1, Length: 2},
+			},
+			expectedError: ErrBadEntities,
+		},
+		{
+			name: "неизвестный тип",
+			text: "text",
+			entities: []models.MessageEntity{
+				{Type: "underline", Offset: 0, Length: 4},
+			},
+			expectedError: ErrBadEntities,
+		},
+		{
+			name: "ссылка без url",
+			text: "text",
+			entities: []models.MessageEntity{
+				{Type: models.EntityLink, Offset: 0, Length: 4},
+			},
+			expectedError: ErrBadEntities,
+		},
+		{
+			name: "ссылка не на http",
+			text: "text",
+			entities: []models.MessageEntity{
+				{Type: models.EntityLink, Offset: 0, Length: 4, URL: ptr("javascript:alert(1)")},
+			},
+			expectedError: ErrBadEntities,
+		},
+		{
+			name: "упоминание без пользователя",
+			text: "@user",
+			entities: []models.MessageEntity{
+				{Type: models.EntityMention, Offset: 0, Length: 5},
+			},
+			expectedError: ErrBadEntities,
+		},
+		{
+			name: "язык не у блока кода",
+			text: "text",
+			entities: []models.MessageEntity{
+				{Type: models.EntityCode, Offset: 0, Length: 4, Language: ptr("go")},
+			},
+			expectedError: ErrBadEntities,
+		},
+		{
+			name: "пересекаются, но не вложены",
+			text: "abcdef",
+			entities: []models.MessageEntity{
+				{Type: models.EntityBold, Offset: 0, Length: 4},
+				{Type: models.EntityItalic, Offset: 2, Length: 4},
+			},
+			expectedError: ErrBadEntities,
+		},
+		{
+			name: "одинаковый тип вложен сам в себя",
+			text: "abcdef",
+			entities: []models.MessageEntity{
+				{Type: models.EntityBold, Offset: 0, Length: 6},
+				{Type: models.EntityBold, Offset: 1, Length: 2},
+			},
+			expectedError: ErrBadEntities,
+		},
+		{
+			name: "форматирование внутри кода",
+			text: "abcdef",
+			entities: []models.MessageEntity{
+				{Type: models.EntityCode, Offset: 0, Length: 6},
+				{Type: models.EntityBold, Offset: 1, Length: 2},
+			},
+			expectedError: ErrBadEntities,
+		},
+		{
+			name: "упоминание внутри ссылки",
+			text: "abcdef",
+			entities: []models.MessageEntity{
+				{Type: models.EntityLink, Offset: 0, Length: 6, URL: ptr("https://example.com")},
+				{Type: models.EntityMention, Offset: 1, Length: 2, UserID: ptr(uuid.New())},
+			},
+			expectedError: ErrBadEntities,
+		},
+		{
+			name: "соседние сущности не пересекаются",
+			text: "abcdef",
+			entities: []models.MessageEntity{
+				{Type: models.EntityCode, Offset: 3, Length: 3},
+				{Type: models.EntityCode, Offset: 0, Length: 3},
+			},
+			expectedOrder: []string{models.EntityCode, models.EntityCode},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sorted, err := prepareEntities(tt.text, tt.entities)
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			if tt.expectedError != nil {
+				return
+			}
+
+			types := make([]string, 0, len(sorted))
+			for _, entity := range sorted {
+				types = append(types, entity.Type)
+			}
+			assert.Equal(t, tt.expectedOrder, types)
+			assert.IsNonDecreasing(t, offsets(sorted), "сущности не отсортированы по смещению")
+		})
+	}
+}
+
+func TestPrepareEntitiesLimit(t *testing.T) {
+	entities := make([]models.MessageEntity, MaxEntities+1)
+	for i := range entities {
+		entities[i] = models.MessageEntity{Type: models.EntityBold, Offset: i, Length: 1}
+	}
+
+	_, err := prepareEntities(string(make([]byte, MaxEntities+1)), entities)
+	assert.ErrorIs(t, err, ErrBadEntities)
+}
+
+func offsets(entities []models.MessageEntity) []int {
+	result := make([]int, 0, len(entities))
+	for _, entity := range entities {
+		result = append(result, entity.Offset)
+	}
+	return result
+}
@@ -0,0 +1,218 @@
+package usecase
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+
+	"github.com/google/uuid"
+)
+
+// exportHeader шапка выгрузки
+type exportHeader struct {
+	ChatId     uuid.UUID  `json:"chatId"`
+	ChatName   string     `json:"chatName"`
+	ExportedAt time.Time  `json:"exportedAt"`
+	From       *time.Time `json:"from"`
+	To         *time.Time `json:"to"`
+}
+
+// exportEncoder пишет выгрузку по одному сообщению, не держа всю историю в памяти
+type exportEncoder interface {
+	begin(header exportHeader) error
+	write(message models.ExportedMessage) error
+	end() error
+}
+
+func newExportEncoder(format string, w io.Writer) exportEncoder {
+	switch format {
+	case models.ExportHTML:
+		return &htmlExportEncoder{w: w}
+	case models.ExportCSV:
+		return &csvExportEncoder{w: csv.NewWriter(w)}
+	default:
+		return &jsonExportEncoder{w: w}
+	}
+}
+
+// ExportContentType тип содержимого файла выгрузки
+func ExportContentType(format string) string {
+	switch format {
+	case models.ExportHTML:
+		return "text/html; charset=utf-8"
+	case models.ExportCSV:
+		return "text/csv; charset=utf-8"
+	default:
+		return "application/json"
+	}
+}
+
+// ExportFilename имя, под которым клиент сохранит выгрузку
+func ExportFilename(chatId uuid.UUID, format string) string {
+	return "chat-" + chatId.String() + "." + format
+}
+
+// jsonExportEncoder {"chat": {...}, "messages": [...]}
+type jsonExportEncoder struct {
+	w       io.Writer
+	written int
+}
+
+func (e *jsonExportEncoder) begin(header exportHeader) error {
+	data, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(e.w, `{"chat":`+string(data)+`,"messages":[`)
+	return err
+}
+
+func (e *jsonExportEncoder) write(message models.ExportedMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	if e.written > 0 {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return err
+		}
+	}
+	e.written++
+
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonExportEncoder) end() error {
+	_, err := io.WriteString(e.w, "]}\n")
+	return err
+}
+
+// csvExportEncoder одна строка на сообщение, вложения через пробел
+type csvExportEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvExportEncoder) begin(_ exportHeader) error {
+	return e.w.Write([]string{
+		"messageId", "datetime", "authorID", "authorName", "text", "editedAt",
+		"replyTo", "forwardedFrom", "branchId", "sticker", "payloads",
+	})
+}
+
+func (e *csvExportEncoder) write(message models.ExportedMessage) error {
+	payloads := make([]string, 0, len(message.Payloads))
+	for _, payload := range message.Payloads {
+		payloads = append(payloads, payload.URL)
+	}
+
+	return e.w.Write([]string{
+		message.MessageId.String(),
+		message.SentAt.UTC().Format(time.RFC3339),
+		message.AuthorID.String(),
+		csvCell(message.AuthorName),
+		csvCell(message.Text),
+		formatOptionalTime(message.EditedAt),
+		formatOptionalUUID(message.ReplyTo),
+		csvCell(forwardedFromName(message.ForwardedFrom)),
+		formatOptionalUUID(message.BranchID),
+		optionalString(message.Sticker),
+		strings.Join(payloads, " "),
+	})
+}
+
+func (e *csvExportEncoder) end() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// csvCell не дает табличным редакторам выполнить текст сообщения как формулу
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+var exportHTMLHead = template.Must(template.New("head").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>{{.ChatName}}</title>
+<style>
+body { font-family: sans-serif; max-width: 800px; margin: 0 auto; }
+.message { border-bottom: 1px solid #ddd; padding: 8px 0; }
+.meta { color: #777; font-size: 12px; }
+.text { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>{{.ChatName}}</h1>
+<p class="meta">Выгружено {{.ExportedAt.Format "02.01.2006 15:04"}}{{with .From}}, с {{.Format "02.01.2006 15:04"}}{{end}}{{with .To}}, по {{.Format "02.01.2006 15:04"}}{{end}}</p>
+`))
+
+var exportHTMLMessage = template.Must(template.New("message").Parse(`<div class="message" id="{{.MessageId}}">
+<div class="meta"><b>{{.AuthorName}}</b> {{.SentAt.Format "02.01.2006 15:04"}}{{with .EditedAt}} (изменено {{.Format "02.01.2006 15:04"}}){{end}}</div>
+{{with .ReplyTo}}<div class="meta">В ответ на <a href="#{{.}}">сообщение</a></div>{{end}}
+{{with .ForwardedFrom}}<div class="meta">Переслано от {{with .AuthorName}}{{.}}{{else}}{{.AuthorID}}{{end}}</div>{{end}}
+{{with .Sticker}}<div class="meta">Стикер: {{.}}</div>{{end}}
+<div class="text">{{.Text}}</div>
+{{range .Payloads}}<div class="meta">Вложение: <a href="{{.URL}}">{{.Filename}}</a></div>{{end}}
+{{with .BranchID}}<div class="meta">Есть ветка обсуждения {{.}}</div>{{end}}
+</div>
+`))
+
+type htmlExportEncoder struct {
+	w io.Writer
+}
+
+func (e *htmlExportEncoder) begin(header exportHeader) error {
+	return exportHTMLHead.Execute(e.w, header)
+}
+
+func (e *htmlExportEncoder) write(message models.ExportedMessage) error {
+	return exportHTMLMessage.Execute(e.w, message)
+}
+
+func (e *htmlExportEncoder) end() error {
+	_, err := io.WriteString(e.w, "</body>\n</html>\n")
+	return err
+}
+
+func forwardedFromName(from *models.ForwardedFrom) string {
+	if from == nil {
+		return ""
+	}
+	if from.AuthorName != nil {
+		return *from.AuthorName
+	}
+	return from.AuthorID.String()
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatOptionalUUID(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+func optionalString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
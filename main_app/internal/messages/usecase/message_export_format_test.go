@@ -0,0 +1,206 @@
+package usecase
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// encodeExport прогоняет сообщения через кодировщик формата так же, как фоновая выгрузка
+func encodeExport(t *testing.T, format string, messages []models.ExportedMessage) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	encoder := newExportEncoder(format, &buf)
+
+	header := exportHeader{
+		ChatId:     uuid.New(),
+		ChatName:   "Чат <b>",
+		ExportedAt: time.Date(2024, 4, 13, 8, 30, 0, 0, time.UTC),
+	}
+	assert.NoError(t, encoder.begin(header))
+	for _, message := range messages {
+		assert.NoError(t, encoder.write(message))
+	}
+	assert.NoError(t, encoder.end())
+
+	return buf.String()
+}
+
+func exportedMessage(text string) models.ExportedMessage {
+	return models.ExportedMessage{
+		MessageId:  uuid.New(),
+		AuthorID:   uuid.New(),
+		AuthorName: "Vincent Vega",
+		Text:       text,
+		SentAt:     time.Date(2024, 4, 13, 8, 30, 0, 0, time.UTC),
+		Payloads:   []models.Payload{},
+	}
+}
+
+func TestJSONExportEncoder(t *testing.T) {
+	tests := []struct {
+		name  string
+		texts []string
+	}{
+		{
+			name:  "без сообщений",
+			texts: []string{},
+		},
+		{
+			name:  "кавычки и переводы строк",
+			texts: []string{`"привет"`, "строка\nстрока", `\`},
+		},
+		{
+			name:  "html в тексте сохраняется как есть",
+			texts: []string{"<script>alert(1)</script>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := make([]models.ExportedMessage, 0, len(tt.texts))
+			for _, text := range tt.texts {
+				messages = append(messages, exportedMessage(text))
+			}
+
+			var decoded struct {
+				Chat     exportHeader             `json:"chat"`
+				Messages []models.ExportedMessage `json:"messages"`
+			}
+			err := json.Unmarshal([]byte(encodeExport(t, models.ExportJSON, messages)), &decoded)
+			if !assert.NoError(t, err, "ожидался корректный json") {
+				return
+			}
+
+			assert.Equal(t, "Чат <b>", decoded.Chat.ChatName)
+			texts := make([]string, 0, len(decoded.Messages))
+			for _, message := range decoded.Messages {
+				texts = append(texts, message.Text)
+			}
+			assert.Equal(t, tt.texts, texts)
+		})
+	}
+}
+
+func TestCSVExportEncoder(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		expectedText string
+	}{
+		{
+			name:         "обычный текст",
+			text:         "привет",
+			expectedText: "привет",
+		},
+		{
+			name:         "запятые, кавычки и переводы строк",
+			text:         "a, \"b\"\nc",
+			expectedText: "a, \"b\"\nc",
+		},
+		{
+			name:         "формула",
+			text:         "=HYPERLINK(\"http://evil\")",
+			expectedText: "'=HYPERLINK(\"http://evil\")",
+		},
+		{
+			name:         "начинается с минуса",
+			text:         "-1+2",
+			expectedText: "'-1+2",
+		},
+		{
+			name:         "начинается с @",
+			text:         "@user",
+			expectedText: "'@user",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := exportedMessage(tt.text)
+			message.Payloads = []models.Payload{{URL: "/uploads/a.png"}, {URL: "/uploads/b.png"}}
+
+			records, err := csv.NewReader(strings.NewReader(encodeExport(t, models.ExportCSV,
+				[]models.ExportedMessage{message}))).ReadAll()
+			if !assert.NoError(t, err, "ожидался корректный csv") {
+				return
+			}
+
+			// заголовок и одна строка
+			if !assert.Len(t, records, 2) {
+				return
+			}
+			assert.Equal(t, "text", records[0][4])
+			assert.Equal(t, tt.expectedText, records[1][4])
+			// вложения через пробел
+			assert.Equal(t, "/uploads/a.png /uploads/b.png", records[1][10])
+		})
+	}
+}
+
+func TestHTMLExportEncoder(t *testing.T) {
+	tests := []struct {
+		name        string
+		message     func() models.ExportedMessage
+		contains    []string
+		notContains []string
+	}{
+		{
+			name: "текст экранируется",
+			message: func() models.ExportedMessage {
+				return exportedMessage("<script>alert(1)</script>")
+			},
+			contains:    []string{"&lt;script&gt;alert(1)&lt;/script&gt;", "<title>Чат &lt;b&gt;</title>"},
+			notContains: []string{"<script>"},
+		},
+		{
+			name: "имя автора экранируется",
+			message: func() models.ExportedMessage {
+				message := exportedMessage("привет")
+				message.AuthorName = `<img src=x onerror="alert(1)">`
+				return message
+			},
+			notContains: []string{"<img"},
+		},
+		{
+			name: "опасная ссылка во вложении не попадает в href",
+			message: func() models.ExportedMessage {
+				message := exportedMessage("привет")
+				message.Payloads = []models.Payload{{URL: "javascript:alert(1)", Filename: "file.txt"}}
+				return message
+			},
+			notContains: []string{`href="javascript:`},
+		},
+		{
+			name: "ответ ссылается на якорь сообщения",
+			message: func() models.ExportedMessage {
+				message := exportedMessage("ответ")
+				message.ReplyTo = ptr(uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"))
+				return message
+			},
+			contains: []string{`href="#123e4567-e89b-12d3-a456-426614174000"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := encodeExport(t, models.ExportHTML, []models.ExportedMessage{tt.message()})
+
+			assert.True(t, strings.HasSuffix(out, "</body>\n</html>\n"), "документ не закрыт: %s", out)
+			for _, s := range tt.contains {
+				assert.Contains(t, out, s)
+			}
+			for _, s := range tt.notContains {
+				assert.NotContains(t, out, s)
+			}
+		})
+	}
+}
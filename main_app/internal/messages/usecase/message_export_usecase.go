@@ -0,0 +1,263 @@
+package usecase
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	jwt "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	customerror "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/custom_error"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+	multipartHepler "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/utils/multipartHelper"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxSyncExportMessages выгрузки больше этого выполняются в фоне
+	MaxSyncExportMessages = 5000
+	exportBatchSize       = 500
+	exportDir             = "export"
+	// выгрузку, которая выполняется дольше, считаем брошенной и начинаем заново
+	exportStaleAfter = 30 * time.Minute
+	// сколько хранится готовый файл выгрузки
+	exportKeepFor = 24 * time.Hour
+)
+
+var (
+	ErrBadExportFormat = errors.New("формат выгрузки должен быть json, html или csv")
+	ErrBadExportPeriod = errors.New("начало периода выгрузки должно быть не позже конца")
+	ErrExportNotFound  = errors.New("выгрузка не найдена")
+	ErrExportNotReady  = errors.New("выгрузка еще не готова")
+)
+
+func checkExportInput(input models.ExportInput) error {
+	switch input.Format {
+	case models.ExportJSON, models.ExportHTML, models.ExportCSV:
+	default:
+		return ErrBadExportFormat
+	}
+
+	if input.From != nil && input.To != nil && input.From.After(*input.To) {
+		return ErrBadExportPeriod
+	}
+
+	return nil
+}
+
+// PrepareExport проверяет доступ и параметры выгрузки. Большую выгрузку ставит в очередь и возвращает задачу,
+// для небольшой возвращает nil: ее нужно сразу записать через WriteExport
+func (u *MessageUsecaseImplm) PrepareExport(ctx context.Context, user jwt.User, chatId uuid.UUID, input models.ExportInput) (*models.ChatExport, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Infof("пользователь %v выгружает историю чата %v в %s", user.ID, chatId, input.Format)
+
+	err := checkExportInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := u.chatRepository.GetUserRoleInChat(ctx, user.ID, chatId)
+	if err != nil {
+		return nil, err
+	}
+
+	if role == NotInChat {
+		return nil, &customerror.NoPermissionError{
+			Area: fmt.Sprintf("чат %v", chatId),
+			User: user.ID.String(),
+		}
+	}
+
+	count, err := u.messageRepository.CountMessagesForExport(ctx, user.ID, chatId, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if count <= MaxSyncExportMessages {
+		return nil, nil
+	}
+
+	export := models.ChatExport{
+		Id:        uuid.New(),
+		ChatId:    chatId,
+		UserId:    user.ID,
+		Format:    input.Format,
+		From:      input.From,
+		To:        input.To,
+		Status:    models.ExportPending,
+		CreatedAt: time.Now(),
+	}
+
+	err = u.messageRepository.CreateExport(ctx, export)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("выгрузка %d сообщений чата %v поставлена в очередь: %v", count, chatId, export.Id)
+	return &export, nil
+}
+
+// WriteExport пишет историю чата в w. Доступ должен быть проверен через PrepareExport
+func (u *MessageUsecaseImplm) WriteExport(ctx context.Context, user jwt.User, chatId uuid.UUID, input models.ExportInput, w io.Writer) error {
+	return u.writeExport(ctx, user.ID, chatId, input, w)
+}
+
+// writeExport сообщения читаются страницами по exportBatchSize, поэтому память не зависит от размера чата
+func (u *MessageUsecaseImplm) writeExport(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, input models.ExportInput, w io.Writer) error {
+	chat, err := u.chatRepository.GetChatById(ctx, chatId)
+	if err != nil {
+		return err
+	}
+
+	buffered := bufio.NewWriter(w)
+	encoder := newExportEncoder(input.Format, buffered)
+
+	err = encoder.begin(exportHeader{
+		ChatId:     chatId,
+		ChatName:   chat.ChatName,
+		ExportedAt: time.Now().UTC(),
+		From:       input.From,
+		To:         input.To,
+	})
+	if err != nil {
+		return err
+	}
+
+	var cursor *models.ExportCursor
+	for {
+		messages, err := u.messageRepository.GetMessagesForExport(ctx, userId, chatId, input, cursor, exportBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, message := range messages {
+			if err := encoder.write(message); err != nil {
+				return err
+			}
+		}
+
+		if len(messages) < exportBatchSize {
+			break
+		}
+
+		last := messages[len(messages)-1]
+		cursor = &models.ExportCursor{
+			SentAt:    last.SentAt,
+			MessageId: last.MessageId,
+		}
+	}
+
+	if err := encoder.end(); err != nil {
+		return err
+	}
+
+	return buffered.Flush()
+}
+
+// GetExport выгрузку видит только пользователь, который ее запросил
+func (u *MessageUsecaseImplm) GetExport(ctx context.Context, user jwt.User, exportId uuid.UUID) (models.ChatExport, error) {
+	export, err := u.messageRepository.GetExport(ctx, exportId)
+	if err != nil {
+		return models.ChatExport{}, err
+	}
+
+	if export.Id == uuid.Nil || export.UserId != user.ID {
+		return models.ChatExport{}, ErrExportNotFound
+	}
+
+	if export.Status == models.ExportDone {
+		url := fmt.Sprintf("/exports/%v/file", export.Id)
+		export.URL = &url
+	}
+
+	return export, nil
+}
+
+// GetExportFile готовая выгрузка с путем к файлу
+func (u *MessageUsecaseImplm) GetExportFile(ctx context.Context, user jwt.User, exportId uuid.UUID) (models.ChatExport, error) {
+	export, err := u.GetExport(ctx, user, exportId)
+	if err != nil {
+		return models.ChatExport{}, err
+	}
+
+	if export.Status != models.ExportDone || export.FilePath == nil {
+		return models.ChatExport{}, ErrExportNotReady
+	}
+
+	return export, nil
+}
+
+// RunPendingExports выполняет выгрузки из очереди и удаляет устаревшие файлы.
+// Возвращает количество выполненных выгрузок
+func (u *MessageUsecaseImplm) RunPendingExports(ctx context.Context) (int, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+
+	paths, err := u.messageRepository.DeleteFinishedExports(ctx, time.Now().Add(-exportKeepFor))
+	if err != nil {
+		return 0, err
+	}
+	for _, path := range paths {
+		if err := multipartHepler.RemoveFile(path); err != nil {
+			log.Errorf("не удалось удалить файл выгрузки %v: %v", path, err)
+		}
+	}
+
+	done := 0
+	for {
+		now := time.Now()
+		export, err := u.messageRepository.TakePendingExport(ctx, now, now.Add(-exportStaleAfter))
+		if err != nil {
+			return done, err
+		}
+
+		if export.Id == uuid.Nil {
+			return done, nil
+		}
+
+		var filePath, exportErr *string
+		path, err := u.runExport(ctx, export)
+		if err != nil {
+			log.Errorf("не удалось выполнить выгрузку %v: %v", export.Id, err)
+			errText := err.Error()
+			exportErr = &errText
+		} else {
+			filePath = &path
+		}
+
+		err = u.messageRepository.FinishExport(ctx, export.Id, filePath, exportErr, time.Now())
+		if err != nil {
+			return done, err
+		}
+		done++
+	}
+}
+
+// runExport пишет выгрузку в файл и возвращает путь к нему
+func (u *MessageUsecaseImplm) runExport(ctx context.Context, export models.ChatExport) (string, error) {
+	file, err := multipartHepler.CreateFile(exportDir, "."+export.Format)
+	if err != nil {
+		return "", err
+	}
+
+	err = u.writeExport(ctx, export.UserId, export.ChatId, models.ExportInput{
+		Format: export.Format,
+		From:   export.From,
+		To:     export.To,
+	}, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		if removeErr := multipartHepler.RemoveFile(file.Name()); removeErr != nil {
+			logger.LoggerWithCtx(ctx, logger.Log).Errorf("не удалось удалить файл выгрузки %v: %v", file.Name(), removeErr)
+		}
+		return "", err
+	}
+
+	return file.Name(), nil
+}
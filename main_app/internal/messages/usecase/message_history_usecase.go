@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	jwt "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	customerror "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/custom_error"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+
+	"github.com/google/uuid"
+)
+
+// GetMessageHistory возвращает прежние версии сообщения. Если владелец скрыл историю,
+// то ее видят только автор сообщения, владелец и админы
+func (u *MessageUsecaseImplm) GetMessageHistory(ctx context.Context, user jwt.User, messageId uuid.UUID) (models.MessageHistoryDTO, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Infof("пользователь %v запросил историю сообщения %v", user.ID, messageId)
+
+	message, err := u.getMessageForMember(ctx, user, messageId)
+	if err != nil {
+		return models.MessageHistoryDTO{}, err
+	}
+
+	if message.AuthorID != user.ID {
+		isVisible, err := u.messageRepository.GetHistoryVisibility(ctx, message.ChatId)
+		if err != nil {
+			return models.MessageHistoryDTO{}, err
+		}
+
+		if !isVisible {
+			role, err := u.chatRepository.GetUserRoleInChat(ctx, user.ID, message.ChatId)
+			if err != nil {
+				return models.MessageHistoryDTO{}, err
+			}
+
+			if role != owner && role != admin {
+				return models.MessageHistoryDTO{}, &customerror.NoPermissionError{
+					Area: fmt.Sprintf("история сообщений чата %v скрыта", message.ChatId),
+					User: user.ID.String(),
+				}
+			}
+		}
+	}
+
+	revisions, err := u.messageRepository.GetMessageRevisions(ctx, messageId)
+	if err != nil {
+		return models.MessageHistoryDTO{}, err
+	}
+
+	return models.MessageHistoryDTO{
+		MessageId: messageId,
+		Revisions: revisions,
+	}, nil
+}
+
+func (u *MessageUsecaseImplm) GetHistorySettings(ctx context.Context, user jwt.User, chatId uuid.UUID) (models.HistorySettingsDTO, error) {
+	role, err := u.chatRepository.GetUserRoleInChat(ctx, user.ID, chatId)
+	if err != nil {
+		return models.HistorySettingsDTO{}, err
+	}
+
+	if role == NotInChat {
+		return models.HistorySettingsDTO{}, &customerror.NoPermissionError{
+			Area: fmt.Sprintf("чат %v", chatId),
+			User: user.ID.String(),
+		}
+	}
+
+	isVisible, err := u.messageRepository.GetHistoryVisibility(ctx, chatId)
+	if err != nil {
+		return models.HistorySettingsDTO{}, err
+	}
+
+	return models.HistorySettingsDTO{
+		IsHistoryVisible: isVisible,
+	}, nil
+}
+
+// SetHistorySettings показывать ли историю правок решает только владелец чата
+func (u *MessageUsecaseImplm) SetHistorySettings(ctx context.Context, user jwt.User, chatId uuid.UUID, input models.HistorySettingsDTO) error {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Infof("пользователь %v меняет видимость истории правок в чате %v на %v", user.ID, chatId, input.IsHistoryVisible)
+
+	role, err := u.chatRepository.GetUserRoleInChat(ctx, user.ID, chatId)
+	if err != nil {
+		return err
+	}
+
+	if role != owner {
+		return &customerror.NoPermissionError{
+			Area: fmt.Sprintf("настройки истории чата %v", chatId),
+			User: user.ID.String(),
+		}
+	}
+
+	return u.messageRepository.SetHistoryVisibility(ctx, chatId, input.IsHistoryVisible)
+}
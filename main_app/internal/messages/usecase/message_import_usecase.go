@@ -0,0 +1,545 @@
+package usecase
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	jwt "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	chatModel "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/models"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+	multipartHepler "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/utils/multipartHelper"
+
+	"github.com/google/uuid"
+)
+
+const (
+	importBatchSize = 200
+	// MaxImportErrors сколько ошибок по сообщениям хранится у импорта, остальные только считаются
+	MaxImportErrors = 1000
+	importDir       = "import"
+	// импорт, который не сохранял прогресс дольше, считаем брошенным и начинаем заново
+	importStaleAfter = 30 * time.Minute
+	// максимальный размер одного файла из архива с медиа
+	maxImportMediaSize = 50 << 20
+	// название чата, если в выгрузке его нет
+	importChatName = "Импорт из Telegram"
+	// имя автора-заглушки для удаленных аккаунтов Telegram
+	deletedAuthorName = "Удаленный аккаунт"
+	// так Telegram Desktop помечает файлы, которые не попали в выгрузку
+	telegramSkippedFile = "(File "
+)
+
+var (
+	ErrBadImportMedia    = errors.New("медиа нужно загрузить zip-архивом папки выгрузки")
+	ErrImportUnknownUser = errors.New("пользователь не найден")
+	// другой пользователь не соглашался на сообщения от своего имени
+	ErrImportForeignAuthor = errors.New("отправителя можно сопоставить только со своим логином")
+	ErrImportNotFound      = errors.New("импорт не найден")
+)
+
+// StartImport проверяет сопоставление авторов, сохраняет файлы выгрузки и ставит импорт в очередь
+func (u *MessageUsecaseImplm) StartImport(ctx context.Context, user jwt.User, input models.ImportInput,
+	source *multipart.FileHeader, media *multipart.FileHeader) (models.ChatImport, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Infof("пользователь %v импортирует историю из Telegram", user.ID)
+
+	if source == nil {
+		return models.ChatImport{}, ErrBadTelegramExport
+	}
+
+	authors, err := u.resolveImportAuthors(ctx, user, input.Authors)
+	if err != nil {
+		return models.ChatImport{}, err
+	}
+
+	if media != nil {
+		if err := checkImportMedia(media); err != nil {
+			return models.ChatImport{}, err
+		}
+	}
+
+	sourcePath, err := saveImportFile(source)
+	if err != nil {
+		return models.ChatImport{}, err
+	}
+
+	var mediaPath *string
+	if media != nil {
+		saved, err := saveImportFile(media)
+		if err != nil {
+			u.removeImportFiles(ctx, sourcePath, nil)
+			return models.ChatImport{}, err
+		}
+		mediaPath = &saved
+	}
+
+	chatImport := models.ChatImport{
+		Id:         uuid.New(),
+		UserId:     user.ID,
+		Status:     models.ImportPending,
+		Errors:     []models.ImportError{},
+		CreatedAt:  time.Now(),
+		SourcePath: sourcePath,
+		MediaPath:  mediaPath,
+		Authors:    authors,
+	}
+
+	err = u.messageRepository.CreateImport(ctx, chatImport)
+	if err != nil {
+		u.removeImportFiles(ctx, sourcePath, mediaPath)
+		return models.ChatImport{}, err
+	}
+
+	log.Infof("импорт %v поставлен в очередь", chatImport.Id)
+	return chatImport, nil
+}
+
+// resolveImportAuthors from_id отправителя -> id пользователя. Неизвестный или чужой логин - ошибка,
+// чтобы история не ушла авторам-заглушкам из-за опечатки и не появилась от имени другого пользователя
+func (u *MessageUsecaseImplm) resolveImportAuthors(ctx context.Context, user jwt.User, usernames map[string]string) (map[string]uuid.UUID, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+
+	authors := map[string]uuid.UUID{}
+	if len(usernames) == 0 {
+		return authors, nil
+	}
+
+	list := make([]string, 0, len(usernames))
+	for _, username := range usernames {
+		list = append(list, username)
+	}
+
+	ids, err := u.messageRepository.GetUserIdsByUsernames(ctx, list)
+	if err != nil {
+		return nil, err
+	}
+
+	for fromId, username := range usernames {
+		id, ok := ids[strings.ToLower(username)]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrImportUnknownUser, username)
+		}
+		if id != user.ID {
+			log.Infof("отправитель %v сопоставлен с чужим логином %v", fromId, username)
+			return nil, fmt.Errorf("%w: отправитель %s, логин %s", ErrImportForeignAuthor, fromId, username)
+		}
+		authors[fromId] = id
+	}
+
+	return authors, nil
+}
+
+func checkImportMedia(media *multipart.FileHeader) error {
+	file, err := media.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := zip.NewReader(file, media.Size); err != nil {
+		return ErrBadImportMedia
+	}
+
+	return nil
+}
+
+func saveImportFile(header *multipart.FileHeader) (string, error) {
+	file, err := header.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	return multipartHepler.SaveFile(file, header.Filename, importDir)
+}
+
+func (u *MessageUsecaseImplm) removeImportFiles(ctx context.Context, sourcePath string, mediaPath *string) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+
+	paths := []string{sourcePath}
+	if mediaPath != nil {
+		paths = append(paths, *mediaPath)
+	}
+
+	for _, filePath := range paths {
+		if err := multipartHepler.RemoveFile(filePath); err != nil {
+			log.Errorf("не удалось удалить файл импорта %v: %v", filePath, err)
+		}
+	}
+}
+
+// GetImport импорт видит только пользователь, который его запустил
+func (u *MessageUsecaseImplm) GetImport(ctx context.Context, user jwt.User, importId uuid.UUID) (models.ChatImport, error) {
+	chatImport, err := u.messageRepository.GetImport(ctx, importId)
+	if err != nil {
+		return models.ChatImport{}, err
+	}
+
+	if chatImport.Id == uuid.Nil || chatImport.UserId != user.ID {
+		return models.ChatImport{}, ErrImportNotFound
+	}
+
+	if chatImport.Errors == nil {
+		chatImport.Errors = []models.ImportError{}
+	}
+
+	return chatImport, nil
+}
+
+// RunPendingImports выполняет импорты из очереди и удаляет загруженные файлы выгрузок.
+// Возвращает количество выполненных импортов
+func (u *MessageUsecaseImplm) RunPendingImports(ctx context.Context) (int, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+
+	done := 0
+	for {
+		now := time.Now()
+		chatImport, err := u.messageRepository.TakePendingImport(ctx, now, now.Add(-importStaleAfter))
+		if err != nil {
+			return done, err
+		}
+
+		if chatImport.Id == uuid.Nil {
+			return done, nil
+		}
+
+		var importErr *string
+		err = u.runImport(ctx, chatImport)
+		if err != nil {
+			log.Errorf("не удалось выполнить импорт %v: %v", chatImport.Id, err)
+			errText := err.Error()
+			importErr = &errText
+		}
+
+		err = u.messageRepository.FinishImport(ctx, chatImport.Id, importErr, time.Now())
+		if err != nil {
+			return done, err
+		}
+
+		u.removeImportFiles(ctx, chatImport.SourcePath, chatImport.MediaPath)
+		done++
+	}
+}
+
+// importRun состояние одного выполнения импорта
+type importRun struct {
+	chatImport models.ChatImport
+	chatId     uuid.UUID
+	// файлы архива с медиа, nil - архив не загружен
+	media map[string]*zip.File
+	// from_id -> id автора, включая уже созданных авторов-заглушек
+	authors map[string]uuid.UUID
+	// id сообщений Telegram, которые уже встречались, для ответов
+	seen     map[int64]struct{}
+	batch    []models.Message
+	batchIds []int64
+	progress models.ImportProgress
+	// ошибки, еще не сохраненные в базу
+	errors []models.ImportError
+}
+
+func (run *importRun) addError(messageId int64, err error) {
+	run.errors = append(run.errors, models.ImportError{
+		MessageId: messageId,
+		Error:     err.Error(),
+	})
+}
+
+// runImport читает выгрузку дважды: сначала считает сообщения, потом сохраняет их пачками по importBatchSize
+func (u *MessageUsecaseImplm) runImport(ctx context.Context, chatImport models.ChatImport) error {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+
+	chatName := importChatName
+	total := 0
+	err := readTelegramFile(chatImport.SourcePath, func(name string) {
+		if name != "" {
+			chatName = name
+		}
+	}, func(message telegramMessage) error {
+		if message.Type == "message" {
+			total++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	run := &importRun{
+		chatImport: chatImport,
+		authors:    map[string]uuid.UUID{},
+		seen:       map[int64]struct{}{},
+		progress:   models.ImportProgress{Total: total},
+	}
+	for fromId, id := range chatImport.Authors {
+		run.authors[fromId] = id
+	}
+
+	if chatImport.MediaPath != nil {
+		archive, err := zip.OpenReader(*chatImport.MediaPath)
+		if err != nil {
+			return ErrBadImportMedia
+		}
+		defer archive.Close()
+		run.media = mediaIndex(&archive.Reader)
+	}
+
+	if chatImport.ChatId != nil {
+		run.chatId = *chatImport.ChatId
+	} else {
+		run.chatId, err = u.createImportChat(ctx, chatImport, chatName)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Infof("импорт %v: %d сообщений в чат %v", chatImport.Id, total, run.chatId)
+
+	err = readTelegramFile(chatImport.SourcePath, func(string) {}, func(message telegramMessage) error {
+		// служебные сообщения (вход в группу, закрепление и т.п.) не переносятся
+		if message.Type != "message" {
+			return nil
+		}
+
+		run.progress.Processed++
+		converted, err := u.convertImportMessage(ctx, run, message)
+		if err != nil {
+			run.progress.Failed++
+			run.addError(message.Id, err)
+		} else {
+			run.batch = append(run.batch, converted)
+			run.batchIds = append(run.batchIds, message.Id)
+		}
+		run.seen[message.Id] = struct{}{}
+
+		if len(run.batch) >= importBatchSize {
+			return u.flushImport(ctx, run)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return u.flushImport(ctx, run)
+}
+
+func readTelegramFile(filePath string, onName func(string), onMessage func(telegramMessage) error) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return readTelegramExport(file, onName, onMessage)
+}
+
+// createImportChat создает группу с импортирующим в роли владельца. Авторы-заглушки добавляются в чат при первой встрече
+func (u *MessageUsecaseImplm) createImportChat(ctx context.Context, chatImport models.ChatImport, chatName string) (uuid.UUID, error) {
+	chatId := uuid.New()
+
+	err := u.chatRepository.CreateNewChat(ctx, chatModel.Chat{
+		ChatId:   chatId,
+		ChatName: chatName,
+		ChatType: group,
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	err = u.chatRepository.AddUserIntoChat(ctx, chatImport.UserId, chatId, owner)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	err = u.messageRepository.SetImportChat(ctx, chatImport.Id, chatId)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return chatId, nil
+}
+
+// convertImportMessage переводит сообщение Telegram в сообщение приложения. Ошибки во вложениях и разметке
+// не мешают импорту сообщения и попадают в журнал, ошибка в ответе означает, что сообщение не импортировать.
+// id сообщений и авторов-заглушек выводятся из id импорта, поэтому повторный запуск не создает дублей
+func (u *MessageUsecaseImplm) convertImportMessage(ctx context.Context, run *importRun, message telegramMessage) (models.Message, error) {
+	sentAt, err := telegramTime(message.DateUnix, message.Date)
+	if err != nil {
+		return models.Message{}, fmt.Errorf("некорректная дата %q", message.Date)
+	}
+
+	authorId, err := u.importAuthor(ctx, run, message)
+	if err != nil {
+		return models.Message{}, err
+	}
+	authorName := importAuthorName(message)
+
+	text, entities := telegramText(message)
+	entities, err = prepareEntities(text, entities)
+	if err != nil {
+		run.addError(message.Id, fmt.Errorf("разметка пропущена: %v", err))
+		entities = nil
+	}
+
+	converted := models.Message{
+		MessageId:    importMessageId(run.chatImport.Id, message.Id),
+		AuthorID:     authorId,
+		Message:      text,
+		SentAt:       sentAt,
+		ChatId:       run.chatId,
+		Entities:     entities,
+		Payloads:     []models.Payload{},
+		ImportedFrom: &authorName,
+	}
+
+	if message.Edited != "" || message.EditedUnix != "" {
+		editedAt, err := telegramTime(message.EditedUnix, message.Edited)
+		if err == nil {
+			converted.IsRedacted = true
+			converted.EditedAt = &editedAt
+		}
+	}
+
+	// ответ на сообщение, которого нет в выгрузке, превращается в обычное сообщение
+	if message.ReplyTo != nil {
+		if _, ok := run.seen[*message.ReplyTo]; ok {
+			replyTo := importMessageId(run.chatImport.Id, *message.ReplyTo)
+			converted.ReplyTo = &replyTo
+		}
+	}
+
+	if mediaPath, filename := telegramMedia(message); mediaPath != "" {
+		payload, err := saveImportMedia(run.media, mediaPath, filename)
+		if err != nil {
+			run.addError(message.Id, err)
+		} else {
+			converted.Payloads = append(converted.Payloads, payload)
+		}
+	}
+
+	if converted.Message == "" && len(converted.Payloads) == 0 {
+		return models.Message{}, ErrEmptyMessage
+	}
+
+	return converted, nil
+}
+
+func importMessageId(importId uuid.UUID, messageId int64) uuid.UUID {
+	return uuid.NewSHA1(importId, []byte("message:"+strconv.FormatInt(messageId, 10)))
+}
+
+// importAuthor автор сообщения. Для несопоставленного отправителя при первой встрече создается автор-заглушка
+func (u *MessageUsecaseImplm) importAuthor(ctx context.Context, run *importRun, message telegramMessage) (uuid.UUID, error) {
+	fromId := message.FromId
+	if fromId == "" {
+		fromId = "unknown"
+	}
+
+	if id, ok := run.authors[fromId]; ok {
+		return id, nil
+	}
+
+	id := uuid.NewSHA1(run.chatImport.Id, []byte("author:"+fromId))
+	err := u.messageRepository.AddImportedAuthor(ctx, id, run.chatId, importAuthorName(message))
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	run.authors[fromId] = id
+	return id, nil
+}
+
+// importAuthorName имя отправителя в выгрузке, у удаленных аккаунтов его нет
+func importAuthorName(message telegramMessage) string {
+	if message.From != nil && *message.From != "" {
+		return *message.From
+	}
+	return deletedAuthorName
+}
+
+// saveImportMedia копирует файл из архива в папку вложений
+func saveImportMedia(media map[string]*zip.File, mediaPath string, filename string) (models.Payload, error) {
+	if strings.HasPrefix(mediaPath, telegramSkippedFile) {
+		return models.Payload{}, errors.New("вложение не попало в выгрузку Telegram")
+	}
+
+	if media == nil {
+		return models.Payload{}, fmt.Errorf("вложение %s пропущено: архив с медиа не загружен", mediaPath)
+	}
+
+	file, ok := media[path.Clean(mediaPath)]
+	if !ok {
+		return models.Payload{}, fmt.Errorf("вложение %s не найдено в архиве", mediaPath)
+	}
+
+	if file.UncompressedSize64 > maxImportMediaSize {
+		return models.Payload{}, fmt.Errorf("вложение %s больше %d МБ", mediaPath, maxImportMediaSize>>20)
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		return models.Payload{}, fmt.Errorf("вложение %s не читается: %v", mediaPath, err)
+	}
+	defer reader.Close()
+
+	url, err := multipartHepler.SaveStream(io.LimitReader(reader, maxImportMediaSize), filename, payloadDir)
+	if err != nil {
+		return models.Payload{}, err
+	}
+
+	return models.Payload{
+		URL:      url,
+		Filename: filename,
+		Size:     int64(file.UncompressedSize64),
+	}, nil
+}
+
+// flushImport сохраняет накопленную пачку. Если пачка не сохранилась, сообщения сохраняются по одному,
+// чтобы записать в журнал, какие именно не удалось импортировать.
+// Вложения, которые уже сохранил прерванный запуск этого импорта, заново скопированы зря и удаляются
+func (u *MessageUsecaseImplm) flushImport(ctx context.Context, run *importRun) error {
+	if len(run.batch) > 0 {
+		skipped, err := u.messageRepository.AddImportedMessages(ctx, run.chatId, run.batch)
+		if err == nil {
+			run.progress.Imported += len(run.batch)
+			u.removeOrphanFiles(ctx, skipped)
+		} else {
+			for i, message := range run.batch {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+
+				skipped, err := u.messageRepository.AddImportedMessages(ctx, run.chatId, []models.Message{message})
+				if err != nil {
+					run.progress.Failed++
+					run.addError(run.batchIds[i], fmt.Errorf("не удалось сохранить сообщение: %v", err))
+					u.removePayloads(ctx, message.Payloads)
+					continue
+				}
+				run.progress.Imported++
+				u.removeOrphanFiles(ctx, skipped)
+			}
+		}
+	}
+
+	err := u.messageRepository.UpdateImportProgress(ctx, run.chatImport.Id, run.progress, run.errors, MaxImportErrors, time.Now())
+	if err != nil {
+		return err
+	}
+
+	run.batch = run.batch[:0]
+	run.batchIds = run.batchIds[:0]
+	run.errors = nil
+	return nil
+}
@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	jwt "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	messagesMockRepo "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/repository/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveImportAuthors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	messageRepo := messagesMockRepo.NewMockMessageRepository(ctrl)
+	usecase := &MessageUsecaseImplm{
+		messageRepository: messageRepo,
+	}
+
+	user := jwt.User{ID: uuid.New()}
+	other := uuid.New()
+
+	tests := []struct {
+		name            string
+		usernames       map[string]string
+		prepareMock     func()
+		expectedAuthors map[string]uuid.UUID
+		expectedError   error
+		// отправитель, который должен быть назван в ошибке
+		expectedSender string
+	}{
+		{
+			name:            "без сопоставления",
+			usernames:       map[string]string{},
+			prepareMock:     func() {},
+			expectedAuthors: map[string]uuid.UUID{},
+		},
+		{
+			name:      "свой логин в другом регистре",
+			usernames: map[string]string{"user1": "Me"},
+			prepareMock: func() {
+				messageRepo.EXPECT().GetUserIdsByUsernames(gomock.Any(), []string{"Me"}).
+					Return(map[string]uuid.UUID{"me": user.ID}, nil)
+			},
+			expectedAuthors: map[string]uuid.UUID{"user1": user.ID},
+		},
+		{
+			name:      "чужой логин",
+			usernames: map[string]string{"user2": "friend"},
+			prepareMock: func() {
+				messageRepo.EXPECT().GetUserIdsByUsernames(gomock.Any(), []string{"friend"}).
+					Return(map[string]uuid.UUID{"friend": other}, nil)
+			},
+			expectedError:  ErrImportForeignAuthor,
+			expectedSender: "user2",
+		},
+		{
+			name:      "неизвестный логин",
+			usernames: map[string]string{"user3": "nobody"},
+			prepareMock: func() {
+				messageRepo.EXPECT().GetUserIdsByUsernames(gomock.Any(), []string{"nobody"}).
+					Return(map[string]uuid.UUID{}, nil)
+			},
+			expectedError: ErrImportUnknownUser,
+		},
+		{
+			name:      "ошибка репозитория",
+			usernames: map[string]string{"user1": "me"},
+			prepareMock: func() {
+				messageRepo.EXPECT().GetUserIdsByUsernames(gomock.Any(), []string{"me"}).Return(nil, errRepo)
+			},
+			expectedError: errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.prepareMock()
+
+			authors, err := usecase.resolveImportAuthors(context.Background(), user, tt.usernames)
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			if tt.expectedError != nil {
+				if tt.expectedSender != "" {
+					assert.Contains(t, err.Error(), tt.expectedSender)
+				}
+				return
+			}
+			assert.Equal(t, tt.expectedAuthors, authors)
+		})
+	}
+}
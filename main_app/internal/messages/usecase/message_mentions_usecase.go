@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	socketUsecase "github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/events"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	jwt "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+
+	"github.com/google/uuid"
+)
+
+// mentionPattern @username, перед которым нет буквы или цифры, чтобы не путать упоминание с почтой
+var mentionPattern = regexp.MustCompile(`(?:^|[^a-zA-Z0-9_@])@([a-zA-Z0-9_]+)`)
+
+// parseMentions возвращает упомянутые в тексте логины в нижнем регистре без повторов
+func parseMentions(text string) map[string]struct{} {
+	usernames := map[string]struct{}{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		usernames[strings.ToLower(match[1])] = struct{}{}
+	}
+
+	return usernames
+}
+
+func hasMentionEntities(entities []models.MessageEntity) bool {
+	for _, entity := range entities {
+		if entity.Type == models.EntityMention {
+			return true
+		}
+	}
+	return false
+}
+
+// updateMentions сохраняет упомянутых участников чата и уведомляет тех, кого упомянули впервые.
+// Упомянуть можно через @username в тексте или сущностью mention.
+// Сообщение к этому моменту уже сохранено, поэтому ошибки только логируются
+func (u *MessageUsecaseImplm) updateMentions(ctx context.Context, message models.Message) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+
+	usernames := parseMentions(message.Message)
+	mentionedIds := map[uuid.UUID]struct{}{}
+	for _, entity := range message.Entities {
+		if entity.Type == models.EntityMention && entity.UserID != nil {
+			mentionedIds[*entity.UserID] = struct{}{}
+		}
+	}
+
+	userIds := []uuid.UUID{}
+	if len(usernames) > 0 || len(mentionedIds) > 0 {
+		users, err := u.chatRepository.GetUsersFromChat(ctx, message.ChatId)
+		if err != nil {
+			log.Errorf("не удалось получить участников чата %v для упоминаний: %v", message.ChatId, err)
+			return
+		}
+
+		for _, user := range users {
+			if user.ID == message.AuthorID {
+				continue
+			}
+			_, byUsername := usernames[strings.ToLower(user.Username)]
+			_, byEntity := mentionedIds[user.ID]
+			if byUsername || byEntity {
+				userIds = append(userIds, user.ID)
+			}
+		}
+	}
+
+	added, err := u.messageRepository.ReplaceMentions(ctx, message.MessageId, userIds)
+	if err != nil {
+		log.Errorf("не удалось сохранить упоминания сообщения %v: %v", message.MessageId, err)
+		return
+	}
+
+	if len(added) == 0 {
+		return
+	}
+
+	// отдельное событие: заглушенный чат (muted у участника) не уведомляет о новых сообщениях,
+	// а упоминание должно дойти и до тех, кто его заглушил, поэтому muted здесь не проверяется
+	u.publishIvent(ctx, socketUsecase.MessageEvent{
+		Action:     socketUsecase.Mention,
+		Message:    convertMessageToEvent(message),
+		Recipients: added,
+	})
+}
+
+// GetMentions упоминания пользователя во всех его чатах, от новых к старым
+func (u *MessageUsecaseImplm) GetMentions(ctx context.Context, user jwt.User, before *uuid.UUID, unreadOnly bool, limit int) (models.MentionsDTO, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Infof("пользователь %v запросил свои упоминания", user.ID)
+
+	limit = normalizePageSize(limit)
+
+	// берем на одно упоминание больше, чтобы понять, есть ли следующая страница
+	mentions, err := u.messageRepository.GetUserMentions(ctx, user.ID, before, unreadOnly, limit+1)
+	if err != nil {
+		return models.MentionsDTO{}, err
+	}
+
+	result := models.MentionsDTO{
+		Mentions: mentions,
+	}
+	if len(mentions) > limit {
+		result.Mentions = mentions[:limit]
+		result.HasMore = true
+	}
+
+	return result, nil
+}
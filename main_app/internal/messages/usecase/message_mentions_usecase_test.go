@@ -0,0 +1,243 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	socketUsecase "github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/events"
+	jwt "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	chatModel "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/models"
+	chatMockRepo "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/repository/mocks"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+	messagesMockRepo "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/repository/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{
+			name:     "нет упоминаний",
+			text:     "привет всем",
+			expected: []string{},
+		},
+		{
+			name:     "упоминание в начале текста",
+			text:     "@alice привет",
+			expected: []string{"alice"},
+		},
+		{
+			name:     "несколько упоминаний и знаки препинания",
+			text:     "@alice, (@bob_2) и @carol!",
+			expected: []string{"alice", "bob_2", "carol"},
+		},
+		{
+			name:     "повторы в разном регистре",
+			text:     "@Alice и снова @ALICE",
+			expected: []string{"alice"},
+		},
+		{
+			name:     "почта не считается упоминанием",
+			text:     "пиши на alice@mail.ru",
+			expected: []string{},
+		},
+		{
+			name:     "двойная собака не считается упоминанием",
+			text:     "@@alice",
+			expected: []string{},
+		},
+		{
+			name:     "одиночная собака",
+			text:     "встречаемся @ 18:00",
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usernames := parseMentions(tt.text)
+
+			got := make([]string, 0, len(usernames))
+			for username := range usernames {
+				got = append(got, username)
+			}
+			assert.ElementsMatch(t, tt.expected, got)
+		})
+	}
+}
+
+func TestUpdateMentions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	messageRepo := messagesMockRepo.NewMockMessageRepository(ctrl)
+	chatRepo := chatMockRepo.NewMockChatRepository(ctrl)
+	publisher := &fakePublisher{}
+	usecase := &MessageUsecaseImplm{
+		messageRepository: messageRepo,
+		chatRepository:    chatRepo,
+		ch:                publisher,
+	}
+
+	author := chatModel.UserInChatDAO{ID: uuid.New(), Username: "author"}
+	alice := chatModel.UserInChatDAO{ID: uuid.New(), Username: "Alice"}
+	bob := chatModel.UserInChatDAO{ID: uuid.New(), Username: "bob"}
+	members := []chatModel.UserInChatDAO{author, alice, bob}
+
+	chatId := uuid.New()
+	message := func(text string) models.Message {
+		return models.Message{
+			MessageId: uuid.New(),
+			ChatId:    chatId,
+			AuthorID:  author.ID,
+			Message:   text,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		message     models.Message
+		prepareMock func(message models.Message)
+		// кому должно уйти событие упоминания, nil - события нет
+		expectedRecipients []uuid.UUID
+	}{
+		{
+			name:    "без упоминаний участники не запрашиваются",
+			message: message("привет"),
+			prepareMock: func(message models.Message) {
+				messageRepo.EXPECT().ReplaceMentions(gomock.Any(), message.MessageId, []uuid.UUID{}).Return(nil, nil)
+			},
+		},
+		{
+			name:    "упоминаются только участники чата",
+			message: message("@alice и @stranger, гляньте"),
+			prepareMock: func(message models.Message) {
+				chatRepo.EXPECT().GetUsersFromChat(gomock.Any(), chatId).Return(members, nil)
+				messageRepo.EXPECT().ReplaceMentions(gomock.Any(), message.MessageId, []uuid.UUID{alice.ID}).
+					Return([]uuid.UUID{alice.ID}, nil)
+			},
+			expectedRecipients: []uuid.UUID{alice.ID},
+		},
+		{
+			name:    "автор не упоминает сам себя",
+			message: message("@author @bob"),
+			prepareMock: func(message models.Message) {
+				chatRepo.EXPECT().GetUsersFromChat(gomock.Any(), chatId).Return(members, nil)
+				messageRepo.EXPECT().ReplaceMentions(gomock.Any(), message.MessageId, []uuid.UUID{bob.ID}).
+					Return([]uuid.UUID{bob.ID}, nil)
+			},
+			expectedRecipients: []uuid.UUID{bob.ID},
+		},
+		{
+			name:    "повторно упомянутых не уведомляем",
+			message: message("@alice @bob"),
+			prepareMock: func(message models.Message) {
+				chatRepo.EXPECT().GetUsersFromChat(gomock.Any(), chatId).Return(members, nil)
+				messageRepo.EXPECT().ReplaceMentions(gomock.Any(), message.MessageId, []uuid.UUID{alice.ID, bob.ID}).
+					Return([]uuid.UUID{bob.ID}, nil)
+			},
+			expectedRecipients: []uuid.UUID{bob.ID},
+		},
+		{
+			name:    "ошибка получения участников",
+			message: message("@alice"),
+			prepareMock: func(message models.Message) {
+				chatRepo.EXPECT().GetUsersFromChat(gomock.Any(), chatId).Return(nil, errRepo)
+			},
+		},
+		{
+			name:    "ошибка сохранения упоминаний",
+			message: message("@alice"),
+			prepareMock: func(message models.Message) {
+				chatRepo.EXPECT().GetUsersFromChat(gomock.Any(), chatId).Return(members, nil)
+				messageRepo.EXPECT().ReplaceMentions(gomock.Any(), message.MessageId, []uuid.UUID{alice.ID}).
+					Return(nil, errRepo)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher.events = nil
+			tt.prepareMock(tt.message)
+
+			usecase.updateMentions(context.Background(), tt.message)
+
+			if tt.expectedRecipients == nil {
+				assert.Empty(t, publisher.events)
+				return
+			}
+			if assert.Len(t, publisher.events, 1) {
+				assert.Equal(t, socketUsecase.Mention, publisher.events[0].Action)
+				assert.Equal(t, tt.message.MessageId, publisher.events[0].Message.MessageId)
+				assert.Equal(t, tt.expectedRecipients, publisher.events[0].Recipients)
+			}
+		})
+	}
+}
+
+func TestGetMentions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	messageRepo := messagesMockRepo.NewMockMessageRepository(ctrl)
+	usecase := &MessageUsecaseImplm{
+		messageRepository: messageRepo,
+	}
+
+	user := jwt.User{ID: uuid.New()}
+	mentions := []models.Mention{{}, {}, {}}
+
+	tests := []struct {
+		name            string
+		limit           int
+		prepareMock     func()
+		expectedCount   int
+		expectedHasMore bool
+		expectedError   error
+	}{
+		{
+			name:  "есть следующая страница",
+			limit: 2,
+			prepareMock: func() {
+				messageRepo.EXPECT().GetUserMentions(gomock.Any(), user.ID, nil, true, 3).Return(mentions, nil)
+			},
+			expectedCount:   2,
+			expectedHasMore: true,
+		},
+		{
+			name:  "последняя страница",
+			limit: 3,
+			prepareMock: func() {
+				messageRepo.EXPECT().GetUserMentions(gomock.Any(), user.ID, nil, true, 4).Return(mentions, nil)
+			},
+			expectedCount: 3,
+		},
+		{
+			name:  "ошибка репозитория",
+			limit: 2,
+			prepareMock: func() {
+				messageRepo.EXPECT().GetUserMentions(gomock.Any(), user.ID, nil, true, 3).Return(nil, errRepo)
+			},
+			expectedError: errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.prepareMock()
+
+			result, err := usecase.GetMentions(context.Background(), user, nil, true, tt.limit)
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			if tt.expectedError != nil {
+				return
+			}
+			assert.Len(t, result.Mentions, tt.expectedCount)
+			assert.Equal(t, tt.expectedHasMore, result.HasMore)
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"unicode"
+	"unicode/utf8"
+
+	socketUsecase "github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/events"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	jwt "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	customerror "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/custom_error"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+
+	"github.com/google/uuid"
+)
+
+// ограничения на реакции
+const (
+	// эмодзи с модификаторами и ZWJ-последовательности занимают несколько рун
+	maxEmojiRunes = 10
+	// максимальное число разрешенных реакций в канале
+	MaxAllowedReactions = 50
+)
+
+var (
+	ErrInvalidReaction     = errors.New("реакция должна быть эмодзи")
+	ErrReactionNotAllowed  = errors.New("эта реакция запрещена в канале")
+	ErrNotChannel          = errors.New("ограничить реакции можно только в канале")
+	ErrTooManyAllowedReact = fmt.Errorf("в канале можно разрешить не больше %d реакций", MaxAllowedReactions)
+)
+
+// isValidEmoji грубая проверка, что строка похожа на эмодзи, а не на произвольный текст
+func isValidEmoji(emoji string) bool {
+	if !utf8.ValidString(emoji) {
+		return false
+	}
+
+	count := utf8.RuneCountInString(emoji)
+	if count == 0 || count > maxEmojiRunes {
+		return false
+	}
+
+	hasNonASCII := false
+	for _, r := range emoji {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || unicode.IsLetter(r) {
+			return false
+		}
+		if r > unicode.MaxASCII {
+			hasNonASCII = true
+		}
+	}
+
+	return hasNonASCII
+}
+
+// getMessageForMember возвращает сообщение, если пользователь состоит в его чате
+func (u *MessageUsecaseImplm) getMessageForMember(ctx context.Context, user jwt.User, messageId uuid.UUID) (models.Message, error) {
+	message, err := u.messageRepository.GetMessageById(ctx, user.ID, messageId)
+	if err != nil {
+		return models.Message{}, err
+	}
+
+	if message.MessageId == uuid.Nil {
+		return models.Message{}, ErrMessageNotFound
+	}
+
+	role, err := u.chatRepository.GetUserRoleInChat(ctx, user.ID, message.ChatId)
+	if err != nil {
+		return models.Message{}, err
+	}
+
+	if role == NotInChat {
+		return models.Message{}, &customerror.NoPermissionError{
+			Area: fmt.Sprintf("чат %v", message.ChatId),
+			User: user.ID.String(),
+		}
+	}
+
+	return message, nil
+}
+
+func (u *MessageUsecaseImplm) AddReaction(ctx context.Context, user jwt.User, messageId uuid.UUID, emoji string) error {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Infof("пользователь %v ставит реакцию %s на сообщение %v", user.ID, emoji, messageId)
+
+	if !isValidEmoji(emoji) {
+		return ErrInvalidReaction
+	}
+
+	message, err := u.getMessageForMember(ctx, user, messageId)
+	if err != nil {
+		return err
+	}
+
+	chatType, err := u.chatRepository.GetChatType(ctx, message.ChatId)
+	if err != nil {
+		return err
+	}
+
+	// в каналах владелец может ограничить набор реакций
+	if chatType == channel {
+		allowed, err := u.messageRepository.GetAllowedReactions(ctx, message.ChatId)
+		if err != nil {
+			return err
+		}
+
+		if len(allowed) > 0 && !slices.Contains(allowed, emoji) {
+			return ErrReactionNotAllowed
+		}
+	}
+
+	err = u.messageRepository.AddReaction(ctx, messageId, user.ID, emoji)
+	if err != nil {
+		log.Errorf("не удалось поставить реакцию: %v", err)
+		return err
+	}
+
+	u.sendReactionIvent(ctx, user, messageId, emoji, true)
+	return nil
+}
+
+func (u *MessageUsecaseImplm) DeleteReaction(ctx context.Context, user jwt.User, messageId uuid.UUID, emoji string) error {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Infof("пользователь %v убирает реакцию %s с сообщения %v", user.ID, emoji, messageId)
+
+	if !isValidEmoji(emoji) {
+		return ErrInvalidReaction
+	}
+
+	_, err := u.getMessageForMember(ctx, user, messageId)
+	if err != nil {
+		return err
+	}
+
+	err = u.messageRepository.DeleteReaction(ctx, messageId, user.ID, emoji)
+	if err != nil {
+		log.Errorf("не удалось убрать реакцию: %v", err)
+		return err
+	}
+
+	u.sendReactionIvent(ctx, user, messageId, emoji, false)
+	return nil
+}
+
+// sendReactionIvent отправляет в сокет новые счетчики реакций сообщения
+func (u *MessageUsecaseImplm) sendReactionIvent(ctx context.Context, user jwt.User, messageId uuid.UUID, emoji string, isAdded bool) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+
+	message, err := u.messageRepository.GetMessageById(ctx, user.ID, messageId)
+	if err != nil {
+		log.Errorf("не удалось получить сообщение %v для отправки реакций: %v", messageId, err)
+		return
+	}
+
+	eventMessage := convertMessageToEvent(message)
+	eventMessage.ReactionUpdate = &socketUsecase.ReactionUpdate{
+		UserId:  user.ID,
+		Emoji:   emoji,
+		IsAdded: isAdded,
+	}
+
+	u.publishIvent(ctx, socketUsecase.MessageEvent{
+		Action:  socketUsecase.ReactionUpdated,
+		Message: eventMessage,
+	})
+}
+
+func (u *MessageUsecaseImplm) GetAllowedReactions(ctx context.Context, user jwt.User, chatId uuid.UUID) (models.AllowedReactionsDTO, error) {
+	role, err := u.chatRepository.GetUserRoleInChat(ctx, user.ID, chatId)
+	if err != nil {
+		return models.AllowedReactionsDTO{}, err
+	}
+
+	if role == NotInChat {
+		return models.AllowedReactionsDTO{}, &customerror.NoPermissionError{
+			Area: fmt.Sprintf("чат %v", chatId),
+			User: user.ID.String(),
+		}
+	}
+
+	reactions, err := u.messageRepository.GetAllowedReactions(ctx, chatId)
+	if err != nil {
+		return models.AllowedReactionsDTO{}, err
+	}
+
+	return models.AllowedReactionsDTO{
+		Reactions: reactions,
+	}, nil
+}
+
+func (u *MessageUsecaseImplm) SetAllowedReactions(ctx context.Context, user jwt.User, chatId uuid.UUID, input models.AllowedReactionsDTO) error {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Infof("пользователь %v меняет разрешенные реакции в чате %v", user.ID, chatId)
+
+	role, err := u.chatRepository.GetUserRoleInChat(ctx, user.ID, chatId)
+	if err != nil {
+		return err
+	}
+
+	if role != owner && role != admin {
+		return &customerror.NoPermissionError{
+			Area: fmt.Sprintf("настройки реакций чата %v", chatId),
+			User: user.ID.String(),
+		}
+	}
+
+	chatType, err := u.chatRepository.GetChatType(ctx, chatId)
+	if err != nil {
+		return err
+	}
+
+	if chatType != channel {
+		return ErrNotChannel
+	}
+
+	if len(input.Reactions) > MaxAllowedReactions {
+		return ErrTooManyAllowedReact
+	}
+
+	for _, emoji := range input.Reactions {
+		if !isValidEmoji(emoji) {
+			return ErrInvalidReaction
+		}
+	}
+
+	return u.messageRepository.SetAllowedReactions(ctx, chatId, input.Reactions)
+}
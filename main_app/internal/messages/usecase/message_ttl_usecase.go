@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	socketUsecase "github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/events"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	jwt "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	customerror "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/custom_error"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+
+	"github.com/google/uuid"
+)
+
+// границы времени жизни исчезающих сообщений в секундах
+const (
+	MinMessageTTL = 5
+	MaxMessageTTL = 365 * 24 * 60 * 60
+)
+
+// сколько истекших сообщений удаляется за одну транзакцию
+const expireBatchSize = 100
+
+var ErrBadMessageTTL = fmt.Errorf("время жизни сообщений должно быть от %d до %d секунд", MinMessageTTL, MaxMessageTTL)
+
+func (u *MessageUsecaseImplm) GetMessageTTL(ctx context.Context, user jwt.User, chatId uuid.UUID) (models.MessageTTLDTO, error) {
+	role, err := u.chatRepository.GetUserRoleInChat(ctx, user.ID, chatId)
+	if err != nil {
+		return models.MessageTTLDTO{}, err
+	}
+
+	if role == NotInChat {
+		return models.MessageTTLDTO{}, &customerror.NoPermissionError{
+			Area: fmt.Sprintf("чат %v", chatId),
+			User: user.ID.String(),
+		}
+	}
+
+	ttl, err := u.messageRepository.GetMessageTTL(ctx, chatId)
+	if err != nil {
+		return models.MessageTTLDTO{}, err
+	}
+
+	return models.MessageTTLDTO{
+		TTL: ttl,
+	}, nil
+}
+
+// SetMessageTTL время жизни меняет владелец чата, в личном чате - любой из собеседников.
+// Уже отправленные сообщения исчезнут в прежний срок
+func (u *MessageUsecaseImplm) SetMessageTTL(ctx context.Context, user jwt.User, chatId uuid.UUID, input models.MessageTTLDTO) error {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Infof("пользователь %v меняет время жизни сообщений в чате %v на %v", user.ID, chatId, input.TTL)
+
+	if input.TTL != nil && (*input.TTL < MinMessageTTL || *input.TTL > MaxMessageTTL) {
+		return ErrBadMessageTTL
+	}
+
+	role, err := u.chatRepository.GetUserRoleInChat(ctx, user.ID, chatId)
+	if err != nil {
+		return err
+	}
+
+	canChange := role == owner
+	if role != NotInChat && !canChange {
+		chatType, err := u.chatRepository.GetChatType(ctx, chatId)
+		if err != nil {
+			return err
+		}
+		canChange = chatType == personal
+	}
+
+	if !canChange {
+		return &customerror.NoPermissionError{
+			Area: fmt.Sprintf("исчезающие сообщения чата %v", chatId),
+			User: user.ID.String(),
+		}
+	}
+
+	return u.messageRepository.SetMessageTTL(ctx, chatId, input.TTL)
+}
+
+// messageExpiresAt когда исчезнет сообщение, отправленное в чат в sentAt. nil - не исчезнет
+func (u *MessageUsecaseImplm) messageExpiresAt(ctx context.Context, chatId uuid.UUID, sentAt time.Time) (*time.Time, error) {
+	ttl, err := u.messageRepository.GetMessageTTL(ctx, chatId)
+	if err != nil {
+		return nil, err
+	}
+
+	return expiresAfter(sentAt, ttl), nil
+}
+
+func expiresAfter(sentAt time.Time, ttl *int) *time.Time {
+	if ttl == nil {
+		return nil
+	}
+
+	expiresAt := sentAt.Add(time.Duration(*ttl) * time.Second)
+	return &expiresAt
+}
+
+// DeleteExpiredMessages удаляет истекшие сообщения вместе с файлами вложений и сообщает об этом клиентам.
+// Возвращает количество удаленных сообщений
+func (u *MessageUsecaseImplm) DeleteExpiredMessages(ctx context.Context) (int, error) {
+	deleted := 0
+	for {
+		messages, orphans, err := u.messageRepository.DeleteExpiredMessages(ctx, time.Now(), expireBatchSize)
+		if err != nil {
+			return deleted, err
+		}
+
+		// счетчик ответов родительского сообщения обновляем один раз на ветку
+		chats := make(map[uuid.UUID]struct{})
+		for _, message := range messages {
+			u.sendIvent(ctx, socketUsecase.DeleteMessage, message)
+			chats[message.ChatId] = struct{}{}
+		}
+		for chatId := range chats {
+			u.sendBranchUpdate(ctx, chatId)
+		}
+
+		u.removeOrphanFiles(ctx, orphans)
+
+		deleted += len(messages)
+		if len(messages) < expireBatchSize {
+			return deleted, nil
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	socketUsecase "github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/events"
+	jwt "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	customerror "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/custom_error"
+	chatMockRepo "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/repository/mocks"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+	messagesMockRepo "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/repository/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetMessageTTL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	messageRepo := messagesMockRepo.NewMockMessageRepository(ctrl)
+	chatRepo := chatMockRepo.NewMockChatRepository(ctrl)
+	usecase := &MessageUsecaseImplm{
+		messageRepository: messageRepo,
+		chatRepository:    chatRepo,
+	}
+
+	user := jwt.User{ID: uuid.New()}
+	chatId := uuid.New()
+	day := 24 * 60 * 60
+
+	tests := []struct {
+		name          string
+		ttl           *int
+		prepareMock   func()
+		expectedError error
+		// ожидается ошибка доступа customerror.NoPermissionError
+		noPermission bool
+	}{
+		{
+			name: "владелец группы",
+			ttl:  ptr(day),
+			prepareMock: func() {
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return(owner, nil)
+				messageRepo.EXPECT().SetMessageTTL(gomock.Any(), chatId, ptr(day)).Return(nil)
+			},
+		},
+		{
+			name: "владелец выключает исчезающие сообщения",
+			ttl:  nil,
+			prepareMock: func() {
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return(owner, nil)
+				messageRepo.EXPECT().SetMessageTTL(gomock.Any(), chatId, nil).Return(nil)
+			},
+		},
+		{
+			name: "собеседник в личном чате",
+			ttl:  ptr(day),
+			prepareMock: func() {
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return(none, nil)
+				chatRepo.EXPECT().GetChatType(gomock.Any(), chatId).Return(personal, nil)
+				messageRepo.EXPECT().SetMessageTTL(gomock.Any(), chatId, ptr(day)).Return(nil)
+			},
+		},
+		{
+			name: "админ группы",
+			ttl:  ptr(day),
+			prepareMock: func() {
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return(admin, nil)
+				chatRepo.EXPECT().GetChatType(gomock.Any(), chatId).Return(group, nil)
+			},
+			noPermission: true,
+		},
+		{
+			name: "участник канала",
+			ttl:  ptr(day),
+			prepareMock: func() {
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return(none, nil)
+				chatRepo.EXPECT().GetChatType(gomock.Any(), chatId).Return(channel, nil)
+			},
+			noPermission: true,
+		},
+		{
+			name: "пользователь не в чате",
+			ttl:  ptr(day),
+			prepareMock: func() {
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return(NotInChat, nil)
+			},
+			noPermission: true,
+		},
+		{
+			name:          "слишком короткое время жизни",
+			ttl:           ptr(MinMessageTTL - 1),
+			prepareMock:   func() {},
+			expectedError: ErrBadMessageTTL,
+		},
+		{
+			name:          "слишком долгое время жизни",
+			ttl:           ptr(MaxMessageTTL + 1),
+			prepareMock:   func() {},
+			expectedError: ErrBadMessageTTL,
+		},
+		{
+			name: "ошибка получения роли",
+			ttl:  ptr(day),
+			prepareMock: func() {
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return("", errRepo)
+			},
+			expectedError: errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.prepareMock()
+
+			err := usecase.SetMessageTTL(context.Background(), user, chatId, models.MessageTTLDTO{TTL: tt.ttl})
+
+			if tt.noPermission {
+				assert.True(t, customerror.IsNoPermission(err), "ожидалась ошибка доступа, получено: %v", err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.expectedError)
+		})
+	}
+}
+
+func TestDeleteExpiredMessages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	messageRepo := messagesMockRepo.NewMockMessageRepository(ctrl)
+	publisher := &fakePublisher{}
+	usecase := &MessageUsecaseImplm{
+		messageRepository: messageRepo,
+		ch:                publisher,
+	}
+
+	chatId := uuid.New()
+	batch := func(n int) []models.Message {
+		messages := make([]models.Message, 0, n)
+		for i := 0; i < n; i++ {
+			messages = append(messages, models.Message{MessageId: uuid.New(), ChatId: chatId})
+		}
+		return messages
+	}
+
+	// чат сообщений не ветка, событие branchUpdated не отправляется
+	expectBranchUpdate := func(times int) {
+		messageRepo.EXPECT().GetBranchParentMessage(gomock.Any(), uuid.Nil, chatId).Return(models.Message{}, nil).Times(times)
+	}
+
+	tests := []struct {
+		name            string
+		prepareMock     func()
+		expectedDeleted int
+		expectedError   error
+	}{
+		{
+			name: "истекших сообщений нет",
+			prepareMock: func() {
+				messageRepo.EXPECT().DeleteExpiredMessages(gomock.Any(), gomock.Any(), expireBatchSize).Return(nil, nil, nil)
+			},
+		},
+		{
+			name: "неполная пачка удаляется за один проход",
+			prepareMock: func() {
+				messageRepo.EXPECT().DeleteExpiredMessages(gomock.Any(), gomock.Any(), expireBatchSize).Return(batch(3), nil, nil)
+				// ветка обновляется один раз на пачку, а не на каждое сообщение
+				expectBranchUpdate(1)
+			},
+			expectedDeleted: 3,
+		},
+		{
+			name: "после полной пачки берется следующая",
+			prepareMock: func() {
+				gomock.InOrder(
+					messageRepo.EXPECT().DeleteExpiredMessages(gomock.Any(), gomock.Any(), expireBatchSize).
+						Return(batch(expireBatchSize), nil, nil),
+					messageRepo.EXPECT().DeleteExpiredMessages(gomock.Any(), gomock.Any(), expireBatchSize).
+						Return(batch(1), nil, nil),
+				)
+				expectBranchUpdate(2)
+			},
+			expectedDeleted: expireBatchSize + 1,
+		},
+		{
+			name: "ошибка на второй пачке не отменяет первую",
+			prepareMock: func() {
+				gomock.InOrder(
+					messageRepo.EXPECT().DeleteExpiredMessages(gomock.Any(), gomock.Any(), expireBatchSize).
+						Return(batch(expireBatchSize), nil, nil),
+					messageRepo.EXPECT().DeleteExpiredMessages(gomock.Any(), gomock.Any(), expireBatchSize).
+						Return(nil, nil, errRepo),
+				)
+				expectBranchUpdate(1)
+			},
+			expectedDeleted: expireBatchSize,
+			expectedError:   errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher.events = nil
+			tt.prepareMock()
+
+			deleted, err := usecase.DeleteExpiredMessages(context.Background())
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			assert.Equal(t, tt.expectedDeleted, deleted)
+
+			// каждое удаленное сообщение пропадает у клиентов
+			assert.Len(t, publisher.events, tt.expectedDeleted)
+			for _, event := range publisher.events {
+				assert.Equal(t, socketUsecase.DeleteMessage, event.Action)
+			}
+		})
+	}
+}
@@ -2,7 +2,13 @@ package usecase
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"mime/multipart"
+	"sort"
+	"strings"
 	"time"
 
 	socketUsecase "github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/events"
@@ -13,8 +19,11 @@ import (
 
 	customerror "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/custom_error"
 	chatRepository "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/repository"
+	chatService "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/usecase"
 	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
 	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/repository"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/utils/linkpreview"
+	multipartHepler "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/utils/multipartHelper"
 
 	"github.com/google/uuid"
 	amqp "github.com/rabbitmq/amqp091-go"
@@ -23,7 +32,7 @@ import (
 type Method = string
 
 func init() {
-	prometheus.MustRegister(sendedMessagesMetric, deleteMessageMetric, updateMessageMetric)
+	prometheus.MustRegister(sendedMessagesMetric, deleteMessageMetric, updateMessageMetric, forwardMessageMetric)
 }
 
 const (
@@ -32,14 +41,67 @@ const (
 	NewMessage  Method = "message"
 )
 
+// роли и типы чатов из chat_type и user_role
+const (
+	owner    = "owner"
+	admin    = "admin"
+	none     = "none"
+	personal = "personal"
+	group    = "group"
+	channel  = "channel"
+	branch   = "branch"
+)
+
+// папка для вложений сообщений
+const payloadDir = "payload"
+
+// максимальное количество вложений в одном сообщении
+const MaxPayloads = 10
+
+// максимальная длина клиентского nonce
+const MaxNonceLength = 64
+
+// ограничения на одну пересылку
+const (
+	MaxForwardMessages = 100
+	MaxForwardChats    = 10
+)
+
+var (
+	ErrEmptyMessage     = errors.New("сообщение не содержит ни текста, ни вложений")
+	ErrTooManyPayloads  = fmt.Errorf("в сообщении не может быть больше %d вложений", MaxPayloads)
+	ErrStickerWithText  = errors.New("стикер не может содержать текст или вложения")
+	ErrStickerUpdate    = errors.New("стикер нельзя изменить")
+	ErrReplyNotInChat   = errors.New("сообщение, на которое отвечают, не найдено в этом чате")
+	ErrMessageNotFound  = errors.New("сообщение не найдено")
+	ErrEmptySearchQuery = errors.New("поисковый запрос пуст")
+	ErrBadSearchCursor  = errors.New("некорректный курсор поиска")
+	ErrBadForward       = fmt.Errorf("можно переслать от 1 до %d сообщений в 1-%d чатов", MaxForwardMessages, MaxForwardChats)
+	ErrBadNonce         = fmt.Errorf("nonce должен быть от 1 до %d символов", MaxNonceLength)
+	ErrNonceExpired     = errors.New("сообщение с этим nonce уже исчезло, отправьте его с новым nonce")
+)
+
+// eventPublisher канал RabbitMQ, через который события уходят в websocket_service
+type eventPublisher interface {
+	PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+}
+
 type MessageUsecaseImplm struct {
 	messageRepository repository.MessageRepository
 	chatRepository    chatRepository.ChatRepository
+	chats             chatService.ChatUsecase
 	queryName         string
-	ch                *amqp.Channel
+	ch                eventPublisher
+	// сколько времени после отправки автор может удалить сообщение у всех
+	deleteWindow time.Duration
+
+	previewFetcher linkpreview.Fetcher
+	// занятые слоты фоновой загрузки превью
+	previewSlots chan struct{}
 }
 
-func NewMessageUsecaseImpl(messageRepository repository.MessageRepository, chatRepository chatRepository.ChatRepository, ch *amqp.Channel) MessageUsecase {
+func NewMessageUsecaseImpl(messageRepository repository.MessageRepository, chatRepository chatRepository.ChatRepository,
+	chats chatService.ChatUsecase, ch *amqp.Channel, deleteWindow time.Duration, previewFetcher linkpreview.Fetcher) MessageUsecase {
 	// объявляем очередь
 	q, err := ch.QueueDeclare(
 		"message", // name
@@ -57,8 +119,12 @@ func NewMessageUsecaseImpl(messageRepository repository.MessageRepository, chatR
 	usecase := MessageUsecaseImplm{
 		messageRepository: messageRepository,
 		chatRepository:    chatRepository,
+		chats:             chats,
 		queryName:         q.Name,
 		ch:                ch,
+		deleteWindow:      deleteWindow,
+		previewFetcher:    previewFetcher,
+		previewSlots:      make(chan struct{}, maxLinkPreviewFetches),
 	}
 	return &usecase
 }
@@ -71,27 +137,382 @@ var sendedMessagesMetric = prometheus.NewGaugeVec(
 	nil, // no labels for this metric
 )
 
-func (u *MessageUsecaseImplm) SendMessage(ctx context.Context, user jwt.User, chatId uuid.UUID, message models.Message) error {
+// SendMessage если клиент передал nonce, то повторная отправка вернет уже сохраненное сообщение.
+// Если сохраненное сообщение уже исчезло, вернется ErrNonceExpired
+func (u *MessageUsecaseImplm) SendMessage(ctx context.Context, user jwt.User, chatId uuid.UUID, message models.Message) (models.Message, error) {
 	log := logger.LoggerWithCtx(ctx, logger.Log)
-	log.Printf("Usecase: начато добавление сообщения в чат %v", chatId)
+
+	if message.Nonce != nil {
+		if *message.Nonce == "" || len(*message.Nonce) > MaxNonceLength {
+			return models.Message{}, ErrBadNonce
+		}
+
+		stored, err := u.getMessageByNonce(ctx, user, chatId, *message.Nonce)
+		if err != nil {
+			return models.Message{}, err
+		}
+		if stored.MessageId != uuid.Nil {
+			log.Infof("повторная отправка сообщения %v с nonce %v", stored.MessageId, *message.Nonce)
+			return stored, nil
+		}
+	}
 
 	message.MessageId = uuid.New()
+	sent, err := u.sendMessage(ctx, user, chatId, message)
+	if errors.Is(err, repository.ErrNonceExists) {
+		// параллельный повтор успел сохранить сообщение раньше
+		return u.getMessageByNonce(ctx, user, chatId, *message.Nonce)
+	}
+
+	return sent, err
+}
+
+// getMessageByNonce уже сохраненное сообщение автора с этим nonce или пустое, если его нет
+func (u *MessageUsecaseImplm) getMessageByNonce(ctx context.Context, user jwt.User, chatId uuid.UUID, nonce string) (models.Message, error) {
+	stored, err := u.messageRepository.GetMessageByNonce(ctx, user.ID, chatId, nonce)
+	if err != nil {
+		return models.Message{}, err
+	}
+
+	// nonce остается занятым, пока строку не удалит фоновая очистка
+	if stored.ExpiresAt != nil && !stored.ExpiresAt.After(time.Now()) {
+		return models.Message{}, ErrNonceExpired
+	}
+
+	return stored, nil
+}
+
+// checkMessageContent проверяет сочетание текста, стикера и вложений и цель ответа
+func (u *MessageUsecaseImplm) checkMessageContent(ctx context.Context, user jwt.User, chatId uuid.UUID,
+	text string, sticker *string, countOfPayloads int, replyTo *uuid.UUID) error {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+
+	if sticker != nil {
+		if text != "" || countOfPayloads != 0 {
+			return ErrStickerWithText
+		}
+	} else if text == "" && countOfPayloads == 0 {
+		return ErrEmptyMessage
+	}
+	if countOfPayloads > MaxPayloads {
+		return ErrTooManyPayloads
+	}
+
+	if replyTo != nil {
+		replied, err := u.messageRepository.GetMessageById(ctx, user.ID, *replyTo)
+		if err != nil {
+			return err
+		}
+
+		if replied.MessageId == uuid.Nil || replied.ChatId != chatId {
+			log.Errorf("Usecase: сообщение %v не найдено в чате %v", *replyTo, chatId)
+			return ErrReplyNotInChat
+		}
+	}
+
+	return nil
+}
+
+// sendMessage общий путь для обычных и отложенных сообщений. Id сообщения задает вызывающий.
+// Возвращает сохраненное сообщение
+func (u *MessageUsecaseImplm) sendMessage(ctx context.Context, user jwt.User, chatId uuid.UUID, message models.Message) (models.Message, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Printf("Usecase: начато добавление сообщения в чат %v", chatId)
+
 	message.SentAt = time.Now()
 	message.AuthorID = user.ID
 	message.ChatId = chatId
 
 	log.Printf("Usecase: сообщение от прользователя: %v", message.AuthorID)
 
-	err := u.messageRepository.AddMessage(message, chatId)
+	err := u.checkMessageContent(ctx, user, chatId, message.Message, message.Sticker,
+		len(message.Files)+len(message.Payloads), message.ReplyTo)
+	if err != nil {
+		return models.Message{}, err
+	}
+
+	message.Entities, err = prepareEntities(message.Message, message.Entities)
+	if err != nil {
+		return models.Message{}, err
+	}
+
+	message.ExpiresAt, err = u.messageExpiresAt(ctx, chatId, message.SentAt)
+	if err != nil {
+		return models.Message{}, err
+	}
+
+	// у отложенных сообщений вложения уже сохранены
+	saved, err := u.savePayloads(ctx, message.Files)
+	if err != nil {
+		log.Errorf("Usecase: не удалось сохранить вложения: %v", err)
+		return models.Message{}, err
+	}
+	message.Payloads = append(message.Payloads, saved...)
+
+	err = u.messageRepository.AddMessage(message, chatId)
 	if err != nil {
 		log.Errorf("Usecase: не удалось добавить сообщение: %v", err)
-		return err
+		u.removePayloads(ctx, saved)
+		return models.Message{}, err
 	}
 
 	log.Printf("Usecase: сообщение успешно добавлено: %v", message.MessageId)
+
+	if message.ReplyTo != nil {
+		// превью цитаты собирается в бд
+		stored, err := u.messageRepository.GetMessageById(ctx, user.ID, message.MessageId)
+		if err != nil {
+			log.Errorf("Usecase: не удалось получить превью ответа: %v", err)
+		} else {
+			message.ReplyPreview = stored.ReplyPreview
+		}
+	}
+
 	u.sendIvent(ctx, socketUsecase.NewMessage, message)
+	if message.Nonce != nil {
+		// nonce нужен только автору, чтобы сопоставить сообщение с отправленным
+		event := convertMessageToEvent(message)
+		event.Nonce = message.Nonce
+		u.publishIvent(ctx, socketUsecase.MessageEvent{
+			Action:     socketUsecase.MessageSent,
+			Message:    event,
+			Recipients: []uuid.UUID{user.ID},
+		})
+	}
+	u.sendBranchUpdate(ctx, chatId)
+	if strings.Contains(message.Message, "@") || hasMentionEntities(message.Entities) {
+		u.updateMentions(ctx, message)
+	}
+	u.requestLinkPreview(ctx, message)
 	metric.IncMetric(*sendedMessagesMetric)
-	return nil
+	return message, nil
+}
+
+// savePayloads сохраняет вложения на диск. При ошибке уже сохраненные файлы удаляются
+func (u *MessageUsecaseImplm) savePayloads(ctx context.Context, files []*multipart.FileHeader) ([]models.Payload, error) {
+	payloads := []models.Payload{}
+
+	for _, header := range files {
+		file, err := header.Open()
+		if err != nil {
+			u.removePayloads(ctx, payloads)
+			return nil, err
+		}
+
+		path, err := multipartHepler.SaveFile(file, header.Filename, payloadDir)
+		file.Close()
+		if err != nil {
+			u.removePayloads(ctx, payloads)
+			return nil, err
+		}
+
+		payloads = append(payloads, models.Payload{
+			URL:      path,
+			Filename: header.Filename,
+			Size:     header.Size,
+		})
+	}
+
+	return payloads, nil
+}
+
+func (u *MessageUsecaseImplm) removePayloads(ctx context.Context, payloads []models.Payload) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+
+	for _, payload := range payloads {
+		if err := multipartHepler.RemoveFile(payload.URL); err != nil {
+			log.Errorf("не удалось удалить вложение %v: %v", payload.URL, err)
+		}
+	}
+}
+
+// removeOrphanFiles удаляет файлы вложений, на которые больше никто не ссылается
+func (u *MessageUsecaseImplm) removeOrphanFiles(ctx context.Context, paths []string) {
+	payloads := make([]models.Payload, 0, len(paths))
+	for _, path := range paths {
+		payloads = append(payloads, models.Payload{URL: path})
+	}
+	u.removePayloads(ctx, payloads)
+}
+
+var forwardMessageMetric = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "count_of_forwarded_messages",
+		Help: "countOfHits",
+	},
+	nil, // no labels for this metric
+)
+
+// canWriteInChat в канал пишут только владелец и админы, в остальные чаты - все участники
+func (u *MessageUsecaseImplm) canWriteInChat(ctx context.Context, userId uuid.UUID, chatId uuid.UUID) (bool, error) {
+	role, err := u.chatRepository.GetUserRoleInChat(ctx, userId, chatId)
+	if err != nil {
+		return false, err
+	}
+
+	if role == NotInChat {
+		return false, nil
+	}
+
+	chatType, err := u.chatRepository.GetChatType(ctx, chatId)
+	if err != nil {
+		return false, err
+	}
+
+	if chatType == channel {
+		return role == owner || role == admin, nil
+	}
+
+	return true, nil
+}
+
+func (u *MessageUsecaseImplm) ForwardMessages(ctx context.Context, user jwt.User, input models.ForwardMessagesInput) (models.MessagesArrayDTO, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Infof("начата пересылка %d сообщений в %d чатов пользователем %v", len(input.MessageIds), len(input.ChatIds), user.ID)
+
+	if len(input.MessageIds) == 0 || len(input.MessageIds) > MaxForwardMessages ||
+		len(input.ChatIds) == 0 || len(input.ChatIds) > MaxForwardChats {
+		return models.MessagesArrayDTO{}, ErrBadForward
+	}
+
+	// проверяем, что пользователь видит пересылаемые сообщения
+	messages := []models.Message{}
+	hasPoll := false
+	checkedChats := map[uuid.UUID]struct{}{}
+	for _, messageId := range input.MessageIds {
+		message, err := u.messageRepository.GetMessageById(ctx, user.ID, messageId)
+		if err != nil {
+			return models.MessagesArrayDTO{}, err
+		}
+		if message.MessageId == uuid.Nil {
+			return models.MessagesArrayDTO{}, ErrMessageNotFound
+		}
+
+		if _, ok := checkedChats[message.ChatId]; !ok {
+			role, err := u.chatRepository.GetUserRoleInChat(ctx, user.ID, message.ChatId)
+			if err != nil {
+				return models.MessagesArrayDTO{}, err
+			}
+
+			if role == NotInChat {
+				return models.MessagesArrayDTO{}, &customerror.NoPermissionError{
+					Area: fmt.Sprintf("чат %v", message.ChatId),
+					User: user.ID.String(),
+				}
+			}
+			checkedChats[message.ChatId] = struct{}{}
+		}
+
+		// в выдаче правильный ответ викторины может быть скрыт, поэтому берем опрос целиком
+		if message.Poll != nil {
+			message.Poll, err = u.messageRepository.GetPoll(ctx, messageId)
+			if err != nil {
+				return models.MessagesArrayDTO{}, err
+			}
+			hasPoll = true
+		}
+
+		messages = append(messages, message)
+	}
+
+	// проверяем, что пользователь может писать во все чаты назначения
+	for _, chatId := range input.ChatIds {
+		canWrite, err := u.canWriteInChat(ctx, user.ID, chatId)
+		if err != nil {
+			return models.MessagesArrayDTO{}, err
+		}
+
+		if !canWrite {
+			return models.MessagesArrayDTO{}, &customerror.NoPermissionError{
+				Area: fmt.Sprintf("нет прав на отправку сообщений в чат %v", chatId),
+				User: user.ID.String(),
+			}
+		}
+
+		// копия опроса подчиняется тем же правилам, что и новый опрос
+		if hasPoll {
+			if err := u.checkPollChatType(ctx, chatId); err != nil {
+				return models.MessagesArrayDTO{}, err
+			}
+		}
+	}
+
+	// пересылаем в хронологическом порядке
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].SentAt.Before(messages[j].SentAt)
+	})
+
+	// копии сохраняются одной транзакцией, чтобы при ошибке пересылка не осталась частичной
+	copies := []models.Message{}
+	for _, chatId := range input.ChatIds {
+		sentAt := time.Now()
+
+		// пересланное сообщение живет столько, сколько задано в чате назначения
+		ttl, err := u.messageRepository.GetMessageTTL(ctx, chatId)
+		if err != nil {
+			return models.MessagesArrayDTO{}, err
+		}
+
+		for _, original := range messages {
+			// сохраняем самый первый источник, если сообщение уже пересылали
+			origin := models.ForwardedFrom{
+				AuthorID: original.AuthorID,
+				ChatId:   original.ChatId,
+			}
+			if original.ForwardedFrom != nil {
+				origin = *original.ForwardedFrom
+			}
+
+			message := models.Message{
+				MessageId:     uuid.New(),
+				AuthorID:      user.ID,
+				Message:       original.Message,
+				SentAt:        sentAt,
+				ChatId:        chatId,
+				Payloads:      original.Payloads,
+				Sticker:       original.Sticker,
+				ForwardedFrom: &origin,
+				LinkPreview:   original.LinkPreview,
+				Entities:      original.Entities,
+				Poll:          original.Poll,
+				ExpiresAt:     expiresAfter(sentAt, ttl),
+			}
+			// чтобы порядок пересланных сообщений не перемешался
+			sentAt = sentAt.Add(time.Microsecond)
+
+			copies = append(copies, message)
+		}
+	}
+
+	err := u.messageRepository.AddMessages(ctx, copies)
+	if err != nil {
+		log.Errorf("не удалось переслать сообщения: %v", err)
+		return models.MessagesArrayDTO{}, err
+	}
+
+	// события уходят только после сохранения всех копий
+	forwarded := []models.Message{}
+	for _, message := range copies {
+		// имена автора и чата источника собираются в бд. Копии уже сохранены,
+		// поэтому ошибка чтения не отменяет пересылку
+		stored, err := u.messageRepository.GetMessageById(ctx, user.ID, message.MessageId)
+		if err != nil {
+			log.Errorf("не удалось получить пересланное сообщение %v: %v", message.MessageId, err)
+			stored = message
+		}
+
+		u.sendIvent(ctx, socketUsecase.NewMessage, stored)
+		metric.IncMetric(*forwardMessageMetric)
+		forwarded = append(forwarded, stored)
+	}
+
+	for _, chatId := range input.ChatIds {
+		u.sendBranchUpdate(ctx, chatId)
+	}
+
+	return models.MessagesArrayDTO{
+		Messages: forwarded,
+	}, nil
 }
 
 var deleteMessageMetric = prometheus.NewGaugeVec(
@@ -102,31 +523,108 @@ var deleteMessageMetric = prometheus.NewGaugeVec(
 	nil, // no labels for this metric
 )
 
+// DeleteMessage удаляет сообщение у всех. Автор может это сделать в течение deleteWindow после отправки,
+// владелец и админы групп и каналов - в любое время
 func (u *MessageUsecaseImplm) DeleteMessage(ctx context.Context, user jwt.User, messageId uuid.UUID) error {
 	log := logger.LoggerWithCtx(ctx, logger.Log)
 	log.Infof("начато удаление сообщения %v пользователем %v", messageId, user.ID)
 
-	message, err := u.messageRepository.GetMessageById(ctx, messageId)
+	message, err := u.messageRepository.GetMessageById(ctx, user.ID, messageId)
 	if err != nil {
 		return err
 	}
 
-	if user.ID != message.AuthorID {
+	if message.MessageId == uuid.Nil {
+		return ErrMessageNotFound
+	}
+
+	canDelete, err := u.canDeleteForEveryone(ctx, user, message, map[uuid.UUID]bool{})
+	if err != nil {
+		return err
+	}
+
+	if !canDelete {
 		return &customerror.NoPermissionError{
-			Area: fmt.Sprintf("сообщение %v принадлежит другому пользователю", messageId),
+			Area: fmt.Sprintf("удаление сообщения %v у всех", messageId),
 			User: user.ID.String(),
 		}
 	}
-	err = u.messageRepository.DeleteMessage(ctx, messageId)
+
+	orphans, err := u.messageRepository.DeleteMessage(ctx, messageId)
 	if err != nil {
 		return err
 	}
 
 	u.sendIvent(ctx, socketUsecase.DeleteMessage, message)
+	u.sendBranchUpdate(ctx, message.ChatId)
+	u.removeOrphanFiles(ctx, orphans)
 	metric.IncMetric(*deleteMessageMetric)
 	return nil
 }
 
+// canDeleteForEveryone правило удаления у всех для одного сообщения, общее для DeleteMessage и DeleteMessages.
+// В moderatedChats запоминаются уже проверенные чаты, чтобы при удалении пачкой роль запрашивалась один раз на чат
+func (u *MessageUsecaseImplm) canDeleteForEveryone(ctx context.Context, user jwt.User, message models.Message,
+	moderatedChats map[uuid.UUID]bool) (bool, error) {
+	if user.ID == message.AuthorID && time.Since(message.SentAt) <= u.deleteWindow {
+		return true, nil
+	}
+
+	if isModerator, ok := moderatedChats[message.ChatId]; ok {
+		return isModerator, nil
+	}
+
+	isModerator, err := u.isChatModerator(ctx, user.ID, message.ChatId)
+	if err != nil {
+		return false, err
+	}
+	moderatedChats[message.ChatId] = isModerator
+	return isModerator, nil
+}
+
+// isChatModerator владелец и админы групп и каналов могут удалять чужие сообщения
+func (u *MessageUsecaseImplm) isChatModerator(ctx context.Context, userId uuid.UUID, chatId uuid.UUID) (bool, error) {
+	role, err := u.chatRepository.GetUserRoleInChat(ctx, userId, chatId)
+	if err != nil {
+		return false, err
+	}
+
+	if role != owner && role != admin {
+		return false, nil
+	}
+
+	chatType, err := u.chatRepository.GetChatType(ctx, chatId)
+	if err != nil {
+		return false, err
+	}
+
+	return chatType == group || chatType == channel, nil
+}
+
+// HideMessage удаляет сообщение только у пользователя. Остальные участники его по-прежнему видят
+func (u *MessageUsecaseImplm) HideMessage(ctx context.Context, user jwt.User, messageId uuid.UUID) error {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Infof("пользователь %v удаляет у себя сообщение %v", user.ID, messageId)
+
+	message, err := u.getMessageForMember(ctx, user, messageId)
+	if err != nil {
+		return err
+	}
+
+	err = u.messageRepository.HideMessage(ctx, user.ID, messageId)
+	if err != nil {
+		return err
+	}
+
+	// остальные сессии пользователя тоже должны убрать сообщение
+	u.publishIvent(ctx, socketUsecase.MessageEvent{
+		Action:     socketUsecase.MessageHidden,
+		Message:    convertMessageToEvent(message),
+		Recipients: []uuid.UUID{user.ID},
+	})
+	return nil
+}
+
 var updateMessageMetric = prometheus.NewGaugeVec(
 	prometheus.GaugeOpts{
 		Name: "count_of_updated_messages",
@@ -141,7 +639,13 @@ func (u *MessageUsecaseImplm) UpdateMessage(ctx context.Context, user jwt.User,
 
 	newText := message.Message
 
-	message, err := u.messageRepository.GetMessageById(ctx, messageId)
+	// разметка относится к старому тексту, поэтому заменяется целиком
+	entities, err := prepareEntities(newText, message.Entities)
+	if err != nil {
+		return err
+	}
+
+	message, err = u.messageRepository.GetMessageById(ctx, user.ID, messageId)
 	if err != nil {
 		return err
 	}
@@ -153,108 +657,412 @@ func (u *MessageUsecaseImplm) UpdateMessage(ctx context.Context, user jwt.User,
 		}
 	}
 
-	u.messageRepository.UpdateMessage(ctx, messageId, newText)
+	if message.Sticker != nil {
+		return ErrStickerUpdate
+	}
+
+	if message.Poll != nil {
+		return ErrPollUpdate
+	}
+
+	editedAt, err := u.messageRepository.UpdateMessage(ctx, messageId, user.ID, newText, entities)
+	if err != nil {
+		return err
+	}
 
 	// отправляем в сокет
 	message.Message = newText
+	message.Entities = entities
 	message.IsRedacted = true
+	message.EditedAt = &editedAt
+	refreshPreview := u.dropStaleLinkPreview(ctx, &message)
 	u.sendIvent(ctx, socketUsecase.UpdateMessage, message)
+	// упоминания пересчитываем всегда: после правки часть из них могла исчезнуть
+	u.updateMentions(ctx, message)
+	if refreshPreview {
+		u.requestLinkPreview(ctx, message)
+	}
 	metric.IncMetric(*updateMessageMetric)
 	return nil
 }
 
 const NotInChat = ""
 
-func (u *MessageUsecaseImplm) SearchMessagesWithQuery(ctx context.Context, user jwt.User, chatId uuid.UUID, searchQuery string) (models.MessagesArrayDTO, error) {
+// ограничения на одну страницу поиска
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 50
+)
+
+func (u *MessageUsecaseImplm) SearchMessagesWithQuery(ctx context.Context, user jwt.User, chatId uuid.UUID,
+	searchQuery string, cursor string, limit int) (models.SearchMessagesDTO, error) {
 	log := logger.LoggerWithCtx(ctx, logger.Log)
 	log.Infof("Начат поиск сообщений в чате %v. Поисковая строка = %v", chatId, searchQuery)
 
+	if strings.TrimSpace(searchQuery) == "" {
+		return models.SearchMessagesDTO{}, ErrEmptySearchQuery
+	}
+
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	limit = min(limit, MaxSearchLimit)
+
+	var after *models.SearchCursor
+	if cursor != "" {
+		decoded, err := decodeSearchCursor(cursor)
+		if err != nil {
+			log.Errorf("получен кривой курсор поиска: %v", err)
+			return models.SearchMessagesDTO{}, ErrBadSearchCursor
+		}
+		after = &decoded
+	}
+
 	role, err := u.chatRepository.GetUserRoleInChat(ctx, user.ID, chatId)
 	if err != nil {
-		return models.MessagesArrayDTO{}, err
+		return models.SearchMessagesDTO{}, err
 	}
 
 	if role == NotInChat {
-		return models.MessagesArrayDTO{},
+		return models.SearchMessagesDTO{},
 			&customerror.NoPermissionError{
 				Area: fmt.Sprintf("Нет доступа к чату %v", chatId),
 				User: user.ID.String(),
 			}
 	}
 
-	messages, err := u.messageRepository.SearchMessagesWithQuery(ctx, chatId, searchQuery)
-
+	// берем на один результат больше, чтобы понять, есть ли следующая страница
+	messages, err := u.messageRepository.SearchMessagesWithQuery(ctx, user.ID, chatId, searchQuery, after, limit+1)
 	if err != nil {
-		return models.MessagesArrayDTO{}, err
+		return models.SearchMessagesDTO{}, err
 	}
 
-	return models.MessagesArrayDTO{
+	result := models.SearchMessagesDTO{
 		Messages: messages,
-	}, nil
+	}
+
+	if len(messages) > limit {
+		result.Messages = messages[:limit]
+		last := result.Messages[limit-1]
+		result.NextCursor = encodeSearchCursor(models.SearchCursor{
+			Rank:      last.Rank,
+			SentAt:    last.Message.SentAt,
+			MessageId: last.Message.MessageId,
+		})
+	}
+
+	return result, nil
 }
 
-func (u *MessageUsecaseImplm) GetFirstMessages(ctx context.Context, chatId uuid.UUID) (models.MessagesArrayDTO, error) {
+// encodeSearchCursor курсор непрозрачен для клиента
+func encodeSearchCursor(cursor models.SearchCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSearchCursor(cursor string) (models.SearchCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return models.SearchCursor{}, err
+	}
+
+	var decoded models.SearchCursor
+	err = json.Unmarshal(data, &decoded)
+	return decoded, err
+}
+
+func (u *MessageUsecaseImplm) GetFirstMessages(ctx context.Context, userId uuid.UUID, chatId uuid.UUID) (models.MessagesArrayDTO, error) {
 	log := logger.LoggerWithCtx(ctx, logger.Log)
 	log.Printf("Usecase: начато получение сообщений")
 
-	messages, err := u.messageRepository.GetFirstMessages(ctx, chatId)
+	messages, err := u.messageRepository.GetFirstMessages(ctx, userId, chatId)
 	if err != nil {
 		log.Errorf("Usecase: не удалось получить сообщения: %v", err)
 		return models.MessagesArrayDTO{}, err
 	}
 	log.Printf("Usecase: сообщения получены")
 
+	u.trackViews(ctx, userId, chatId, messages)
+
 	return models.MessagesArrayDTO{
 		Messages: messages,
 	}, nil
 }
 
-func (u *MessageUsecaseImplm) GetMessagesWithPage(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, lastMessageId uuid.UUID) (models.MessagesArrayDTO, error) {
+// ограничения на размер страницы истории
+const (
+	DefaultPageSize = 25
+	MaxPageSize     = 100
+)
+
+func normalizePageSize(limit int) int {
+	if limit <= 0 {
+		return DefaultPageSize
+	}
+	return min(limit, MaxPageSize)
+}
+
+// getAnchorMessage проверяет доступ к чату и что сообщение, от которого строится страница, лежит в нем
+func (u *MessageUsecaseImplm) getAnchorMessage(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, messageId uuid.UUID) (models.Message, error) {
 	log := logger.LoggerWithCtx(ctx, logger.Log)
-	log.Printf("запрошены сообщения из чата: %v, запрос получен от пользовтеля: %v", chatId, userId)
 
 	role, err := u.chatRepository.GetUserRoleInChat(ctx, userId, chatId)
 	if err != nil {
-		return models.MessagesArrayDTO{}, err
+		return models.Message{}, err
 	}
 
 	if role == NotInChat {
 		log.Printf("пользователь %v не состоит в чате %v", userId, chatId)
-		return models.MessagesArrayDTO{},
+		return models.Message{},
 			&customerror.NoPermissionError{
 				Area: fmt.Sprintf("чат %v", chatId),
 				User: fmt.Sprintf("пользователь %v", userId),
 			}
 	}
 
-	messages, err := u.messageRepository.GetAllMessagesAfter(ctx, chatId, lastMessageId)
+	anchor, err := u.messageRepository.GetMessageById(ctx, userId, messageId)
 	if err != nil {
-		return models.MessagesArrayDTO{}, err
+		return models.Message{}, err
 	}
 
-	return models.MessagesArrayDTO{
+	if anchor.MessageId == uuid.Nil || anchor.ChatId != chatId {
+		return models.Message{}, ErrMessageNotFound
+	}
+
+	return anchor, nil
+}
+
+// getPage берет на одно сообщение больше, чтобы понять, есть ли что-то дальше.
+// Лишнее сообщение - самое дальнее от anchorId
+func (u *MessageUsecaseImplm) getPage(ctx context.Context, userId uuid.UUID, chatId uuid.UUID,
+	anchorId uuid.UUID, older bool, limit int) ([]models.Message, bool, error) {
+	messages, err := u.messageRepository.GetMessagesPage(ctx, userId, chatId, anchorId, older, limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(messages) <= limit {
+		return messages, false, nil
+	}
+
+	// страница отсортирована от новых к старым
+	if older {
+		return messages[:limit], true, nil
+	}
+	return messages[1:], true, nil
+}
+
+func (u *MessageUsecaseImplm) GetMessagesWithPage(ctx context.Context, userId uuid.UUID, chatId uuid.UUID,
+	anchorId uuid.UUID, older bool, limit int) (models.MessagesPageDTO, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Printf("запрошены сообщения из чата: %v, запрос получен от пользовтеля: %v", chatId, userId)
+
+	_, err := u.getAnchorMessage(ctx, userId, chatId, anchorId)
+	if err != nil {
+		return models.MessagesPageDTO{}, err
+	}
+
+	messages, hasMore, err := u.getPage(ctx, userId, chatId, anchorId, older, normalizePageSize(limit))
+	if err != nil {
+		return models.MessagesPageDTO{}, err
+	}
+
+	u.trackViews(ctx, userId, chatId, messages)
+
+	// с другой стороны от anchorId есть как минимум оно само
+	page := models.MessagesPageDTO{
 		Messages: messages,
-	}, nil
+		HasOlder: true,
+		HasNewer: true,
+	}
+	if older {
+		page.HasOlder = hasMore
+	} else {
+		page.HasNewer = hasMore
+	}
+
+	return page, nil
+}
+
+// GetMessagesAround окно истории вокруг сообщения: половина страницы до него и половина после
+func (u *MessageUsecaseImplm) GetMessagesAround(ctx context.Context, userId uuid.UUID, chatId uuid.UUID,
+	messageId uuid.UUID, limit int) (models.MessagesPageDTO, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Printf("пользователь %v переходит к сообщению %v в чате %v", userId, messageId, chatId)
+
+	anchor, err := u.getAnchorMessage(ctx, userId, chatId, messageId)
+	if err != nil {
+		return models.MessagesPageDTO{}, err
+	}
 
+	limit = normalizePageSize(limit)
+	olderLimit := limit / 2
+	newerLimit := limit - olderLimit - 1
+
+	older, hasOlder, err := u.getPage(ctx, userId, chatId, messageId, true, olderLimit)
+	if err != nil {
+		return models.MessagesPageDTO{}, err
+	}
+
+	newer, hasNewer, err := u.getPage(ctx, userId, chatId, messageId, false, newerLimit)
+	if err != nil {
+		return models.MessagesPageDTO{}, err
+	}
+
+	messages := make([]models.Message, 0, len(newer)+1+len(older))
+	messages = append(messages, newer...)
+	messages = append(messages, anchor)
+	messages = append(messages, older...)
+
+	u.trackViews(ctx, userId, chatId, messages)
+
+	return models.MessagesPageDTO{
+		Messages: messages,
+		HasOlder: hasOlder,
+		HasNewer: hasNewer,
+	}, nil
 }
 
 func (s *MessageUsecaseImplm) sendIvent(ctx context.Context, action string, message models.Message) {
+	s.publishIvent(ctx, socketUsecase.MessageEvent{
+		Action:  action,
+		Message: convertMessageToEvent(message),
+	})
+}
+
+// convertMessageToEvent переводит сообщение в формат очереди message
+func convertMessageToEvent(message models.Message) socketUsecase.Message {
 	newMessage := socketUsecase.Message{
-		MessageId:  message.MessageId,
-		AuthorID:   message.AuthorID,
-		BranchID:   message.BranchID,
-		Message:    message.Message,
-		SentAt:     message.SentAt,
-		ChatId:     message.ChatId,
-		IsRedacted: message.IsRedacted,
+		MessageId:    message.MessageId,
+		AuthorID:     message.AuthorID,
+		BranchID:     message.BranchID,
+		Message:      message.Message,
+		SentAt:       message.SentAt,
+		ChatId:       message.ChatId,
+		IsRedacted:   message.IsRedacted,
+		EditedAt:     message.EditedAt,
+		Payloads:     []socketUsecase.Payload{},
+		Sticker:      message.Sticker,
+		ReplyTo:      message.ReplyTo,
+		Reactions:    []socketUsecase.Reaction{},
+		Entities:     []socketUsecase.MessageEntity{},
+		ExpiresAt:    message.ExpiresAt,
+		ImportedFrom: message.ImportedFrom,
 	}
 
-	log := logger.LoggerWithCtx(ctx, logger.Log)
-	newEvent := socketUsecase.MessageEvent{
-		Action:  action,
-		Message: newMessage,
+	if message.ForwardedFrom != nil {
+		newMessage.ForwardedFrom = &socketUsecase.ForwardedFrom{
+			AuthorID:   message.ForwardedFrom.AuthorID,
+			AuthorName: message.ForwardedFrom.AuthorName,
+			ChatId:     message.ForwardedFrom.ChatId,
+			ChatName:   message.ForwardedFrom.ChatName,
+			ChatType:   message.ForwardedFrom.ChatType,
+		}
+	}
+
+	if message.ReplyPreview != nil {
+		newMessage.ReplyPreview = &socketUsecase.ReplyPreview{
+			MessageId:  message.ReplyPreview.MessageId,
+			AuthorID:   message.ReplyPreview.AuthorID,
+			AuthorName: message.ReplyPreview.AuthorName,
+			Text:       message.ReplyPreview.Text,
+			Sticker:    message.ReplyPreview.Sticker,
+			IsRedacted: message.ReplyPreview.IsRedacted,
+			IsDeleted:  message.ReplyPreview.IsDeleted,
+		}
+	}
+
+	for _, entity := range message.Entities {
+		newMessage.Entities = append(newMessage.Entities, socketUsecase.MessageEntity{
+			Type:     entity.Type,
+			Offset:   entity.Offset,
+			Length:   entity.Length,
+			URL:      entity.URL,
+			UserID:   entity.UserID,
+			Language: entity.Language,
+		})
+	}
+
+	if message.Poll != nil {
+		newMessage.Poll = convertPollToEvent(*message.Poll)
+	}
+
+	if message.Branch != nil {
+		newMessage.Branch = &socketUsecase.BranchSummary{
+			ReplyCount: message.Branch.ReplyCount,
+		}
+		if reply := message.Branch.LastReply; reply != nil {
+			newMessage.Branch.LastReply = &socketUsecase.BranchReply{
+				MessageId:  reply.MessageId,
+				AuthorID:   reply.AuthorID,
+				AuthorName: reply.AuthorName,
+				Text:       reply.Text,
+				Sticker:    reply.Sticker,
+				SentAt:     reply.SentAt,
+			}
+		}
+	}
+
+	if message.LinkPreview != nil {
+		newMessage.LinkPreview = &socketUsecase.LinkPreview{
+			URL:         message.LinkPreview.URL,
+			Title:       message.LinkPreview.Title,
+			Description: message.LinkPreview.Description,
+			Image:       message.LinkPreview.Image,
+			SiteName:    message.LinkPreview.SiteName,
+		}
+	}
+
+	for _, payload := range message.Payloads {
+		newMessage.Payloads = append(newMessage.Payloads, socketUsecase.Payload{
+			URL:      payload.URL,
+			Filename: payload.Filename,
+			Size:     payload.Size,
+		})
 	}
 
+	for _, reaction := range message.Reactions {
+		newMessage.Reactions = append(newMessage.Reactions, socketUsecase.Reaction{
+			Emoji: reaction.Emoji,
+			Count: reaction.Count,
+		})
+	}
+
+	return newMessage
+}
+
+// convertPollToEvent событие получают все участники чата, поэтому в нем нет отметок
+// текущего пользователя, а правильный ответ викторины появляется только после закрытия
+func convertPollToEvent(poll models.Poll) *socketUsecase.Poll {
+	eventPoll := &socketUsecase.Poll{
+		Question:    poll.Question,
+		Options:     []socketUsecase.PollOption{},
+		IsMultiple:  poll.IsMultiple,
+		IsAnonymous: poll.IsAnonymous,
+		IsQuiz:      poll.IsQuiz,
+		CloseAt:     poll.CloseAt,
+		IsClosed:    poll.IsClosed,
+		TotalVoters: poll.TotalVoters,
+	}
+
+	if poll.IsClosed {
+		eventPoll.CorrectOption = poll.CorrectOption
+	}
+
+	for _, option := range poll.Options {
+		eventPoll.Options = append(eventPoll.Options, socketUsecase.PollOption{
+			Text:   option.Text,
+			Votes:  option.Votes,
+			Voters: option.Voters,
+		})
+	}
+
+	return eventPoll
+}
+
+func (s *MessageUsecaseImplm) publishIvent(ctx context.Context, newEvent socketUsecase.MessageEvent) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+
 	body, err := socketUsecase.SerializeMessageEvent(newEvent)
 	if err != nil {
 		log.Errorf("Не удалось сериализовать объект")
@@ -0,0 +1,849 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	socketUsecase "github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/events"
+	jwt "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	customerror "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/custom_error"
+	chatMockRepo "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/repository/mocks"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/repository"
+	messagesMockRepo "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/repository/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+)
+
+var errRepo = errors.New("repo error")
+
+// fakePublisher запоминает события вместо отправки в очередь
+type fakePublisher struct {
+	events []socketUsecase.MessageEvent
+}
+
+func (p *fakePublisher) PublishWithContext(_ context.Context, _, _ string, _, _ bool, msg amqp.Publishing) error {
+	event, err := socketUsecase.DeserializeMessageEvent(msg.Body)
+	if err != nil {
+		return err
+	}
+	p.events = append(p.events, event)
+	return nil
+}
+
+func TestDeleteMessage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	messageRepo := messagesMockRepo.NewMockMessageRepository(ctrl)
+	chatRepo := chatMockRepo.NewMockChatRepository(ctrl)
+	publisher := &fakePublisher{}
+	usecase := &MessageUsecaseImplm{
+		messageRepository: messageRepo,
+		chatRepository:    chatRepo,
+		ch:                publisher,
+		deleteWindow:      time.Hour,
+	}
+
+	user := jwt.User{ID: uuid.New()}
+	chatId := uuid.New()
+	messageId := uuid.New()
+
+	// сообщение, отправленное ago назад
+	message := func(authorId uuid.UUID, ago time.Duration) models.Message {
+		return models.Message{
+			MessageId: messageId,
+			ChatId:    chatId,
+			AuthorID:  authorId,
+			SentAt:    time.Now().Add(-ago),
+		}
+	}
+
+	tests := []struct {
+		name          string
+		prepareMock   func()
+		expectedError error
+		// ожидается ошибка доступа customerror.NoPermissionError
+		noPermission bool
+	}{
+		{
+			name: "сообщение не найдено",
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessageById(gomock.Any(), user.ID, messageId).Return(models.Message{}, nil)
+			},
+			expectedError: ErrMessageNotFound,
+		},
+		{
+			name: "автор до конца окна удаления",
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessageById(gomock.Any(), user.ID, messageId).
+					Return(message(user.ID, time.Hour-time.Minute), nil)
+				messageRepo.EXPECT().DeleteMessage(gomock.Any(), messageId).Return(nil, nil)
+				messageRepo.EXPECT().GetBranchParentMessage(gomock.Any(), uuid.Nil, chatId).Return(models.Message{}, nil)
+			},
+		},
+		{
+			name: "автор после окна удаления",
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessageById(gomock.Any(), user.ID, messageId).
+					Return(message(user.ID, time.Hour+time.Minute), nil)
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return(none, nil)
+			},
+			noPermission: true,
+		},
+		{
+			name: "чужое сообщение у обычного участника",
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessageById(gomock.Any(), user.ID, messageId).
+					Return(message(uuid.New(), time.Minute), nil)
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return(none, nil)
+			},
+			noPermission: true,
+		},
+		{
+			name: "владелец личного чата не удаляет чужие",
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessageById(gomock.Any(), user.ID, messageId).
+					Return(message(uuid.New(), time.Minute), nil)
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return(owner, nil)
+				chatRepo.EXPECT().GetChatType(gomock.Any(), chatId).Return(personal, nil)
+			},
+			noPermission: true,
+		},
+		{
+			name: "админ группы удаляет чужое в любое время",
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessageById(gomock.Any(), user.ID, messageId).
+					Return(message(uuid.New(), 24*time.Hour), nil)
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return(admin, nil)
+				chatRepo.EXPECT().GetChatType(gomock.Any(), chatId).Return(group, nil)
+				messageRepo.EXPECT().DeleteMessage(gomock.Any(), messageId).Return(nil, nil)
+				messageRepo.EXPECT().GetBranchParentMessage(gomock.Any(), uuid.Nil, chatId).Return(models.Message{}, nil)
+			},
+		},
+		{
+			name: "владелец канала удаляет чужое",
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessageById(gomock.Any(), user.ID, messageId).
+					Return(message(uuid.New(), time.Minute), nil)
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return(owner, nil)
+				chatRepo.EXPECT().GetChatType(gomock.Any(), chatId).Return(channel, nil)
+				messageRepo.EXPECT().DeleteMessage(gomock.Any(), messageId).Return(nil, nil)
+				messageRepo.EXPECT().GetBranchParentMessage(gomock.Any(), uuid.Nil, chatId).Return(models.Message{}, nil)
+			},
+		},
+		{
+			name: "ошибка получения роли",
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessageById(gomock.Any(), user.ID, messageId).
+					Return(message(uuid.New(), time.Minute), nil)
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return("", errRepo)
+			},
+			expectedError: errRepo,
+		},
+		{
+			name: "ошибка удаления",
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessageById(gomock.Any(), user.ID, messageId).
+					Return(message(user.ID, time.Minute), nil)
+				messageRepo.EXPECT().DeleteMessage(gomock.Any(), messageId).Return(nil, errRepo)
+			},
+			expectedError: errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher.events = nil
+			tt.prepareMock()
+
+			err := usecase.DeleteMessage(context.Background(), user, messageId)
+
+			if tt.noPermission {
+				assert.True(t, customerror.IsNoPermission(err), "ожидалась ошибка доступа, получено: %v", err)
+				assert.Empty(t, publisher.events)
+				return
+			}
+			assert.ErrorIs(t, err, tt.expectedError)
+			if tt.expectedError != nil {
+				assert.Empty(t, publisher.events)
+				return
+			}
+
+			if assert.Len(t, publisher.events, 1) {
+				assert.Equal(t, socketUsecase.DeleteMessage, publisher.events[0].Action)
+				assert.Equal(t, messageId, publisher.events[0].Message.MessageId)
+				assert.Empty(t, publisher.events[0].Recipients)
+			}
+		})
+	}
+}
+
+func TestHideMessage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	messageRepo := messagesMockRepo.NewMockMessageRepository(ctrl)
+	chatRepo := chatMockRepo.NewMockChatRepository(ctrl)
+	publisher := &fakePublisher{}
+	usecase := &MessageUsecaseImplm{
+		messageRepository: messageRepo,
+		chatRepository:    chatRepo,
+		ch:                publisher,
+	}
+
+	user := jwt.User{ID: uuid.New()}
+	chatId := uuid.New()
+	message := models.Message{MessageId: uuid.New(), ChatId: chatId, AuthorID: uuid.New()}
+
+	tests := []struct {
+		name          string
+		prepareMock   func()
+		expectedError error
+		// ожидается ошибка доступа customerror.NoPermissionError
+		noPermission bool
+	}{
+		{
+			name: "чужое сообщение скрывается только у пользователя",
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessageById(gomock.Any(), user.ID, message.MessageId).Return(message, nil)
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return(none, nil)
+				messageRepo.EXPECT().HideMessage(gomock.Any(), user.ID, message.MessageId).Return(nil)
+			},
+		},
+		{
+			name: "сообщение не найдено",
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessageById(gomock.Any(), user.ID, message.MessageId).Return(models.Message{}, nil)
+			},
+			expectedError: ErrMessageNotFound,
+		},
+		{
+			name: "пользователь не в чате",
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessageById(gomock.Any(), user.ID, message.MessageId).Return(message, nil)
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return(NotInChat, nil)
+			},
+			noPermission: true,
+		},
+		{
+			name: "ошибка скрытия",
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessageById(gomock.Any(), user.ID, message.MessageId).Return(message, nil)
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return(none, nil)
+				messageRepo.EXPECT().HideMessage(gomock.Any(), user.ID, message.MessageId).Return(errRepo)
+			},
+			expectedError: errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher.events = nil
+			tt.prepareMock()
+
+			err := usecase.HideMessage(context.Background(), user, message.MessageId)
+
+			if tt.noPermission {
+				assert.True(t, customerror.IsNoPermission(err), "ожидалась ошибка доступа, получено: %v", err)
+				assert.Empty(t, publisher.events)
+				return
+			}
+			assert.ErrorIs(t, err, tt.expectedError)
+			if tt.expectedError != nil {
+				assert.Empty(t, publisher.events)
+				return
+			}
+
+			// событие получают только сессии самого пользователя
+			if assert.Len(t, publisher.events, 1) {
+				assert.Equal(t, socketUsecase.MessageHidden, publisher.events[0].Action)
+				assert.Equal(t, []uuid.UUID{user.ID}, publisher.events[0].Recipients)
+			}
+		})
+	}
+}
+
+// pageMessages n сообщений чата, как их возвращает репозиторий: от новых к старым
+func pageMessages(chatId uuid.UUID, n int) []models.Message {
+	messages := make([]models.Message, 0, n)
+	for i := 0; i < n; i++ {
+		messages = append(messages, models.Message{MessageId: uuid.New(), ChatId: chatId})
+	}
+	return messages
+}
+
+func TestGetMessagesWithPage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	messageRepo := messagesMockRepo.NewMockMessageRepository(ctrl)
+	chatRepo := chatMockRepo.NewMockChatRepository(ctrl)
+	usecase := &MessageUsecaseImplm{
+		messageRepository: messageRepo,
+		chatRepository:    chatRepo,
+	}
+
+	userId := uuid.New()
+	chatId := uuid.New()
+	anchorId := uuid.New()
+
+	// пользователь состоит в чате, сообщение-якорь лежит в нем
+	expectAnchor := func() {
+		chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), userId, chatId).Return(none, nil)
+		messageRepo.EXPECT().GetMessageById(gomock.Any(), userId, anchorId).
+			Return(models.Message{MessageId: anchorId, ChatId: chatId}, nil)
+	}
+
+	full := pageMessages(chatId, 4)
+
+	tests := []struct {
+		name             string
+		older            bool
+		limit            int
+		prepareMock      func()
+		expectedMessages []models.Message
+		expectedHasOlder bool
+		expectedHasNewer bool
+		expectedError    error
+		noPermission     bool
+	}{
+		{
+			name:  "пользователь не в чате",
+			older: true,
+			limit: 3,
+			prepareMock: func() {
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), userId, chatId).Return(NotInChat, nil)
+			},
+			noPermission: true,
+		},
+		{
+			name:  "якорь из другого чата",
+			older: true,
+			limit: 3,
+			prepareMock: func() {
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), userId, chatId).Return(none, nil)
+				messageRepo.EXPECT().GetMessageById(gomock.Any(), userId, anchorId).
+					Return(models.Message{MessageId: anchorId, ChatId: uuid.New()}, nil)
+			},
+			expectedError: ErrMessageNotFound,
+		},
+		{
+			name:  "якоря нет",
+			older: false,
+			limit: 3,
+			prepareMock: func() {
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), userId, chatId).Return(none, nil)
+				messageRepo.EXPECT().GetMessageById(gomock.Any(), userId, anchorId).Return(models.Message{}, nil)
+			},
+			expectedError: ErrMessageNotFound,
+		},
+		{
+			name:  "старше якоря, есть еще",
+			older: true,
+			limit: 3,
+			prepareMock: func() {
+				expectAnchor()
+				messageRepo.EXPECT().GetMessagesPage(gomock.Any(), userId, chatId, anchorId, true, 4).Return(full, nil)
+			},
+			expectedMessages: full[:3],
+			expectedHasOlder: true,
+			expectedHasNewer: true,
+		},
+		{
+			name:  "старше якоря, последняя страница",
+			older: true,
+			limit: 5,
+			prepareMock: func() {
+				expectAnchor()
+				messageRepo.EXPECT().GetMessagesPage(gomock.Any(), userId, chatId, anchorId, true, 6).Return(full, nil)
+			},
+			expectedMessages: full,
+			expectedHasOlder: false,
+			expectedHasNewer: true,
+		},
+		{
+			name:  "новее якоря, лишнее сообщение - самое новое",
+			older: false,
+			limit: 3,
+			prepareMock: func() {
+				expectAnchor()
+				messageRepo.EXPECT().GetMessagesPage(gomock.Any(), userId, chatId, anchorId, false, 4).Return(full, nil)
+			},
+			expectedMessages: full[1:],
+			expectedHasOlder: true,
+			expectedHasNewer: true,
+		},
+		{
+			name:  "новее якоря, сообщений нет",
+			older: false,
+			limit: 3,
+			prepareMock: func() {
+				expectAnchor()
+				messageRepo.EXPECT().GetMessagesPage(gomock.Any(), userId, chatId, anchorId, false, 4).
+					Return([]models.Message{}, nil)
+			},
+			expectedMessages: []models.Message{},
+			expectedHasOlder: true,
+			expectedHasNewer: false,
+		},
+		{
+			name:  "размер страницы по умолчанию",
+			older: true,
+			limit: 0,
+			prepareMock: func() {
+				expectAnchor()
+				messageRepo.EXPECT().GetMessagesPage(gomock.Any(), userId, chatId, anchorId, true, DefaultPageSize+1).
+					Return([]models.Message{}, nil)
+			},
+			expectedMessages: []models.Message{},
+			expectedHasOlder: false,
+			expectedHasNewer: true,
+		},
+		{
+			name:  "размер страницы ограничен",
+			older: true,
+			limit: MaxPageSize * 10,
+			prepareMock: func() {
+				expectAnchor()
+				messageRepo.EXPECT().GetMessagesPage(gomock.Any(), userId, chatId, anchorId, true, MaxPageSize+1).
+					Return([]models.Message{}, nil)
+			},
+			expectedMessages: []models.Message{},
+			expectedHasOlder: false,
+			expectedHasNewer: true,
+		},
+		{
+			name:  "ошибка репозитория",
+			older: true,
+			limit: 3,
+			prepareMock: func() {
+				expectAnchor()
+				messageRepo.EXPECT().GetMessagesPage(gomock.Any(), userId, chatId, anchorId, true, 4).Return(nil, errRepo)
+			},
+			expectedError: errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.prepareMock()
+
+			page, err := usecase.GetMessagesWithPage(context.Background(), userId, chatId, anchorId, tt.older, tt.limit)
+
+			if tt.noPermission {
+				assert.True(t, customerror.IsNoPermission(err), "ожидалась ошибка доступа, получено: %v", err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.expectedError)
+			if tt.expectedError != nil {
+				return
+			}
+
+			assertMessageIds(t, tt.expectedMessages, page.Messages)
+			assert.Equal(t, tt.expectedHasOlder, page.HasOlder)
+			assert.Equal(t, tt.expectedHasNewer, page.HasNewer)
+		})
+	}
+}
+
+func TestGetMessagesAround(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	messageRepo := messagesMockRepo.NewMockMessageRepository(ctrl)
+	chatRepo := chatMockRepo.NewMockChatRepository(ctrl)
+	usecase := &MessageUsecaseImplm{
+		messageRepository: messageRepo,
+		chatRepository:    chatRepo,
+	}
+
+	userId := uuid.New()
+	chatId := uuid.New()
+	anchor := models.Message{MessageId: uuid.New(), ChatId: chatId}
+
+	older := pageMessages(chatId, 3)
+	newer := pageMessages(chatId, 3)
+
+	tests := []struct {
+		name             string
+		limit            int
+		prepareMock      func()
+		expectedMessages []models.Message
+		expectedHasOlder bool
+		expectedHasNewer bool
+		expectedError    error
+	}{
+		{
+			name:  "окно делится пополам вокруг сообщения",
+			limit: 5,
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessagesPage(gomock.Any(), userId, chatId, anchor.MessageId, true, 3).Return(older, nil)
+				messageRepo.EXPECT().GetMessagesPage(gomock.Any(), userId, chatId, anchor.MessageId, false, 3).Return(newer, nil)
+			},
+			expectedMessages: []models.Message{newer[1], newer[2], anchor, older[0], older[1]},
+			expectedHasOlder: true,
+			expectedHasNewer: true,
+		},
+		{
+			name:  "сообщение в начале истории",
+			limit: 5,
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessagesPage(gomock.Any(), userId, chatId, anchor.MessageId, true, 3).
+					Return([]models.Message{}, nil)
+				messageRepo.EXPECT().GetMessagesPage(gomock.Any(), userId, chatId, anchor.MessageId, false, 3).
+					Return(newer[1:], nil)
+			},
+			expectedMessages: []models.Message{newer[1], newer[2], anchor},
+			expectedHasOlder: false,
+			expectedHasNewer: false,
+		},
+		{
+			name:  "ошибка при загрузке новых",
+			limit: 5,
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessagesPage(gomock.Any(), userId, chatId, anchor.MessageId, true, 3).Return(older, nil)
+				messageRepo.EXPECT().GetMessagesPage(gomock.Any(), userId, chatId, anchor.MessageId, false, 3).Return(nil, errRepo)
+			},
+			expectedError: errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), userId, chatId).Return(none, nil)
+			messageRepo.EXPECT().GetMessageById(gomock.Any(), userId, anchor.MessageId).Return(anchor, nil)
+			tt.prepareMock()
+
+			page, err := usecase.GetMessagesAround(context.Background(), userId, chatId, anchor.MessageId, tt.limit)
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			if tt.expectedError != nil {
+				return
+			}
+
+			assertMessageIds(t, tt.expectedMessages, page.Messages)
+			assert.Equal(t, tt.expectedHasOlder, page.HasOlder)
+			assert.Equal(t, tt.expectedHasNewer, page.HasNewer)
+		})
+	}
+}
+
+// assertMessageIds сравнивает порядок сообщений по их id
+func assertMessageIds(t *testing.T, expected []models.Message, got []models.Message) {
+	t.Helper()
+
+	expectedIds := make([]uuid.UUID, 0, len(expected))
+	for _, message := range expected {
+		expectedIds = append(expectedIds, message.MessageId)
+	}
+	gotIds := make([]uuid.UUID, 0, len(got))
+	for _, message := range got {
+		gotIds = append(gotIds, message.MessageId)
+	}
+	assert.Equal(t, expectedIds, gotIds)
+}
+
+func TestSendMessageNonce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	messageRepo := messagesMockRepo.NewMockMessageRepository(ctrl)
+	chatRepo := chatMockRepo.NewMockChatRepository(ctrl)
+	publisher := &fakePublisher{}
+	usecase := &MessageUsecaseImplm{
+		messageRepository: messageRepo,
+		chatRepository:    chatRepo,
+		ch:                publisher,
+	}
+
+	user := jwt.User{ID: uuid.New()}
+	chatId := uuid.New()
+	nonce := "client-nonce-1"
+	stored := models.Message{MessageId: uuid.New(), ChatId: chatId, AuthorID: user.ID, Message: "привет", Nonce: &nonce}
+	// исчезающее сообщение, время жизни которого уже вышло, но строку еще не удалила фоновая очистка
+	expired := stored
+	expired.ExpiresAt = ptr(time.Now().Add(-time.Minute))
+
+	tests := []struct {
+		name        string
+		nonce       *string
+		prepareMock func()
+		// ожидается ранее сохраненное сообщение, а не новое
+		expectStored  bool
+		expectedError error
+		// сколько событий ушло в websocket_service
+		expectedEvents int
+	}{
+		{
+			name:          "пустой nonce",
+			nonce:         ptr(""),
+			prepareMock:   func() {},
+			expectedError: ErrBadNonce,
+		},
+		{
+			name:          "слишком длинный nonce",
+			nonce:         ptr(strings.Repeat("n", MaxNonceLength+1)),
+			prepareMock:   func() {},
+			expectedError: ErrBadNonce,
+		},
+		{
+			name:  "повторная отправка возвращает сохраненное сообщение",
+			nonce: &nonce,
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessageByNonce(gomock.Any(), user.ID, chatId, nonce).Return(stored, nil)
+			},
+			expectStored: true,
+		},
+		{
+			name:  "первая отправка сохраняет сообщение",
+			nonce: &nonce,
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessageByNonce(gomock.Any(), user.ID, chatId, nonce).Return(models.Message{}, nil)
+				messageRepo.EXPECT().GetMessageTTL(gomock.Any(), chatId).Return(nil, nil)
+				messageRepo.EXPECT().AddMessage(gomock.Any(), chatId).Return(nil)
+				messageRepo.EXPECT().GetBranchParentMessage(gomock.Any(), uuid.Nil, chatId).Return(models.Message{}, nil)
+			},
+			// рассылка в чат и отдельное подтверждение автору
+			expectedEvents: 2,
+		},
+		{
+			name:  "параллельный повтор успел сохранить сообщение",
+			nonce: &nonce,
+			prepareMock: func() {
+				gomock.InOrder(
+					messageRepo.EXPECT().GetMessageByNonce(gomock.Any(), user.ID, chatId, nonce).Return(models.Message{}, nil),
+					messageRepo.EXPECT().GetMessageByNonce(gomock.Any(), user.ID, chatId, nonce).Return(stored, nil),
+				)
+				messageRepo.EXPECT().GetMessageTTL(gomock.Any(), chatId).Return(nil, nil)
+				messageRepo.EXPECT().AddMessage(gomock.Any(), chatId).Return(repository.ErrNonceExists)
+			},
+			expectStored: true,
+		},
+		{
+			name:  "повтор исчезнувшего сообщения",
+			nonce: &nonce,
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessageByNonce(gomock.Any(), user.ID, chatId, nonce).Return(expired, nil)
+			},
+			expectedError: ErrNonceExpired,
+		},
+		{
+			name:  "параллельный повтор нашел исчезнувшее сообщение",
+			nonce: &nonce,
+			prepareMock: func() {
+				gomock.InOrder(
+					messageRepo.EXPECT().GetMessageByNonce(gomock.Any(), user.ID, chatId, nonce).Return(models.Message{}, nil),
+					messageRepo.EXPECT().GetMessageByNonce(gomock.Any(), user.ID, chatId, nonce).Return(expired, nil),
+				)
+				messageRepo.EXPECT().GetMessageTTL(gomock.Any(), chatId).Return(nil, nil)
+				messageRepo.EXPECT().AddMessage(gomock.Any(), chatId).Return(repository.ErrNonceExists)
+			},
+			expectedError: ErrNonceExpired,
+		},
+		{
+			name:  "ошибка поиска по nonce",
+			nonce: &nonce,
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessageByNonce(gomock.Any(), user.ID, chatId, nonce).Return(models.Message{}, errRepo)
+			},
+			expectedError: errRepo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher.events = nil
+			tt.prepareMock()
+
+			sent, err := usecase.SendMessage(context.Background(), user, chatId, models.Message{
+				Message: "привет",
+				Nonce:   tt.nonce,
+			})
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			assert.Len(t, publisher.events, tt.expectedEvents)
+			if tt.expectedError != nil {
+				return
+			}
+
+			if tt.expectStored {
+				assert.Equal(t, stored.MessageId, sent.MessageId)
+				return
+			}
+			assert.NotEqual(t, uuid.Nil, sent.MessageId)
+			assert.NotEqual(t, stored.MessageId, sent.MessageId)
+			assert.Equal(t, user.ID, sent.AuthorID)
+
+			// nonce не уходит всему чату, автор получает его отдельным событием
+			if assert.Len(t, publisher.events, 2) {
+				assert.Equal(t, socketUsecase.NewMessage, publisher.events[0].Action)
+				assert.Nil(t, publisher.events[0].Message.Nonce)
+				assert.Empty(t, publisher.events[0].Recipients)
+
+				assert.Equal(t, socketUsecase.MessageSent, publisher.events[1].Action)
+				assert.Equal(t, tt.nonce, publisher.events[1].Message.Nonce)
+				assert.Equal(t, sent.MessageId, publisher.events[1].Message.MessageId)
+				assert.Equal(t, []uuid.UUID{user.ID}, publisher.events[1].Recipients)
+			}
+		})
+	}
+}
+
+func TestForwardMessages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	messageRepo := messagesMockRepo.NewMockMessageRepository(ctrl)
+	chatRepo := chatMockRepo.NewMockChatRepository(ctrl)
+	publisher := &fakePublisher{}
+	usecase := &MessageUsecaseImplm{
+		messageRepository: messageRepo,
+		chatRepository:    chatRepo,
+		ch:                publisher,
+	}
+
+	user := jwt.User{ID: uuid.New()}
+	sourceChat := uuid.New()
+	targets := []uuid.UUID{uuid.New(), uuid.New()}
+
+	first := models.Message{MessageId: uuid.New(), ChatId: sourceChat, AuthorID: uuid.New(), Message: "первое", SentAt: time.Now().Add(-time.Hour)}
+	second := models.Message{MessageId: uuid.New(), ChatId: sourceChat, AuthorID: uuid.New(), Message: "второе", SentAt: time.Now()}
+	input := models.ForwardMessagesInput{
+		// порядок в запросе не совпадает с хронологическим
+		MessageIds: []uuid.UUID{second.MessageId, first.MessageId},
+		ChatIds:    targets,
+	}
+
+	// проверки исходных сообщений
+	expectSources := func() {
+		messageRepo.EXPECT().GetMessageById(gomock.Any(), user.ID, second.MessageId).Return(second, nil)
+		messageRepo.EXPECT().GetMessageById(gomock.Any(), user.ID, first.MessageId).Return(first, nil)
+		chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, sourceChat).Return(none, nil)
+	}
+	// пользователь может писать во все чаты назначения
+	expectTargets := func() {
+		for _, chatId := range targets {
+			chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return(none, nil)
+			chatRepo.EXPECT().GetChatType(gomock.Any(), chatId).Return(group, nil)
+			messageRepo.EXPECT().GetMessageTTL(gomock.Any(), chatId).Return(nil, nil)
+		}
+	}
+	// копии читаются из бд и рассылаются только после сохранения
+	expectPublish := func(readErr error) {
+		messageRepo.EXPECT().GetMessageById(gomock.Any(), user.ID, gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ uuid.UUID, messageId uuid.UUID) (models.Message, error) {
+				if readErr != nil {
+					return models.Message{}, readErr
+				}
+				return models.Message{MessageId: messageId}, nil
+			}).Times(4)
+		for _, chatId := range targets {
+			messageRepo.EXPECT().GetBranchParentMessage(gomock.Any(), uuid.Nil, chatId).Return(models.Message{}, nil)
+		}
+	}
+
+	var saved []models.Message
+
+	tests := []struct {
+		name           string
+		input          models.ForwardMessagesInput
+		prepareMock    func()
+		expectedError  error
+		noPermission   bool
+		expectedEvents int
+	}{
+		{
+			name:          "пустой список сообщений",
+			input:         models.ForwardMessagesInput{ChatIds: targets},
+			prepareMock:   func() {},
+			expectedError: ErrBadForward,
+		},
+		{
+			name:  "все копии сохраняются одной транзакцией",
+			input: input,
+			prepareMock: func() {
+				expectSources()
+				expectTargets()
+				messageRepo.EXPECT().AddMessages(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, messages []models.Message) error {
+						saved = messages
+						return nil
+					})
+				expectPublish(nil)
+			},
+			expectedEvents: 4,
+		},
+		{
+			name:  "ошибка сохранения не рассылает события",
+			input: input,
+			prepareMock: func() {
+				expectSources()
+				expectTargets()
+				messageRepo.EXPECT().AddMessages(gomock.Any(), gomock.Any()).Return(errRepo)
+			},
+			expectedError: errRepo,
+		},
+		{
+			name:  "ошибка чтения после сохранения не отменяет пересылку",
+			input: input,
+			prepareMock: func() {
+				expectSources()
+				expectTargets()
+				messageRepo.EXPECT().AddMessages(gomock.Any(), gomock.Any()).Return(nil)
+				expectPublish(errRepo)
+			},
+			expectedEvents: 4,
+		},
+		{
+			name:  "нет прав писать в один из чатов",
+			input: input,
+			prepareMock: func() {
+				expectSources()
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, targets[0]).Return(none, nil)
+				chatRepo.EXPECT().GetChatType(gomock.Any(), targets[0]).Return(group, nil)
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, targets[1]).Return(NotInChat, nil)
+			},
+			noPermission: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher.events = nil
+			saved = nil
+			tt.prepareMock()
+
+			result, err := usecase.ForwardMessages(context.Background(), user, tt.input)
+
+			assert.Len(t, publisher.events, tt.expectedEvents)
+			if tt.noPermission {
+				assert.True(t, customerror.IsNoPermission(err), "ожидалась ошибка доступа, получено: %v", err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.expectedError)
+			if tt.expectedError != nil {
+				return
+			}
+
+			assert.Len(t, result.Messages, tt.expectedEvents)
+			for _, event := range publisher.events {
+				assert.Equal(t, socketUsecase.NewMessage, event.Action)
+			}
+
+			if saved == nil {
+				return
+			}
+			// в каждый чат копии уходят в хронологическом порядке
+			if assert.Len(t, saved, 4) {
+				for i, chatId := range targets {
+					assert.Equal(t, chatId, saved[2*i].ChatId)
+					assert.Equal(t, first.Message, saved[2*i].Message)
+					assert.Equal(t, chatId, saved[2*i+1].ChatId)
+					assert.Equal(t, second.Message, saved[2*i+1].Message)
+					assert.Equal(t, sourceChat, saved[2*i].ForwardedFrom.ChatId)
+					assert.Equal(t, user.ID, saved[2*i].AuthorID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	socketUsecase "github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/events"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	jwt "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	customerror "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/custom_error"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxViewMessages сколько постов можно отметить просмотренными одним запросом
+	MaxViewMessages = 100
+	// сколько изменившихся счетчиков просмотров забирается из базы за раз
+	viewsBatchSize = 500
+)
+
+var ErrBadViews = fmt.Errorf("можно отметить просмотренными от 1 до %d сообщений", MaxViewMessages)
+
+// ViewMessages засчитывает просмотр постов канала. Сообщения не из канала, свои посты
+// и уже просмотренные пропускаются
+func (u *MessageUsecaseImplm) ViewMessages(ctx context.Context, user jwt.User, chatId uuid.UUID, messageIds []uuid.UUID) error {
+	if len(messageIds) == 0 || len(messageIds) > MaxViewMessages {
+		return ErrBadViews
+	}
+
+	role, err := u.chatRepository.GetUserRoleInChat(ctx, user.ID, chatId)
+	if err != nil {
+		return err
+	}
+
+	if role == NotInChat {
+		return &customerror.NoPermissionError{
+			Area: fmt.Sprintf("чат %v", chatId),
+			User: user.ID.String(),
+		}
+	}
+
+	_, err = u.messageRepository.AddMessageViews(ctx, user.ID, chatId, messageIds, time.Now())
+	return err
+}
+
+// trackViews засчитывает просмотр постов канала, которые пользователь получил в истории,
+// и учитывает его в отдаваемых счетчиках. Ошибка только логируется, чтобы не мешать отдать историю
+func (u *MessageUsecaseImplm) trackViews(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, messages []models.Message) {
+	// просмотры есть только у постов каналов, поэтому для остальных чатов запроса в базу не будет
+	ids := []uuid.UUID{}
+	for _, message := range messages {
+		if message.Views != nil && message.AuthorID != userId {
+			ids = append(ids, message.MessageId)
+		}
+	}
+
+	if len(ids) == 0 {
+		return
+	}
+
+	viewed, err := u.messageRepository.AddMessageViews(ctx, userId, chatId, ids, time.Now())
+	if err != nil {
+		logger.LoggerWithCtx(ctx, logger.Log).Errorf("не удалось засчитать просмотры в чате %v: %v", chatId, err)
+		return
+	}
+
+	viewedSet := make(map[uuid.UUID]struct{}, len(viewed))
+	for _, id := range viewed {
+		viewedSet[id] = struct{}{}
+	}
+
+	for i := range messages {
+		if _, ok := viewedSet[messages[i].MessageId]; ok {
+			*messages[i].Views++
+		}
+	}
+}
+
+// PublishViewUpdates рассылает изменившиеся счетчики просмотров: одно событие на чат
+// со всеми его постами вместо события на каждый просмотр. Возвращает количество постов
+func (u *MessageUsecaseImplm) PublishViewUpdates(ctx context.Context) (int, error) {
+	published := 0
+	for {
+		changed, err := u.messageRepository.TakeChangedViews(ctx, viewsBatchSize)
+		if err != nil {
+			return published, err
+		}
+
+		chats := []uuid.UUID{}
+		byChat := map[uuid.UUID][]socketUsecase.MessageViews{}
+		for _, views := range changed {
+			if _, ok := byChat[views.ChatId]; !ok {
+				chats = append(chats, views.ChatId)
+			}
+			byChat[views.ChatId] = append(byChat[views.ChatId], socketUsecase.MessageViews{
+				MessageId: views.MessageId,
+				Views:     views.Views,
+			})
+		}
+
+		for _, chatId := range chats {
+			u.publishIvent(ctx, socketUsecase.MessageEvent{
+				Action: socketUsecase.ViewsUpdated,
+				Message: socketUsecase.Message{
+					ChatId: chatId,
+					Views:  byChat[chatId],
+				},
+			})
+		}
+		published += len(changed)
+
+		if len(changed) < viewsBatchSize {
+			return published, nil
+		}
+	}
+}
@@ -2,6 +2,8 @@ package usecase
 
 import (
 	"context"
+	"io"
+	"mime/multipart"
 
 	auth "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
 	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
@@ -12,12 +14,69 @@ import (
 //go:generate mockgen -source=messages_usecase_interface.go -destination=mocks/mocks.go
 
 type MessageUsecase interface {
-	SendMessage(ctx context.Context, user auth.User, chatId uuid.UUID, message models.Message) error
+	// SendMessage повторная отправка с тем же nonce вернет уже сохраненное сообщение
+	SendMessage(ctx context.Context, user auth.User, chatId uuid.UUID, message models.Message) (models.Message, error)
 	DeleteMessage(ctx context.Context, user auth.User, messageId uuid.UUID) error
+	HideMessage(ctx context.Context, user auth.User, messageId uuid.UUID) error
+	// DeleteMessages и HideMessages не прерываются на сообщениях без доступа, а отмечают их в результатах
+	DeleteMessages(ctx context.Context, user auth.User, messageIds []uuid.UUID) (models.BulkDeleteDTO, error)
+	HideMessages(ctx context.Context, user auth.User, messageIds []uuid.UUID) (models.BulkDeleteDTO, error)
 	UpdateMessage(ctx context.Context, user auth.User, messageId uuid.UUID, message models.Message) error
+	ForwardMessages(ctx context.Context, user auth.User, input models.ForwardMessagesInput) (models.MessagesArrayDTO, error)
 
-	SearchMessagesWithQuery(ctx context.Context, user auth.User, chatId uuid.UUID, searchQuery string) (models.MessagesArrayDTO, error)
-	GetMessagesWithPage(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, lastMessageId uuid.UUID) (models.MessagesArrayDTO, error)
+	SendPoll(ctx context.Context, user auth.User, chatId uuid.UUID, input models.PollInput) error
+	// VotePoll пустой список вариантов отзывает голос. Возвращает результаты для проголосовавшего
+	VotePoll(ctx context.Context, user auth.User, messageId uuid.UUID, input models.PollVoteInput) (models.Poll, error)
 
-	GetFirstMessages(ctx context.Context, chatId uuid.UUID) (models.MessagesArrayDTO, error)
+	AddReaction(ctx context.Context, user auth.User, messageId uuid.UUID, emoji string) error
+	DeleteReaction(ctx context.Context, user auth.User, messageId uuid.UUID, emoji string) error
+	GetAllowedReactions(ctx context.Context, user auth.User, chatId uuid.UUID) (models.AllowedReactionsDTO, error)
+	SetAllowedReactions(ctx context.Context, user auth.User, chatId uuid.UUID, input models.AllowedReactionsDTO) error
+
+	// GetMentions before == nil - первая страница, limit <= 0 - размер страницы по умолчанию
+	GetMentions(ctx context.Context, user auth.User, before *uuid.UUID, unreadOnly bool, limit int) (models.MentionsDTO, error)
+
+	GetMessageHistory(ctx context.Context, user auth.User, messageId uuid.UUID) (models.MessageHistoryDTO, error)
+	GetHistorySettings(ctx context.Context, user auth.User, chatId uuid.UUID) (models.HistorySettingsDTO, error)
+	SetHistorySettings(ctx context.Context, user auth.User, chatId uuid.UUID, input models.HistorySettingsDTO) error
+	GetMessageTTL(ctx context.Context, user auth.User, chatId uuid.UUID) (models.MessageTTLDTO, error)
+	SetMessageTTL(ctx context.Context, user auth.User, chatId uuid.UUID, input models.MessageTTLDTO) error
+
+	// SearchMessagesWithQuery пустой cursor - первая страница, limit <= 0 - размер страницы по умолчанию
+	SearchMessagesWithQuery(ctx context.Context, user auth.User, chatId uuid.UUID, searchQuery string, cursor string, limit int) (models.SearchMessagesDTO, error)
+	// SearchAllMessages пустой cursor - первая страница, limit <= 0 - размер страницы по умолчанию
+	SearchAllMessages(ctx context.Context, user auth.User, filter models.GlobalSearchFilter, cursor string, limit int) (models.GlobalSearchDTO, error)
+	// GetMessagesWithPage страница старше (older) или новее anchorId. limit <= 0 - размер страницы по умолчанию
+	GetMessagesWithPage(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, anchorId uuid.UUID, older bool, limit int) (models.MessagesPageDTO, error)
+	GetMessagesAround(ctx context.Context, userId uuid.UUID, chatId uuid.UUID, messageId uuid.UUID, limit int) (models.MessagesPageDTO, error)
+
+	GetFirstMessages(ctx context.Context, userId uuid.UUID, chatId uuid.UUID) (models.MessagesArrayDTO, error)
+
+	ScheduleMessage(ctx context.Context, user auth.User, chatId uuid.UUID, input models.ScheduledMessageInput) (models.ScheduledMessage, error)
+	GetScheduledMessages(ctx context.Context, user auth.User, chatId uuid.UUID) (models.ScheduledMessagesDTO, error)
+	UpdateScheduledMessage(ctx context.Context, user auth.User, id uuid.UUID, input models.ScheduledMessageUpdate) (models.ScheduledMessage, error)
+	CancelScheduledMessage(ctx context.Context, user auth.User, id uuid.UUID) error
+	// DispatchScheduledMessages отправляет отложенные сообщения, время которых наступило
+	DispatchScheduledMessages(ctx context.Context) (int, error)
+	// DeleteExpiredMessages удаляет исчезающие сообщения, время которых вышло
+	DeleteExpiredMessages(ctx context.Context) (int, error)
+
+	// PrepareExport большую выгрузку ставит в очередь и возвращает задачу, для небольшой возвращает nil
+	PrepareExport(ctx context.Context, user auth.User, chatId uuid.UUID, input models.ExportInput) (*models.ChatExport, error)
+	WriteExport(ctx context.Context, user auth.User, chatId uuid.UUID, input models.ExportInput, w io.Writer) error
+	GetExport(ctx context.Context, user auth.User, exportId uuid.UUID) (models.ChatExport, error)
+	GetExportFile(ctx context.Context, user auth.User, exportId uuid.UUID) (models.ChatExport, error)
+	// RunPendingExports выполняет выгрузки из очереди
+	RunPendingExports(ctx context.Context) (int, error)
+
+	// StartImport сохраняет выгрузку Telegram и ставит импорт в очередь
+	StartImport(ctx context.Context, user auth.User, input models.ImportInput, source *multipart.FileHeader, media *multipart.FileHeader) (models.ChatImport, error)
+	GetImport(ctx context.Context, user auth.User, importId uuid.UUID) (models.ChatImport, error)
+	// RunPendingImports выполняет импорты из очереди
+	RunPendingImports(ctx context.Context) (int, error)
+
+	// ViewMessages засчитывает просмотр постов канала
+	ViewMessages(ctx context.Context, user auth.User, chatId uuid.UUID, messageIds []uuid.UUID) error
+	// PublishViewUpdates рассылает изменившиеся счетчики просмотров
+	PublishViewUpdates(ctx context.Context) (int, error)
 }
@@ -6,6 +6,8 @@ package mock_usecase
 
 import (
 	context "context"
+	io "io"
+	multipart "mime/multipart"
 	reflect "reflect"
 
 	models "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
@@ -37,6 +39,49 @@ func (m *MockMessageUsecase) EXPECT() *MockMessageUsecaseMockRecorder {
 	return m.recorder
 }
 
+// AddReaction mocks base method.
+func (m *MockMessageUsecase) AddReaction(ctx context.Context, user models.User, messageId uuid.UUID, emoji string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddReaction", ctx, user, messageId, emoji)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddReaction indicates an expected call of AddReaction.
+func (mr *MockMessageUsecaseMockRecorder) AddReaction(ctx, user, messageId, emoji interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddReaction", reflect.TypeOf((*MockMessageUsecase)(nil).AddReaction), ctx, user, messageId, emoji)
+}
+
+// CancelScheduledMessage mocks base method.
+func (m *MockMessageUsecase) CancelScheduledMessage(ctx context.Context, user models.User, id uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelScheduledMessage", ctx, user, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelScheduledMessage indicates an expected call of CancelScheduledMessage.
+func (mr *MockMessageUsecaseMockRecorder) CancelScheduledMessage(ctx, user, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelScheduledMessage", reflect.TypeOf((*MockMessageUsecase)(nil).CancelScheduledMessage), ctx, user, id)
+}
+
+// DeleteExpiredMessages mocks base method.
+func (m *MockMessageUsecase) DeleteExpiredMessages(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredMessages", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredMessages indicates an expected call of DeleteExpiredMessages.
+func (mr *MockMessageUsecaseMockRecorder) DeleteExpiredMessages(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredMessages", reflect.TypeOf((*MockMessageUsecase)(nil).DeleteExpiredMessages), ctx)
+}
+
 // DeleteMessage mocks base method.
 func (m *MockMessageUsecase) DeleteMessage(ctx context.Context, user models.User, messageId uuid.UUID) error {
 	m.ctrl.T.Helper()
@@ -51,57 +96,386 @@ func (mr *MockMessageUsecaseMockRecorder) DeleteMessage(ctx, user, messageId int
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMessage", reflect.TypeOf((*MockMessageUsecase)(nil).DeleteMessage), ctx, user, messageId)
 }
 
+// DeleteMessages mocks base method.
+func (m *MockMessageUsecase) DeleteMessages(ctx context.Context, user models.User, messageIds []uuid.UUID) (models0.BulkDeleteDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteMessages", ctx, user, messageIds)
+	ret0, _ := ret[0].(models0.BulkDeleteDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteMessages indicates an expected call of DeleteMessages.
+func (mr *MockMessageUsecaseMockRecorder) DeleteMessages(ctx, user, messageIds interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMessages", reflect.TypeOf((*MockMessageUsecase)(nil).DeleteMessages), ctx, user, messageIds)
+}
+
+// DeleteReaction mocks base method.
+func (m *MockMessageUsecase) DeleteReaction(ctx context.Context, user models.User, messageId uuid.UUID, emoji string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteReaction", ctx, user, messageId, emoji)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteReaction indicates an expected call of DeleteReaction.
+func (mr *MockMessageUsecaseMockRecorder) DeleteReaction(ctx, user, messageId, emoji interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteReaction", reflect.TypeOf((*MockMessageUsecase)(nil).DeleteReaction), ctx, user, messageId, emoji)
+}
+
+// DispatchScheduledMessages mocks base method.
+func (m *MockMessageUsecase) DispatchScheduledMessages(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DispatchScheduledMessages", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DispatchScheduledMessages indicates an expected call of DispatchScheduledMessages.
+func (mr *MockMessageUsecaseMockRecorder) DispatchScheduledMessages(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DispatchScheduledMessages", reflect.TypeOf((*MockMessageUsecase)(nil).DispatchScheduledMessages), ctx)
+}
+
+// ForwardMessages mocks base method.
+func (m *MockMessageUsecase) ForwardMessages(ctx context.Context, user models.User, input models0.ForwardMessagesInput) (models0.MessagesArrayDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForwardMessages", ctx, user, input)
+	ret0, _ := ret[0].(models0.MessagesArrayDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ForwardMessages indicates an expected call of ForwardMessages.
+func (mr *MockMessageUsecaseMockRecorder) ForwardMessages(ctx, user, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForwardMessages", reflect.TypeOf((*MockMessageUsecase)(nil).ForwardMessages), ctx, user, input)
+}
+
+// GetAllowedReactions mocks base method.
+func (m *MockMessageUsecase) GetAllowedReactions(ctx context.Context, user models.User, chatId uuid.UUID) (models0.AllowedReactionsDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllowedReactions", ctx, user, chatId)
+	ret0, _ := ret[0].(models0.AllowedReactionsDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllowedReactions indicates an expected call of GetAllowedReactions.
+func (mr *MockMessageUsecaseMockRecorder) GetAllowedReactions(ctx, user, chatId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllowedReactions", reflect.TypeOf((*MockMessageUsecase)(nil).GetAllowedReactions), ctx, user, chatId)
+}
+
+// GetExport mocks base method.
+func (m *MockMessageUsecase) GetExport(ctx context.Context, user models.User, exportId uuid.UUID) (models0.ChatExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExport", ctx, user, exportId)
+	ret0, _ := ret[0].(models0.ChatExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExport indicates an expected call of GetExport.
+func (mr *MockMessageUsecaseMockRecorder) GetExport(ctx, user, exportId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExport", reflect.TypeOf((*MockMessageUsecase)(nil).GetExport), ctx, user, exportId)
+}
+
+// GetExportFile mocks base method.
+func (m *MockMessageUsecase) GetExportFile(ctx context.Context, user models.User, exportId uuid.UUID) (models0.ChatExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExportFile", ctx, user, exportId)
+	ret0, _ := ret[0].(models0.ChatExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExportFile indicates an expected call of GetExportFile.
+func (mr *MockMessageUsecaseMockRecorder) GetExportFile(ctx, user, exportId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExportFile", reflect.TypeOf((*MockMessageUsecase)(nil).GetExportFile), ctx, user, exportId)
+}
+
 // GetFirstMessages mocks base method.
-func (m *MockMessageUsecase) GetFirstMessages(ctx context.Context, chatId uuid.UUID) (models0.MessagesArrayDTO, error) {
+func (m *MockMessageUsecase) GetFirstMessages(ctx context.Context, userId, chatId uuid.UUID) (models0.MessagesArrayDTO, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetFirstMessages", ctx, chatId)
+	ret := m.ctrl.Call(m, "GetFirstMessages", ctx, userId, chatId)
 	ret0, _ := ret[0].(models0.MessagesArrayDTO)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetFirstMessages indicates an expected call of GetFirstMessages.
-func (mr *MockMessageUsecaseMockRecorder) GetFirstMessages(ctx, chatId interface{}) *gomock.Call {
+func (mr *MockMessageUsecaseMockRecorder) GetFirstMessages(ctx, userId, chatId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFirstMessages", reflect.TypeOf((*MockMessageUsecase)(nil).GetFirstMessages), ctx, userId, chatId)
+}
+
+// GetHistorySettings mocks base method.
+func (m *MockMessageUsecase) GetHistorySettings(ctx context.Context, user models.User, chatId uuid.UUID) (models0.HistorySettingsDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHistorySettings", ctx, user, chatId)
+	ret0, _ := ret[0].(models0.HistorySettingsDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHistorySettings indicates an expected call of GetHistorySettings.
+func (mr *MockMessageUsecaseMockRecorder) GetHistorySettings(ctx, user, chatId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHistorySettings", reflect.TypeOf((*MockMessageUsecase)(nil).GetHistorySettings), ctx, user, chatId)
+}
+
+// GetImport mocks base method.
+func (m *MockMessageUsecase) GetImport(ctx context.Context, user models.User, importId uuid.UUID) (models0.ChatImport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImport", ctx, user, importId)
+	ret0, _ := ret[0].(models0.ChatImport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImport indicates an expected call of GetImport.
+func (mr *MockMessageUsecaseMockRecorder) GetImport(ctx, user, importId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImport", reflect.TypeOf((*MockMessageUsecase)(nil).GetImport), ctx, user, importId)
+}
+
+// GetMentions mocks base method.
+func (m *MockMessageUsecase) GetMentions(ctx context.Context, user models.User, before *uuid.UUID, unreadOnly bool, limit int) (models0.MentionsDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMentions", ctx, user, before, unreadOnly, limit)
+	ret0, _ := ret[0].(models0.MentionsDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMentions indicates an expected call of GetMentions.
+func (mr *MockMessageUsecaseMockRecorder) GetMentions(ctx, user, before, unreadOnly, limit interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFirstMessages", reflect.TypeOf((*MockMessageUsecase)(nil).GetFirstMessages), ctx, chatId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMentions", reflect.TypeOf((*MockMessageUsecase)(nil).GetMentions), ctx, user, before, unreadOnly, limit)
+}
+
+// GetMessageHistory mocks base method.
+func (m *MockMessageUsecase) GetMessageHistory(ctx context.Context, user models.User, messageId uuid.UUID) (models0.MessageHistoryDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMessageHistory", ctx, user, messageId)
+	ret0, _ := ret[0].(models0.MessageHistoryDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMessageHistory indicates an expected call of GetMessageHistory.
+func (mr *MockMessageUsecaseMockRecorder) GetMessageHistory(ctx, user, messageId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessageHistory", reflect.TypeOf((*MockMessageUsecase)(nil).GetMessageHistory), ctx, user, messageId)
+}
+
+// GetMessageTTL mocks base method.
+func (m *MockMessageUsecase) GetMessageTTL(ctx context.Context, user models.User, chatId uuid.UUID) (models0.MessageTTLDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMessageTTL", ctx, user, chatId)
+	ret0, _ := ret[0].(models0.MessageTTLDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMessageTTL indicates an expected call of GetMessageTTL.
+func (mr *MockMessageUsecaseMockRecorder) GetMessageTTL(ctx, user, chatId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessageTTL", reflect.TypeOf((*MockMessageUsecase)(nil).GetMessageTTL), ctx, user, chatId)
+}
+
+// GetMessagesAround mocks base method.
+func (m *MockMessageUsecase) GetMessagesAround(ctx context.Context, userId, chatId, messageId uuid.UUID, limit int) (models0.MessagesPageDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMessagesAround", ctx, userId, chatId, messageId, limit)
+	ret0, _ := ret[0].(models0.MessagesPageDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMessagesAround indicates an expected call of GetMessagesAround.
+func (mr *MockMessageUsecaseMockRecorder) GetMessagesAround(ctx, userId, chatId, messageId, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessagesAround", reflect.TypeOf((*MockMessageUsecase)(nil).GetMessagesAround), ctx, userId, chatId, messageId, limit)
 }
 
 // GetMessagesWithPage mocks base method.
-func (m *MockMessageUsecase) GetMessagesWithPage(ctx context.Context, userId, chatId, lastMessageId uuid.UUID) (models0.MessagesArrayDTO, error) {
+func (m *MockMessageUsecase) GetMessagesWithPage(ctx context.Context, userId, chatId, anchorId uuid.UUID, older bool, limit int) (models0.MessagesPageDTO, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetMessagesWithPage", ctx, userId, chatId, lastMessageId)
-	ret0, _ := ret[0].(models0.MessagesArrayDTO)
+	ret := m.ctrl.Call(m, "GetMessagesWithPage", ctx, userId, chatId, anchorId, older, limit)
+	ret0, _ := ret[0].(models0.MessagesPageDTO)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetMessagesWithPage indicates an expected call of GetMessagesWithPage.
-func (mr *MockMessageUsecaseMockRecorder) GetMessagesWithPage(ctx, userId, chatId, lastMessageId interface{}) *gomock.Call {
+func (mr *MockMessageUsecaseMockRecorder) GetMessagesWithPage(ctx, userId, chatId, anchorId, older, limit interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessagesWithPage", reflect.TypeOf((*MockMessageUsecase)(nil).GetMessagesWithPage), ctx, userId, chatId, lastMessageId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessagesWithPage", reflect.TypeOf((*MockMessageUsecase)(nil).GetMessagesWithPage), ctx, userId, chatId, anchorId, older, limit)
+}
+
+// GetScheduledMessages mocks base method.
+func (m *MockMessageUsecase) GetScheduledMessages(ctx context.Context, user models.User, chatId uuid.UUID) (models0.ScheduledMessagesDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetScheduledMessages", ctx, user, chatId)
+	ret0, _ := ret[0].(models0.ScheduledMessagesDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetScheduledMessages indicates an expected call of GetScheduledMessages.
+func (mr *MockMessageUsecaseMockRecorder) GetScheduledMessages(ctx, user, chatId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScheduledMessages", reflect.TypeOf((*MockMessageUsecase)(nil).GetScheduledMessages), ctx, user, chatId)
+}
+
+// HideMessage mocks base method.
+func (m *MockMessageUsecase) HideMessage(ctx context.Context, user models.User, messageId uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HideMessage", ctx, user, messageId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HideMessage indicates an expected call of HideMessage.
+func (mr *MockMessageUsecaseMockRecorder) HideMessage(ctx, user, messageId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HideMessage", reflect.TypeOf((*MockMessageUsecase)(nil).HideMessage), ctx, user, messageId)
+}
+
+// HideMessages mocks base method.
+func (m *MockMessageUsecase) HideMessages(ctx context.Context, user models.User, messageIds []uuid.UUID) (models0.BulkDeleteDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HideMessages", ctx, user, messageIds)
+	ret0, _ := ret[0].(models0.BulkDeleteDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HideMessages indicates an expected call of HideMessages.
+func (mr *MockMessageUsecaseMockRecorder) HideMessages(ctx, user, messageIds interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HideMessages", reflect.TypeOf((*MockMessageUsecase)(nil).HideMessages), ctx, user, messageIds)
+}
+
+// PrepareExport mocks base method.
+func (m *MockMessageUsecase) PrepareExport(ctx context.Context, user models.User, chatId uuid.UUID, input models0.ExportInput) (*models0.ChatExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PrepareExport", ctx, user, chatId, input)
+	ret0, _ := ret[0].(*models0.ChatExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PrepareExport indicates an expected call of PrepareExport.
+func (mr *MockMessageUsecaseMockRecorder) PrepareExport(ctx, user, chatId, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PrepareExport", reflect.TypeOf((*MockMessageUsecase)(nil).PrepareExport), ctx, user, chatId, input)
+}
+
+// PublishViewUpdates mocks base method.
+func (m *MockMessageUsecase) PublishViewUpdates(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PublishViewUpdates", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PublishViewUpdates indicates an expected call of PublishViewUpdates.
+func (mr *MockMessageUsecaseMockRecorder) PublishViewUpdates(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublishViewUpdates", reflect.TypeOf((*MockMessageUsecase)(nil).PublishViewUpdates), ctx)
+}
+
+// RunPendingExports mocks base method.
+func (m *MockMessageUsecase) RunPendingExports(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunPendingExports", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RunPendingExports indicates an expected call of RunPendingExports.
+func (mr *MockMessageUsecaseMockRecorder) RunPendingExports(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunPendingExports", reflect.TypeOf((*MockMessageUsecase)(nil).RunPendingExports), ctx)
+}
+
+// RunPendingImports mocks base method.
+func (m *MockMessageUsecase) RunPendingImports(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunPendingImports", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RunPendingImports indicates an expected call of RunPendingImports.
+func (mr *MockMessageUsecaseMockRecorder) RunPendingImports(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunPendingImports", reflect.TypeOf((*MockMessageUsecase)(nil).RunPendingImports), ctx)
+}
+
+// ScheduleMessage mocks base method.
+func (m *MockMessageUsecase) ScheduleMessage(ctx context.Context, user models.User, chatId uuid.UUID, input models0.ScheduledMessageInput) (models0.ScheduledMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ScheduleMessage", ctx, user, chatId, input)
+	ret0, _ := ret[0].(models0.ScheduledMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ScheduleMessage indicates an expected call of ScheduleMessage.
+func (mr *MockMessageUsecaseMockRecorder) ScheduleMessage(ctx, user, chatId, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScheduleMessage", reflect.TypeOf((*MockMessageUsecase)(nil).ScheduleMessage), ctx, user, chatId, input)
+}
+
+// SearchAllMessages mocks base method.
+func (m *MockMessageUsecase) SearchAllMessages(ctx context.Context, user models.User, filter models0.GlobalSearchFilter, cursor string, limit int) (models0.GlobalSearchDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchAllMessages", ctx, user, filter, cursor, limit)
+	ret0, _ := ret[0].(models0.GlobalSearchDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchAllMessages indicates an expected call of SearchAllMessages.
+func (mr *MockMessageUsecaseMockRecorder) SearchAllMessages(ctx, user, filter, cursor, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchAllMessages", reflect.TypeOf((*MockMessageUsecase)(nil).SearchAllMessages), ctx, user, filter, cursor, limit)
 }
 
 // SearchMessagesWithQuery mocks base method.
-func (m *MockMessageUsecase) SearchMessagesWithQuery(ctx context.Context, user models.User, chatId uuid.UUID, searchQuery string) (models0.MessagesArrayDTO, error) {
+func (m *MockMessageUsecase) SearchMessagesWithQuery(ctx context.Context, user models.User, chatId uuid.UUID, searchQuery, cursor string, limit int) (models0.SearchMessagesDTO, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SearchMessagesWithQuery", ctx, user, chatId, searchQuery)
-	ret0, _ := ret[0].(models0.MessagesArrayDTO)
+	ret := m.ctrl.Call(m, "SearchMessagesWithQuery", ctx, user, chatId, searchQuery, cursor, limit)
+	ret0, _ := ret[0].(models0.SearchMessagesDTO)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SearchMessagesWithQuery indicates an expected call of SearchMessagesWithQuery.
-func (mr *MockMessageUsecaseMockRecorder) SearchMessagesWithQuery(ctx, user, chatId, searchQuery interface{}) *gomock.Call {
+func (mr *MockMessageUsecaseMockRecorder) SearchMessagesWithQuery(ctx, user, chatId, searchQuery, cursor, limit interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchMessagesWithQuery", reflect.TypeOf((*MockMessageUsecase)(nil).SearchMessagesWithQuery), ctx, user, chatId, searchQuery)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchMessagesWithQuery", reflect.TypeOf((*MockMessageUsecase)(nil).SearchMessagesWithQuery), ctx, user, chatId, searchQuery, cursor, limit)
 }
 
 // SendMessage mocks base method.
-func (m *MockMessageUsecase) SendMessage(ctx context.Context, user models.User, chatId uuid.UUID, message models0.Message) error {
+func (m *MockMessageUsecase) SendMessage(ctx context.Context, user models.User, chatId uuid.UUID, message models0.Message) (models0.Message, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "SendMessage", ctx, user, chatId, message)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(models0.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // SendMessage indicates an expected call of SendMessage.
@@ -110,6 +484,77 @@ func (mr *MockMessageUsecaseMockRecorder) SendMessage(ctx, user, chatId, message
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessage", reflect.TypeOf((*MockMessageUsecase)(nil).SendMessage), ctx, user, chatId, message)
 }
 
+// SendPoll mocks base method.
+func (m *MockMessageUsecase) SendPoll(ctx context.Context, user models.User, chatId uuid.UUID, input models0.PollInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendPoll", ctx, user, chatId, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendPoll indicates an expected call of SendPoll.
+func (mr *MockMessageUsecaseMockRecorder) SendPoll(ctx, user, chatId, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendPoll", reflect.TypeOf((*MockMessageUsecase)(nil).SendPoll), ctx, user, chatId, input)
+}
+
+// SetAllowedReactions mocks base method.
+func (m *MockMessageUsecase) SetAllowedReactions(ctx context.Context, user models.User, chatId uuid.UUID, input models0.AllowedReactionsDTO) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetAllowedReactions", ctx, user, chatId, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetAllowedReactions indicates an expected call of SetAllowedReactions.
+func (mr *MockMessageUsecaseMockRecorder) SetAllowedReactions(ctx, user, chatId, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAllowedReactions", reflect.TypeOf((*MockMessageUsecase)(nil).SetAllowedReactions), ctx, user, chatId, input)
+}
+
+// SetHistorySettings mocks base method.
+func (m *MockMessageUsecase) SetHistorySettings(ctx context.Context, user models.User, chatId uuid.UUID, input models0.HistorySettingsDTO) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetHistorySettings", ctx, user, chatId, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetHistorySettings indicates an expected call of SetHistorySettings.
+func (mr *MockMessageUsecaseMockRecorder) SetHistorySettings(ctx, user, chatId, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHistorySettings", reflect.TypeOf((*MockMessageUsecase)(nil).SetHistorySettings), ctx, user, chatId, input)
+}
+
+// SetMessageTTL mocks base method.
+func (m *MockMessageUsecase) SetMessageTTL(ctx context.Context, user models.User, chatId uuid.UUID, input models0.MessageTTLDTO) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMessageTTL", ctx, user, chatId, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetMessageTTL indicates an expected call of SetMessageTTL.
+func (mr *MockMessageUsecaseMockRecorder) SetMessageTTL(ctx, user, chatId, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMessageTTL", reflect.TypeOf((*MockMessageUsecase)(nil).SetMessageTTL), ctx, user, chatId, input)
+}
+
+// StartImport mocks base method.
+func (m *MockMessageUsecase) StartImport(ctx context.Context, user models.User, input models0.ImportInput, source, media *multipart.FileHeader) (models0.ChatImport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartImport", ctx, user, input, source, media)
+	ret0, _ := ret[0].(models0.ChatImport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartImport indicates an expected call of StartImport.
+func (mr *MockMessageUsecaseMockRecorder) StartImport(ctx, user, input, source, media interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartImport", reflect.TypeOf((*MockMessageUsecase)(nil).StartImport), ctx, user, input, source, media)
+}
+
 // UpdateMessage mocks base method.
 func (m *MockMessageUsecase) UpdateMessage(ctx context.Context, user models.User, messageId uuid.UUID, message models0.Message) error {
 	m.ctrl.T.Helper()
@@ -123,3 +568,61 @@ func (mr *MockMessageUsecaseMockRecorder) UpdateMessage(ctx, user, messageId, me
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMessage", reflect.TypeOf((*MockMessageUsecase)(nil).UpdateMessage), ctx, user, messageId, message)
 }
+
+// UpdateScheduledMessage mocks base method.
+func (m *MockMessageUsecase) UpdateScheduledMessage(ctx context.Context, user models.User, id uuid.UUID, input models0.ScheduledMessageUpdate) (models0.ScheduledMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateScheduledMessage", ctx, user, id, input)
+	ret0, _ := ret[0].(models0.ScheduledMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateScheduledMessage indicates an expected call of UpdateScheduledMessage.
+func (mr *MockMessageUsecaseMockRecorder) UpdateScheduledMessage(ctx, user, id, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateScheduledMessage", reflect.TypeOf((*MockMessageUsecase)(nil).UpdateScheduledMessage), ctx, user, id, input)
+}
+
+// ViewMessages mocks base method.
+func (m *MockMessageUsecase) ViewMessages(ctx context.Context, user models.User, chatId uuid.UUID, messageIds []uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ViewMessages", ctx, user, chatId, messageIds)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ViewMessages indicates an expected call of ViewMessages.
+func (mr *MockMessageUsecaseMockRecorder) ViewMessages(ctx, user, chatId, messageIds interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ViewMessages", reflect.TypeOf((*MockMessageUsecase)(nil).ViewMessages), ctx, user, chatId, messageIds)
+}
+
+// VotePoll mocks base method.
+func (m *MockMessageUsecase) VotePoll(ctx context.Context, user models.User, messageId uuid.UUID, input models0.PollVoteInput) (models0.Poll, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VotePoll", ctx, user, messageId, input)
+	ret0, _ := ret[0].(models0.Poll)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VotePoll indicates an expected call of VotePoll.
+func (mr *MockMessageUsecaseMockRecorder) VotePoll(ctx, user, messageId, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VotePoll", reflect.TypeOf((*MockMessageUsecase)(nil).VotePoll), ctx, user, messageId, input)
+}
+
+// WriteExport mocks base method.
+func (m *MockMessageUsecase) WriteExport(ctx context.Context, user models.User, chatId uuid.UUID, input models0.ExportInput, w io.Writer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteExport", ctx, user, chatId, input, w)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteExport indicates an expected call of WriteExport.
+func (mr *MockMessageUsecaseMockRecorder) WriteExport(ctx, user, chatId, input, w interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteExport", reflect.TypeOf((*MockMessageUsecase)(nil).WriteExport), ctx, user, chatId, input, w)
+}
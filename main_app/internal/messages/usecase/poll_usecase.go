@@ -0,0 +1,214 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	socketUsecase "github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/events"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	jwt "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	customerror "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/custom_error"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+
+	"github.com/google/uuid"
+)
+
+// ограничения на опросы
+const (
+	MinPollOptions      = 2
+	MaxPollOptions      = 10
+	maxPollQuestionRune = 300
+	maxPollOptionRunes  = 100
+)
+
+var (
+	ErrBadPoll         = errors.New("некорректный опрос")
+	ErrPollChatType    = errors.New("опросы можно создавать только в группах и каналах")
+	ErrNotPoll         = errors.New("сообщение не является опросом")
+	ErrPollClosed      = errors.New("опрос закрыт")
+	ErrBadPollVote     = errors.New("некорректный выбор вариантов")
+	ErrQuizRevote      = errors.New("в викторине нельзя изменить ответ")
+	ErrPollUpdate      = errors.New("опрос нельзя изменить")
+	ErrPollWithoutVote = errors.New("пользователь не голосовал в опросе")
+)
+
+func badPoll(reason string) error {
+	return fmt.Errorf("%w: %s", ErrBadPoll, reason)
+}
+
+// checkPollInput проверяет опрос и переводит его в формат сообщения
+func checkPollInput(input models.PollInput) (models.Poll, error) {
+	question := strings.TrimSpace(input.Question)
+	if question == "" || utf8.RuneCountInString(question) > maxPollQuestionRune {
+		return models.Poll{}, badPoll(fmt.Sprintf("вопрос должен быть от 1 до %d символов", maxPollQuestionRune))
+	}
+
+	if len(input.Options) < MinPollOptions || len(input.Options) > MaxPollOptions {
+		return models.Poll{}, badPoll(fmt.Sprintf("вариантов должно быть от %d до %d", MinPollOptions, MaxPollOptions))
+	}
+
+	options := make([]models.PollOption, 0, len(input.Options))
+	for _, text := range input.Options {
+		text = strings.TrimSpace(text)
+		if text == "" || utf8.RuneCountInString(text) > maxPollOptionRunes {
+			return models.Poll{}, badPoll(fmt.Sprintf("вариант должен быть от 1 до %d символов", maxPollOptionRunes))
+		}
+		options = append(options, models.PollOption{Text: text})
+	}
+
+	if input.IsQuiz {
+		if input.IsMultiple {
+			return models.Poll{}, badPoll("в викторине можно выбрать только один вариант")
+		}
+		if input.CorrectOption == nil || *input.CorrectOption < 0 || *input.CorrectOption >= len(options) {
+			return models.Poll{}, badPoll("у викторины должен быть правильный вариант")
+		}
+	} else if input.CorrectOption != nil {
+		return models.Poll{}, badPoll("правильный вариант бывает только у викторины")
+	}
+
+	if input.CloseAt != nil && !input.CloseAt.After(time.Now()) {
+		return models.Poll{}, badPoll("время закрытия должно быть в будущем")
+	}
+
+	poll := models.Poll{
+		Question:      question,
+		Options:       options,
+		IsMultiple:    input.IsMultiple,
+		IsAnonymous:   input.IsAnonymous,
+		IsQuiz:        input.IsQuiz,
+		CorrectOption: input.CorrectOption,
+	}
+	if input.CloseAt != nil {
+		closeAt := input.CloseAt.UTC()
+		poll.CloseAt = &closeAt
+	}
+
+	return poll, nil
+}
+
+// SendPoll опрос отправляется как сообщение, текст которого - вопрос
+func (u *MessageUsecaseImplm) SendPoll(ctx context.Context, user jwt.User, chatId uuid.UUID, input models.PollInput) error {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Infof("пользователь %v создает опрос в чате %v", user.ID, chatId)
+
+	poll, err := checkPollInput(input)
+	if err != nil {
+		return err
+	}
+
+	canWrite, err := u.canWriteInChat(ctx, user.ID, chatId)
+	if err != nil {
+		return err
+	}
+
+	if !canWrite {
+		return &customerror.NoPermissionError{
+			Area: fmt.Sprintf("чат %v", chatId),
+			User: user.ID.String(),
+		}
+	}
+
+	if err := u.checkPollChatType(ctx, chatId); err != nil {
+		return err
+	}
+
+	_, err = u.sendMessage(ctx, user, chatId, models.Message{
+		MessageId: uuid.New(),
+		Message:   poll.Question,
+		Poll:      &poll,
+	})
+	return err
+}
+
+// checkPollChatType опросы живут только в группах и каналах, в том числе пересланные
+func (u *MessageUsecaseImplm) checkPollChatType(ctx context.Context, chatId uuid.UUID) error {
+	chatType, err := u.chatRepository.GetChatType(ctx, chatId)
+	if err != nil {
+		return err
+	}
+
+	if chatType != group && chatType != channel {
+		return ErrPollChatType
+	}
+
+	return nil
+}
+
+// VotePoll заменяет голос пользователя. Пустой список вариантов отзывает голос
+func (u *MessageUsecaseImplm) VotePoll(ctx context.Context, user jwt.User, messageId uuid.UUID, input models.PollVoteInput) (models.Poll, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Infof("пользователь %v голосует в опросе %v: %v", user.ID, messageId, input.Options)
+
+	_, err := u.getMessageForMember(ctx, user, messageId)
+	if err != nil {
+		return models.Poll{}, err
+	}
+
+	poll, err := u.messageRepository.GetPoll(ctx, messageId)
+	if err != nil {
+		return models.Poll{}, err
+	}
+
+	if poll == nil {
+		return models.Poll{}, ErrNotPoll
+	}
+
+	if poll.IsClosed {
+		return models.Poll{}, ErrPollClosed
+	}
+
+	options := slices.Clone(input.Options)
+	slices.Sort(options)
+	options = slices.Compact(options)
+
+	if len(options) > 1 && !poll.IsMultiple {
+		return models.Poll{}, fmt.Errorf("%w: можно выбрать только один вариант", ErrBadPollVote)
+	}
+	for _, option := range options {
+		if option < 0 || option >= len(poll.Options) {
+			return models.Poll{}, fmt.Errorf("%w: нет варианта %d", ErrBadPollVote, option)
+		}
+	}
+
+	current, err := u.messageRepository.GetPollVotes(ctx, messageId, user.ID)
+	if err != nil {
+		return models.Poll{}, err
+	}
+
+	if len(options) == 0 && len(current) == 0 {
+		return models.Poll{}, ErrPollWithoutVote
+	}
+
+	if poll.IsQuiz && len(current) > 0 {
+		return models.Poll{}, ErrQuizRevote
+	}
+
+	err = u.messageRepository.SetPollVotes(ctx, messageId, user.ID, options)
+	if err != nil {
+		return models.Poll{}, err
+	}
+
+	return u.sendPollIvent(ctx, user, messageId)
+}
+
+// sendPollIvent отправляет в сокет новые результаты опроса и возвращает их для проголосовавшего
+func (u *MessageUsecaseImplm) sendPollIvent(ctx context.Context, user jwt.User, messageId uuid.UUID) (models.Poll, error) {
+	stored, err := u.messageRepository.GetMessageById(ctx, user.ID, messageId)
+	if err != nil {
+		return models.Poll{}, err
+	}
+
+	if stored.Poll == nil {
+		return models.Poll{}, ErrNotPoll
+	}
+
+	u.sendIvent(ctx, socketUsecase.PollUpdated, stored)
+
+	return *stored.Poll, nil
+}
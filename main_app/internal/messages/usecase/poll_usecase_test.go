@@ -0,0 +1,300 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	jwt "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	customerror "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/custom_error"
+	chatMockRepo "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/repository/mocks"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+	messagesMockRepo "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/repository/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckPollInput(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         models.PollInput
+		expectedError error
+	}{
+		{
+			name: "обычный опрос",
+			input: models.PollInput{
+				Question: " Куда идем? ",
+				Options:  []string{"В кино", "В театр"},
+			},
+		},
+		{
+			name: "викторина с правильным вариантом",
+			input: models.PollInput{
+				Question:      "2 + 2",
+				Options:       []string{"3", "4"},
+				IsQuiz:        true,
+				CorrectOption: ptr(1),
+			},
+		},
+		{
+			name: "пустой вопрос",
+			input: models.PollInput{
+				Question: "   ",
+				Options:  []string{"a", "b"},
+			},
+			expectedError: ErrBadPoll,
+		},
+		{
+			name: "слишком длинный вопрос",
+			input: models.PollInput{
+				Question: strings.Repeat("я", maxPollQuestionRune+1),
+				Options:  []string{"a", "b"},
+			},
+			expectedError: ErrBadPoll,
+		},
+		{
+			name: "один вариант",
+			input: models.PollInput{
+				Question: "?",
+				Options:  []string{"a"},
+			},
+			expectedError: ErrBadPoll,
+		},
+		{
+			name: "слишком много вариантов",
+			input: models.PollInput{
+				Question: "?",
+				Options:  strings.Split(strings.Repeat("a,", MaxPollOptions)+"a", ","),
+			},
+			expectedError: ErrBadPoll,
+		},
+		{
+			name: "пустой вариант",
+			input: models.PollInput{
+				Question: "?",
+				Options:  []string{"a", " "},
+			},
+			expectedError: ErrBadPoll,
+		},
+		{
+			name: "викторина с несколькими ответами",
+			input: models.PollInput{
+				Question:      "?",
+				Options:       []string{"a", "b"},
+				IsQuiz:        true,
+				IsMultiple:    true,
+				CorrectOption: ptr(0),
+			},
+			expectedError: ErrBadPoll,
+		},
+		{
+			name: "викторина без правильного варианта",
+			input: models.PollInput{
+				Question: "?",
+				Options:  []string{"a", "b"},
+				IsQuiz:   true,
+			},
+			expectedError: ErrBadPoll,
+		},
+		{
+			name: "правильный вариант вне списка",
+			input: models.PollInput{
+				Question:      "?",
+				Options:       []string{"a", "b"},
+				IsQuiz:        true,
+				CorrectOption: ptr(2),
+			},
+			expectedError: ErrBadPoll,
+		},
+		{
+			name: "правильный вариант не у викторины",
+			input: models.PollInput{
+				Question:      "?",
+				Options:       []string{"a", "b"},
+				CorrectOption: ptr(0),
+			},
+			expectedError: ErrBadPoll,
+		},
+		{
+			name: "время закрытия в прошлом",
+			input: models.PollInput{
+				Question: "?",
+				Options:  []string{"a", "b"},
+				CloseAt:  ptr(time.Now().Add(-time.Minute)),
+			},
+			expectedError: ErrBadPoll,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poll, err := checkPollInput(tt.input)
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			if tt.expectedError != nil {
+				return
+			}
+
+			assert.Equal(t, strings.TrimSpace(tt.input.Question), poll.Question)
+			assert.Len(t, poll.Options, len(tt.input.Options))
+		})
+	}
+}
+
+func TestVotePoll(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	messageRepo := messagesMockRepo.NewMockMessageRepository(ctrl)
+	chatRepo := chatMockRepo.NewMockChatRepository(ctrl)
+	usecase := &MessageUsecaseImplm{
+		messageRepository: messageRepo,
+		chatRepository:    chatRepo,
+	}
+
+	user := jwt.User{ID: uuid.New()}
+	messageId := uuid.New()
+	chatId := uuid.New()
+
+	quiz := &models.Poll{
+		Options:       []models.PollOption{{Text: "a"}, {Text: "b"}},
+		IsQuiz:        true,
+		CorrectOption: ptr(0),
+	}
+	single := &models.Poll{
+		Options: []models.PollOption{{Text: "a"}, {Text: "b"}},
+	}
+
+	// сообщение найдено и пользователь состоит в чате
+	expectMember := func() {
+		messageRepo.EXPECT().GetMessageById(gomock.Any(), user.ID, messageId).
+			Return(models.Message{MessageId: messageId, ChatId: chatId}, nil)
+		chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return(none, nil)
+	}
+
+	tests := []struct {
+		name          string
+		options       []int
+		prepareMock   func()
+		expectedError error
+		// ожидается ошибка доступа customerror.NoPermissionError
+		noPermission bool
+	}{
+		{
+			name:    "не опрос",
+			options: []int{0},
+			prepareMock: func() {
+				expectMember()
+				messageRepo.EXPECT().GetPoll(gomock.Any(), messageId).Return(nil, nil)
+			},
+			expectedError: ErrNotPoll,
+		},
+		{
+			name:    "опрос закрыт",
+			options: []int{0},
+			prepareMock: func() {
+				expectMember()
+				messageRepo.EXPECT().GetPoll(gomock.Any(), messageId).Return(&models.Poll{
+					Options:  single.Options,
+					IsClosed: true,
+				}, nil)
+			},
+			expectedError: ErrPollClosed,
+		},
+		{
+			name:    "несколько вариантов в опросе с одним ответом",
+			options: []int{0, 1},
+			prepareMock: func() {
+				expectMember()
+				messageRepo.EXPECT().GetPoll(gomock.Any(), messageId).Return(single, nil)
+			},
+			expectedError: ErrBadPollVote,
+		},
+		{
+			name:    "повтор одного варианта считается одним голосом",
+			options: []int{1, 1},
+			prepareMock: func() {
+				expectMember()
+				messageRepo.EXPECT().GetPoll(gomock.Any(), messageId).Return(single, nil)
+				messageRepo.EXPECT().GetPollVotes(gomock.Any(), messageId, user.ID).Return(nil, nil)
+				messageRepo.EXPECT().SetPollVotes(gomock.Any(), messageId, user.ID, []int{1}).Return(errRepo)
+			},
+			expectedError: errRepo,
+		},
+		{
+			name:    "несуществующий вариант",
+			options: []int{2},
+			prepareMock: func() {
+				expectMember()
+				messageRepo.EXPECT().GetPoll(gomock.Any(), messageId).Return(single, nil)
+			},
+			expectedError: ErrBadPollVote,
+		},
+		{
+			name:    "отзыв голоса, которого нет",
+			options: []int{},
+			prepareMock: func() {
+				expectMember()
+				messageRepo.EXPECT().GetPoll(gomock.Any(), messageId).Return(single, nil)
+				messageRepo.EXPECT().GetPollVotes(gomock.Any(), messageId, user.ID).Return(nil, nil)
+			},
+			expectedError: ErrPollWithoutVote,
+		},
+		{
+			name:    "повторный ответ в викторине",
+			options: []int{1},
+			prepareMock: func() {
+				expectMember()
+				messageRepo.EXPECT().GetPoll(gomock.Any(), messageId).Return(quiz, nil)
+				messageRepo.EXPECT().GetPollVotes(gomock.Any(), messageId, user.ID).Return([]int{0}, nil)
+			},
+			expectedError: ErrQuizRevote,
+		},
+		{
+			name:    "отзыв ответа в викторине",
+			options: []int{},
+			prepareMock: func() {
+				expectMember()
+				messageRepo.EXPECT().GetPoll(gomock.Any(), messageId).Return(quiz, nil)
+				messageRepo.EXPECT().GetPollVotes(gomock.Any(), messageId, user.ID).Return([]int{0}, nil)
+			},
+			expectedError: ErrQuizRevote,
+		},
+		{
+			name:    "первый ответ в викторине сохраняется",
+			options: []int{1},
+			prepareMock: func() {
+				expectMember()
+				messageRepo.EXPECT().GetPoll(gomock.Any(), messageId).Return(quiz, nil)
+				messageRepo.EXPECT().GetPollVotes(gomock.Any(), messageId, user.ID).Return(nil, nil)
+				messageRepo.EXPECT().SetPollVotes(gomock.Any(), messageId, user.ID, []int{1}).Return(errRepo)
+			},
+			expectedError: errRepo,
+		},
+		{
+			name:    "пользователь не в чате",
+			options: []int{0},
+			prepareMock: func() {
+				messageRepo.EXPECT().GetMessageById(gomock.Any(), user.ID, messageId).
+					Return(models.Message{MessageId: messageId, ChatId: chatId}, nil)
+				chatRepo.EXPECT().GetUserRoleInChat(gomock.Any(), user.ID, chatId).Return(NotInChat, nil)
+			},
+			noPermission: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.prepareMock()
+
+			_, err := usecase.VotePoll(context.Background(), user, messageId, models.PollVoteInput{Options: tt.options})
+
+			if tt.noPermission {
+				assert.True(t, customerror.IsNoPermission(err), "ожидалась ошибка доступа, получено: %v", err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.expectedError)
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	jwt "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/auth/models"
+	customerror "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/chats/custom_error"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/repository"
+
+	"github.com/google/uuid"
+)
+
+// сколько отложенных сообщений диспетчер берет за один проход
+const dispatchBatchSize = 100
+
+var (
+	ErrScheduleInPast         = errors.New("время отправки должно быть в будущем")
+	ErrScheduledNotFound      = errors.New("отложенное сообщение не найдено")
+	ErrEmptyScheduledUpdate   = errors.New("не указано ни одного поля для изменения")
+	ErrScheduledStickerUpdate = errors.New("текст отложенного стикера нельзя изменить")
+)
+
+func (u *MessageUsecaseImplm) ScheduleMessage(ctx context.Context, user jwt.User, chatId uuid.UUID, input models.ScheduledMessageInput) (models.ScheduledMessage, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Infof("пользователь %v планирует сообщение в чат %v на %v", user.ID, chatId, input.SendAt)
+
+	canWrite, err := u.canWriteInChat(ctx, user.ID, chatId)
+	if err != nil {
+		return models.ScheduledMessage{}, err
+	}
+
+	if !canWrite {
+		return models.ScheduledMessage{}, &customerror.NoPermissionError{
+			Area: fmt.Sprintf("чат %v", chatId),
+			User: user.ID.String(),
+		}
+	}
+
+	if !input.SendAt.After(time.Now()) {
+		return models.ScheduledMessage{}, ErrScheduleInPast
+	}
+
+	err = u.checkMessageContent(ctx, user, chatId, input.Message, input.Sticker, len(input.Files), input.ReplyTo)
+	if err != nil {
+		return models.ScheduledMessage{}, err
+	}
+
+	entities, err := prepareEntities(input.Message, input.Entities)
+	if err != nil {
+		return models.ScheduledMessage{}, err
+	}
+
+	payloads, err := u.savePayloads(ctx, input.Files)
+	if err != nil {
+		log.Errorf("не удалось сохранить вложения отложенного сообщения: %v", err)
+		return models.ScheduledMessage{}, err
+	}
+
+	scheduled := models.ScheduledMessage{
+		Id:        uuid.New(),
+		ChatId:    chatId,
+		AuthorID:  user.ID,
+		Message:   input.Message,
+		Entities:  entities,
+		Sticker:   input.Sticker,
+		ReplyTo:   input.ReplyTo,
+		Payloads:  payloads,
+		SendAt:    input.SendAt.UTC(),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	err = u.messageRepository.AddScheduledMessage(ctx, scheduled)
+	if err != nil {
+		log.Errorf("не удалось сохранить отложенное сообщение: %v", err)
+		u.removePayloads(ctx, payloads)
+		return models.ScheduledMessage{}, err
+	}
+
+	return scheduled, nil
+}
+
+// GetScheduledMessages владелец и админы видят все отложенные сообщения чата, остальные - только свои
+func (u *MessageUsecaseImplm) GetScheduledMessages(ctx context.Context, user jwt.User, chatId uuid.UUID) (models.ScheduledMessagesDTO, error) {
+	role, err := u.chatRepository.GetUserRoleInChat(ctx, user.ID, chatId)
+	if err != nil {
+		return models.ScheduledMessagesDTO{}, err
+	}
+
+	if role == NotInChat {
+		return models.ScheduledMessagesDTO{}, &customerror.NoPermissionError{
+			Area: fmt.Sprintf("чат %v", chatId),
+			User: user.ID.String(),
+		}
+	}
+
+	var authorId *uuid.UUID
+	if role != owner && role != admin {
+		authorId = &user.ID
+	}
+
+	messages, err := u.messageRepository.GetScheduledMessages(ctx, chatId, authorId)
+	if err != nil {
+		return models.ScheduledMessagesDTO{}, err
+	}
+
+	return models.ScheduledMessagesDTO{
+		Messages: messages,
+	}, nil
+}
+
+func (u *MessageUsecaseImplm) UpdateScheduledMessage(ctx context.Context, user jwt.User, id uuid.UUID, input models.ScheduledMessageUpdate) (models.ScheduledMessage, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Infof("пользователь %v изменяет отложенное сообщение %v", user.ID, id)
+
+	if input.Message == nil && input.SendAt == nil {
+		return models.ScheduledMessage{}, ErrEmptyScheduledUpdate
+	}
+
+	scheduled, err := u.messageRepository.GetScheduledMessage(ctx, id)
+	if err != nil {
+		return models.ScheduledMessage{}, err
+	}
+
+	if scheduled.Id == uuid.Nil {
+		return models.ScheduledMessage{}, ErrScheduledNotFound
+	}
+
+	if scheduled.AuthorID != user.ID {
+		return models.ScheduledMessage{}, &customerror.NoPermissionError{
+			Area: fmt.Sprintf("отложенное сообщение %v принадлежит другому пользователю", id),
+			User: user.ID.String(),
+		}
+	}
+
+	if input.Message != nil {
+		if scheduled.Sticker != nil {
+			return models.ScheduledMessage{}, ErrScheduledStickerUpdate
+		}
+		if *input.Message == "" && len(scheduled.Payloads) == 0 {
+			return models.ScheduledMessage{}, ErrEmptyMessage
+		}
+
+		scheduled.Entities, err = prepareEntities(*input.Message, input.Entities)
+		if err != nil {
+			return models.ScheduledMessage{}, err
+		}
+		scheduled.Message = *input.Message
+	}
+
+	if input.SendAt != nil {
+		if !input.SendAt.After(time.Now()) {
+			return models.ScheduledMessage{}, ErrScheduleInPast
+		}
+		scheduled.SendAt = input.SendAt.UTC()
+	}
+
+	err = u.messageRepository.UpdateScheduledMessage(ctx, scheduled)
+	if err != nil {
+		log.Errorf("не удалось изменить отложенное сообщение %v: %v", id, err)
+		return models.ScheduledMessage{}, err
+	}
+
+	return scheduled, nil
+}
+
+// CancelScheduledMessage отменить может автор, а также владелец и админы чата
+func (u *MessageUsecaseImplm) CancelScheduledMessage(ctx context.Context, user jwt.User, id uuid.UUID) error {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	log.Infof("пользователь %v отменяет отложенное сообщение %v", user.ID, id)
+
+	scheduled, err := u.messageRepository.GetScheduledMessage(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if scheduled.Id == uuid.Nil {
+		return ErrScheduledNotFound
+	}
+
+	if scheduled.AuthorID != user.ID {
+		role, err := u.chatRepository.GetUserRoleInChat(ctx, user.ID, scheduled.ChatId)
+		if err != nil {
+			return err
+		}
+
+		if role != owner && role != admin {
+			return &customerror.NoPermissionError{
+				Area: fmt.Sprintf("отложенное сообщение %v", id),
+				User: user.ID.String(),
+			}
+		}
+	}
+
+	err = u.messageRepository.DeleteScheduledMessage(ctx, id)
+	if err != nil {
+		log.Errorf("не удалось удалить отложенное сообщение %v: %v", id, err)
+		return err
+	}
+
+	u.removePayloads(ctx, scheduled.Payloads)
+	return nil
+}
+
+// DispatchScheduledMessages отправляет отложенные сообщения, время которых наступило.
+// Возвращает количество отправленных сообщений
+func (u *MessageUsecaseImplm) DispatchScheduledMessages(ctx context.Context) (int, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+
+	sent := 0
+	for {
+		due, err := u.messageRepository.GetDueScheduledMessages(ctx, time.Now(), dispatchBatchSize)
+		if err != nil {
+			return sent, err
+		}
+
+		dispatched := 0
+		for _, scheduled := range due {
+			ok, err := u.dispatchScheduledMessage(ctx, scheduled)
+			if err != nil {
+				// временная ошибка, попробуем на следующем проходе
+				log.Errorf("не удалось отправить отложенное сообщение %v: %v", scheduled.Id, err)
+				continue
+			}
+
+			dispatched++
+			if ok {
+				sent++
+			}
+		}
+
+		// если ни одно сообщение не ушло, то повторять сейчас бессмысленно
+		if len(due) < dispatchBatchSize || dispatched == 0 {
+			return sent, nil
+		}
+	}
+}
+
+// dispatchScheduledMessage отправляет сообщение и удаляет его из отложенных.
+// Возвращает true, если сообщение было отправлено. Ошибка означает, что сообщение осталось в очереди
+func (u *MessageUsecaseImplm) dispatchScheduledMessage(ctx context.Context, scheduled models.ScheduledMessage) (bool, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+	author := jwt.User{ID: scheduled.AuthorID}
+
+	canWrite, err := u.canWriteInChat(ctx, author.ID, scheduled.ChatId)
+	if err != nil {
+		return false, err
+	}
+
+	if canWrite {
+		// id отложенного сообщения становится id сообщения, поэтому после перезапуска
+		// повторная вставка упадет на первичном ключе и сообщение не задвоится
+		_, err = u.sendMessage(ctx, author, scheduled.ChatId, models.Message{
+			MessageId: scheduled.Id,
+			Message:   scheduled.Message,
+			Entities:  scheduled.Entities,
+			Sticker:   scheduled.Sticker,
+			ReplyTo:   scheduled.ReplyTo,
+			Payloads:  scheduled.Payloads,
+		})
+	}
+
+	sent, discarded := false, false
+	switch {
+	case canWrite && err == nil:
+		log.Infof("отложенное сообщение %v отправлено в чат %v", scheduled.Id, scheduled.ChatId)
+		sent = true
+	case errors.Is(err, repository.ErrMessageExists):
+		log.Warnf("отложенное сообщение %v уже было отправлено", scheduled.Id)
+	case !canWrite, errors.Is(err, ErrEmptyMessage), errors.Is(err, ErrStickerWithText),
+		errors.Is(err, ErrTooManyPayloads), errors.Is(err, ErrReplyNotInChat),
+		errors.Is(err, ErrBadEntities), errors.Is(err, repository.ErrStickerNotFound):
+		// отправить сообщение уже не получится, поэтому выбрасываем его вместе с вложениями
+		log.Warnf("отложенное сообщение %v отброшено: права=%v, ошибка=%v", scheduled.Id, canWrite, err)
+		discarded = true
+	default:
+		return false, err
+	}
+
+	err = u.messageRepository.DeleteScheduledMessage(ctx, scheduled.Id)
+	if err != nil {
+		return false, err
+	}
+
+	if discarded {
+		u.removePayloads(ctx, scheduled.Payloads)
+	}
+
+	return sent, nil
+}
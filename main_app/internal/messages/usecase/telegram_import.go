@@ -0,0 +1,245 @@
+package usecase
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+)
+
+var ErrBadTelegramExport = errors.New("файл не похож на выгрузку одного чата из Telegram Desktop (result.json)")
+
+// telegramMessage сообщение из result.json Telegram Desktop. Нужные импорту поля
+type telegramMessage struct {
+	Id         int64  `json:"id"`
+	Type       string `json:"type"`
+	Date       string `json:"date"`
+	DateUnix   string `json:"date_unixtime"`
+	Edited     string `json:"edited"`
+	EditedUnix string `json:"edited_unixtime"`
+	// у удаленных аккаунтов имени нет
+	From   *string `json:"from"`
+	FromId string  `json:"from_id"`
+	// строка или массив из строк и фрагментов telegramTextPart
+	Text         json.RawMessage    `json:"text"`
+	TextEntities []telegramTextPart `json:"text_entities"`
+	ReplyTo      *int64             `json:"reply_to_message_id"`
+	// пути медиа относительно папки выгрузки
+	Photo    string `json:"photo"`
+	File     string `json:"file"`
+	FileName string `json:"file_name"`
+}
+
+type telegramTextPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	Href     string `json:"href"`
+	Language string `json:"language"`
+}
+
+// readTelegramExport потоково читает выгрузку одного чата: onName получает название чата,
+// onMessage - сообщения по одному. Вся выгрузка в память не загружается
+func readTelegramExport(r io.Reader, onName func(string), onMessage func(telegramMessage) error) error {
+	dec := json.NewDecoder(r)
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	hasMessages := false
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrBadTelegramExport, err)
+		}
+
+		switch token {
+		case "name":
+			var name *string
+			if err := dec.Decode(&name); err != nil {
+				return fmt.Errorf("%w: %v", ErrBadTelegramExport, err)
+			}
+			if name != nil {
+				onName(*name)
+			}
+		case "messages":
+			hasMessages = true
+			if err := expectDelim(dec, '['); err != nil {
+				return err
+			}
+			for dec.More() {
+				var message telegramMessage
+				if err := dec.Decode(&message); err != nil {
+					return fmt.Errorf("%w: %v", ErrBadTelegramExport, err)
+				}
+				if err := onMessage(message); err != nil {
+					return err
+				}
+			}
+			if err := expectDelim(dec, ']'); err != nil {
+				return err
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return fmt.Errorf("%w: %v", ErrBadTelegramExport, err)
+			}
+		}
+	}
+
+	if !hasMessages {
+		return ErrBadTelegramExport
+	}
+
+	return nil
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadTelegramExport, err)
+	}
+	if token != delim {
+		return ErrBadTelegramExport
+	}
+	return nil
+}
+
+// telegramTime в новых выгрузках есть время в unix, в старых - только локальное время без зоны
+func telegramTime(unix string, local string) (time.Time, error) {
+	if unix != "" {
+		seconds, err := strconv.ParseInt(unix, 10, 64)
+		if err == nil {
+			return time.Unix(seconds, 0).UTC(), nil
+		}
+	}
+
+	return time.ParseInLocation("2006-01-02T15:04:05", local, time.UTC)
+}
+
+// telegramText собирает текст и разметку сообщения. Разметку, которой нет в приложении, пропускает
+func telegramText(message telegramMessage) (string, []models.MessageEntity) {
+	parts := message.TextEntities
+	if len(parts) == 0 {
+		parts = parseTelegramText(message.Text)
+	}
+
+	var text strings.Builder
+	offset := 0
+	entities := []models.MessageEntity{}
+	for _, part := range parts {
+		text.WriteString(part.Text)
+		length := len(utf16.Encode([]rune(part.Text)))
+
+		entity := models.MessageEntity{
+			Offset: offset,
+			Length: length,
+		}
+		switch part.Type {
+		case "bold":
+			entity.Type = models.EntityBold
+		case "italic":
+			entity.Type = models.EntityItalic
+		case "code":
+			entity.Type = models.EntityCode
+		case "pre":
+			entity.Type = models.EntityPre
+			if part.Language != "" {
+				language := part.Language
+				entity.Language = &language
+			}
+		case "spoiler":
+			entity.Type = models.EntitySpoiler
+		case "text_link":
+			entity.Type = models.EntityLink
+			href := part.Href
+			entity.URL = &href
+		case "link":
+			if strings.HasPrefix(part.Text, "http://") || strings.HasPrefix(part.Text, "https://") {
+				entity.Type = models.EntityLink
+				href := part.Text
+				entity.URL = &href
+			}
+		}
+
+		if entity.Type != "" && length > 0 {
+			entities = append(entities, entity)
+		}
+		offset += length
+	}
+
+	return text.String(), entities
+}
+
+// parseTelegramText разбирает поле text старых выгрузок: строка или массив строк и фрагментов
+func parseTelegramText(raw json.RawMessage) []telegramTextPart {
+	var plain string
+	if err := json.Unmarshal(raw, &plain); err == nil {
+		return []telegramTextPart{{Type: "plain", Text: plain}}
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil
+	}
+
+	parts := make([]telegramTextPart, 0, len(items))
+	for _, item := range items {
+		var part telegramTextPart
+		if err := json.Unmarshal(item, &plain); err == nil {
+			part = telegramTextPart{Type: "plain", Text: plain}
+		} else if err := json.Unmarshal(item, &part); err != nil {
+			continue
+		}
+		parts = append(parts, part)
+	}
+
+	return parts
+}
+
+// telegramMedia путь к медиа сообщения и имя файла. Пустой путь - медиа нет
+func telegramMedia(message telegramMessage) (string, string) {
+	mediaPath := message.Photo
+	if mediaPath == "" {
+		mediaPath = message.File
+	}
+	if mediaPath == "" {
+		return "", ""
+	}
+
+	filename := message.FileName
+	if filename == "" {
+		filename = path.Base(mediaPath)
+	}
+
+	return mediaPath, filename
+}
+
+// mediaIndex файлы архива с медиа по путям из result.json. Архив может содержать
+// папку выгрузки целиком, поэтому файлы доступны и без первого каталога в пути
+func mediaIndex(archive *zip.Reader) map[string]*zip.File {
+	index := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		name := path.Clean(file.Name)
+		index[name] = file
+		if _, rest, ok := strings.Cut(name, "/"); ok {
+			if _, exists := index[rest]; !exists {
+				index[rest] = file
+			}
+		}
+	}
+
+	return index
+}
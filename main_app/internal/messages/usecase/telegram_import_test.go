@@ -0,0 +1,314 @@
+package usecase
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/messages/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadTelegramExport(t *testing.T) {
+	tests := []struct {
+		name             string
+		export           string
+		expectedName     string
+		expectedMessages []int64
+		expectedError    error
+	}{
+		{
+			name: "выгрузка чата",
+			export: `{
+				"name": "Друзья",
+				"type": "private_group",
+				"id": 42,
+				"messages": [
+					{"id": 1, "type": "service", "action": "create_group"},
+					{"id": 2, "type": "message", "from": "Mia", "from_id": "user1", "text": "привет"}
+				]
+			}`,
+			expectedName:     "Друзья",
+			expectedMessages: []int64{1, 2},
+		},
+		{
+			name:             "название null",
+			export:           `{"name": null, "messages": []}`,
+			expectedMessages: []int64{},
+		},
+		{
+			name:             "сообщения раньше названия",
+			export:           `{"messages": [{"id": 7, "type": "message"}], "name": "Чат"}`,
+			expectedName:     "Чат",
+			expectedMessages: []int64{7},
+		},
+		{
+			name:          "нет сообщений",
+			export:        `{"name": "Чат"}`,
+			expectedError: ErrBadTelegramExport,
+		},
+		{
+			name:          "выгрузка всех чатов",
+			export:        `{"chats": {"list": []}}`,
+			expectedError: ErrBadTelegramExport,
+		},
+		{
+			name:          "не объект",
+			export:        `[]`,
+			expectedError: ErrBadTelegramExport,
+		},
+		{
+			name:          "сообщения не массив",
+			export:        `{"messages": {}}`,
+			expectedError: ErrBadTelegramExport,
+		},
+		{
+			name:          "обрезанный файл",
+			export:        `{"name": "Чат", "messages": [{"id": 1`,
+			expectedError: ErrBadTelegramExport,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := ""
+			messages := []int64{}
+			err := readTelegramExport(strings.NewReader(tt.export), func(n string) {
+				name = n
+			}, func(message telegramMessage) error {
+				messages = append(messages, message.Id)
+				return nil
+			})
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			if tt.expectedError != nil {
+				return
+			}
+
+			assert.Equal(t, tt.expectedName, name)
+			assert.Equal(t, tt.expectedMessages, messages)
+		})
+	}
+}
+
+func TestReadTelegramExportStopsOnError(t *testing.T) {
+	errStop := errors.New("stop")
+	count := 0
+
+	err := readTelegramExport(strings.NewReader(`{"messages": [{"id": 1}, {"id": 2}]}`), func(string) {},
+		func(telegramMessage) error {
+			count++
+			return errStop
+		})
+
+	assert.ErrorIs(t, err, errStop)
+	// после ошибки следующие сообщения не читаются
+	assert.Equal(t, 1, count)
+}
+
+func TestTelegramTime(t *testing.T) {
+	tests := []struct {
+		name          string
+		unix          string
+		local         string
+		expected      time.Time
+		expectedError bool
+	}{
+		{
+			name:     "время в unix",
+			unix:     "1713000600",
+			local:    "2024-04-13T12:30:00",
+			expected: time.Unix(1713000600, 0).UTC(),
+		},
+		{
+			name:     "старая выгрузка без unix",
+			local:    "2024-04-13T08:30:00",
+			expected: time.Date(2024, 4, 13, 8, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "некорректный unix",
+			unix:     "abc",
+			local:    "2024-04-13T08:30:00",
+			expected: time.Date(2024, 4, 13, 8, 30, 0, 0, time.UTC),
+		},
+		{
+			name:          "нет даты",
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := telegramTime(tt.unix, tt.local)
+
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, got.Equal(tt.expected), "ожидалось %v, получено %v", tt.expected, got)
+		})
+	}
+}
+
+func TestTelegramText(t *testing.T) {
+	tests := []struct {
+		name             string
+		message          string
+		expectedText     string
+		expectedEntities []models.MessageEntity
+	}{
+		{
+			name:             "текст строкой",
+			message:          `{"text": "привет"}`,
+			expectedText:     "привет",
+			expectedEntities: []models.MessageEntity{},
+		},
+		{
+			name: "text_entities важнее text",
+			message: `{
+				"text": "устарело",
+				"text_entities": [
+					{"type": "plain", "text": "смотри "},
+					{"type": "bold", "text": "тут"}
+				]
+			}`,
+			expectedText: "смотри тут",
+			expectedEntities: []models.MessageEntity{
+				{Type: models.EntityBold, Offset: 7, Length: 3},
+			},
+		},
+		{
+			name:         "старый формат массивом",
+			message:      `{"text": ["😀 ", {"type": "italic", "text": "да"}, "!"]}`,
+			expectedText: "😀 да!",
+			expectedEntities: []models.MessageEntity{
+				{Type: models.EntityItalic, Offset: 3, Length: 2},
+			},
+		},
+		{
+			name: "ссылки",
+			message: `{"text_entities": [
+				{"type": "text_link", "text": "сайт", "href": "https://example.com"},
+				{"type": "plain", "text": " "},
+				{"type": "link", "text": "https://go.dev"},
+				{"type": "plain", "text": " "},
+				{"type": "link", "text": "example.org"}
+			]}`,
+			expectedText: "сайт https://go.dev example.org",
+			expectedEntities: []models.MessageEntity{
+				{Type: models.EntityLink, Offset: 0, Length: 4, URL: ptr("https://example.com")},
+				{Type: models.EntityLink, Offset: 5, Length: 14, URL: ptr("https://go.dev")},
+			},
+		},
+		{
+			name: "блок кода с языком",
+			message: `{"text_entities": [
+				{"type": "pre", "text": "fmt.Println()", "language": "go"}
+			]}`,
+			expectedText: "fmt.Println()",
+			expectedEntities: []models.MessageEntity{
+				{Type: models.EntityPre, Offset: 0, Length: 13, Language: ptr("go")},
+			},
+		},
+		{
+			name: "неподдерживаемая разметка остается текстом",
+			message: `{"text_entities": [
+				{"type": "hashtag", "text": "#go"},
+				{"type": "underline", "text": " и "},
+				{"type": "bold", "text": ""}
+			]}`,
+			expectedText:     "#go и ",
+			expectedEntities: []models.MessageEntity{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var message telegramMessage
+			if !assert.NoError(t, json.Unmarshal([]byte(tt.message), &message)) {
+				return
+			}
+
+			text, entities := telegramText(message)
+
+			assert.Equal(t, tt.expectedText, text)
+			assert.Equal(t, tt.expectedEntities, entities)
+		})
+	}
+}
+
+func TestTelegramMedia(t *testing.T) {
+	tests := []struct {
+		name             string
+		message          telegramMessage
+		expectedPath     string
+		expectedFilename string
+	}{
+		{
+			name:             "фото",
+			message:          telegramMessage{Photo: "photos/photo_1.jpg"},
+			expectedPath:     "photos/photo_1.jpg",
+			expectedFilename: "photo_1.jpg",
+		},
+		{
+			name:             "файл с именем",
+			message:          telegramMessage{File: "files/doc.pdf", FileName: "Отчет.pdf"},
+			expectedPath:     "files/doc.pdf",
+			expectedFilename: "Отчет.pdf",
+		},
+		{
+			name:    "без медиа",
+			message: telegramMessage{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mediaPath, filename := telegramMedia(tt.message)
+
+			assert.Equal(t, tt.expectedPath, mediaPath)
+			assert.Equal(t, tt.expectedFilename, filename)
+		})
+	}
+}
+
+func TestMediaIndex(t *testing.T) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, name := range []string{"ChatExport_2024-04-13/", "ChatExport_2024-04-13/photos/photo_1.jpg", "files/doc.pdf"} {
+		_, err := archive.Create(name)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, archive.Close())
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	index := mediaIndex(reader)
+
+	tests := []struct {
+		name     string
+		path     string
+		expected bool
+	}{
+		{name: "путь из result.json внутри папки выгрузки", path: "photos/photo_1.jpg", expected: true},
+		{name: "полный путь в архиве", path: "ChatExport_2024-04-13/photos/photo_1.jpg", expected: true},
+		{name: "файл в корне архива", path: "files/doc.pdf", expected: true},
+		{name: "каталог не индексируется", path: "ChatExport_2024-04-13", expected: false},
+		{name: "нет в архиве", path: "photos/photo_2.jpg", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := index[tt.path]
+			assert.Equal(t, tt.expected, ok)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package delivery
+
+import (
+	"context"
+	"html"
+	"net/http"
+	"time"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/metric"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/responser"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/stickers/models"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/utils/validator"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+//go:generate mockgen -source=handlers.go -destination=mocks/mocks.go
+
+type usecase interface {
+	GetStickerPacks(ctx context.Context) ([]models.StickerPack, error)
+}
+
+type Delivery struct {
+	usecase usecase
+}
+
+func New(usecase usecase) *Delivery {
+	return &Delivery{
+		usecase: usecase,
+	}
+}
+
+func init() {
+	prometheus.MustRegister(requestStickerDuration)
+	log := logger.LoggerWithCtx(context.Background(), logger.Log)
+	log.Info("Метрики для стикеров зарегистрированы")
+}
+
+var requestStickerDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name: "request_sticker_duration_seconds",
+	},
+	[]string{"method"},
+)
+
+// GetStickerPacksHandler godoc
+// @Summary Get all sticker packs
+// @Description Get all sticker packs with their stickers.
+// @Tags stickers
+// @Produce json
+// @Success 200 {object} models.GetStickerPacksRespDTO "Sticker packs found"
+// @Failure 401 {object} responser.ErrorResponse "Unauthorized"
+// @Failure 500 {object} responser.ErrorResponse "Failed to get sticker packs"
+// @Router /stickerpacks [get]
+func (d *Delivery) GetStickerPacksHandler(w http.ResponseWriter, r *http.Request) {
+	metric.IncHit()
+	start := time.Now()
+	defer func() {
+		metric.WriteRequestDuration(start, requestStickerDuration, "GetStickerPacksHandler")
+	}()
+
+	ctx := r.Context()
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+
+	log.Println("пришел запрос на получение стикерпаков")
+
+	packs, err := d.usecase.GetStickerPacks(ctx)
+	if err != nil {
+		responser.SendError(ctx, w, "Failed to get sticker packs", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.GetStickerPacksRespDTO{
+		Packs: []models.StickerPackRespDTO{},
+	}
+
+	for _, pack := range packs {
+		response.Packs = append(response.Packs, convertStickerPackToDTO(pack))
+	}
+
+	if err := validator.Check(response); err != nil {
+		log.Errorf("выходные данные не прошли проверку валидации: %v", err)
+		responser.SendError(ctx, w, "Invalid data", http.StatusBadRequest)
+		return
+	}
+
+	log.Println("стикерпаки успешно отправлены")
+
+	responser.SendStruct(ctx, w, response, http.StatusOK)
+}
+
+func convertStickerPackToDTO(pack models.StickerPack) models.StickerPackRespDTO {
+	stickers := []models.StickerRespDTO{}
+
+	for _, sticker := range pack.Stickers {
+		stickers = append(stickers, models.StickerRespDTO{
+			ID:   sticker.ID,
+			Path: html.EscapeString(sticker.Path),
+		})
+	}
+
+	return models.StickerPackRespDTO{
+		ID:          pack.ID,
+		Name:        html.EscapeString(pack.Name),
+		PreviewPath: validator.EscapePtrString(pack.PreviewPath),
+		Stickers:    stickers,
+	}
+}
@@ -0,0 +1,80 @@
+package delivery_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/stickers/delivery"
+	mock_usecase "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/stickers/delivery/mocks"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/stickers/models"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetStickerPacksHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockUsecase := mock_usecase.NewMockusecase(ctrl)
+	delivery := delivery.New(mockUsecase)
+
+	tests := []struct {
+		name               string
+		prepareMock        func()
+		expectedStatusCode int
+		expectedPacks      int
+	}{
+		{
+			name: "success",
+			prepareMock: func() {
+				packs := []models.StickerPack{
+					{
+						ID:   uuid.New().String(),
+						Name: "Котики",
+						Stickers: []models.Sticker{
+							{ID: uuid.New().String(), Path: "/uploads/sticker/1.png"},
+						},
+					},
+				}
+
+				mockUsecase.EXPECT().
+					GetStickerPacks(gomock.Any()).
+					Return(packs, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedPacks:      1,
+		},
+		{
+			name: "usecase error",
+			prepareMock: func() {
+				mockUsecase.EXPECT().
+					GetStickerPacks(gomock.Any()).
+					Return(nil, errors.New("db is down"))
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.prepareMock()
+
+			req := httptest.NewRequest(http.MethodGet, "/stickerpacks", bytes.NewBuffer(nil))
+			recorder := httptest.NewRecorder()
+
+			delivery.GetStickerPacksHandler(recorder, req)
+
+			assert.Equal(t, tt.expectedStatusCode, recorder.Code)
+
+			if tt.expectedStatusCode == http.StatusOK {
+				var response models.GetStickerPacksRespDTO
+				assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+				assert.Len(t, response.Packs, tt.expectedPacks)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: handlers.go
+
+// Package mock_delivery is a generated GoMock package.
+package mock_delivery
+
+import (
+	context "context"
+	reflect "reflect"
+
+	models "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/stickers/models"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// Mockusecase is a mock of usecase interface.
+type Mockusecase struct {
+	ctrl     *gomock.Controller
+	recorder *MockusecaseMockRecorder
+}
+
+// MockusecaseMockRecorder is the mock recorder for Mockusecase.
+type MockusecaseMockRecorder struct {
+	mock *Mockusecase
+}
+
+// NewMockusecase creates a new mock instance.
+func NewMockusecase(ctrl *gomock.Controller) *Mockusecase {
+	mock := &Mockusecase{ctrl: ctrl}
+	mock.recorder = &MockusecaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *Mockusecase) EXPECT() *MockusecaseMockRecorder {
+	return m.recorder
+}
+
+// GetStickerPacks mocks base method.
+func (m *Mockusecase) GetStickerPacks(ctx context.Context) ([]models.StickerPack, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStickerPacks", ctx)
+	ret0, _ := ret[0].([]models.StickerPack)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStickerPacks indicates an expected call of GetStickerPacks.
+func (mr *MockusecaseMockRecorder) GetStickerPacks(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStickerPacks", reflect.TypeOf((*Mockusecase)(nil).GetStickerPacks), ctx)
+}
@@ -0,0 +1,46 @@
+package models
+
+import "github.com/google/uuid"
+
+// @Schema
+type StickerRespDTO struct {
+	ID   string `json:"id" example:"08a0f350-e122-467b-8ba8-524d2478b56e" valid:"uuid"`
+	Path string `json:"path" example:"/uploads/sticker/642c5a57-ebc7-49d0-ac2d-f2f1f474bee7.png" valid:"-"`
+}
+
+// @Schema
+type StickerPackRespDTO struct {
+	ID          string           `json:"id" example:"08a0f350-e122-467b-8ba8-524d2478b56e" valid:"uuid"`
+	Name        string           `json:"name" example:"Котики" valid:"-"`
+	PreviewPath *string          `json:"previewPath" example:"/uploads/sticker/642c5a57-ebc7-49d0-ac2d-f2f1f474bee7.png" valid:"-"`
+	Stickers    []StickerRespDTO `json:"stickers" valid:"-"`
+}
+
+// @Schema
+type GetStickerPacksRespDTO struct {
+	Packs []StickerPackRespDTO `json:"packs" valid:"-"`
+}
+
+type Sticker struct {
+	ID   string
+	Path string
+}
+
+type StickerPack struct {
+	ID          string
+	Name        string
+	PreviewPath *string
+	Stickers    []Sticker
+}
+
+type StickerDAO struct {
+	ID   uuid.UUID `json:"id"`
+	Path string    `json:"path"`
+}
+
+type StickerPackDAO struct {
+	ID          uuid.UUID
+	Name        string
+	PreviewPath *string
+	Stickers    []StickerDAO
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/stickers/models"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+func New(pool *pgxpool.Pool) *Repository {
+	return &Repository{
+		pool: pool,
+	}
+}
+
+func (r *Repository) GetStickerPacks(ctx context.Context) ([]models.StickerPackDAO, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		log.Errorf("Не удалось соединиться с базой данных: %v", err)
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(
+		ctx,
+		`SELECT
+			sp.id,
+			sp.name,
+			sp.preview_path,
+			COALESCE((
+				SELECT json_agg(json_build_object(
+					'id', s.id,
+					'path', s.sticker_path
+				) ORDER BY s.position)
+				FROM public.sticker AS s
+				WHERE s.pack_id = sp.id
+			), '[]'::json)
+		FROM public.sticker_pack AS sp
+		ORDER BY sp.name;`,
+	)
+	if err != nil {
+		log.Errorf("Не удалось получить стикерпаки: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var packs []models.StickerPackDAO
+
+	for rows.Next() {
+		var pack models.StickerPackDAO
+
+		if err = rows.Scan(&pack.ID, &pack.Name, &pack.PreviewPath, &pack.Stickers); err != nil {
+			log.Errorf("Не удалось получить стикерпаки: %v", err)
+			return nil, err
+		}
+		packs = append(packs, pack)
+	}
+
+	log.Println("стикерпаки получены")
+
+	return packs, nil
+}
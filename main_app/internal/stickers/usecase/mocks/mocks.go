@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: usecase.go
+
+// Package mock_usecase is a generated GoMock package.
+package mock_usecase
+
+import (
+	context "context"
+	reflect "reflect"
+
+	models "github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/stickers/models"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockRepository is a mock of Repository interface.
+type MockRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRepositoryMockRecorder
+}
+
+// MockRepositoryMockRecorder is the mock recorder for MockRepository.
+type MockRepositoryMockRecorder struct {
+	mock *MockRepository
+}
+
+// NewMockRepository creates a new mock instance.
+func NewMockRepository(ctrl *gomock.Controller) *MockRepository {
+	mock := &MockRepository{ctrl: ctrl}
+	mock.recorder = &MockRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetStickerPacks mocks base method.
+func (m *MockRepository) GetStickerPacks(ctx context.Context) ([]models.StickerPackDAO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStickerPacks", ctx)
+	ret0, _ := ret[0].([]models.StickerPackDAO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStickerPacks indicates an expected call of GetStickerPacks.
+func (mr *MockRepositoryMockRecorder) GetStickerPacks(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStickerPacks", reflect.TypeOf((*MockRepository)(nil).GetStickerPacks), ctx)
+}
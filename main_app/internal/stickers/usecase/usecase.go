@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/main_app/internal/stickers/models"
+)
+
+//go:generate mockgen -source=usecase.go -destination=mocks/mocks.go
+
+type Repository interface {
+	GetStickerPacks(ctx context.Context) ([]models.StickerPackDAO, error)
+}
+
+type Usecase struct {
+	repo Repository
+}
+
+func New(repo Repository) *Usecase {
+	return &Usecase{
+		repo: repo,
+	}
+}
+
+func (u *Usecase) GetStickerPacks(ctx context.Context) ([]models.StickerPack, error) {
+	log := logger.LoggerWithCtx(ctx, logger.Log)
+
+	packsDAO, err := u.repo.GetStickerPacks(ctx)
+	if err != nil {
+		log.Errorf("не удалось получить стикерпаки: %v", err)
+		return nil, err
+	}
+	log.Println("данные получены")
+
+	var packs []models.StickerPack
+
+	for _, packDAO := range packsDAO {
+		packs = append(packs, convertStickerPackFromDAO(packDAO))
+	}
+
+	return packs, nil
+}
+
+func convertStickerPackFromDAO(pack models.StickerPackDAO) models.StickerPack {
+	stickers := []models.Sticker{}
+
+	for _, sticker := range pack.Stickers {
+		stickers = append(stickers, models.Sticker{
+			ID:   sticker.ID.String(),
+			Path: sticker.Path,
+		})
+	}
+
+	return models.StickerPack{
+		ID:          pack.ID.String(),
+		Name:        pack.Name,
+		PreviewPath: pack.PreviewPath,
+		Stickers:    stickers,
+	}
+}
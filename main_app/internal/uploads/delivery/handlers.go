@@ -32,6 +32,13 @@ func (d *Delivery) GetImage(w http.ResponseWriter, r *http.Request) {
 	folder := vars["folder"]
 	name := vars["name"]
 
+	// выгрузки истории чатов отдаются только их владельцам через /exports/{exportId}/file,
+	// загруженные для импорта файлы наружу не отдаются вообще
+	if folder == "export" || folder == "import" {
+		http.NotFound(w, r)
+		return
+	}
+
 	imagePath := "/uploads/" + folder + "/" + name
 
 	log.Println("пришел запрос на получение картинки ", imagePath)
@@ -0,0 +1,292 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ограничения на превью
+const (
+	maxRedirects  = 5
+	maxTitleRunes = 300
+	maxDescRunes  = 1000
+)
+
+var (
+	ErrBadURL          = errors.New("ссылка должна быть http или https")
+	ErrForbiddenTarget = errors.New("ссылка ведет во внутреннюю сеть")
+	ErrNotHTML         = errors.New("ссылка ведет не на html страницу")
+	ErrNoPreview       = errors.New("на странице нет данных для превью")
+)
+
+// urlPattern ссылка в тексте сообщения. Знаки препинания в конце отрезаются в FindURL
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// Preview метаданные страницы из OpenGraph, Twitter card или <title>
+type Preview struct {
+	URL         string
+	Title       string
+	Description string
+	Image       string
+	SiteName    string
+}
+
+//go:generate mockgen -source=linkpreview.go -destination=mocks/mocks.go
+
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) (Preview, error)
+}
+
+type fetcher struct {
+	client      *http.Client
+	maxBodySize int64
+}
+
+// NewFetcher client определяет, куда можно ходить. Для настоящих запросов это NewSafeClient,
+// в тестах - клиент httptest сервера. Читается не больше maxBodySize байт страницы
+func NewFetcher(client *http.Client, maxBodySize int64) Fetcher {
+	return &fetcher{
+		client:      client,
+		maxBodySize: maxBodySize,
+	}
+}
+
+// NewSafeClient http клиент, который не подключается к локальным и приватным адресам.
+// Адрес проверяется после резолва, поэтому его не обойти ни DNS, ни редиректом
+func NewSafeClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			ip := net.ParseIP(host)
+			if ip == nil || IsForbiddenIP(ip) {
+				return fmt.Errorf("%w: %s", ErrForbiddenTarget, host)
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("больше %d редиректов", maxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return ErrBadURL
+			}
+			return nil
+		},
+	}
+}
+
+// forbiddenNets служебные диапазоны, для которых в net.IP нет отдельной проверки
+var forbiddenNets = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),     // "эта сеть", в linux 0.x.x.x ведет на локальную машину
+	mustParseCIDR("100.64.0.0/10"), // провайдерский NAT
+	mustParseCIDR("198.18.0.0/15"), // тестирование сетевого оборудования
+	mustParseCIDR("240.0.0.0/4"),   // зарезервировано, включая broadcast
+	mustParseCIDR("64:ff9b::/96"),  // NAT64, внутри может быть любой ipv4 адрес
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return ipNet
+}
+
+// IsForbiddenIP адреса, на которые нельзя ходить с сервера
+func IsForbiddenIP(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		inForbiddenNets(ip)
+}
+
+func inForbiddenNets(ip net.IP) bool {
+	for _, ipNet := range forbiddenNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindURL первая ссылка в тексте или пустая строка
+func FindURL(text string) string {
+	found := urlPattern.FindString(text)
+	return strings.TrimRight(found, ".,!?;:)]}'")
+}
+
+func (f *fetcher) Fetch(ctx context.Context, rawURL string) (Preview, error) {
+	pageURL, err := url.Parse(rawURL)
+	if err != nil || (pageURL.Scheme != "http" && pageURL.Scheme != "https") || pageURL.Host == "" {
+		return Preview{}, ErrBadURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL.String(), nil)
+	if err != nil {
+		return Preview{}, err
+	}
+	req.Header.Set("Accept", "text/html")
+	req.Header.Set("User-Agent", "PatefonLinkPreview/1.0")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return Preview{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Preview{}, fmt.Errorf("страница ответила %d", resp.StatusCode)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return Preview{}, ErrNotHTML
+	}
+
+	// после редиректов относительные ссылки считаются от итоговой страницы
+	preview := parseHead(io.LimitReader(resp.Body, f.maxBodySize), resp.Request.URL)
+	preview.URL = rawURL
+
+	if preview.Title == "" && preview.Description == "" && preview.Image == "" {
+		return Preview{}, ErrNoPreview
+	}
+
+	return preview, nil
+}
+
+// parseHead читает метаданные до начала <body>. OpenGraph важнее Twitter card, а она - <title>
+func parseHead(body io.Reader, pageURL *url.URL) Preview {
+	og := map[string]string{}
+	twitter := map[string]string{}
+	var title string
+
+	tokenizer := html.NewTokenizer(body)
+	inTitle := false
+
+loop:
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			// конец страницы или лимит размера
+			break loop
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.DataAtom {
+			case atom.Body:
+				break loop
+			case atom.Title:
+				inTitle = title == ""
+			case atom.Meta:
+				key, content := metaAttrs(token)
+				switch {
+				case strings.HasPrefix(key, "og:"):
+					if _, ok := og[key]; !ok {
+						og[key] = content
+					}
+				case strings.HasPrefix(key, "twitter:"):
+					if _, ok := twitter[key]; !ok {
+						twitter[key] = content
+					}
+				}
+			}
+		case html.TextToken:
+			if inTitle {
+				title = string(tokenizer.Text())
+				inTitle = false
+			}
+		case html.EndTagToken:
+			if tokenizer.Token().DataAtom == atom.Head {
+				break loop
+			}
+		}
+	}
+
+	preview := Preview{
+		Title:       truncate(firstNonEmpty(og["og:title"], twitter["twitter:title"], title), maxTitleRunes),
+		Description: truncate(firstNonEmpty(og["og:description"], twitter["twitter:description"]), maxDescRunes),
+		SiteName:    truncate(og["og:site_name"], maxTitleRunes),
+	}
+
+	image := firstNonEmpty(og["og:image"], og["og:image:url"], twitter["twitter:image"], twitter["twitter:image:src"])
+	if image != "" {
+		preview.Image = resolveImage(pageURL, image)
+	}
+
+	return preview
+}
+
+// metaAttrs OpenGraph задается через property, Twitter card - через name
+func metaAttrs(token html.Token) (string, string) {
+	var key, content string
+	for _, attr := range token.Attr {
+		switch strings.ToLower(attr.Key) {
+		case "property", "name":
+			if key == "" {
+				key = strings.ToLower(strings.TrimSpace(attr.Val))
+			}
+		case "content":
+			content = attr.Val
+		}
+	}
+	return key, content
+}
+
+// resolveImage картинка может быть указана относительно страницы. Ссылки не на http(s) отбрасываются
+func resolveImage(pageURL *url.URL, image string) string {
+	imageURL, err := pageURL.Parse(strings.TrimSpace(image))
+	if err != nil || (imageURL.Scheme != "http" && imageURL.Scheme != "https") {
+		return ""
+	}
+	return imageURL.String()
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func truncate(text string, maxRunes int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= maxRunes {
+		return text
+	}
+	return string([]rune(text)[:maxRunes]) + "…"
+}
@@ -0,0 +1,193 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testMaxBody = 64 * 1024
+
+func newTestServer(contentType string, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		fmt.Fprint(w, body)
+	}))
+}
+
+func TestFetch(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        Preview
+		wantErr     error
+	}{
+		{
+			name:        "OpenGraph",
+			contentType: "text/html; charset=utf-8",
+			body: `<html><head>
+				<title>Заголовок страницы</title>
+				<meta property="og:title" content="Котики">
+				<meta property="og:description" content="Много котиков">
+				<meta property="og:image" content="/img/cat.png">
+				<meta property="og:site_name" content="Кошачий сайт">
+				</head><body><meta property="og:title" content="не отсюда"></body></html>`,
+			want: Preview{
+				Title:       "Котики",
+				Description: "Много котиков",
+				Image:       "/img/cat.png",
+				SiteName:    "Кошачий сайт",
+			},
+		},
+		{
+			name:        "Twitter card и title",
+			contentType: "text/html",
+			body: `<html><head>
+				<title>  Заголовок
+				страницы </title>
+				<meta name="twitter:description" content="Описание">
+				<meta name="twitter:image" content="https://cdn.example.com/a.png">
+				</head></html>`,
+			want: Preview{
+				Title:       "Заголовок страницы",
+				Description: "Описание",
+				Image:       "https://cdn.example.com/a.png",
+			},
+		},
+		{
+			name:        "Картинка не http",
+			contentType: "text/html",
+			body:        `<head><meta property="og:title" content="t"><meta property="og:image" content="javascript:alert(1)"></head>`,
+			want:        Preview{Title: "t"},
+		},
+		{
+			name:        "Нет метаданных",
+			contentType: "text/html",
+			body:        `<html><head></head><body>текст</body></html>`,
+			wantErr:     ErrNoPreview,
+		},
+		{
+			name:        "Не html",
+			contentType: "application/json",
+			body:        `{"title": "t"}`,
+			wantErr:     ErrNotHTML,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(tt.contentType, tt.body)
+			defer server.Close()
+
+			preview, err := NewFetcher(server.Client(), testMaxBody).Fetch(context.Background(), server.URL+"/page")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			if strings.HasPrefix(tt.want.Image, "/") {
+				tt.want.Image = server.URL + tt.want.Image
+			}
+			tt.want.URL = server.URL + "/page"
+			assert.Equal(t, tt.want, preview)
+		})
+	}
+}
+
+func TestFetchBodyLimit(t *testing.T) {
+	// метаданные лежат после лимита и не должны быть прочитаны
+	body := "<html><head><!--" + strings.Repeat("x", testMaxBody) + `--><meta property="og:title" content="t"></head></html>`
+	server := newTestServer("text/html", body)
+	defer server.Close()
+
+	_, err := NewFetcher(server.Client(), testMaxBody).Fetch(context.Background(), server.URL)
+	assert.ErrorIs(t, err, ErrNoPreview)
+}
+
+func TestFetchTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	client := server.Client()
+	client.Timeout = 50 * time.Millisecond
+
+	_, err := NewFetcher(client, testMaxBody).Fetch(context.Background(), server.URL)
+	assert.Error(t, err)
+}
+
+func TestFetchBadURL(t *testing.T) {
+	fetcher := NewFetcher(http.DefaultClient, testMaxBody)
+
+	for _, rawURL := range []string{"ftp://example.com", "file:///etc/passwd", "http://", "не ссылка"} {
+		_, err := fetcher.Fetch(context.Background(), rawURL)
+		assert.ErrorIs(t, err, ErrBadURL, rawURL)
+	}
+}
+
+func TestSafeClientRejectsLocalServer(t *testing.T) {
+	server := newTestServer("text/html", `<head><meta property="og:title" content="t"></head>`)
+	defer server.Close()
+
+	_, err := NewFetcher(NewSafeClient(time.Second), testMaxBody).Fetch(context.Background(), server.URL)
+	assert.True(t, errors.Is(err, ErrForbiddenTarget), "получили %v", err)
+}
+
+func TestIsForbiddenIP(t *testing.T) {
+	tests := []struct {
+		name      string
+		ip        string
+		forbidden bool
+	}{
+		{name: "loopback", ip: "127.0.0.1", forbidden: true},
+		{name: "приватная сеть 10/8", ip: "10.1.2.3", forbidden: true},
+		{name: "приватная сеть 172.16/12", ip: "172.16.0.1", forbidden: true},
+		{name: "приватная сеть 192.168/16", ip: "192.168.1.1", forbidden: true},
+		{name: "метаданные облака", ip: "169.254.169.254", forbidden: true},
+		{name: "провайдерский NAT", ip: "100.64.0.1", forbidden: true},
+		{name: "неуказанный адрес", ip: "0.0.0.0", forbidden: true},
+		{name: "сеть 0/8", ip: "0.1.2.3", forbidden: true},
+		{name: "тестирование оборудования", ip: "198.18.0.1", forbidden: true},
+		{name: "конец тестового диапазона", ip: "198.19.255.254", forbidden: true},
+		{name: "зарезервированный диапазон", ip: "240.0.0.1", forbidden: true},
+		{name: "broadcast", ip: "255.255.255.255", forbidden: true},
+		{name: "ipv6 loopback", ip: "::1", forbidden: true},
+		{name: "ipv6 unique local", ip: "fc00::1", forbidden: true},
+		{name: "ipv6 link local", ip: "fe80::1", forbidden: true},
+		{name: "NAT64 с приватным адресом", ip: "64:ff9b::a00:1", forbidden: true},
+		{name: "NAT64 с loopback", ip: "64:ff9b::127.0.0.1", forbidden: true},
+		{name: "ipv4 в ipv6 записи", ip: "::ffff:198.18.0.1", forbidden: true},
+
+		{name: "публичный dns", ip: "8.8.8.8"},
+		{name: "публичный адрес", ip: "212.233.98.59"},
+		{name: "рядом с тестовым диапазоном", ip: "198.20.0.1"},
+		{name: "последний адрес перед резервом", ip: "223.255.255.254"},
+		{name: "публичный ipv6", ip: "2a00:1450:4010:c0e::65"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.forbidden, IsForbiddenIP(net.ParseIP(tt.ip)), tt.ip)
+		})
+	}
+}
+
+func TestFindURL(t *testing.T) {
+	assert.Equal(t, "https://example.com/a?b=1", FindURL("смотри https://example.com/a?b=1, классно"))
+	assert.Equal(t, "http://example.com", FindURL("(http://example.com)"))
+	assert.Equal(t, "", FindURL("ссылок нет, только example.com"))
+}
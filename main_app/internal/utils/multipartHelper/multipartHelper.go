@@ -5,6 +5,9 @@ import (
 	"io"
 	"mime/multipart"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/disintegration/imaging"
 	"github.com/google/uuid"
@@ -55,6 +58,51 @@ func SavePhoto(file multipart.File, folderName string) (string, error) {
 	return path, nil
 }
 
+var extRegexp = regexp.MustCompile(`^\.[a-z0-9]{1,10}$`)
+
+// SaveFile сохраняет произвольный файл в папку folderName, сохраняя расширение исходного файла
+func SaveFile(file multipart.File, originalName string, folderName string) (string, error) {
+	return SaveStream(file, originalName, folderName)
+}
+
+// SaveStream то же, что SaveFile, для файлов не из формы, например из архива
+func SaveStream(file io.Reader, originalName string, folderName string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(originalName))
+	if !extRegexp.MatchString(ext) {
+		ext = ""
+	}
+
+	dir := uploadPath + folderName
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	path := dir + "/" + uuid.NewString() + ext
+
+	dst, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer dst.Close()
+
+	_, err = io.Copy(dst, file)
+	if err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// CreateFile создает пустой файл со случайным именем в папке folderName для потоковой записи
+func CreateFile(folderName string, ext string) (*os.File, error) {
+	dir := uploadPath + folderName
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return os.Create(dir + "/" + uuid.NewString() + ext)
+}
+
 func RewritePhoto(file multipart.File, photoURL string) error {
 	dst, err := os.Create(photoURL)
 	if err != nil {
@@ -79,6 +127,10 @@ func RemovePhoto(photoURL string) error {
 	return nil
 }
 
+func RemoveFile(path string) error {
+	return os.Remove(path)
+}
+
 func IsImageFile(file multipart.File) bool {
 	img, err := imaging.Decode(file)
 	if err != nil {
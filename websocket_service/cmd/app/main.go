@@ -39,7 +39,7 @@ func main() {
 	log.Println("rebbit mq подключен")
 
 	socketUsecase := usecase.NewWebsocketUsecase(ch, host, port)
-	socketDelivery := delivery.NewWebsocket(*socketUsecase)
+	socketDelivery := delivery.NewWebsocket(socketUsecase)
 
 	router := mux.NewRouter()
 
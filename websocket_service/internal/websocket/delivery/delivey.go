@@ -2,7 +2,6 @@ package delivery
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
 	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/metric"
@@ -40,10 +39,10 @@ var upgrader = websocket.Upgrader{
 }
 
 type Webcosket struct {
-	usecase websocketUsecase.WebsocketUsecase
+	usecase *websocketUsecase.WebsocketUsecase
 }
 
-func NewWebsocket(usecase websocketUsecase.WebsocketUsecase) Webcosket {
+func NewWebsocket(usecase *websocketUsecase.WebsocketUsecase) Webcosket {
 	return Webcosket{
 		usecase: usecase,
 	}
@@ -77,20 +76,34 @@ func (h *Webcosket) HandleConnection(w http.ResponseWriter, r *http.Request) {
 
 		return
 	}
+	// остальные устройства пользователя остаются подключенными
+	defer h.usecase.RemoveConnection(user.ID, eventChannel)
+
+	// клиент ничего не присылает, чтение нужно только чтобы заметить закрытие соединения
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
 
 	// пока соеденено
-	duration := 500 * time.Millisecond
-
 	for {
 		select {
 		case message := <-eventChannel:
 			// запись новых сообщений
 			log.Println("Message delivery websocket: получены новые сообщения")
 
-			conn.WriteJSON(message.Event)
+			if err := conn.WriteJSON(message.Event); err != nil {
+				log.Printf("Message delivery websocket: не удалось отправить событие: %v", err)
+				return
+			}
 
-		default:
-			time.Sleep(duration)
+		case <-closed:
+			return
 		}
 	}
 }
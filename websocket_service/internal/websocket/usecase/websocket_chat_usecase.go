@@ -19,8 +19,9 @@ type ChatEventMain struct {
 }
 
 type ChatEvent struct {
-	ChatId uuid.UUID   `json:"chatId"`
-	Users  []uuid.UUID `json:"users"`
+	ChatId    uuid.UUID   `json:"chatId"`
+	Users     []uuid.UUID `json:"users"`
+	MessageId *uuid.UUID  `json:"messageId,omitempty"`
 }
 
 // consumeChats принимает информацию об изменении чатов
@@ -111,7 +112,7 @@ func (w *WebsocketUsecase) getOnlineUsersInChat(chatId uuid.UUID) (map[uuid.UUID
 		}
 
 		// если пользователь онлайн, то добавляем его в чат
-		if _, ok := w.onlineUsers[userUUID]; ok {
+		if w.isOnline(userUUID) {
 			onlineUsersInChat[userUUID] = struct{}{}
 		}
 	}
@@ -125,6 +126,8 @@ const (
 	NewChat             = "newChat"
 	DeleteUsersFromChat = "delUsers"
 	AddNewUsersInChat   = "addUsers"
+	PinMessage          = "pinMessage"
+	UnpinMessage        = "unpinMessage"
 
 	// пользователь стал онлайн
 	AddWebcosketUser = "addWebSocketUser"
@@ -160,14 +163,14 @@ func (w *WebsocketUsecase) chatBroker(chatId uuid.UUID) {
 			go w.sendEventToAllUsers(users, newEvent)
 			delete(w.onlineChats, chatId)
 			return
-		case NewChat, UpdateChat:
+		case NewChat, UpdateChat, PinMessage, UnpinMessage:
 			go w.sendEventToAllUsers(users, newEvent)
 		case DeleteUsersFromChat:
 			w.sendEventToDeletedUsers(newEvent.Users, newEvent)
 			go w.sendEventToAllUsers(users, newEvent)
 			// удаляем юзеров, если они были в подписчиках
 			for _, userId := range newEvent.Users {
-				if _, ok := w.onlineUsers[userId]; ok {
+				if w.isOnline(userId) {
 					delete(users, userId)
 				}
 			}
@@ -175,7 +178,7 @@ func (w *WebsocketUsecase) chatBroker(chatId uuid.UUID) {
 			log.Infof("Добавляем новыйх подписчиков на брокер чата %v", chatId)
 			// если пользователь онлайны, то добавляем в подписчики
 			for _, userId := range newEvent.Users {
-				if _, ok := w.onlineUsers[userId]; ok {
+				if w.isOnline(userId) {
 					users[userId] = struct{}{}
 				}
 			}
@@ -187,18 +190,19 @@ func (w *WebsocketUsecase) chatBroker(chatId uuid.UUID) {
 func (w *WebsocketUsecase) sendEventToAllUsers(users map[uuid.UUID]struct{}, event chatEvent.Event) {
 	log := logger.LoggerWithCtx(context.Background(), logger.Log)
 	for userId := range users {
-		if _, ok := w.onlineUsers[userId]; ok {
+		if w.isOnline(userId) {
 			log.Infof("Отправляем ивент пользователю %v", userId)
-			w.onlineUsers[userId] <- AnyEvent{
+			w.sendToUser(userId, AnyEvent{
 				TypeOfEvent: Chat,
 				Event: ChatEventMain{
 					Action: event.Action,
 					Payload: ChatEvent{
-						ChatId: event.ChatId,
-						Users:  event.Users,
+						ChatId:    event.ChatId,
+						Users:     event.Users,
+						MessageId: event.MessageId,
 					},
 				},
-			}
+			})
 		}
 	}
 }
@@ -213,11 +217,9 @@ func (w *WebsocketUsecase) sendEventToDeletedUsers(users []uuid.UUID, event chat
 	event.Users = nil
 
 	for _, userId := range users {
-		if _, ok := w.onlineUsers[userId]; ok {
-			w.onlineUsers[userId] <- AnyEvent{
-				TypeOfEvent: Chat,
-				Event:       event,
-			}
-		}
+		w.sendToUser(userId, AnyEvent{
+			TypeOfEvent: Chat,
+			Event:       event,
+		})
 	}
 }
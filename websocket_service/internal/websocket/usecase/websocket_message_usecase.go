@@ -6,17 +6,42 @@ import (
 
 	messageModel "github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/events"
 	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
+	"github.com/google/uuid"
 )
 
 type MessageEvent struct {
 	Action  string               `json:"action"`
 	Message messageModel.Message `json:"payload"`
+	// если задано, то событие получат только эти участники чата
+	Recipients []uuid.UUID `json:"recipients,omitempty"`
 }
 
 const (
 	DeleteMessage = "deleteMessage"
 	NewMessage    = "newMessage"
 	UpdateMessage = "updateMessage"
+	// изменились реакции на сообщении
+	ReactionUpdated = "reactionUpdated"
+	// участник прочитал сообщения чата
+	MessagesRead = "messagesRead"
+	// пользователь удалил сообщение только у себя
+	MessageHidden = "messageHidden"
+	// пользователя упомянули в сообщении
+	Mention = "mention"
+	// изменились результаты опроса
+	PollUpdated = "pollUpdated"
+	// пользователь изменил черновик чата
+	DraftUpdated = "draftUpdated"
+	// несколько сообщений чата удалены одним запросом
+	DeleteMessages = "deleteMessages"
+	// пользователь удалил у себя несколько сообщений
+	MessagesHidden = "messagesHidden"
+	// изменились просмотры постов канала
+	ViewsUpdated = "viewsUpdated"
+	// появился ответ в ветке сообщения
+	BranchUpdated = "branchUpdated"
+	// сообщение с nonce сохранено, приходит только автору
+	MessageSent = "messageSent"
 )
 
 func SerializeMessageEvent(event MessageEvent) ([]byte, error) {
@@ -70,10 +95,20 @@ func (w *WebsocketUsecase) sendMessage(event MessageEvent) {
 	chatId := event.Message.ChatId
 	users := w.onlineChats[chatId].users
 
+	if len(event.Recipients) > 0 {
+		recipients := make(map[uuid.UUID]struct{}, len(event.Recipients))
+		for _, user := range event.Recipients {
+			if _, ok := users[user]; ok {
+				recipients[user] = struct{}{}
+			}
+		}
+		users = recipients
+	}
+
 	for user := range users {
-		w.onlineUsers[user] <- AnyEvent{
+		w.sendToUser(user, AnyEvent{
 			TypeOfEvent: Message,
 			Event:       event,
-		}
+		})
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net"
 	"strconv"
+	"sync"
 
 	chatModels "github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/events"
 	"github.com/go-park-mail-ru/2024_2_EaglesDesigner/global_utils/logger"
@@ -31,8 +32,10 @@ type WebsocketUsecase struct {
 	ch *amqp.Channel
 	// мапа с чатами и каналами для ивентов по чатам
 	onlineChats map[uuid.UUID]ChatInfo
-	// мапа с онлайн пользователями и
-	onlineUsers    map[uuid.UUID]chan AnyEvent
+	// онлайн пользователи и их соединения: у пользователя может быть открыто несколько устройств.
+	// Каждому каналу событий соответствует канал, закрываемый при отключении устройства
+	onlineUsers    map[uuid.UUID]map[chan AnyEvent]chan struct{}
+	usersMu        sync.RWMutex
 	chatRepository grpcChat.ChatServiceClient
 }
 
@@ -78,7 +81,7 @@ func NewWebsocketUsecase(ch *amqp.Channel, host string, port int) *WebsocketUsec
 	socket := &WebsocketUsecase{
 		ch:             ch,
 		onlineChats:    map[uuid.UUID]ChatInfo{},
-		onlineUsers:    map[uuid.UUID]chan AnyEvent{},
+		onlineUsers:    map[uuid.UUID]map[chan AnyEvent]chan struct{}{},
 		chatRepository: grpcClient,
 	}
 
@@ -97,8 +100,14 @@ func (w *WebsocketUsecase) InitBrokersForUser(userId uuid.UUID, eventChannel cha
 		return err
 	}
 
-	w.onlineUsers[userId] = eventChannel
-	log.Infof("Пользователь %v онлайн", userId)
+	w.usersMu.Lock()
+	if _, ok := w.onlineUsers[userId]; !ok {
+		w.onlineUsers[userId] = map[chan AnyEvent]chan struct{}{}
+	}
+	w.onlineUsers[userId][eventChannel] = make(chan struct{})
+	connections := len(w.onlineUsers[userId])
+	w.usersMu.Unlock()
+	log.Infof("Пользователь %v онлайн, открытых соединений: %d", userId, connections)
 
 	// Добавляем в брокеры пользователей
 	for _, chatId := range chats.ChatIds {
@@ -117,3 +126,47 @@ func (w *WebsocketUsecase) InitBrokersForUser(userId uuid.UUID, eventChannel cha
 	}
 	return nil
 }
+
+// RemoveConnection отключает устройство пользователя. Остальные его устройства продолжают получать события
+func (w *WebsocketUsecase) RemoveConnection(userId uuid.UUID, eventChannel chan AnyEvent) {
+	log := logger.LoggerWithCtx(context.Background(), logger.Log)
+
+	w.usersMu.Lock()
+	defer w.usersMu.Unlock()
+
+	connections := w.onlineUsers[userId]
+	if done, ok := connections[eventChannel]; ok {
+		close(done)
+		delete(connections, eventChannel)
+	}
+	if len(connections) == 0 {
+		delete(w.onlineUsers, userId)
+		log.Infof("Пользователь %v офлайн", userId)
+	}
+}
+
+func (w *WebsocketUsecase) isOnline(userId uuid.UUID) bool {
+	w.usersMu.RLock()
+	defer w.usersMu.RUnlock()
+
+	_, ok := w.onlineUsers[userId]
+	return ok
+}
+
+// sendToUser отправляет событие во все соединения пользователя. Отключившееся
+// во время отправки устройство пропускается, а не блокирует отправку остальным
+func (w *WebsocketUsecase) sendToUser(userId uuid.UUID, event AnyEvent) {
+	w.usersMu.RLock()
+	connections := make(map[chan AnyEvent]chan struct{}, len(w.onlineUsers[userId]))
+	for eventChannel, done := range w.onlineUsers[userId] {
+		connections[eventChannel] = done
+	}
+	w.usersMu.RUnlock()
+
+	for eventChannel, done := range connections {
+		select {
+		case eventChannel <- event:
+		case <-done:
+		}
+	}
+}